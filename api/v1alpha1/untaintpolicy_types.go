@@ -0,0 +1,168 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Condition types and reasons reported on UntaintPolicy status. The reasons
+// are a stable taxonomy shared by the node conditions, the events, the logs and
+// the metrics of the operator, so automation can key off them.
+const (
+	// ConditionUntainted reports whether the target taints have been removed from a node
+	ConditionUntainted = "Untainted"
+	// ConditionBlockedNodes reports whether any matching node is blocked, with
+	// the most common blocking reason and the node blocked the longest
+	ConditionBlockedNodes = "BlockedNodes"
+
+	// ReasonNodeNotReady means the node itself is not ready or its network is
+	// not available yet
+	ReasonNodeNotReady = "NodeNotReady"
+	// ReasonNodeTooNew means the node has not existed for the configured
+	// minimum age yet
+	ReasonNodeTooNew = "NodeTooNew"
+	// ReasonKubeletLeaseStale means the node's kubelet has not renewed its
+	// Lease recently enough
+	ReasonKubeletLeaseStale = "KubeletLeaseStale"
+	// ReasonNodeProblem means one of the configured blocking conditions, such as
+	// those reported by node-problem-detector, is True on the node
+	ReasonNodeProblem = "NodeProblem"
+	// ReasonCNINotReady means the custom resources of the network plugin don't
+	// report the node's datapath ready yet
+	ReasonCNINotReady = "CNINotReady"
+	// ReasonWaitingForWorkload means no pod of a required workload runs on the node yet
+	ReasonWaitingForWorkload = "WaitingForWorkload"
+	// ReasonWorkloadUnready means a pod of a required workload is not ready yet
+	ReasonWorkloadUnready = "WorkloadUnready"
+	// ReasonWorkloadStabilizing means a pod of a required workload is ready but
+	// has not been ready for the configured minimum time yet
+	ReasonWorkloadStabilizing = "WorkloadStabilizing"
+	// ReasonWorkloadOutdated means a pod of a required DaemonSet still runs a
+	// previous revision of its template while the DaemonSet rolls out
+	ReasonWorkloadOutdated = "WorkloadOutdated"
+	// ReasonCSIDriverNotRegistered means a CSI driver the rule requires has not
+	// registered in the node's CSINode yet
+	ReasonCSIDriverNotRegistered = "CSIDriverNotRegistered"
+	// ReasonResourceNotAdvertised means the node doesn't advertise the
+	// allocatable resources the rule requires yet, e.g. before its device
+	// plugin registered
+	ReasonResourceNotAdvertised = "ResourceNotAdvertised"
+	// ReasonResourceNotReady means a resource a resource gate of the rule checks
+	// doesn't exist or doesn't report the node ready yet
+	ReasonResourceNotReady = "ResourceNotReady"
+	// ReasonProbeFailed means a ready pod of a required workload failed the
+	// rule's active probe
+	ReasonProbeFailed = "ProbeFailed"
+	// ReasonCanaryNotReady means the canary pod scheduled onto the node has not
+	// become ready or passed its probe yet
+	ReasonCanaryNotReady = "CanaryNotReady"
+	// ReasonUntaintPaused means the untaint windows pause untainting right now
+	ReasonUntaintPaused = "UntaintPaused"
+	// ReasonRateLimited means the workloads are ready but the untaint rate
+	// limit was reached
+	ReasonRateLimited = "RateLimited"
+	// ReasonUntaintVetoed means the pre-untaint hook vetoed removing a target
+	// taint, or failed to answer
+	ReasonUntaintVetoed = "UntaintVetoed"
+	// ReasonCoolingDown means a target taint was re-added shortly after the
+	// operator removed it and is ignored until the cooldown ends
+	ReasonCoolingDown = "CoolingDown"
+	// ReasonTaintDowngraded means the workloads are ready and a target taint is
+	// being stepped down to weaker effects before its removal
+	ReasonTaintDowngraded = "TaintDowngraded"
+	// ReasonTaintRemoved means all target taints were removed from the node
+	ReasonTaintRemoved = "TaintRemoved"
+	// ReasonUpdateConflict means the node changed while the operator wrote to
+	// it, or another field manager owns its taints
+	ReasonUpdateConflict = "UpdateConflict"
+	// ReasonReverted means a target taint was put back on the node after the
+	// operator removed it
+	ReasonReverted = "Reverted"
+	// ReasonNoNodesBlocked means no matching node is blocked
+	ReasonNoNodesBlocked = "NoNodesBlocked"
+)
+
+// UntaintPolicySpec defines the desired state of UntaintPolicy
+type UntaintPolicySpec struct {
+}
+
+// NodeStatus describes why a single node still carries a target taint
+type NodeStatus struct {
+	// Name is the name of the node
+	Name string `json:"name"`
+	// Conditions describe the state of the node's target taints
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// UntaintPolicyStatus defines the observed state of UntaintPolicy
+type UntaintPolicyStatus struct {
+	// MatchingNodes is the number of nodes that carry, or carried, a target taint
+	// +optional
+	MatchingNodes int32 `json:"matchingNodes"`
+	// UntaintedNodes is the number of matching nodes whose target taints were removed
+	// +optional
+	UntaintedNodes int32 `json:"untaintedNodes"`
+	// BlockedNodes is the number of matching nodes still waiting on their workloads
+	// +optional
+	BlockedNodes int32 `json:"blockedNodes"`
+	// Nodes lists the blocked nodes and the reason each one is blocked
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Nodes []NodeStatus `json:"nodes,omitempty"`
+	// Conditions roll the blocking reasons of the nodes up, for alerting on
+	// the policy as a whole
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Matching",type=integer,JSONPath=`.status.matchingNodes`
+// +kubebuilder:printcolumn:name="Untainted",type=integer,JSONPath=`.status.untaintedNodes`
+// +kubebuilder:printcolumn:name="Blocked",type=integer,JSONPath=`.status.blockedNodes`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// UntaintPolicy is the Schema for the untaintpolicies API. The operator
+// publishes the progress of its taint rules in the status of this object.
+type UntaintPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   UntaintPolicySpec   `json:"spec,omitempty"`
+	Status UntaintPolicyStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// UntaintPolicyList contains a list of UntaintPolicy
+type UntaintPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []UntaintPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&UntaintPolicy{}, &UntaintPolicyList{})
+}
@@ -0,0 +1,310 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// kubectl-untaint is a kubectl plugin to inspect and steer the operator:
+//
+//	kubectl untaint blocked [node]          list the blocked nodes and why, from the status API
+//	kubectl untaint force <node>            remove the target taints of a node now
+//	kubectl untaint pause|resume [node...]  pause or resume untainting of nodes, or of every node
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/jslay88/generic-untaint-operator/internal/config"
+	"github.com/jslay88/generic-untaint-operator/internal/controller"
+	"github.com/jslay88/generic-untaint-operator/internal/untaintctl"
+)
+
+const usage = `Usage: kubectl untaint <command> [flags] [args]
+
+Commands:
+  blocked [node]          List the nodes keeping their target taints and why, from the status API
+  force <node>            Remove the target taints of a node, whatever the operator's rules say
+  pause [node...]         Pause untainting of the nodes, or of every node through the operator's ConfigMap
+  resume [node...]        Resume untainting of the nodes, or of every node through the operator's ConfigMap
+
+Run kubectl untaint <command> -h for the flags of a command.
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	var err error
+	switch command, args := os.Args[1], os.Args[2:]; command {
+	case "blocked":
+		err = runBlocked(ctx, args)
+	case "force":
+		err = runForce(ctx, args)
+	case "pause":
+		err = runPause(ctx, args, true)
+	case "resume":
+		err = runPause(ctx, args, false)
+	case "-h", "--help", "help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", command, usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(0)
+		}
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+// clusterFlags select the cluster to talk to, as kubectl does
+type clusterFlags struct {
+	kubeconfig string
+	context    string
+}
+
+func (f *clusterFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.kubeconfig, "kubeconfig", "", "Path to the kubeconfig file, KUBECONFIG or ~/.kube/config when unset")
+	fs.StringVar(&f.context, "context", "", "The kubeconfig context to use")
+}
+
+// restConfig loads the client configuration
+func (f *clusterFlags) restConfig() (*rest.Config, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = f.kubeconfig
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: f.context}
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides).ClientConfig()
+}
+
+// client returns a client of the cluster
+func (f *clusterFlags) client() (client.Client, error) {
+	restConfig, err := f.restConfig()
+	if err != nil {
+		return nil, err
+	}
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
+	return client.New(restConfig, client.Options{Scheme: scheme})
+}
+
+// apiFlags reach the operator's status API
+type apiFlags struct {
+	clusterFlags
+	url      string
+	token    string
+	caFile   string
+	insecure bool
+}
+
+func (f *apiFlags) register(fs *flag.FlagSet) {
+	f.clusterFlags.register(fs)
+	fs.StringVar(&f.url, "api-url", os.Getenv("UNTAINT_API_URL"),
+		"Base URL of the operator's status API, e.g. https://localhost:8444 behind kubectl port-forward")
+	fs.StringVar(&f.token, "token", os.Getenv("UNTAINT_API_TOKEN"),
+		"Bearer token for the status API, the token of the kubeconfig when unset")
+	fs.StringVar(&f.caFile, "certificate-authority", "", "CA certificate file verifying the status API")
+	fs.BoolVar(&f.insecure, "insecure-skip-tls-verify", false,
+		"Don't verify the status API certificate, e.g. when it is self-signed")
+}
+
+// statusClient returns a client of the status API
+func (f *apiFlags) statusClient() (*untaintctl.StatusClient, error) {
+	if f.url == "" {
+		return nil, errors.New("--api-url is required to reach the status API")
+	}
+	token := f.token
+	if token == "" {
+		if restConfig, err := f.restConfig(); err == nil {
+			token = restConfig.BearerToken
+		}
+	}
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12, InsecureSkipVerify: f.insecure}
+	if f.caFile != "" {
+		ca, err := os.ReadFile(f.caFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificate found in %s", f.caFile)
+		}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	return &untaintctl.StatusClient{URL: f.url, Token: token, HTTP: &http.Client{Transport: transport}}, nil
+}
+
+// runBlocked lists the blocked nodes, or the block of one node
+func runBlocked(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("blocked", flag.ContinueOnError)
+	var flags apiFlags
+	flags.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	status, err := flags.statusClient()
+	if err != nil {
+		return err
+	}
+
+	var nodes []controller.BlockedNode
+	switch fs.NArg() {
+	case 0:
+		nodes, err = status.BlockedNodes(ctx)
+	case 1:
+		var node *controller.BlockedNode
+		if node, err = status.BlockedNode(ctx, fs.Arg(0)); err == nil {
+			nodes = append(nodes, *node)
+		}
+	default:
+		return errors.New("blocked takes at most one node")
+	}
+	if err != nil {
+		return err
+	}
+	if len(nodes) == 0 {
+		fmt.Println("No blocked nodes")
+		return nil
+	}
+	return untaintctl.PrintBlocked(os.Stdout, nodes)
+}
+
+// runForce removes the target taints of a node, given with --taint or read
+// from the status API
+func runForce(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("force", flag.ContinueOnError)
+	var flags apiFlags
+	flags.register(fs)
+	var taints stringSliceValue
+	fs.Var(&taints, "taint", "Key of a taint to remove; may be repeated. "+
+		"The target taints the node keeps are read from the status API when unset.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("force takes exactly one node")
+	}
+	node := fs.Arg(0)
+
+	keys := taints.values
+	if len(keys) == 0 {
+		status, err := flags.statusClient()
+		if err != nil {
+			return fmt.Errorf("%w, or name the taints with --taint", err)
+		}
+		blocked, err := status.BlockedNode(ctx, node)
+		if err != nil {
+			return err
+		}
+		keys = blocked.Taints
+	}
+	c, err := flags.client()
+	if err != nil {
+		return err
+	}
+	removed, err := untaintctl.ForceUntaint(ctx, c, node, keys)
+	if err != nil {
+		return err
+	}
+	if len(removed) == 0 {
+		fmt.Printf("node/%s carries none of the taints %s\n", node, strings.Join(keys, ", "))
+		return nil
+	}
+	fmt.Printf("node/%s untainted: removed %s\n", node, strings.Join(removed, ", "))
+	return nil
+}
+
+// runPause pauses or resumes untainting of the given nodes, or of every node
+// through the operator's ConfigMap when none is given
+func runPause(ctx context.Context, args []string, paused bool) error {
+	command, verb := "resume", "resumed"
+	if paused {
+		command, verb = "pause", "paused"
+	}
+	fs := flag.NewFlagSet(command, flag.ContinueOnError)
+	var flags clusterFlags
+	flags.register(fs)
+	configMap := fs.String("config-map", os.Getenv("UNTAINT_CONFIG_MAP"),
+		"Namespace/name of the operator's ConfigMap, edited when no node is given")
+	configMapKey := fs.String("config-map-key", config.DefaultConfigMapKey,
+		"The ConfigMap data key holding the YAML config")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	var cm types.NamespacedName
+	if fs.NArg() == 0 {
+		namespace, name, ok := strings.Cut(*configMap, "/")
+		if !ok || namespace == "" || name == "" {
+			return errors.New("name nodes, or the operator's ConfigMap in namespace/name form with --config-map")
+		}
+		cm = types.NamespacedName{Namespace: namespace, Name: name}
+	}
+	c, err := flags.client()
+	if err != nil {
+		return err
+	}
+
+	if fs.NArg() > 0 {
+		for _, node := range fs.Args() {
+			if err := untaintctl.SetNodePaused(ctx, c, node, paused); err != nil {
+				return err
+			}
+			fmt.Printf("node/%s %s\n", node, verb)
+		}
+		return nil
+	}
+	if err := untaintctl.SetPaused(ctx, c, cm, *configMapKey, paused); err != nil {
+		return err
+	}
+	fmt.Printf("untainting %s through configmap/%s\n", verb, cm.Name)
+	return nil
+}
+
+// stringSliceValue is a flag.Value collecting every occurrence of a repeatable flag
+type stringSliceValue struct {
+	values []string
+}
+
+func (s *stringSliceValue) String() string {
+	return strings.Join(s.values, ",")
+}
+
+func (s *stringSliceValue) Set(value string) error {
+	s.values = append(s.values, value)
+	return nil
+}
@@ -17,26 +17,68 @@ limitations under the License.
 package main
 
 import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
 	"flag"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
 	"os"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
+	uberzap "go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/client-go/util/flowcontrol"
+	"k8s.io/utils/ptr"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/metrics/filters"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 
+	untaintv1alpha1 "github.com/jslay88/generic-untaint-operator/api/v1alpha1"
+	"github.com/jslay88/generic-untaint-operator/internal/asg"
+	"github.com/jslay88/generic-untaint-operator/internal/config"
 	"github.com/jslay88/generic-untaint-operator/internal/controller"
+	"github.com/jslay88/generic-untaint-operator/internal/version"
 	// +kubebuilder:scaffold:imports
 )
 
+// leaderElectionID names the leader election lease by default
+const leaderElectionID = "generic-untaint-operator-leader-election"
+
+// inClusterNamespacePath holds the namespace the operator runs in, where
+// controller-runtime puts the leader election lease
+const inClusterNamespacePath = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
 var (
 	scheme   = runtime.NewScheme()
 	setupLog = ctrl.Log.WithName("setup")
@@ -45,16 +87,73 @@ var (
 func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 
+	utilruntime.Must(untaintv1alpha1.AddToScheme(scheme))
+
 	// +kubebuilder:scaffold:scheme
 }
 
 func main() {
 	var (
-		metricsAddr          string
-		enableLeaderElection bool
-		probeAddr            string
-		targetTaint          string
-		ownedByNames         string
+		metricsAddr           string
+		enableLeaderElection  bool
+		electionName          string
+		electionNamespace     string
+		electionLock          string
+		probeAddr             string
+		secureMetrics         bool
+		metricsCertDir        string
+		debugAddr             string
+		apiAddr               string
+		apiCertDir            string
+		targetTaints          stringSliceValue
+		ownedBy               stringSliceValue
+		ownedByNames          string
+		configFile            string
+		configMap             string
+		configMapKey          string
+		statusPolicy          string
+		statusInterval        string
+		statusMaxNodes        string
+		largeCluster          bool
+		cloudEventsSink       string
+		eventBus              string
+		otlpEndpoint          string
+		statsdAddress         string
+		statsdFormat          string
+		auditLog              string
+		logLevel              string
+		logFormat             string
+		concurrency           string
+		prioritizeNewNodes    bool
+		resyncPeriod          string
+		shutdownTimeout       string
+		shardCount            string
+		shardIndex            string
+		nodeSelector          string
+		podLabelSelector      string
+		podNamespaces         string
+		uncachedPods          bool
+		kubeAPIQPS            string
+		kubeAPIBurst          string
+		maxNodeWrites         string
+		maxReconciles         string
+		readinessMode         string
+		requireInitContainers bool
+		requireNodeReady      bool
+		requireNetwork        bool
+		recordUntaint         bool
+		paused                bool
+		nodeConditionType     string
+		removalStrategy       string
+		forceApply            bool
+		presets               string
+		profile               string
+		karpenter             bool
+		clusterAPI            bool
+		cni                   string
+		skipRemediation       bool
+		printVersion          bool
+		tuning                tuningFlagValues
 	)
 
 	// Read from environment variables first, fall back to command line flags
@@ -70,6 +169,37 @@ func main() {
 		getEnvOrDefault("HEALTH_PROBE_BIND_ADDRESS", ":8081"),
 		"The address the probe endpoint binds to.",
 	)
+	flag.BoolVar(
+		&secureMetrics,
+		"metrics-secure",
+		getEnvOrDefault("METRICS_SECURE", "false") == "true",
+		"Serve the metrics over HTTPS, to clients authenticated and authorized against the Kubernetes API.",
+	)
+	flag.StringVar(
+		&metricsCertDir,
+		"metrics-cert-dir",
+		getEnvOrDefault("METRICS_CERT_DIR", ""),
+		"Directory holding the tls.crt and tls.key of the secure metrics endpoint. "+
+			"A self-signed certificate is used when empty.",
+	)
+	flag.StringVar(
+		&debugAddr,
+		"debug-bind-address",
+		getEnvOrDefault("DEBUG_BIND_ADDRESS", ""),
+		"The address the debug endpoint listing blocked nodes binds to. Not served when empty.",
+	)
+	flag.StringVar(
+		&apiAddr,
+		"api-bind-address",
+		getEnvOrDefault("API_BIND_ADDRESS", ""),
+		"The address the authenticated, read-only status API binds to. Not served when empty.",
+	)
+	flag.StringVar(
+		&apiCertDir,
+		"api-cert-dir",
+		getEnvOrDefault("API_CERT_DIR", ""),
+		"Directory holding the tls.crt and tls.key of the status API. A self-signed certificate is used when empty.",
+	)
 	flag.BoolVar(
 		&enableLeaderElection,
 		"leader-elect",
@@ -77,64 +207,713 @@ func main() {
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
 	flag.StringVar(
-		&targetTaint,
+		&electionName,
+		"leader-election-id",
+		getEnvOrDefault("LEADER_ELECTION_ID", leaderElectionID),
+		"Name of the leader election lease, so differently configured instances of the operator elect "+
+			"their leaders independently",
+	)
+	flag.StringVar(
+		&electionNamespace,
+		"leader-election-namespace",
+		getEnvOrDefault("LEADER_ELECTION_NAMESPACE", ""),
+		"Namespace of the leader election lease. The namespace the operator runs in when empty.",
+	)
+	flag.StringVar(
+		&electionLock,
+		"leader-election-resource-lock",
+		getEnvOrDefault("LEADER_ELECTION_RESOURCE_LOCK", resourcelock.LeasesResourceLock),
+		"Type of the resource the leader election lock is held on. Only leases are supported.",
+	)
+	envErr := loadRuleFlagsFromEnv(&targetTaints, &ownedBy)
+	flag.Var(
+		&targetTaints,
 		"target-taint",
-		os.Getenv("TARGET_TAINT"),
-		"The taint key to watch for and remove",
+		"A taint key to watch for and remove. May be repeated to manage several taints.",
+	)
+	flag.Var(
+		&ownedBy,
+		"owned-by",
+		"A workload name to check for readiness. May be repeated; the value is used verbatim.",
+	)
+	flag.StringVar(
+		&presets,
+		"presets",
+		os.Getenv("PRESETS"),
+		"Comma-separated built-in rules for well-known startup taints, e.g. cilium,ebs-csi. One of: "+
+			strings.Join(config.PresetNames(), ", ")+".",
+	)
+	flag.StringVar(
+		&profile,
+		"profile",
+		os.Getenv("PROFILE"),
+		"Built-in profile of a managed Kubernetes offering, adding rules for its bootstrap taints and gating "+
+			"rules without --owned-by on its agents. One of: "+strings.Join(config.ProfileNames(), ", ")+".",
 	)
 	flag.StringVar(
 		&ownedByNames,
 		"owned-by-names",
 		os.Getenv("OWNED_BY_NAMES"),
-		"Comma-separated list of workload names to check for readiness",
+		"Comma-separated list of workload names to check for readiness. Deprecated: use --owned-by.",
 	)
-	opts := zap.Options{
-		Development: true,
-	}
+	flag.StringVar(
+		&configFile,
+		"config",
+		os.Getenv("CONFIG_FILE"),
+		"Path to a YAML config file with taint rules. The file is reloaded when it changes.",
+	)
+	flag.StringVar(
+		&configMap,
+		"config-map",
+		os.Getenv("CONFIG_MAP"),
+		"Namespace/name of a ConfigMap with taint rules. The ConfigMap is watched for changes.",
+	)
+	flag.StringVar(
+		&configMapKey,
+		"config-map-key",
+		getEnvOrDefault("CONFIG_MAP_KEY", config.DefaultConfigMapKey),
+		"The ConfigMap data key holding the YAML config",
+	)
+	flag.StringVar(
+		&statusPolicy,
+		"status-policy",
+		os.Getenv("STATUS_POLICY"),
+		"Name of the cluster-scoped UntaintPolicy to publish progress to. Status is not published when empty.",
+	)
+	flag.StringVar(
+		&statusInterval,
+		"status-interval",
+		getEnvOrDefault("STATUS_INTERVAL", controller.DefaultStatusInterval.String()),
+		"How often the UntaintPolicy status is written at most, batching the changes in between",
+	)
+	flag.StringVar(
+		&cloudEventsSink,
+		"cloudevents-sink",
+		getEnvOrDefault("CLOUDEVENTS_SINK", os.Getenv("K_SINK")),
+		"URL of a CloudEvents sink receiving NodeUntainted, NodeBlocked and NodeRetainted events. "+
+			"Defaults to K_SINK, as injected by a Knative SinkBinding. No events are sent when empty.",
+	)
+	flag.StringVar(
+		&eventBus,
+		"event-bus",
+		getEnvOrDefault("EVENT_BUS", ""),
+		"Message bus also receiving the CloudEvents, as kafka://broker[,broker...]/topic, nats://server/subject "+
+			"or tls://server/subject for NATS over TLS. No events are sent when empty.",
+	)
+	flag.StringVar(
+		&otlpEndpoint,
+		"otlp-endpoint",
+		getEnvOrDefault("OTLP_ENDPOINT", ""),
+		"URL of an OTLP gRPC endpoint, such as an OpenTelemetry collector, receiving the operator metrics. "+
+			"An http URL connects without TLS. No metrics are pushed when empty.",
+	)
+	flag.StringVar(
+		&statsdAddress,
+		"statsd-address",
+		getEnvOrDefault("STATSD_ADDRESS", ""),
+		"host:port of a StatsD or DogStatsD server receiving the operator metrics over UDP. "+
+			"No metrics are sent when empty.",
+	)
+	flag.StringVar(
+		&statsdFormat,
+		"statsd-format",
+		getEnvOrDefault("STATSD_FORMAT", string(controller.StatsDFormatDogStatsD)),
+		"How metric labels are sent to the StatsD server: dogstatsd sends them as tags, statsd appends "+
+			"their values to the metric names.",
+	)
+	flag.StringVar(
+		&auditLog,
+		"audit-log",
+		getEnvOrDefault("AUDIT_LOG", ""),
+		"File receiving a JSON line for every target taint removed, downgraded or re-added, or - for stdout. "+
+			"Nothing is recorded when empty.",
+	)
+	flag.BoolVar(
+		&karpenter,
+		"karpenter",
+		getEnvOrDefault("KARPENTER", "false") == "true",
+		"Report untaint progress as an Untainted condition on Karpenter NodeClaims and warn about NodePool "+
+			"startupTaints no rule manages. Requires the Karpenter CRDs.",
+	)
+	flag.BoolVar(
+		&clusterAPI,
+		"cluster-api",
+		getEnvOrDefault("CLUSTER_API", "false") == "true",
+		"Report untaint progress as an Untainted condition on the Cluster API Machines backing the nodes. "+
+			"Requires the Cluster API CRDs.",
+	)
+	flag.BoolVar(
+		&skipRemediation,
+		"skip-remediation",
+		getEnvOrDefault("SKIP_REMEDIATION", "false") == "true",
+		"With --cluster-api, annotate the Machine of a node with cluster.x-k8s.io/skip-remediation while its "+
+			"target taints are blocked, so MachineHealthChecks don't remediate nodes waiting on their workloads",
+	)
+	flag.StringVar(
+		&cni,
+		"cni",
+		getEnvOrDefault("CNI", ""),
+		"Network plugin, Cilium or Calico, whose custom resources must report a node's datapath ready "+
+			"before its taints are removed. Requires the plugin's CRDs. Not checked when empty.",
+	)
+	flag.StringVar(
+		&concurrency,
+		"max-concurrent-reconciles",
+		getEnvOrDefault("MAX_CONCURRENT_RECONCILES", "1"),
+		"How many nodes are reconciled in parallel. A node is never reconciled by two workers at once.",
+	)
+	flag.StringVar(
+		&shardCount,
+		"shard-count",
+		getEnvOrDefault("SHARD_COUNT", "1"),
+		"Number of shards the nodes are split into by a hash of their name, each reconciled by its own "+
+			"replicas. Every node is reconciled by every replica when 1.",
+	)
+	flag.StringVar(
+		&shardIndex,
+		"shard-index",
+		getEnvOrDefault("SHARD_INDEX", "0"),
+		"Shard of the nodes this replica reconciles, from 0 to shard-count - 1",
+	)
+	flag.StringVar(
+		&resyncPeriod,
+		"resync-period",
+		getEnvOrDefault("RESYNC_PERIOD", "0"),
+		"How often every node carrying a target taint is re-evaluated even if no event fired for it, "+
+			"guarding against missed watch events. Nodes are only re-evaluated on events and requeues when 0.",
+	)
+	flag.StringVar(
+		&shutdownTimeout,
+		"graceful-shutdown-timeout",
+		getEnvOrDefault("GRACEFUL_SHUTDOWN_TIMEOUT", "25s"),
+		"How long to let in-flight reconciles finish on shutdown before the leader election lease is "+
+			"released. Keep it below the pod's terminationGracePeriodSeconds.",
+	)
+	flag.BoolVar(
+		&prioritizeNewNodes,
+		"prioritize-new-nodes",
+		getEnvOrDefault("PRIORITIZE_NEW_NODES", "false") == "true",
+		"Reconcile the most recently created nodes first, ahead of nodes that have been blocked for a while",
+	)
+	flag.StringVar(
+		&nodeSelector,
+		"node-selector",
+		getEnvOrDefault("NODE_SELECTOR", ""),
+		"Label selector restricting the nodes the operator watches, caches and untaints, e.g. to a node pool. "+
+			"Every node is watched when empty.",
+	)
+	flag.StringVar(
+		&podLabelSelector,
+		"pod-label-selector",
+		getEnvOrDefault("POD_LABEL_SELECTOR", ""),
+		"Label selector restricting the pods the operator watches and caches, e.g. to the pods of the "+
+			"required workloads. Pods it doesn't match never count towards a rule. Every pod is watched when empty.",
+	)
+	flag.StringVar(
+		&podNamespaces,
+		"pod-namespaces",
+		getEnvOrDefault("POD_NAMESPACES", ""),
+		"Comma-separated namespaces the operator watches and caches pods in, e.g. kube-system. Pods "+
+			"outside of them never count towards a rule. Pods of every namespace are watched when empty.",
+	)
+	flag.StringVar(
+		&kubeAPIQPS,
+		"kube-api-qps",
+		getEnvOrDefault("KUBE_API_QPS", "20"),
+		"Sustained queries per second the operator sends to the API server",
+	)
+	flag.StringVar(
+		&kubeAPIBurst,
+		"kube-api-burst",
+		getEnvOrDefault("KUBE_API_BURST", "30"),
+		"Queries the operator may send to the API server at once, above kube-api-qps",
+	)
+	flag.StringVar(
+		&maxNodeWrites,
+		"max-node-writes-per-second",
+		getEnvOrDefault("MAX_NODE_WRITES_PER_SECOND", "0"),
+		"Maximum number of writes per second the operator makes to nodes across all workers, so mass untaints "+
+			"can't overwhelm the API server; 0 disables the cap",
+	)
+	flag.StringVar(
+		&maxReconciles,
+		"max-reconciles-per-second",
+		getEnvOrDefault("MAX_RECONCILES_PER_SECOND", "0"),
+		"Maximum number of nodes reconciled per second across all workers, spreading out the burst of queued "+
+			"nodes an API server restart can cause; 0 disables the cap",
+	)
+	flag.BoolVar(
+		&uncachedPods,
+		"uncached-pods",
+		getEnvOrDefault("UNCACHED_PODS", "false") == "true",
+		"List the pods of each reconciled node from the API server instead of watching and caching every "+
+			"pod, trading API server load for memory. Pod changes are only noticed on the next requeue.",
+	)
+	flag.StringVar(
+		&readinessMode,
+		"readiness-mode",
+		getEnvOrDefault("READINESS_MODE", string(config.ReadinessModePodReady)),
+		"How pod readiness is determined: PodReady (the pod's Ready condition) or Containers "+
+			"(additionally every container, including sidecars, started and ready)",
+	)
+	flag.BoolVar(
+		&requireInitContainers,
+		"require-init-containers",
+		getEnvOrDefault("REQUIRE_INIT_CONTAINERS", "false") == "true",
+		"Additionally require every init container of the target pods, other than sidecars, to have completed",
+	)
+	flag.StringVar(
+		&nodeConditionType,
+		"node-condition-type",
+		os.Getenv("NODE_CONDITION_TYPE"),
+		"Type of a node condition kept True once the target taints are removed and False with the blocking "+
+			"reason until then, e.g. AgentsReady. No condition is written when empty.",
+	)
+	flag.BoolVar(
+		&recordUntaint,
+		"record-untaint",
+		getEnvOrDefault("RECORD_UNTAINT", "false") == "true",
+		"Annotate nodes with who removed their target taints, when, and which pods satisfied the gate",
+	)
+	flag.BoolVar(
+		&paused,
+		"paused",
+		getEnvOrDefault("PAUSED", "false") == "true",
+		"Pause untainting of every node without stopping the operator",
+	)
+	flag.BoolVar(
+		&requireNodeReady,
+		"require-node-ready",
+		getEnvOrDefault("REQUIRE_NODE_READY", "false") == "true",
+		"Additionally require the node's own Ready condition to be True",
+	)
+	flag.BoolVar(
+		&requireNetwork,
+		"require-network-available",
+		getEnvOrDefault("REQUIRE_NETWORK_AVAILABLE", "false") == "true",
+		"Additionally require the node's NetworkUnavailable condition, when reported, to be False",
+	)
+	flag.StringVar(
+		&tuning.csiDrivers,
+		"csi-drivers",
+		getEnvOrDefault("CSI_DRIVERS", ""),
+		"Comma-separated CSI drivers that must be registered in the node's CSINode before the taint is removed",
+	)
+	flag.StringVar(
+		&tuning.requiredResources,
+		"required-resources",
+		getEnvOrDefault("REQUIRED_RESOURCES", ""),
+		"Comma-separated name=quantity allocatable resources, such as nvidia.com/gpu=1, "+
+			"the node must advertise before the taint is removed",
+	)
+	flag.StringVar(
+		&tuning.blockingConditions,
+		"blocking-conditions",
+		getEnvOrDefault("BLOCKING_CONDITIONS", ""),
+		"Comma-separated node condition types, such as KernelDeadlock, that keep the taints while True",
+	)
+	flag.StringVar(
+		&tuning.minReadySeconds,
+		"min-ready-seconds",
+		getEnvOrDefault("MIN_READY_SECONDS", "0"),
+		"How long a target pod must have been ready without interruption before the taint is removed",
+	)
+	flag.StringVar(
+		&removalStrategy,
+		"removal-strategy",
+		getEnvOrDefault("REMOVAL_STRATEGY", string(config.RemovalStrategyPatch)),
+		"How taints are removed: Patch (merge patch of spec.taints), Apply (server-side apply) "+
+			"or JSONPatch (JSON patch testing each entry before removing it)",
+	)
+	flag.BoolVar(
+		&forceApply,
+		"force-apply",
+		getEnvOrDefault("FORCE_APPLY", "false") == "true",
+		"With the Apply removal strategy, take ownership of spec.taints from other field managers",
+	)
+	flag.StringVar(
+		&tuning.requeueInterval,
+		"requeue-interval",
+		getEnvOrDefault("REQUEUE_INTERVAL", config.DefaultRequeueInterval.String()),
+		"How long to wait before re-checking a node whose workloads are not ready yet",
+	)
+	flag.StringVar(
+		&tuning.maxRequeueInterval,
+		"max-requeue-interval",
+		getEnvOrDefault("MAX_REQUEUE_INTERVAL", config.DefaultMaxRequeueInterval.String()),
+		"Cap of the backoff applied to a node that stays blocked by unchanged pods",
+	)
+	flag.StringVar(
+		&tuning.requeueJitter,
+		"requeue-jitter",
+		getEnvOrDefault("REQUEUE_JITTER", strconv.FormatFloat(config.DefaultRequeueJitter, 'f', -1, 64)),
+		"Maximum fraction of the requeue interval added at random to each requeue; 0 disables jitter",
+	)
+	flag.StringVar(
+		&tuning.minNodeAge,
+		"min-node-age",
+		getEnvOrDefault("MIN_NODE_AGE", "0s"),
+		"How long a node must have existed before its taints are removed; 0 disables the check",
+	)
+	flag.StringVar(
+		&tuning.maxLeaseAge,
+		"max-lease-age",
+		getEnvOrDefault("MAX_LEASE_AGE", "0s"),
+		"Require the node's kubelet Lease to have been renewed within this period; 0 disables the check",
+	)
+	flag.StringVar(
+		&tuning.untaintCooldown,
+		"untaint-cooldown",
+		getEnvOrDefault("UNTAINT_COOLDOWN", "0s"),
+		"How long to ignore a target taint re-added to a node after it was removed; 0 disables the cooldown",
+	)
+	flag.StringVar(
+		&tuning.maxNodeUntaints,
+		"max-node-untaints-per-minute",
+		getEnvOrDefault("MAX_NODE_UNTAINTS_PER_MINUTE", "0"),
+		"Maximum number of times the target taints of a single node are removed per minute; 0 disables the cap",
+	)
+	flag.StringVar(
+		&tuning.untaintRateLimit,
+		"untaint-rate-limit",
+		getEnvOrDefault("UNTAINT_RATE_LIMIT", ""),
+		"Maximum number of nodes to untaint per interval, as count/interval such as 5/5m. Unlimited when empty.",
+	)
+	flag.BoolVar(
+		&tuning.spreadZones,
+		"spread-zones",
+		getEnvOrDefault("SPREAD_ZONES", "false") == "true",
+		"Hand the untaints of --untaint-rate-limit out round-robin across the zones of the waiting nodes",
+	)
+	flag.StringVar(
+		&tuning.maxRestarts,
+		"max-restarts",
+		os.Getenv("MAX_RESTARTS"),
+		"Treat a target pod as not ready while a container restarted more than this many times "+
+			"within --restart-window. Disabled when empty.",
+	)
+	flag.StringVar(
+		&tuning.restartWindow,
+		"restart-window",
+		getEnvOrDefault("RESTART_WINDOW", config.DefaultRestartWindow.String()),
+		"The period over which container restarts are counted against --max-restarts",
+	)
+	flag.StringVar(
+		&tuning.stuckNodeThreshold,
+		"stuck-node-threshold",
+		getEnvOrDefault("STUCK_NODE_THRESHOLD", config.DefaultStuckNodeThreshold.String()),
+		"How long a node must have been blocked before the untaint_operator_stuck_nodes metric counts it",
+	)
+	flag.StringVar(
+		&tuning.maxWait,
+		"max-wait",
+		os.Getenv("MAX_WAIT"),
+		"How long a target taint may wait for its workloads before --on-max-wait is taken. Disabled when empty.",
+	)
+	flag.StringVar(
+		&tuning.onMaxWait,
+		"on-max-wait",
+		getEnvOrDefault("ON_MAX_WAIT", string(config.MaxWaitActionEvent)),
+		"What to do once a taint outlived --max-wait: Event (keep the taint and emit a warning event) "+
+			"or ForceRemove (remove the taint anyway)",
+	)
+	flag.StringVar(
+		&tuning.taintEffects,
+		"taint-effects",
+		os.Getenv("TAINT_EFFECTS"),
+		"Comma-separated effects of the target taints to remove; entries with other effects are left in place. "+
+			"Every effect is removed when empty.",
+	)
+	flag.StringVar(
+		&tuning.stagedRemoval,
+		"staged-removal",
+		os.Getenv("STAGED_REMOVAL"),
+		"Comma-separated effect=dwell stages a ready taint is downgraded through before removal, "+
+			"e.g. NoSchedule=30s,PreferNoSchedule=1m. The taint is removed right away when empty.",
+	)
+	flag.StringVar(
+		&tuning.setLabels,
+		"set-labels",
+		os.Getenv("SET_LABELS"),
+		"Comma-separated key=value labels to set on the node when its target taint is removed",
+	)
+	flag.StringVar(
+		&tuning.removeLabels,
+		"remove-labels",
+		os.Getenv("REMOVE_LABELS"),
+		"Comma-separated label keys to remove from the node when its target taint is removed",
+	)
+	flag.StringVar(
+		&tuning.untaintActions,
+		"untaint-actions",
+		os.Getenv("UNTAINT_ACTIONS"),
+		"Comma-separated steps taken, in order, when a target taint is removed: Untaint, Uncordon, Label, "+
+			"Annotate, CompleteLifecycleAction and Notify. Defaults to Untaint plus whatever --uncordon, the label "+
+			"flags, --record-untaint and --aws-lifecycle-hook ask for, followed by Notify.",
+	)
+	flag.StringVar(
+		&tuning.awsLifecycleHook,
+		"aws-lifecycle-hook",
+		os.Getenv("AWS_LIFECYCLE_HOOK"),
+		"Name of the AWS Auto Scaling launch lifecycle hook to complete once a node's target taint is removed. "+
+			"Nothing is completed when empty.",
+	)
+	flag.StringVar(
+		&tuning.preUntaintHook,
+		"pre-untaint-hook",
+		os.Getenv("PRE_UNTAINT_HOOK"),
+		"URL POSTed to before a node's target taints are removed. It answers {\"allowed\": false} to keep them. "+
+			"Not called when empty.",
+	)
+	flag.StringVar(
+		&tuning.postUntaintHook,
+		"post-untaint-hook",
+		os.Getenv("POST_UNTAINT_HOOK"),
+		"URL POSTed to once a node's target taints were removed. Not called when empty.",
+	)
+	flag.StringVar(
+		&tuning.notifyURL,
+		"notify-url",
+		os.Getenv("NOTIFY_URL"),
+		"Slack or generic webhook URL to post to about stuck nodes and removed taints. Nothing is posted when empty.",
+	)
+	flag.StringVar(
+		&tuning.notifyFormat,
+		"notify-format",
+		getEnvOrDefault("NOTIFY_FORMAT", string(config.NotificationFormatSlack)),
+		"Body of the notifications: Slack ({\"text\": message}) or JSON (the message along with its details)",
+	)
+	flag.StringVar(
+		&tuning.notifyStuckAfter,
+		"notify-stuck-after",
+		getEnvOrDefault("NOTIFY_STUCK_AFTER", "0s"),
+		"Post once about a node that kept its target taints this long; 0 disables stuck notifications",
+	)
+	flag.BoolVar(
+		&tuning.notifyOnUntaint,
+		"notify-on-untaint",
+		getEnvOrDefault("NOTIFY_ON_UNTAINT", "false") == "true",
+		"Post whenever target taints are removed from a node",
+	)
+	flag.BoolVar(
+		&tuning.uncordon,
+		"uncordon",
+		getEnvOrDefault("UNCORDON", "false") == "true",
+		"Also uncordon the node (clear spec.unschedulable) when its target taint is removed",
+	)
+	flag.StringVar(
+		&logLevel,
+		"log-level",
+		getEnvOrDefault("LOG_LEVEL", "info"),
+		"Lowest level logged: debug, info, error, or a verbosity of 1 and up to also log debug messages "+
+			"of that verbosity.",
+	)
+	flag.StringVar(
+		&logFormat,
+		"log-format",
+		getEnvOrDefault("LOG_FORMAT", "json"),
+		"Log format: json for centralized logging, or console for humans.",
+	)
+	flag.StringVar(
+		&statusMaxNodes,
+		"status-max-nodes",
+		getEnvOrDefault("STATUS_MAX_NODES", "0"),
+		"Most blocked nodes listed in the policy status, keeping those blocked longest. Every blocked node "+
+			"is listed when 0.",
+	)
+	flag.BoolVar(
+		&largeCluster,
+		"large-cluster",
+		getEnvOrDefault("LARGE_CLUSTER", "false") == "true",
+		"Tune the flags not set explicitly for clusters with thousands of nodes: "+largeClusterSummary(),
+	)
+	flag.BoolVar(&printVersion, "version", false, "Print the version of the operator and exit.")
+	opts := zap.Options{}
 	opts.BindFlags(flag.CommandLine)
 	flag.Parse()
 
+	if err := handleEarlyFlags(printVersion, largeCluster); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if err := setLogOptions(&opts, logLevel, logFormat); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
+	setupLog.Info("generic-untaint-operator", version.KeysAndValues()...)
 
-	if targetTaint == "" {
-		setupLog.Error(nil, "target-taint flag or TARGET_TAINT environment variable is required")
+	if envErr != nil {
+		setupLog.Error(envErr, "invalid environment variable")
+		os.Exit(1)
+	}
+
+	flagConfig := &config.Config{
+		Profile:                 profile,
+		ReadinessMode:           config.ReadinessMode(readinessMode),
+		RequireInitContainers:   requireInitContainers,
+		RequireNodeReady:        requireNodeReady,
+		RequireNetworkAvailable: requireNetwork,
+		RecordUntaint:           recordUntaint,
+		Paused:                  paused,
+		NodeConditionType:       corev1.NodeConditionType(nodeConditionType),
+		RemovalStrategy:         config.RemovalStrategy(removalStrategy),
+		ForceApply:              forceApply,
+	}
+	err := parseConfigSourceFlags(flagConfig, configFile, configMap, targetTaints.values, ownedBy.values,
+		ownedByNames, presets, tuning)
+	if err != nil {
+		setupLog.Error(err, "invalid flags")
+		os.Exit(1)
+	}
+
+	nodeLabelSelector, podSelector, podNamespaceList, err := parseCacheFlags(nodeSelector, podLabelSelector,
+		podNamespaces)
+	if err != nil {
+		setupLog.Error(err, "invalid flags")
+		os.Exit(1)
+	}
+	cacheOptions, configMapName, err := newCacheOptions(configMap, nodeLabelSelector, podSelector, podNamespaceList)
+	if err != nil {
+		setupLog.Error(err, "invalid config-map flag")
+		os.Exit(1)
+	}
+
+	shards, shard, err := parseShardFlags(shardCount, shardIndex)
+	if err != nil {
+		setupLog.Error(err, "invalid flags")
+		os.Exit(1)
+	}
+	if err := parseLeaderElectionFlags(electionName, electionNamespace, electionLock); err != nil {
+		setupLog.Error(err, "invalid flags")
+		os.Exit(1)
+	}
+	electionID := shardLeaderElectionID(electionName, shards, shard)
+	scale, err := parseScaleFlags(resyncPeriod, shutdownTimeout, maxNodeWrites, maxReconciles)
+	if err != nil {
+		setupLog.Error(err, "invalid flags")
 		os.Exit(1)
 	}
 
-	if ownedByNames == "" {
-		setupLog.Error(nil, "owned-by-names flag or OWNED_BY_NAMES environment variable is required")
+	restConfig, err := newRestConfig(kubeAPIQPS, kubeAPIBurst)
+	if err != nil {
+		setupLog.Error(err, "unable to configure the API client")
 		os.Exit(1)
 	}
 
-	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
-		Scheme:                 scheme,
-		Metrics:                metricsserver.Options{BindAddress: metricsAddr},
-		HealthProbeBindAddress: probeAddr,
-		LeaderElection:         enableLeaderElection,
-		LeaderElectionID:       "generic-untaint-operator-leader-election",
+	mgr, err := ctrl.NewManager(restConfig, ctrl.Options{
+		Scheme:                     scheme,
+		Metrics:                    metricsOptions(metricsAddr, secureMetrics, metricsCertDir),
+		HealthProbeBindAddress:     probeAddr,
+		LeaderElection:             enableLeaderElection,
+		LeaderElectionID:           electionID,
+		LeaderElectionNamespace:    electionNamespace,
+		LeaderElectionResourceLock: electionLock,
+		// Hand the lease over as soon as the workers finished their reconciles,
+		// rather than leave the nodes to wait for it to expire. The process
+		// exits right after the manager stops, which makes this safe.
+		LeaderElectionReleaseOnCancel: true,
+		GracefulShutdownTimeout:       &scale.gracefulShutdown,
+		Cache:                         cacheOptions,
+		// Karpenter, Cluster API and CNI objects are read as unstructured and
+		// must come from the cache to be looked up by index
+		Client: client.Options{Cache: &client.CacheOptions{Unstructured: karpenter || clusterAPI || cni != ""}},
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
 		os.Exit(1)
 	}
 
-	if err = (&controller.NodeReconciler{
-		Client:       mgr.GetClient(),
-		Scheme:       mgr.GetScheme(),
-		TargetTaint:  targetTaint,
-		OwnedByNames: strings.Split(ownedByNames, ","),
-	}).SetupWithManager(mgr); err != nil {
+	cfg, err := loadConfig(mgr, configFile, configMapName, configMapKey, flagConfig)
+	if err != nil {
+		setupLog.Error(err, "unable to load config")
+		os.Exit(1)
+	}
+	configStore := config.NewStore(cfg)
+
+	maxConcurrentReconciles, err := concurrentReconciles(concurrency, cfg)
+	if err != nil {
+		setupLog.Error(err, "invalid concurrency")
+		os.Exit(1)
+	}
+
+	if err := setupConfigWatchers(mgr, configStore, configFile, configMapName, configMapKey); err != nil {
+		setupLog.Error(err, "unable to watch the config")
+		os.Exit(1)
+	}
+
+	audit, err := openAuditLog(auditLog)
+	if err != nil {
+		setupLog.Error(err, "unable to open the audit log")
+		os.Exit(1)
+	}
+
+	statusReporter, cloudEvents, err := setupReporters(mgr, statusPolicy, statusInterval, statusMaxNodes,
+		cloudEventsSink, eventBus)
+	if err != nil {
+		setupLog.Error(err, "unable to set up reporters")
+		os.Exit(1)
+	}
+	if err := setupMetricsExporters(mgr, otlpEndpoint, statsdAddress, statsdFormat); err != nil {
+		setupLog.Error(err, "unable to set up metrics exporters")
+		os.Exit(1)
+	}
+	notifier := &controller.Notifier{}
+	if err := mgr.Add(notifier); err != nil {
+		setupLog.Error(err, "unable to set up notifications")
+		os.Exit(1)
+	}
+
+	nodeReconciler := &controller.NodeReconciler{
+		Client:    mgr.GetClient(),
+		Scheme:    mgr.GetScheme(),
+		Config:    configStore,
+		Status:    statusReporter,
+		APIReader: mgr.GetAPIReader(),
+		Recorder:  mgr.GetEventRecorderFor("generic-untaint-operator"),
+
+		CloudEvents:     cloudEvents,
+		Notifier:        notifier,
+		Audit:           audit,
+		Lifecycle:       &asg.Lifecycle{},
+		Karpenter:       karpenter,
+		ClusterAPI:      clusterAPI,
+		SkipRemediation: skipRemediation,
+		CNI:             controller.CNIProvider(cni),
+
+		MaxConcurrentReconciles: maxConcurrentReconciles,
+		PrioritizeNewNodes:      prioritizeNewNodes,
+		ShardCount:              shards,
+		ShardIndex:              shard,
+		ResyncPeriod:            scale.resync,
+		NodeSelector:            nodeLabelSelector,
+		PodSelector:             podSelector,
+		PodNamespaces:           podNamespaceList,
+		UncachedPods:            uncachedPods,
+		NodeWriteLimiter:        scale.nodeWriteLimiter,
+		ReconcileLimiter:        scale.reconcileLimiter,
+	}
+	if err = nodeReconciler.SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Node")
 		os.Exit(1)
 	}
+	if err := setupServers(mgr, nodeReconciler, debugAddr, apiAddr, apiCertDir); err != nil {
+		setupLog.Error(err, "unable to set up debug endpoint or status API")
+		os.Exit(1)
+	}
+	if err := setupKarpenter(mgr, configStore, karpenter); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "NodePool")
+		os.Exit(1)
+	}
 	// +kubebuilder:scaffold:builder
 
-	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
-		setupLog.Error(err, "unable to set up health check")
+	if err := setupLeaderStatus(mgr, enableLeaderElection, electionNamespace, electionID); err != nil {
+		setupLog.Error(err, "unable to set up leader metrics")
 		os.Exit(1)
 	}
-	if err := mgr.AddReadyzCheck("readyz", healthz.Ping); err != nil {
-		setupLog.Error(err, "unable to set up ready check")
+	if err := setupHealthChecks(mgr, configStore, nodeReconciler); err != nil {
+		setupLog.Error(err, "unable to set up health checks")
 		os.Exit(1)
 	}
 
@@ -143,6 +922,615 @@ func main() {
 		setupLog.Error(err, "problem running manager")
 		os.Exit(1)
 	}
+	setupLog.Info("manager stopped")
+}
+
+// setLogOptions sets the level and encoder of opts from the log-level and
+// log-format flags, unless the zap flags they stand for were given. Both
+// formats use the production encoder config, with ISO 8601 timestamps.
+func setLogOptions(opts *zap.Options, level, format string) error {
+	if len(explicitFlags("zap-log-level")) == 0 {
+		var zapLevel zapcore.Level
+		if verbosity, err := strconv.Atoi(level); err == nil && verbosity > 0 {
+			zapLevel = zapcore.Level(-verbosity)
+		} else if err := zapLevel.UnmarshalText([]byte(level)); err != nil {
+			return fmt.Errorf("log-level must be debug, info, error or a positive verbosity, got %q", level)
+		}
+		opts.Level = zapLevel
+	}
+	if len(explicitFlags("zap-encoder")) > 0 {
+		return nil
+	}
+	encoderConfig := uberzap.NewProductionEncoderConfig()
+	encoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+	switch format {
+	case "json":
+		opts.Encoder = zapcore.NewJSONEncoder(encoderConfig)
+	case "console":
+		opts.Encoder = zapcore.NewConsoleEncoder(encoderConfig)
+	default:
+		return fmt.Errorf("log-format must be json or console, got %q", format)
+	}
+	return nil
+}
+
+// parseConfigSourceFlags checks that the config and config-map flags aren't
+// both given, and fills flagConfig from the rule and tuning flags when neither
+// is. The comma-separated ownedByNames add to ownedBy.
+func parseConfigSourceFlags(
+	flagConfig *config.Config,
+	configFile, configMap string,
+	targetTaints, ownedBy []string,
+	ownedByNames, presets string,
+	tuning tuningFlagValues,
+) error {
+	if configFile != "" && configMap != "" {
+		return errors.New("config and config-map flags are mutually exclusive")
+	}
+	if ownedByNames != "" {
+		ownedBy = append(ownedBy, strings.Split(ownedByNames, ",")...)
+	}
+	presetNames := config.ParsePresets(presets)
+	if configFile != "" || configMap != "" {
+		return checkConfigSourceFlags(targetTaints, ownedBy, presetNames, flagConfig.Profile)
+	}
+	return parseRuleFlags(flagConfig, targetTaints, ownedBy, presetNames, tuning)
+}
+
+// checkConfigSourceFlags checks that no rule or tuning flags are given along
+// with a config file or ConfigMap, which replace them
+func checkConfigSourceFlags(targetTaints, ownedBy, presets []string, profile string) error {
+	if len(targetTaints) > 0 || len(ownedBy) > 0 || len(presets) > 0 || profile != "" {
+		return errors.New("target-taint, owned-by, presets and profile flags (or TARGET_TAINT(S), OWNED_BY, " +
+			"OWNED_BY_NAMES, PRESETS and PROFILE environment variables) cannot be combined with config or config-map")
+	}
+	if set := explicitFlags(tuningFlags...); len(set) > 0 {
+		return fmt.Errorf("tuning flags %s cannot be combined with config or config-map, "+
+			"set them in the config instead", strings.Join(set, ", "))
+	}
+	return nil
+}
+
+// parseRuleFlags fills cfg with a rule for each target taint given with flags,
+// gated on the workloads given with flags, and a rule for each preset, then
+// applies the tuning flags. Presets without workloads of their own are gated
+// on the workloads given with flags as well. The profile of cfg, when set,
+// adds its own rules and workloads once cfg is defaulted.
+func parseRuleFlags(cfg *config.Config, targetTaints, ownedBy, presets []string, tuning tuningFlagValues) error {
+	profile, _ := config.LookupProfile(cfg.Profile)
+	if len(targetTaints) == 0 && len(presets) == 0 && len(profile.Presets) == 0 {
+		return errors.New("target-taint, presets or profile flag (or TARGET_TAINT, PRESETS or PROFILE " +
+			"environment variable) is required")
+	}
+	if len(targetTaints) > 0 && len(ownedBy) == 0 && len(profile.OwnedByNames) == 0 {
+		return errors.New("owned-by flag or OWNED_BY environment variable is required")
+	}
+	if err := validateRuleFlags(targetTaints, ownedBy, presets, cfg); err != nil {
+		return err
+	}
+	for _, taint := range targetTaints {
+		cfg.Rules = append(cfg.Rules, config.Rule{TargetTaint: taint, OwnedByNames: ownedBy})
+	}
+	for _, name := range presets {
+		rule := config.Rule{Preset: name}
+		if preset, _ := config.LookupPreset(name); len(preset.OwnedByNames) == 0 {
+			rule.OwnedByNames = ownedBy
+		}
+		cfg.Rules = append(cfg.Rules, rule)
+	}
+	return parseTuningFlags(cfg, tuning)
+}
+
+// loadConfig reads the initial configuration from the config file or the
+// ConfigMap, when one is named, and defaults flagConfig otherwise
+func loadConfig(
+	mgr ctrl.Manager,
+	configFile string,
+	configMapName types.NamespacedName,
+	configMapKey string,
+	flagConfig *config.Config,
+) (*config.Config, error) {
+	switch {
+	case configFile != "":
+		cfg, err := config.Load(configFile)
+		if err != nil {
+			return nil, fmt.Errorf("config file %s: %w", configFile, err)
+		}
+		return cfg, nil
+	case configMapName.Name != "":
+		// The cache isn't running yet, so read the initial configuration directly
+		cm := &corev1.ConfigMap{}
+		err := mgr.GetAPIReader().Get(context.Background(), configMapName, cm)
+		var cfg *config.Config
+		if err == nil {
+			cfg, err = config.FromConfigMap(cm, configMapKey)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("ConfigMap %s: %w", configMapName, err)
+		}
+		return cfg, nil
+	default:
+		flagConfig.Default()
+		return flagConfig, nil
+	}
+}
+
+// concurrentReconciles returns how many nodes are reconciled in parallel: the
+// maxConcurrentReconciles of cfg when set, and the flag value otherwise
+func concurrentReconciles(flagValue string, cfg *config.Config) (int, error) {
+	if cfg.MaxConcurrentReconciles > 0 {
+		return int(cfg.MaxConcurrentReconciles), nil
+	}
+	workers, err := strconv.Atoi(flagValue)
+	if err != nil || workers < 1 {
+		return 0, fmt.Errorf("max-concurrent-reconciles must be a positive integer, got %q", flagValue)
+	}
+	return workers, nil
+}
+
+// setupConfigWatchers adds what reloads the config in store when configFile
+// or the named ConfigMap change to mgr, for whichever is set
+func setupConfigWatchers(
+	mgr ctrl.Manager,
+	store *config.Store,
+	configFile string,
+	configMap types.NamespacedName,
+	key string,
+) error {
+	if configFile != "" {
+		if err := mgr.Add(&config.FileWatcher{Path: configFile, Store: store}); err != nil {
+			return fmt.Errorf("config watcher: %w", err)
+		}
+	}
+	if configMap.Name != "" {
+		if err := (&controller.ConfigMapReconciler{
+			Client:    mgr.GetClient(),
+			ConfigMap: configMap,
+			Key:       key,
+			Store:     store,
+		}).SetupWithManager(mgr); err != nil {
+			return fmt.Errorf("ConfigMap controller: %w", err)
+		}
+	}
+	return nil
+}
+
+// openAuditLog returns the audit log appending to path, or writing to stdout
+// when path is -. It is nil when path is empty.
+func openAuditLog(path string) (*controller.AuditLog, error) {
+	switch path {
+	case "":
+		return nil, nil
+	case "-":
+		return controller.NewAuditLog(os.Stdout), nil
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return controller.NewAuditLog(file), nil
+}
+
+// setupReporters adds the reporter publishing progress to the status of the
+// named UntaintPolicy at most every statusInterval, listing up to
+// statusMaxNodes blocked nodes, and the publisher sending CloudEvents to sink
+// and eventBus to mgr. The reporter is nil when the name is empty and the
+// publisher when both sink and eventBus are.
+func setupReporters(
+	mgr ctrl.Manager,
+	statusPolicy string,
+	statusInterval string,
+	statusMaxNodes string,
+	sink string,
+	eventBus string,
+) (*controller.PolicyStatusReporter, *controller.CloudEventPublisher, error) {
+	var statusReporter *controller.PolicyStatusReporter
+	if statusPolicy != "" {
+		interval, err := time.ParseDuration(statusInterval)
+		if err != nil || interval <= 0 {
+			return nil, nil, fmt.Errorf("status-interval must be a positive duration, got %q", statusInterval)
+		}
+		maxNodes, err := strconv.Atoi(statusMaxNodes)
+		if err != nil || maxNodes < 0 {
+			return nil, nil, fmt.Errorf("status-max-nodes must be a non-negative integer, got %q", statusMaxNodes)
+		}
+		statusReporter = &controller.PolicyStatusReporter{
+			Client:     mgr.GetClient(),
+			PolicyName: statusPolicy,
+			Interval:   interval,
+			MaxNodes:   maxNodes,
+		}
+		if err := mgr.Add(statusReporter); err != nil {
+			return nil, nil, fmt.Errorf("policy status reporter: %w", err)
+		}
+	}
+
+	var publisher *controller.CloudEventPublisher
+	if sink != "" || eventBus != "" {
+		if sink != "" && !isHTTPURL(sink) {
+			return nil, nil, fmt.Errorf("cloudevents-sink must be an absolute http or https URL, got %q", sink)
+		}
+		publisher = &controller.CloudEventPublisher{SinkURL: sink}
+		if eventBus != "" {
+			bus, err := controller.NewCloudEventBus(eventBus)
+			if err != nil {
+				return nil, nil, fmt.Errorf("event-bus: %w", err)
+			}
+			publisher.Buses = append(publisher.Buses, bus)
+		}
+		if err := mgr.Add(publisher); err != nil {
+			return nil, nil, fmt.Errorf("CloudEvents publisher: %w", err)
+		}
+	}
+
+	return statusReporter, publisher, nil
+}
+
+// setupMetricsExporters adds the exporter pushing metrics to otlpEndpoint and
+// the emitter sending them to the StatsD server at statsdAddress, labelled as
+// statsdFormat, to mgr. Each is skipped when its address is empty.
+func setupMetricsExporters(mgr ctrl.Manager, otlpEndpoint, statsdAddress, statsdFormat string) error {
+	if otlpEndpoint != "" {
+		if !isHTTPURL(otlpEndpoint) {
+			return fmt.Errorf("otlp-endpoint must be an absolute http or https URL, got %q", otlpEndpoint)
+		}
+		if err := mgr.Add(&controller.OTLPExporter{EndpointURL: otlpEndpoint}); err != nil {
+			return fmt.Errorf("OTLP exporter: %w", err)
+		}
+	}
+	if statsdAddress != "" {
+		if _, _, err := net.SplitHostPort(statsdAddress); err != nil {
+			return fmt.Errorf("statsd-address must be in host:port form, got %q", statsdAddress)
+		}
+		format := controller.StatsDFormat(statsdFormat)
+		if format != controller.StatsDFormatDogStatsD && format != controller.StatsDFormatStatsD {
+			return fmt.Errorf("statsd-format must be %s or %s, got %q",
+				controller.StatsDFormatDogStatsD, controller.StatsDFormatStatsD, statsdFormat)
+		}
+		if err := mgr.Add(&controller.StatsDEmitter{Address: statsdAddress, Format: format}); err != nil {
+			return fmt.Errorf("StatsD emitter: %w", err)
+		}
+	}
+	return nil
+}
+
+// largeClusterDefaults are the flag values --large-cluster uses in place of
+// the defaults, for flags set neither on the command line nor through their
+// environment variable
+var largeClusterDefaults = []struct {
+	flag  string
+	env   string
+	value string
+}{
+	{flag: "max-concurrent-reconciles", env: "MAX_CONCURRENT_RECONCILES", value: "10"},
+	{flag: "kube-api-qps", env: "KUBE_API_QPS", value: "100"},
+	{flag: "kube-api-burst", env: "KUBE_API_BURST", value: "200"},
+	{flag: "status-interval", env: "STATUS_INTERVAL", value: "30s"},
+	{flag: "status-max-nodes", env: "STATUS_MAX_NODES", value: "100"},
+}
+
+// largeClusterSummary describes the flag values of --large-cluster
+func largeClusterSummary() string {
+	values := make([]string, len(largeClusterDefaults))
+	for i, d := range largeClusterDefaults {
+		values[i] = d.flag + "=" + d.value
+	}
+	return strings.Join(values, ", ")
+}
+
+// applyLargeClusterDefaults sets the flags of largeClusterDefaults that
+// weren't set explicitly
+func applyLargeClusterDefaults() error {
+	set := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	for _, d := range largeClusterDefaults {
+		if _, ok := os.LookupEnv(d.env); ok || set[d.flag] {
+			continue
+		}
+		if err := flag.Set(d.flag, d.value); err != nil {
+			return fmt.Errorf("large-cluster: %w", err)
+		}
+	}
+	return nil
+}
+
+// handleEarlyFlags prints the version and exits when printVersion is set, and
+// applies the defaults of --large-cluster when largeCluster is
+func handleEarlyFlags(printVersion, largeCluster bool) error {
+	if printVersion {
+		fmt.Println(version.String())
+		os.Exit(0)
+	}
+	if !largeCluster {
+		return nil
+	}
+	return applyLargeClusterDefaults()
+}
+
+// newRestConfig returns the configuration of the API client, limited to qps
+// queries per second in bursts of up to burst
+func newRestConfig(qps, burst string) (*rest.Config, error) {
+	qpsValue, err := strconv.ParseFloat(qps, 32)
+	if err != nil || qpsValue <= 0 {
+		return nil, fmt.Errorf("kube-api-qps must be a positive number, got %q", qps)
+	}
+	burstValue, err := strconv.Atoi(burst)
+	if err != nil || burstValue < 1 {
+		return nil, fmt.Errorf("kube-api-burst must be a positive integer, got %q", burst)
+	}
+	restConfig, err := ctrl.GetConfig()
+	if err != nil {
+		return nil, err
+	}
+	restConfig.QPS = float32(qpsValue)
+	restConfig.Burst = burstValue
+	return restConfig, nil
+}
+
+// newRateLimiter returns the limiter allowing rate operations per second,
+// given with the named flag, or nil when rate is 0
+func newRateLimiter(name, rate string) (flowcontrol.RateLimiter, error) {
+	perSecond, err := strconv.ParseFloat(rate, 32)
+	if err != nil || perSecond < 0 {
+		return nil, fmt.Errorf("%s must not be negative, got %q", name, rate)
+	}
+	if perSecond == 0 {
+		return nil, nil
+	}
+	return flowcontrol.NewTokenBucketRateLimiter(float32(perSecond), int(math.Ceil(perSecond))), nil
+}
+
+// scaleSettings are the parsed flags tuning the operator to the size of the
+// cluster
+type scaleSettings struct {
+	resync           time.Duration
+	gracefulShutdown time.Duration
+	nodeWriteLimiter flowcontrol.RateLimiter
+	reconcileLimiter flowcontrol.RateLimiter
+}
+
+// parseScaleFlags parses the resync period, the graceful shutdown timeout and
+// the node write and reconcile rate limits
+func parseScaleFlags(resyncPeriod, shutdownTimeout, maxNodeWrites, maxReconciles string) (scaleSettings, error) {
+	var scale scaleSettings
+	errs := parseDurationFlag("resync-period", resyncPeriod, true, &scale.resync)
+	errs = append(errs, parseDurationFlag("graceful-shutdown-timeout", shutdownTimeout, true,
+		&scale.gracefulShutdown)...)
+	if len(errs) > 0 {
+		return scale, errs.ToAggregate()
+	}
+	var err error
+	if scale.nodeWriteLimiter, err = newRateLimiter("max-node-writes-per-second", maxNodeWrites); err != nil {
+		return scale, err
+	}
+	if scale.reconcileLimiter, err = newRateLimiter("max-reconciles-per-second", maxReconciles); err != nil {
+		return scale, err
+	}
+	return scale, nil
+}
+
+// parseCacheFlags parses the label selector restricting the node cache, and
+// the label selector and the comma-separated namespaces restricting the pod
+// cache, each of which is nil when empty
+func parseCacheFlags(
+	nodeSelector, podSelector, namespaces string,
+) (labels.Selector, labels.Selector, []string, error) {
+	var nodes, pods labels.Selector
+	var err error
+	if nodeSelector != "" {
+		if nodes, err = labels.Parse(nodeSelector); err != nil {
+			return nil, nil, nil, fmt.Errorf("node-selector: %w", err)
+		}
+	}
+	if podSelector != "" {
+		if pods, err = labels.Parse(podSelector); err != nil {
+			return nil, nil, nil, fmt.Errorf("pod-label-selector: %w", err)
+		}
+	}
+	if namespaces == "" {
+		return nodes, pods, nil, nil
+	}
+	var podNamespaces []string
+	for _, namespace := range strings.Split(namespaces, ",") {
+		namespace = strings.TrimSpace(namespace)
+		if errs := validation.IsDNS1123Label(namespace); len(errs) > 0 {
+			return nil, nil, nil, fmt.Errorf("pod-namespaces: invalid namespace %q: %s", namespace,
+				strings.Join(errs, ", "))
+		}
+		podNamespaces = append(podNamespaces, namespace)
+	}
+	return nodes, pods, podNamespaces, nil
+}
+
+// newCacheOptions returns the cache options stripping cached nodes and pods
+// down to what the operator reads, and only caching the nodes matching
+// nodeSelector, the pods matching podSelector in podNamespaces, when set, and
+// the ConfigMap named by configMap, in namespace/name form, we read the
+// configuration from, along with its name. ConfigMaps aren't cached specially
+// when configMap is empty.
+func newCacheOptions(
+	configMap string,
+	nodeSelector labels.Selector,
+	podSelector labels.Selector,
+	podNamespaces []string,
+) (cache.Options, types.NamespacedName, error) {
+	pods := cache.ByObject{Label: podSelector, Transform: controller.TransformPod}
+	if len(podNamespaces) > 0 {
+		pods.Namespaces = make(map[string]cache.Config, len(podNamespaces))
+		for _, namespace := range podNamespaces {
+			pods.Namespaces[namespace] = cache.Config{}
+		}
+	}
+	options := cache.Options{
+		ByObject: map[client.Object]cache.ByObject{
+			&corev1.Node{}: {Label: nodeSelector, Transform: controller.TransformNode},
+			&corev1.Pod{}:  pods,
+		},
+	}
+	if configMap == "" {
+		return options, types.NamespacedName{}, nil
+	}
+	name, err := parseNamespacedName(configMap)
+	if err != nil {
+		return cache.Options{}, types.NamespacedName{}, err
+	}
+	options.ByObject[&corev1.ConfigMap{}] = cache.ByObject{
+		Namespaces: map[string]cache.Config{name.Namespace: {}},
+		Field:      fields.OneTermEqualSelector("metadata.name", name.Name),
+	}
+	return options, name, nil
+}
+
+// parseShardFlags parses the number of shards and the shard of this replica
+func parseShardFlags(count, index string) (int, int, error) {
+	shards, err := strconv.Atoi(count)
+	if err != nil || shards < 1 {
+		return 0, 0, fmt.Errorf("shard-count must be a positive integer, got %q", count)
+	}
+	shard, err := strconv.Atoi(index)
+	if err != nil || shard < 0 || shard >= shards {
+		return 0, 0, fmt.Errorf("shard-index must be an integer from 0 to %d, got %q", shards-1, index)
+	}
+	return shards, shard, nil
+}
+
+// parseLeaderElectionFlags validates the name, namespace and resource lock of
+// the leader election lease
+func parseLeaderElectionFlags(name, namespace, lock string) error {
+	if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+		return fmt.Errorf("leader-election-id: invalid lease name %q: %s", name, strings.Join(errs, ", "))
+	}
+	if namespace != "" {
+		if errs := validation.IsDNS1123Label(namespace); len(errs) > 0 {
+			return fmt.Errorf("leader-election-namespace: invalid namespace %q: %s", namespace,
+				strings.Join(errs, ", "))
+		}
+	}
+	if lock != resourcelock.LeasesResourceLock {
+		return fmt.Errorf("leader-election-resource-lock must be %s, got %q", resourcelock.LeasesResourceLock, lock)
+	}
+	return nil
+}
+
+// shardLeaderElectionID returns the name of the leader election lease of a
+// shard, the lease named name suffixed with the shard, so the replicas of each
+// shard elect their own leader
+func shardLeaderElectionID(name string, shards, shard int) string {
+	if shards <= 1 {
+		return name
+	}
+	return fmt.Sprintf("%s-shard-%d", name, shard)
+}
+
+// setupLeaderStatus keeps the leader metrics up to date, reading the leader
+// election lease named electionID in electionNamespace, or the namespace the
+// operator runs in when empty, when leaderElection is enabled
+func setupLeaderStatus(mgr ctrl.Manager, leaderElection bool, electionNamespace, electionID string) error {
+	status := &controller.LeaderStatus{Reader: mgr.GetAPIReader(), Elected: mgr.Elected()}
+	if leaderElection {
+		if electionNamespace == "" {
+			namespace, err := os.ReadFile(inClusterNamespacePath)
+			if err != nil {
+				return fmt.Errorf("failed to find the leader election namespace: %w", err)
+			}
+			electionNamespace = strings.TrimSpace(string(namespace))
+		}
+		status.Lease = types.NamespacedName{Namespace: electionNamespace, Name: electionID}
+	}
+	return mgr.Add(status)
+}
+
+// setupHealthChecks adds the liveness check, failing while the API server is
+// unreachable, and the readiness checks, also failing until the informer
+// caches synced and the leader reconciled every tainted node once, and while
+// the latest config reload failed, to mgr
+func setupHealthChecks(mgr ctrl.Manager, store *config.Store, reconciler *controller.NodeReconciler) error {
+	apiServer, err := controller.APIServerCheck(mgr.GetConfig(), mgr.GetHTTPClient())
+	if err != nil {
+		return err
+	}
+	if err := mgr.AddHealthzCheck("apiserver", apiServer); err != nil {
+		return err
+	}
+	for name, check := range map[string]healthz.Checker{
+		"apiserver": apiServer,
+		"informers": controller.CacheSyncCheck(mgr.GetCache()),
+		"config":    store.Check,
+		"initial":   reconciler.InitialPassCheck(mgr.Elected()),
+	} {
+		if err := mgr.AddReadyzCheck(name, check); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// metricsOptions returns the options of the metrics server on addr. Secure
+// metrics are served over HTTPS with the certificate of certDir, or a
+// self-signed one, to clients allowed to get /metrics by the Kubernetes API.
+func metricsOptions(addr string, secure bool, certDir string) metricsserver.Options {
+	if !secure {
+		return metricsserver.Options{BindAddress: addr}
+	}
+	return metricsserver.Options{
+		BindAddress:    addr,
+		SecureServing:  true,
+		FilterProvider: filters.WithAuthenticationAndAuthorization,
+		CertDir:        certDir,
+		CertName:       "tls.crt",
+		KeyName:        "tls.key",
+		// HTTP/2 is disabled to avoid the HTTP/2 Stream Cancellation and
+		// Rapid Reset vulnerabilities
+		TLSOpts: []func(*tls.Config){func(c *tls.Config) { c.NextProtos = []string{"http/1.1"} }},
+	}
+}
+
+// setupServers adds the server listing the nodes reconciler keeps blocked on
+// debugAddr and the status API on apiAddr, authenticated against the
+// Kubernetes API, to mgr. Each is skipped when its address is empty.
+func setupServers(
+	mgr ctrl.Manager,
+	reconciler *controller.NodeReconciler,
+	debugAddr, apiAddr, apiCertDir string,
+) error {
+	if debugAddr != "" {
+		mux := http.NewServeMux()
+		mux.Handle(controller.BlockedNodesPath, reconciler.BlockedNodesHandler())
+		err := mgr.Add(&manager.Server{
+			Name:   "debug",
+			Server: &http.Server{Addr: debugAddr, Handler: mux, ReadHeaderTimeout: 10 * time.Second},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if apiAddr == "" {
+		return nil
+	}
+	filter, err := filters.WithAuthenticationAndAuthorization(mgr.GetConfig(), mgr.GetHTTPClient())
+	if err != nil {
+		return fmt.Errorf("status API authentication: %w", err)
+	}
+	return mgr.Add(&controller.StatusAPI{Addr: apiAddr, CertDir: apiCertDir, Reconciler: reconciler, Filter: filter})
+}
+
+// isHTTPURL reports whether value is an absolute http or https URL
+func isHTTPURL(value string) bool {
+	u, err := url.Parse(value)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// setupKarpenter adds the controller checking the startupTaints of Karpenter
+// NodePools against the rules in store to mgr, when enabled
+func setupKarpenter(mgr ctrl.Manager, store *config.Store, enabled bool) error {
+	if !enabled {
+		return nil
+	}
+	return (&controller.NodePoolReconciler{
+		Client:   mgr.GetClient(),
+		Config:   store,
+		Recorder: mgr.GetEventRecorderFor("generic-untaint-operator"),
+	}).SetupWithManager(mgr)
 }
 
 // getEnvOrDefault returns the value of the environment variable if it exists,
@@ -153,3 +1541,487 @@ func getEnvOrDefault(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// tuningFlags are the flags that set config knobs when no config file or
+// ConfigMap is used
+var tuningFlags = []string{
+	"readiness-mode", "require-init-containers", "require-node-ready", "require-network-available",
+	"blocking-conditions", "csi-drivers", "required-resources",
+	"min-node-age", "max-lease-age", "min-ready-seconds", "removal-strategy", "force-apply",
+	"requeue-interval", "max-requeue-interval", "requeue-jitter", "untaint-cooldown",
+	"max-node-untaints-per-minute", "untaint-rate-limit", "spread-zones", "max-restarts",
+	"restart-window", "max-wait", "on-max-wait", "staged-removal", "taint-effects", "uncordon",
+	"set-labels", "remove-labels", "record-untaint", "paused", "node-condition-type",
+	"untaint-actions", "pre-untaint-hook", "post-untaint-hook", "notify-url", "notify-format",
+	"notify-stuck-after", "notify-on-untaint", "aws-lifecycle-hook",
+}
+
+// explicitFlags returns which of the named flags were set on the command line
+func explicitFlags(names ...string) []string {
+	var set []string
+	flag.Visit(func(f *flag.Flag) {
+		if slices.Contains(names, f.Name) {
+			set = append(set, "--"+f.Name)
+		}
+	})
+	return set
+}
+
+// validateRuleFlags checks the rule flags the same way a config file is
+// validated, reporting errors against the flag names
+func validateRuleFlags(targetTaints, ownedBy, presets []string, cfg *config.Config) error {
+	var errs field.ErrorList
+	profile, _ := config.LookupProfile(cfg.Profile)
+	taintPath := field.NewPath("--target-taint")
+	seen := make(map[string]bool, len(targetTaints)+len(presets))
+	for i, taint := range targetTaints {
+		errs = append(errs, config.ValidateTaintKey(taintPath.Index(i), taint)...)
+		if seen[taint] {
+			errs = append(errs, field.Duplicate(taintPath.Index(i), taint))
+		}
+		seen[taint] = true
+	}
+	presetPath := field.NewPath("--presets")
+	for i, name := range presets {
+		errs = append(errs, config.ValidatePreset(presetPath.Index(i), name)...)
+		preset, ok := config.LookupPreset(name)
+		switch {
+		case !ok:
+		case seen[preset.TargetTaint]:
+			errs = append(errs, field.Duplicate(presetPath.Index(i), preset.TargetTaint))
+		case len(preset.OwnedByNames) == 0 && len(ownedBy) == 0 && len(profile.OwnedByNames) == 0:
+			errs = append(errs, field.Required(presetPath.Index(i),
+				"preset has no workloads of its own, --owned-by is required"))
+		}
+		seen[preset.TargetTaint] = true
+	}
+	if len(ownedBy) > 0 {
+		errs = append(errs, config.ValidateWorkloadNames(field.NewPath("--owned-by"), ownedBy)...)
+	}
+	errs = append(errs, config.ValidateProfile(field.NewPath("--profile"), cfg.Profile)...)
+	errs = append(errs, config.ValidateReadinessMode(field.NewPath("--readiness-mode"), cfg.ReadinessMode)...)
+	errs = append(errs, config.ValidateRemovalStrategy(field.NewPath("--removal-strategy"), cfg.RemovalStrategy)...)
+	errs = append(errs, config.ValidateNodeConditionType(field.NewPath("--node-condition-type"),
+		cfg.NodeConditionType)...)
+	return errs.ToAggregate()
+}
+
+// tuningFlagValues holds the string-valued tuning flags until they are parsed
+type tuningFlagValues struct {
+	requeueInterval    string
+	maxRequeueInterval string
+	requeueJitter      string
+	minReadySeconds    string
+	untaintCooldown    string
+	maxNodeUntaints    string
+	untaintRateLimit   string
+	spreadZones        bool
+	maxLeaseAge        string
+	blockingConditions string
+	csiDrivers         string
+	requiredResources  string
+	minNodeAge         string
+	maxRestarts        string
+	restartWindow      string
+	stuckNodeThreshold string
+	maxWait            string
+	onMaxWait          string
+	stagedRemoval      string
+	taintEffects       string
+	uncordon           bool
+	setLabels          string
+	removeLabels       string
+	untaintActions     string
+	awsLifecycleHook   string
+	preUntaintHook     string
+	postUntaintHook    string
+	notifyURL          string
+	notifyFormat       string
+	notifyStuckAfter   string
+	notifyOnUntaint    bool
+}
+
+// parseTuningFlags parses the string-valued tuning flags into cfg
+func parseTuningFlags(cfg *config.Config, tuning tuningFlagValues) error {
+	var errs field.ErrorList
+	errs = append(errs, parseDurationFlag("requeue-interval", tuning.requeueInterval, false,
+		&cfg.RequeueInterval.Duration)...)
+	errs = append(errs, parseDurationFlag("max-requeue-interval", tuning.maxRequeueInterval, false,
+		&cfg.MaxRequeueInterval.Duration)...)
+	errs = append(errs, parseDurationFlag("untaint-cooldown", tuning.untaintCooldown, true,
+		&cfg.UntaintCooldown.Duration)...)
+	errs = append(errs, parseDurationFlag("min-node-age", tuning.minNodeAge, true,
+		&cfg.MinNodeAge.Duration)...)
+	errs = append(errs, parseDurationFlag("max-lease-age", tuning.maxLeaseAge, true,
+		&cfg.MaxLeaseAge.Duration)...)
+	errs = append(errs, parseDurationFlag("restart-window", tuning.restartWindow, false,
+		&cfg.RestartWindow.Duration)...)
+	errs = append(errs, parseDurationFlag("stuck-node-threshold", tuning.stuckNodeThreshold, false,
+		&cfg.StuckNodeThreshold.Duration)...)
+	if tuning.maxRestarts != "" {
+		maxRestarts, err := strconv.ParseInt(tuning.maxRestarts, 10, 32)
+		switch {
+		case err != nil:
+			errs = append(errs, field.Invalid(field.NewPath("--max-restarts"), tuning.maxRestarts, err.Error()))
+		case maxRestarts < 0:
+			errs = append(errs, field.Invalid(field.NewPath("--max-restarts"), tuning.maxRestarts,
+				"must not be negative"))
+		default:
+			cfg.MaxRestarts = ptr.To(int32(maxRestarts))
+		}
+	}
+	// The deadline applies to every rule given with flags
+	var maxWait time.Duration
+	if tuning.maxWait != "" {
+		errs = append(errs, parseDurationFlag("max-wait", tuning.maxWait, false, &maxWait)...)
+	}
+	onMaxWait := config.MaxWaitAction(tuning.onMaxWait)
+	errs = append(errs, config.ValidateMaxWaitAction(field.NewPath("--on-max-wait"), onMaxWait)...)
+	var limitErrs field.ErrorList
+	cfg.UntaintRateLimit, limitErrs = parseRateLimitFlag(tuning.untaintRateLimit, tuning.spreadZones)
+	errs = append(errs, limitErrs...)
+	var conditionErrs field.ErrorList
+	cfg.BlockingConditions, conditionErrs = parseBlockingConditionsFlag(tuning.blockingConditions, cfg.NodeConditionType)
+	errs = append(errs, conditionErrs...)
+	stages, stageErrs := parseStagedRemovalFlag(tuning.stagedRemoval)
+	errs = append(errs, stageErrs...)
+	var effects []corev1.TaintEffect
+	if tuning.taintEffects != "" {
+		for _, effect := range strings.Split(tuning.taintEffects, ",") {
+			effects = append(effects, corev1.TaintEffect(strings.TrimSpace(effect)))
+		}
+		errs = append(errs, config.ValidateTaintEffects(field.NewPath("--taint-effects"), effects)...)
+	}
+	csiDrivers, driverErrs := parseCSIDriversFlag(tuning.csiDrivers)
+	errs = append(errs, driverErrs...)
+	resources, resourceErrs := parseResourcesFlag(tuning.requiredResources)
+	errs = append(errs, resourceErrs...)
+	setLabels, labelErrs := parseLabelsFlag(tuning.setLabels)
+	errs = append(errs, labelErrs...)
+	errs = append(errs, metav1validation.ValidateLabels(setLabels, field.NewPath("--set-labels"))...)
+	var removeLabels []string
+	if tuning.removeLabels != "" {
+		for _, key := range strings.Split(tuning.removeLabels, ",") {
+			removeLabels = append(removeLabels, strings.TrimSpace(key))
+		}
+		errs = append(errs, config.ValidateRemoveLabels(field.NewPath("--remove-labels"), removeLabels,
+			cfg.RemovalStrategy)...)
+	}
+	var actions []config.UntaintAction
+	if tuning.untaintActions != "" {
+		for _, action := range strings.Split(tuning.untaintActions, ",") {
+			actions = append(actions, config.UntaintAction(strings.TrimSpace(action)))
+		}
+		actionsPath := field.NewPath("--untaint-actions")
+		errs = append(errs, config.ValidateUntaintActions(actionsPath, actions)...)
+		if tuning.uncordon && !slices.Contains(actions, config.UntaintActionUncordon) {
+			errs = append(errs, field.Invalid(actionsPath, tuning.untaintActions, "--uncordon requires Uncordon"))
+		}
+		if (len(setLabels) > 0 || len(removeLabels) > 0) && !slices.Contains(actions, config.UntaintActionLabel) {
+			errs = append(errs, field.Invalid(actionsPath, tuning.untaintActions, "label flags require Label"))
+		}
+		if tuning.awsLifecycleHook != "" && !slices.Contains(actions, config.UntaintActionCompleteLifecycle) {
+			errs = append(errs, field.Invalid(actionsPath, tuning.untaintActions,
+				"--aws-lifecycle-hook requires CompleteLifecycleAction"))
+		}
+	}
+	if tuning.awsLifecycleHook != "" {
+		errs = append(errs, config.ValidateLifecycleHookName(field.NewPath("--aws-lifecycle-hook"),
+			tuning.awsLifecycleHook)...)
+	}
+	for i := range cfg.Rules {
+		if maxWait > 0 {
+			cfg.Rules[i].MaxWait = &metav1.Duration{Duration: maxWait}
+		}
+		cfg.Rules[i].OnMaxWait = onMaxWait
+		cfg.Rules[i].StagedRemoval = stages
+		cfg.Rules[i].Effects = effects
+		cfg.Rules[i].CSIDrivers = csiDrivers
+		cfg.Rules[i].RequiredResources = resources
+		cfg.Rules[i].Uncordon = tuning.uncordon
+		cfg.Rules[i].SetLabels = setLabels
+		cfg.Rules[i].RemoveLabels = removeLabels
+		cfg.Rules[i].AWSLifecycleHook = tuning.awsLifecycleHook
+		cfg.Rules[i].Actions = actions
+	}
+	var hookErrs field.ErrorList
+	cfg.PreUntaintHook, hookErrs = parseHookFlag("pre-untaint-hook", tuning.preUntaintHook)
+	errs = append(errs, hookErrs...)
+	cfg.PostUntaintHook, hookErrs = parseHookFlag("post-untaint-hook", tuning.postUntaintHook)
+	errs = append(errs, hookErrs...)
+	var notifyErrs field.ErrorList
+	cfg.Notifications, notifyErrs = parseNotifyFlags(tuning)
+	errs = append(errs, notifyErrs...)
+	minReady, err := strconv.ParseInt(tuning.minReadySeconds, 10, 32)
+	switch {
+	case err != nil:
+		errs = append(errs, field.Invalid(field.NewPath("--min-ready-seconds"), tuning.minReadySeconds, err.Error()))
+	case minReady < 0:
+		errs = append(errs, field.Invalid(field.NewPath("--min-ready-seconds"), tuning.minReadySeconds,
+			"must not be negative"))
+	default:
+		cfg.MinReadySeconds = int32(minReady)
+	}
+	errs = append(errs, parseCountFlag("max-node-untaints-per-minute", tuning.maxNodeUntaints,
+		&cfg.MaxNodeUntaintsPerMinute)...)
+	jitter, err := strconv.ParseFloat(tuning.requeueJitter, 64)
+	switch {
+	case err != nil:
+		errs = append(errs, field.Invalid(field.NewPath("--requeue-jitter"), tuning.requeueJitter, err.Error()))
+	case jitter < 0 || jitter > 1:
+		errs = append(errs, field.Invalid(field.NewPath("--requeue-jitter"), tuning.requeueJitter,
+			"must be between 0 and 1"))
+	default:
+		cfg.RequeueJitter = &jitter
+	}
+	return errs.ToAggregate()
+}
+
+// parseCountFlag parses the non-negative count given with the named flag into
+// target
+func parseCountFlag(name, value string, target *int32) field.ErrorList {
+	count, err := strconv.ParseInt(value, 10, 32)
+	switch {
+	case err != nil:
+		return field.ErrorList{field.Invalid(field.NewPath("--"+name), value, err.Error())}
+	case count < 0:
+		return field.ErrorList{field.Invalid(field.NewPath("--"+name), value, "must not be negative")}
+	}
+	*target = int32(count)
+	return nil
+}
+
+// parseHookFlag returns the hook calling the URL given with the named flag,
+// or nil when it is empty
+func parseHookFlag(name, value string) (*config.Hook, field.ErrorList) {
+	if value == "" {
+		return nil, nil
+	}
+	hook := &config.Hook{URL: value}
+	hook.Default()
+	return hook, config.ValidateHook(field.NewPath("--"+name), hook)
+}
+
+// parseNotifyFlags returns the notifications given with the notify flags, or
+// nil when no URL is given
+func parseNotifyFlags(tuning tuningFlagValues) (*config.Notifications, field.ErrorList) {
+	if tuning.notifyURL == "" {
+		return nil, nil
+	}
+	n := &config.Notifications{
+		URL:       tuning.notifyURL,
+		Format:    config.NotificationFormat(tuning.notifyFormat),
+		OnUntaint: tuning.notifyOnUntaint,
+	}
+	errs := parseDurationFlag("notify-stuck-after", tuning.notifyStuckAfter, true, &n.StuckAfter.Duration)
+	n.Default()
+	errs = append(errs, config.ValidateNotifications(field.NewPath("--notify"), n)...)
+	return n, errs
+}
+
+// parseLabelsFlag parses a comma-separated list of key=value labels
+func parseLabelsFlag(value string) (map[string]string, field.ErrorList) {
+	if value == "" {
+		return nil, nil
+	}
+	path := field.NewPath("--set-labels")
+	labels := make(map[string]string)
+	var errs field.ErrorList
+	for i, label := range strings.Split(value, ",") {
+		key, labelValue, ok := strings.Cut(strings.TrimSpace(label), "=")
+		if !ok {
+			errs = append(errs, field.Invalid(path.Index(i), label, "must be key=value"))
+			continue
+		}
+		labels[key] = labelValue
+	}
+	return labels, errs
+}
+
+// parseBlockingConditionsFlag parses the comma-separated node condition types
+// of --blocking-conditions
+func parseBlockingConditionsFlag(
+	value string,
+	ownType corev1.NodeConditionType,
+) ([]corev1.NodeConditionType, field.ErrorList) {
+	if value == "" {
+		return nil, nil
+	}
+	var conditionTypes []corev1.NodeConditionType
+	for _, conditionType := range strings.Split(value, ",") {
+		conditionTypes = append(conditionTypes, corev1.NodeConditionType(strings.TrimSpace(conditionType)))
+	}
+	return conditionTypes, config.ValidateBlockingConditions(field.NewPath("--blocking-conditions"), conditionTypes,
+		ownType)
+}
+
+// parseRateLimitFlag parses the count/interval of --untaint-rate-limit, spread
+// across zones with --spread-zones
+func parseRateLimitFlag(value string, spreadZones bool) (*config.UntaintRateLimit, field.ErrorList) {
+	path := field.NewPath("--untaint-rate-limit")
+	if value == "" {
+		if spreadZones {
+			return nil, field.ErrorList{field.Required(path, "--spread-zones requires a rate limit")}
+		}
+		return nil, nil
+	}
+	count, interval, ok := strings.Cut(value, "/")
+	if !ok {
+		return nil, field.ErrorList{field.Invalid(path, value, "must be count/interval")}
+	}
+	maxUntaints, err := strconv.ParseInt(count, 10, 32)
+	if err != nil {
+		return nil, field.ErrorList{field.Invalid(path, value, err.Error())}
+	}
+	duration, err := time.ParseDuration(interval)
+	if err != nil {
+		return nil, field.ErrorList{field.Invalid(path, value, err.Error())}
+	}
+	limit := &config.UntaintRateLimit{
+		MaxUntaints: int32(maxUntaints),
+		Interval:    metav1.Duration{Duration: duration},
+		SpreadZones: spreadZones,
+	}
+	return limit, config.ValidateUntaintRateLimit(path, limit)
+}
+
+// parseCSIDriversFlag parses the comma-separated driver names of --csi-drivers
+func parseCSIDriversFlag(value string) ([]string, field.ErrorList) {
+	if value == "" {
+		return nil, nil
+	}
+	var drivers []string
+	for _, driver := range strings.Split(value, ",") {
+		drivers = append(drivers, strings.TrimSpace(driver))
+	}
+	return drivers, config.ValidateCSIDrivers(field.NewPath("--csi-drivers"), drivers)
+}
+
+// parseResourcesFlag parses the comma-separated name=quantity resources of
+// --required-resources
+func parseResourcesFlag(value string) (corev1.ResourceList, field.ErrorList) {
+	if value == "" {
+		return nil, nil
+	}
+	path := field.NewPath("--required-resources")
+	resources := make(corev1.ResourceList)
+	var errs field.ErrorList
+	for i, entry := range strings.Split(value, ",") {
+		name, quantity, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok {
+			errs = append(errs, field.Invalid(path.Index(i), entry, "must be name=quantity"))
+			continue
+		}
+		parsed, err := resource.ParseQuantity(quantity)
+		if err != nil {
+			errs = append(errs, field.Invalid(path.Index(i), entry, err.Error()))
+			continue
+		}
+		resources[corev1.ResourceName(name)] = parsed
+	}
+	return resources, append(errs, config.ValidateRequiredResources(path, resources)...)
+}
+
+// parseStagedRemovalFlag parses a comma-separated list of effect=dwell stages
+func parseStagedRemovalFlag(value string) ([]config.RemovalStage, field.ErrorList) {
+	if value == "" {
+		return nil, nil
+	}
+	path := field.NewPath("--staged-removal")
+	var stages []config.RemovalStage
+	var errs field.ErrorList
+	for i, item := range strings.Split(value, ",") {
+		effect, dwell, ok := strings.Cut(strings.TrimSpace(item), "=")
+		if !ok {
+			errs = append(errs, field.Invalid(path.Index(i), item, "must be effect=dwell"))
+			continue
+		}
+		duration, err := time.ParseDuration(dwell)
+		if err != nil {
+			errs = append(errs, field.Invalid(path.Index(i).Child("dwell"), dwell, err.Error()))
+			continue
+		}
+		stages = append(stages, config.RemovalStage{
+			Effect: corev1.TaintEffect(effect),
+			Dwell:  metav1.Duration{Duration: duration},
+		})
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return stages, config.ValidateRemovalStages(path, stages)
+}
+
+// parseDurationFlag parses the value of the named duration flag into out
+func parseDurationFlag(name, value string, allowZero bool, out *time.Duration) field.ErrorList {
+	path := field.NewPath("--" + name)
+	duration, err := time.ParseDuration(value)
+	switch {
+	case err != nil:
+		return field.ErrorList{field.Invalid(path, value, err.Error())}
+	case duration < 0 || (duration == 0 && !allowZero):
+		if allowZero {
+			return field.ErrorList{field.Invalid(path, value, "must not be negative")}
+		}
+		return field.ErrorList{field.Invalid(path, value, "must be positive")}
+	}
+	*out = duration
+	return nil
+}
+
+// parseNamespacedName parses a "namespace/name" reference
+func parseNamespacedName(value string) (types.NamespacedName, error) {
+	namespace, name, ok := strings.Cut(value, "/")
+	if !ok || namespace == "" || name == "" {
+		return types.NamespacedName{}, fmt.Errorf("%q is not in namespace/name form", value)
+	}
+	return types.NamespacedName{Namespace: namespace, Name: name}, nil
+}
+
+// stringSliceValue is a flag.Value collecting every occurrence of a repeatable flag
+type stringSliceValue struct {
+	values []string
+	// fromEnv marks values loaded from the environment, which the first
+	// occurrence of the flag on the command line replaces
+	fromEnv bool
+}
+
+func (s *stringSliceValue) String() string {
+	return strings.Join(s.values, ",")
+}
+
+func (s *stringSliceValue) Set(value string) error {
+	if s.fromEnv {
+		s.values = nil
+		s.fromEnv = false
+	}
+	s.values = append(s.values, value)
+	return nil
+}
+
+// loadRuleFlagsFromEnv loads the defaults of --target-taint and --owned-by
+// from the TARGET_TAINTS, TARGET_TAINT and OWNED_BY environment variables
+func loadRuleFlagsFromEnv(targetTaints, ownedBy *stringSliceValue) error {
+	if err := targetTaints.setFromEnv("TARGET_TAINTS"); err != nil {
+		return err
+	}
+	if value := os.Getenv("TARGET_TAINT"); value != "" {
+		targetTaints.values = append(targetTaints.values, value)
+		targetTaints.fromEnv = true
+	}
+	return ownedBy.setFromEnv("OWNED_BY")
+}
+
+// setFromEnv loads default values from an environment variable holding a JSON
+// array of strings, e.g. ["a","b,with comma"]
+func (s *stringSliceValue) setFromEnv(key string) error {
+	value, exists := os.LookupEnv(key)
+	if !exists || value == "" {
+		return nil
+	}
+	if err := json.Unmarshal([]byte(value), &s.values); err != nil {
+		return fmt.Errorf("%s must be a JSON array of strings: %w", key, err)
+	}
+	s.fromEnv = true
+	return nil
+}
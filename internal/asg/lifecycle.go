@@ -0,0 +1,132 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package asg completes AWS Auto Scaling lifecycle actions for nodes
+package asg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ContinueResult is the lifecycle action result that lets the instance proceed
+const ContinueResult = "CONTINUE"
+
+// ErrNoInstanceID is wrapped by the errors about nodes that aren't backed by
+// an EC2 instance, which trying again doesn't fix
+var ErrNoInstanceID = errors.New("no EC2 instance ID")
+
+// API is the part of the Auto Scaling API used to complete lifecycle actions
+type API interface {
+	DescribeAutoScalingInstances(ctx context.Context, params *autoscaling.DescribeAutoScalingInstancesInput,
+		optFns ...func(*autoscaling.Options)) (*autoscaling.DescribeAutoScalingInstancesOutput, error)
+	CompleteLifecycleAction(ctx context.Context, params *autoscaling.CompleteLifecycleActionInput,
+		optFns ...func(*autoscaling.Options)) (*autoscaling.CompleteLifecycleActionOutput, error)
+}
+
+// Lifecycle completes the launch lifecycle action of the EC2 instance backing
+// a node, so an Auto Scaling group with lifecycle hooks or a warm pool only
+// moves the instance on once the node is ready for workloads.
+type Lifecycle struct {
+	// Client calls the Auto Scaling API. When unset, a client is created
+	// from the default AWS configuration, e.g. IRSA credentials, on first use.
+	Client API
+
+	mu     sync.Mutex
+	client API
+}
+
+// api returns the Auto Scaling client. A failure to load the AWS
+// configuration isn't kept, so the next call tries again.
+func (l *Lifecycle) api(ctx context.Context) (API, error) {
+	if l.Client != nil {
+		return l.Client, nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.client == nil {
+		cfg, err := awsconfig.LoadDefaultConfig(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load AWS configuration: %w", err)
+		}
+		l.client = autoscaling.NewFromConfig(cfg)
+	}
+	return l.client, nil
+}
+
+// Complete continues the lifecycle action waiting on hookName for the
+// instance backing node. It reports whether an action was completed: nothing
+// is done when the instance isn't part of an Auto Scaling group or isn't
+// waiting on a launch lifecycle hook.
+func (l *Lifecycle) Complete(ctx context.Context, node *corev1.Node, hookName string) (bool, error) {
+	instanceID, err := InstanceID(node)
+	if err != nil {
+		return false, err
+	}
+	client, err := l.api(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	out, err := client.DescribeAutoScalingInstances(ctx, &autoscaling.DescribeAutoScalingInstancesInput{
+		InstanceIds: []string{instanceID},
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to describe instance %s: %w", instanceID, err)
+	}
+	if len(out.AutoScalingInstances) == 0 {
+		return false, nil
+	}
+	instance := out.AutoScalingInstances[0]
+	// Pending:Wait on launch, Warmed:Pending:Wait when launching into a warm pool
+	if !strings.HasSuffix(aws.ToString(instance.LifecycleState), "Pending:Wait") {
+		return false, nil
+	}
+
+	_, err = client.CompleteLifecycleAction(ctx, &autoscaling.CompleteLifecycleActionInput{
+		AutoScalingGroupName:  instance.AutoScalingGroupName,
+		LifecycleHookName:     aws.String(hookName),
+		InstanceId:            aws.String(instanceID),
+		LifecycleActionResult: aws.String(ContinueResult),
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to complete lifecycle action %s of instance %s: %w", hookName, instanceID, err)
+	}
+	return true, nil
+}
+
+// InstanceID returns the EC2 instance ID in the provider ID of node, which
+// has the form aws:///<availability-zone>/<instance-id>
+func InstanceID(node *corev1.Node) (string, error) {
+	providerID := node.Spec.ProviderID
+	if !strings.HasPrefix(providerID, "aws://") {
+		return "", fmt.Errorf("%w: node %s has no AWS provider ID: %q", ErrNoInstanceID, node.Name, providerID)
+	}
+	id := providerID[strings.LastIndex(providerID, "/")+1:]
+	if !strings.HasPrefix(id, "i-") {
+		return "", fmt.Errorf("%w: node %s has no EC2 instance ID in its provider ID: %q",
+			ErrNoInstanceID, node.Name, providerID)
+	}
+	return id, nil
+}
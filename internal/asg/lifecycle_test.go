@@ -0,0 +1,145 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package asg
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling"
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling/types"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// fakeAPI answers DescribeAutoScalingInstances with instances and records the
+// completed lifecycle actions
+type fakeAPI struct {
+	instances   []types.AutoScalingInstanceDetails
+	completeErr error
+	completed   []*autoscaling.CompleteLifecycleActionInput
+}
+
+func (f *fakeAPI) DescribeAutoScalingInstances(_ context.Context, _ *autoscaling.DescribeAutoScalingInstancesInput,
+	_ ...func(*autoscaling.Options)) (*autoscaling.DescribeAutoScalingInstancesOutput, error) {
+	return &autoscaling.DescribeAutoScalingInstancesOutput{AutoScalingInstances: f.instances}, nil
+}
+
+func (f *fakeAPI) CompleteLifecycleAction(_ context.Context, params *autoscaling.CompleteLifecycleActionInput,
+	_ ...func(*autoscaling.Options)) (*autoscaling.CompleteLifecycleActionOutput, error) {
+	f.completed = append(f.completed, params)
+	return &autoscaling.CompleteLifecycleActionOutput{}, f.completeErr
+}
+
+var _ = Describe("Lifecycle", func() {
+	var (
+		api       *fakeAPI
+		lifecycle *Lifecycle
+		node      *corev1.Node
+	)
+
+	BeforeEach(func() {
+		api = &fakeAPI{instances: []types.AutoScalingInstanceDetails{{
+			AutoScalingGroupName: aws.String("workers"),
+			InstanceId:           aws.String("i-0123456789abcdef0"),
+			LifecycleState:       aws.String("Pending:Wait"),
+		}}}
+		lifecycle = &Lifecycle{Client: api}
+		node = &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "node"},
+			Spec:       corev1.NodeSpec{ProviderID: "aws:///us-east-1a/i-0123456789abcdef0"},
+		}
+	})
+
+	It("should continue the lifecycle action of an instance waiting on launch", func() {
+		completed, err := lifecycle.Complete(context.Background(), node, "node-ready")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(completed).To(BeTrue())
+		Expect(api.completed).To(Equal([]*autoscaling.CompleteLifecycleActionInput{{
+			AutoScalingGroupName:  aws.String("workers"),
+			LifecycleHookName:     aws.String("node-ready"),
+			InstanceId:            aws.String("i-0123456789abcdef0"),
+			LifecycleActionResult: aws.String(ContinueResult),
+		}}))
+	})
+
+	It("should continue the lifecycle action of an instance launching into a warm pool", func() {
+		api.instances[0].LifecycleState = aws.String("Warmed:Pending:Wait")
+		completed, err := lifecycle.Complete(context.Background(), node, "node-ready")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(completed).To(BeTrue())
+	})
+
+	It("should do nothing for an instance that isn't waiting", func() {
+		api.instances[0].LifecycleState = aws.String("InService")
+		completed, err := lifecycle.Complete(context.Background(), node, "node-ready")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(completed).To(BeFalse())
+		Expect(api.completed).To(BeEmpty())
+	})
+
+	It("should do nothing for an instance outside of any Auto Scaling group", func() {
+		api.instances = nil
+		completed, err := lifecycle.Complete(context.Background(), node, "node-ready")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(completed).To(BeFalse())
+	})
+
+	It("should report failures to complete the lifecycle action", func() {
+		api.completeErr = errors.New("no active lifecycle action")
+		_, err := lifecycle.Complete(context.Background(), node, "node-ready")
+		Expect(err).To(MatchError(ContainSubstring("no active lifecycle action")))
+	})
+
+	It("should reject nodes without an EC2 provider ID", func() {
+		node.Spec.ProviderID = "gce://project/zone/node"
+		_, err := lifecycle.Complete(context.Background(), node, "node-ready")
+		Expect(err).To(MatchError(ContainSubstring("no AWS provider ID")))
+		Expect(err).To(MatchError(ErrNoInstanceID))
+		Expect(api.completed).To(BeEmpty())
+	})
+
+	It("should load the AWS configuration again after failing to", func() {
+		GinkgoT().Setenv("AWS_CONFIG_FILE", "/nonexistent/config")
+		GinkgoT().Setenv("AWS_SHARED_CREDENTIALS_FILE", "/nonexistent/credentials")
+		GinkgoT().Setenv("AWS_PROFILE", "missing")
+		lifecycle = &Lifecycle{}
+		_, err := lifecycle.Complete(context.Background(), node, "node-ready")
+		Expect(err).To(MatchError(ContainSubstring("failed to load AWS configuration")))
+
+		GinkgoT().Setenv("AWS_PROFILE", "")
+		client, err := lifecycle.api(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(client).NotTo(BeNil())
+	})
+})
+
+var _ = Describe("InstanceID", func() {
+	It("should parse the instance ID from the provider ID", func() {
+		id, err := InstanceID(&corev1.Node{Spec: corev1.NodeSpec{ProviderID: "aws:///eu-west-1b/i-0abc"}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(id).To(Equal("i-0abc"))
+	})
+
+	It("should reject provider IDs without an instance ID", func() {
+		_, err := InstanceID(&corev1.Node{Spec: corev1.NodeSpec{ProviderID: "aws:///fargate-ip-10-0-0-1"}})
+		Expect(err).To(HaveOccurred())
+	})
+})
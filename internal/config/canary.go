@@ -0,0 +1,70 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// DefaultCanaryImage is the image of canary pods by default. It does nothing
+// but keep the pod sandbox running.
+const DefaultCanaryImage = "registry.k8s.io/pause:3.10"
+
+// Canary is a tiny pod the operator schedules onto a node before removing a
+// rule's taint, as end-to-end proof the node can run workloads. It tolerates
+// the taints of every rule and is deleted once it is ready and passed its
+// probe.
+type Canary struct {
+	// Namespace is the namespace the canary pods are created in
+	Namespace string `json:"namespace"`
+	// Image is the image of the canary's container
+	Image string `json:"image,omitempty"`
+	// Command and Args override the entrypoint of the image
+	Command []string `json:"command,omitempty"`
+	Args    []string `json:"args,omitempty"`
+	// Probe, when set, is sent to the canary once it is ready and must pass
+	Probe *PodProbe `json:"probe,omitempty"`
+}
+
+// Default fills in the unset fields of a canary. It does nothing on a nil canary.
+func (c *Canary) Default() {
+	if c == nil {
+		return
+	}
+	if c.Image == "" {
+		c.Image = DefaultCanaryImage
+	}
+	c.Probe.Default()
+}
+
+// ValidateCanary checks that canary, when set, names the namespace of its
+// pods and has a valid probe
+func ValidateCanary(path *field.Path, canary *Canary) field.ErrorList {
+	if canary == nil {
+		return nil
+	}
+	var errs field.ErrorList
+	if canary.Namespace == "" {
+		errs = append(errs, field.Required(path.Child("namespace"), ""))
+	} else {
+		for _, msg := range validation.IsDNS1123Label(canary.Namespace) {
+			errs = append(errs, field.Invalid(path.Child("namespace"), canary.Namespace, msg))
+		}
+	}
+	return append(errs, ValidatePodProbe(path.Child("probe"), canary.Probe)...)
+}
@@ -0,0 +1,882 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/yaml"
+)
+
+// DefaultConfigMapKey is the ConfigMap data key holding the YAML configuration
+const DefaultConfigMapKey = "config.yaml"
+
+// DefaultRequeueInterval is how long a node waits before being re-checked
+// when its required workloads are not ready yet
+const DefaultRequeueInterval = 30 * time.Second
+
+// DefaultMaxRequeueInterval caps the requeue interval of a node that keeps
+// being blocked by the same pods
+const DefaultMaxRequeueInterval = 5 * time.Minute
+
+// DefaultStuckNodeThreshold is how long a node stays blocked before it is
+// counted as stuck when StuckNodeThreshold is unset
+const DefaultStuckNodeThreshold = 15 * time.Minute
+
+// DefaultRestartWindow is the period over which container restarts are counted
+// when a restart threshold is set
+const DefaultRestartWindow = 10 * time.Minute
+
+// DefaultRequeueJitter is the default fraction of the requeue interval added
+// at random, so nodes blocked on the same workload don't requeue in lockstep
+const DefaultRequeueJitter = 0.1
+
+// DefaultHookTimeout is how long an untaint hook may take to answer
+const DefaultHookTimeout = 10 * time.Second
+
+// RemovalStrategy selects how taints are removed from a node
+type RemovalStrategy string
+
+const (
+	// RemovalStrategyPatch sends a merge patch of spec.taints
+	RemovalStrategyPatch RemovalStrategy = "Patch"
+	// RemovalStrategyApply uses server-side apply with the operator's field manager
+	RemovalStrategyApply RemovalStrategy = "Apply"
+	// RemovalStrategyJSONPatch sends a JSON patch that tests each taint entry
+	// before removing it
+	RemovalStrategyJSONPatch RemovalStrategy = "JSONPatch"
+)
+
+// ReadinessMode selects how the readiness of a target pod is determined
+type ReadinessMode string
+
+const (
+	// ReadinessModePodReady trusts the pod's aggregated Ready condition
+	ReadinessModePodReady ReadinessMode = "PodReady"
+	// ReadinessModeContainers additionally requires every container, including
+	// sidecars, to report itself started and ready
+	ReadinessModeContainers ReadinessMode = "Containers"
+)
+
+// MaxWaitAction selects what happens when a taint outlives its rule's maxWait
+type MaxWaitAction string
+
+const (
+	// MaxWaitActionEvent keeps the taint and emits a warning event on the node
+	MaxWaitActionEvent MaxWaitAction = "Event"
+	// MaxWaitActionForceRemove removes the taint even though its workloads are
+	// not ready, and emits a warning event on the node
+	MaxWaitActionForceRemove MaxWaitAction = "ForceRemove"
+)
+
+// UntaintAction is a step taken when a rule's taint is removed
+type UntaintAction string
+
+const (
+	// UntaintActionUntaint removes the taint
+	UntaintActionUntaint UntaintAction = "Untaint"
+	// UntaintActionUncordon clears spec.unschedulable
+	UntaintActionUncordon UntaintAction = "Uncordon"
+	// UntaintActionLabel sets and removes the rule's labels
+	UntaintActionLabel UntaintAction = "Label"
+	// UntaintActionAnnotate records the removal in annotations on the node
+	UntaintActionAnnotate UntaintAction = "Annotate"
+	// UntaintActionNotify emits an event on the node once the taint is removed
+	UntaintActionNotify UntaintAction = "Notify"
+	// UntaintActionCompleteLifecycle completes the AWS Auto Scaling lifecycle
+	// action of the instance backing the node once the taint is removed
+	UntaintActionCompleteLifecycle UntaintAction = "CompleteLifecycleAction"
+)
+
+// untaintActions are the known untaint actions
+var untaintActions = []UntaintAction{
+	UntaintActionUntaint, UntaintActionUncordon, UntaintActionLabel, UntaintActionAnnotate, UntaintActionNotify,
+	UntaintActionCompleteLifecycle,
+}
+
+// HookFailurePolicy selects what happens when the pre-untaint hook can't be
+// reached or doesn't answer successfully
+type HookFailurePolicy string
+
+const (
+	// HookFailurePolicyFail keeps the taints until the hook answers
+	HookFailurePolicyFail HookFailurePolicy = "Fail"
+	// HookFailurePolicyIgnore removes the taints as if the hook allowed it
+	HookFailurePolicyIgnore HookFailurePolicy = "Ignore"
+)
+
+// Hook is an HTTP endpoint the operator POSTs to around the removal of a
+// node's target taints
+type Hook struct {
+	// URL is the http or https endpoint to call
+	URL string `json:"url"`
+	// Timeout is how long the hook may take to answer
+	Timeout metav1.Duration `json:"timeout,omitempty"`
+	// FailurePolicy selects what happens when the pre-untaint hook fails. It is
+	// ignored for the post-untaint hook, whose failures are only logged.
+	FailurePolicy HookFailurePolicy `json:"failurePolicy,omitempty"`
+}
+
+// RemovalStage is a weaker effect a taint is downgraded to, and how long it
+// keeps that effect, on its way to removal
+type RemovalStage struct {
+	// Effect is the effect the taint is downgraded to: NoSchedule or PreferNoSchedule
+	Effect corev1.TaintEffect `json:"effect"`
+	// Dwell is how long the taint keeps the effect before the next stage
+	Dwell metav1.Duration `json:"dwell"`
+}
+
+// Rule ties a taint to the workloads that must be ready before it is removed
+type Rule struct {
+	// Preset, when set, names a built-in rule for a well-known startup taint
+	// that fills in TargetTaint, OwnedByNames and CSIDrivers when they are unset
+	Preset string `json:"preset,omitempty"`
+	// TargetTaint is the taint key to watch for and remove
+	TargetTaint string `json:"targetTaint"`
+	// Effects, when set, limits the rule to the entries of TargetTaint with
+	// these effects; entries with other effects are left in place. All entries
+	// of the key are managed when empty.
+	Effects []corev1.TaintEffect `json:"effects,omitempty"`
+	// OwnedByNames is a list of workload names to check for readiness
+	OwnedByNames []string `json:"ownedByNames"`
+	// CSIDrivers are CSI drivers that must additionally be registered in the
+	// node's CSINode before the taint is removed, so stateful pods don't land
+	// on a node whose storage plugin can't mount their volumes yet
+	CSIDrivers []string `json:"csiDrivers,omitempty"`
+	// RequiredResources are the minimum allocatable quantities, such as
+	// nvidia.com/gpu: 1, the node must advertise before the taint is removed,
+	// since a device plugin's pod can be ready before its resources are
+	RequiredResources corev1.ResourceList `json:"requiredResources,omitempty"`
+	// ResourceGates are resources, such as custom resources a platform creates
+	// for each node, that must additionally report the node ready before the
+	// taint is removed
+	ResourceGates []ResourceGate `json:"resourceGates,omitempty"`
+	// Probe, when set, is sent to every ready pod of the workloads before the
+	// taint is removed, and must pass
+	Probe *PodProbe `json:"probe,omitempty"`
+	// Canary, when set, is scheduled onto the node once everything else is
+	// ready, and must become ready itself before the taint is removed
+	Canary *Canary `json:"canary,omitempty"`
+	// RequeueInterval overrides the global requeue interval for this rule
+	RequeueInterval *metav1.Duration `json:"requeueInterval,omitempty"`
+	// MaxWait, when set, is how long the taint may stay on a node waiting for
+	// its workloads before OnMaxWait is taken, so a broken agent can't strand
+	// capacity indefinitely
+	MaxWait *metav1.Duration `json:"maxWait,omitempty"`
+	// OnMaxWait selects what happens once the taint outlived MaxWait
+	OnMaxWait MaxWaitAction `json:"onMaxWait,omitempty"`
+	// StagedRemoval, when set, downgrades the taint through these effects once
+	// its workloads are ready, from the strongest to the weakest, before
+	// removing it, so workloads trickle onto the node instead of stampeding
+	StagedRemoval []RemovalStage `json:"stagedRemoval,omitempty"`
+	// Actions are the steps taken, in order, when the taint is removed. The
+	// ones editing the node are made in the same write that removes the taint.
+	// Defaults to Untaint, followed by Uncordon, Label, Annotate and
+	// CompleteLifecycleAction when Uncordon, the labels, the config's
+	// RecordUntaint or AWSLifecycleHook ask for them, and Notify.
+	Actions []UntaintAction `json:"actions,omitempty"`
+	// Uncordon additionally clears spec.unschedulable when the taint is
+	// removed, for provisioning pipelines that cordon nodes until their agents
+	// are ready. It adds the Uncordon action to the default actions.
+	Uncordon bool `json:"uncordon,omitempty"`
+	// SetLabels are set on the node by the Label action, so schedulers and
+	// controllers keyed on labels get the same signal
+	SetLabels map[string]string `json:"setLabels,omitempty"`
+	// RemoveLabels are removed from the node by the Label action
+	RemoveLabels []string `json:"removeLabels,omitempty"`
+	// AWSLifecycleHook is the name of the launch lifecycle hook of the node's
+	// Auto Scaling group that the CompleteLifecycleAction action continues, so
+	// the instance only goes into service, or into its warm pool, once its
+	// workloads are ready
+	AWSLifecycleHook string `json:"awsLifecycleHook,omitempty"`
+}
+
+// Config is the runtime configuration of the operator
+type Config struct {
+	// Profile, when set, names a built-in profile of a managed Kubernetes
+	// offering that adds rules for its bootstrap taints and gates rules that
+	// list no workloads on its agents
+	Profile string `json:"profile,omitempty"`
+	// Rules are the taints to manage and the workloads gating each of them
+	Rules []Rule `json:"rules"`
+	// RequeueInterval is how long to wait before re-checking a node whose
+	// workloads are not ready yet
+	RequeueInterval metav1.Duration `json:"requeueInterval,omitempty"`
+	// MaxRequeueInterval caps the backoff applied to a node that stays blocked
+	// by unchanged pods. Set it to the requeue interval to disable the backoff.
+	MaxRequeueInterval metav1.Duration `json:"maxRequeueInterval,omitempty"`
+	// RequeueJitter is the maximum fraction of the requeue interval added at
+	// random to each requeue. Set it to 0 to disable jitter.
+	RequeueJitter *float64 `json:"requeueJitter,omitempty"`
+	// ReadinessMode selects how the readiness of a target pod is determined
+	ReadinessMode ReadinessMode `json:"readinessMode,omitempty"`
+	// RequireInitContainers additionally requires every init container of a
+	// target pod, other than sidecars, to have terminated successfully
+	RequireInitContainers bool `json:"requireInitContainers,omitempty"`
+	// RequireNodeReady additionally requires the node's own Ready condition to
+	// be True, since pods can keep a stale Ready status while the node flaps
+	RequireNodeReady bool `json:"requireNodeReady,omitempty"`
+	// RequireNetworkAvailable additionally requires the node's
+	// NetworkUnavailable condition, when reported, to be False
+	RequireNetworkAvailable bool `json:"requireNetworkAvailable,omitempty"`
+	// BlockingConditions are node condition types, such as the KernelDeadlock
+	// and ReadonlyFilesystem conditions of node-problem-detector, that keep the
+	// taints on a node while any of them is True
+	BlockingConditions []corev1.NodeConditionType `json:"blockingConditions,omitempty"`
+	// Paused suspends untainting of every node without stopping the operator,
+	// for example during incident response. Nodes keep being evaluated and
+	// report the pause, and no maxWait force removes their taints.
+	Paused bool `json:"paused,omitempty"`
+	// UntaintRateLimit, when set, caps how many nodes have their target taints
+	// removed per interval
+	UntaintRateLimit *UntaintRateLimit `json:"untaintRateLimit,omitempty"`
+	// UntaintWindows, when set, are cron-scheduled windows during which
+	// untainting is paused, or outside of which it is
+	UntaintWindows *UntaintWindows `json:"untaintWindows,omitempty"`
+	// NodeConditionType, when set, is the type of a node condition the
+	// operator keeps True while the node's target taints are removed and False
+	// with the blocking reason while they are not, so dashboards and other
+	// controllers can consume the gate without parsing taints
+	NodeConditionType corev1.NodeConditionType `json:"nodeConditionType,omitempty"`
+	// RecordUntaint annotates a node with who removed its target taints, when,
+	// and which pods satisfied the gate. It adds the Annotate action to the
+	// default actions of every rule.
+	RecordUntaint bool `json:"recordUntaint,omitempty"`
+	// MinNodeAge is how long a node must have existed before its taints are
+	// removed, riding out readiness flaps while it bootstraps. Zero disables
+	// the check.
+	MinNodeAge metav1.Duration `json:"minNodeAge,omitempty"`
+	// MaxLeaseAge, when set, requires the node's kubelet Lease to have been
+	// renewed within this period, so scheduling never opens up on a node whose
+	// kubelet went silent. Zero disables the check.
+	MaxLeaseAge metav1.Duration `json:"maxLeaseAge,omitempty"`
+	// MinReadySeconds is how long a target pod must have been ready without
+	// interruption before it counts as ready, so a flapping pod can't release
+	// the taint
+	MinReadySeconds int32 `json:"minReadySeconds,omitempty"`
+	// MaxRestarts, when set, treats a target pod as not ready while one of its
+	// containers restarted more than this many times within RestartWindow, so a
+	// crash-looping agent that briefly reports ready can't release the taint
+	MaxRestarts *int32 `json:"maxRestarts,omitempty"`
+	// RestartWindow is the period over which restarts are counted against MaxRestarts
+	RestartWindow metav1.Duration `json:"restartWindow,omitempty"`
+	// UntaintCooldown is how long a target taint re-added to a node is ignored
+	// after the operator removed it, so another controller that keeps
+	// re-applying it can't drive a tight loop. Zero disables the cooldown.
+	UntaintCooldown metav1.Duration `json:"untaintCooldown,omitempty"`
+	// MaxNodeUntaintsPerMinute caps how often the target taints of a single
+	// node are removed per minute, so a node flapping between ready and not
+	// ready can't drive a storm of writes. Zero disables the cap.
+	MaxNodeUntaintsPerMinute int32 `json:"maxNodeUntaintsPerMinute,omitempty"`
+	// RemovalStrategy selects how taints are removed from a node
+	RemovalStrategy RemovalStrategy `json:"removalStrategy,omitempty"`
+	// ForceApply takes ownership of spec.taints from other field managers when
+	// RemovalStrategy is Apply, instead of failing with a conflict
+	ForceApply bool `json:"forceApply,omitempty"`
+	// PreUntaintHook, when set, is asked before a node's target taints are
+	// removed and can veto the removal, so external systems such as a CMDB or
+	// a provisioning orchestrator take part in the decision
+	PreUntaintHook *Hook `json:"preUntaintHook,omitempty"`
+	// PostUntaintHook, when set, is told once a node's target taints were removed
+	PostUntaintHook *Hook `json:"postUntaintHook,omitempty"`
+	// Notifications, when set, posts to Slack or a generic webhook about nodes
+	// that stay blocked too long and nodes whose taints are removed
+	Notifications *Notifications `json:"notifications,omitempty"`
+	// StuckNodeThreshold is how long a node must have been blocked before the
+	// stuck nodes metric counts it, typically the node readiness SLO
+	StuckNodeThreshold metav1.Duration `json:"stuckNodeThreshold,omitempty"`
+	// MaxConcurrentReconciles is how many nodes are reconciled in parallel,
+	// overriding the max-concurrent-reconciles flag when set. It is only read
+	// at startup.
+	MaxConcurrentReconciles int32 `json:"maxConcurrentReconciles,omitempty"`
+}
+
+// Load reads and validates the YAML configuration file at path
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	return Parse(data)
+}
+
+// Parse decodes and validates a YAML configuration document
+func Parse(data []byte) (*Config, error) {
+	cfg := &Config{}
+	if err := yaml.UnmarshalStrict(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	cfg.Default()
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// FromConfigMap decodes and validates the YAML configuration stored under key in cm
+func FromConfigMap(cm *corev1.ConfigMap, key string) (*Config, error) {
+	data, ok := cm.Data[key]
+	if !ok {
+		return nil, fmt.Errorf("configmap %s/%s has no %q key", cm.Namespace, cm.Name, key)
+	}
+	return Parse([]byte(data))
+}
+
+// Default fills in unset tuning knobs with their default values
+func (c *Config) Default() {
+	if c.RequeueInterval.Duration == 0 {
+		c.RequeueInterval.Duration = DefaultRequeueInterval
+	}
+	if c.MaxRequeueInterval.Duration == 0 {
+		c.MaxRequeueInterval.Duration = max(DefaultMaxRequeueInterval, c.RequeueInterval.Duration)
+	}
+	if c.RequeueJitter == nil {
+		jitter := DefaultRequeueJitter
+		c.RequeueJitter = &jitter
+	}
+	if c.RestartWindow.Duration == 0 {
+		c.RestartWindow.Duration = DefaultRestartWindow
+	}
+	if c.StuckNodeThreshold.Duration == 0 {
+		c.StuckNodeThreshold.Duration = DefaultStuckNodeThreshold
+	}
+	if c.ReadinessMode == "" {
+		c.ReadinessMode = ReadinessModePodReady
+	}
+	if c.RemovalStrategy == "" {
+		c.RemovalStrategy = RemovalStrategyPatch
+	}
+	for _, hook := range []*Hook{c.PreUntaintHook, c.PostUntaintHook} {
+		hook.Default()
+	}
+	c.Notifications.Default()
+	c.UntaintWindows.Default()
+	for i := range c.Rules {
+		applyPreset(&c.Rules[i])
+	}
+	c.applyProfile()
+	for i := range c.Rules {
+		if c.Rules[i].OnMaxWait == "" {
+			c.Rules[i].OnMaxWait = MaxWaitActionEvent
+		}
+		c.Rules[i].Probe.Default()
+		c.Rules[i].Canary.Default()
+		if len(c.Rules[i].Actions) == 0 {
+			c.Rules[i].Actions = c.defaultActions(c.Rules[i])
+		}
+	}
+}
+
+// Default fills in the unset fields of a hook. It does nothing on a nil hook.
+func (h *Hook) Default() {
+	if h == nil {
+		return
+	}
+	if h.Timeout.Duration == 0 {
+		h.Timeout.Duration = DefaultHookTimeout
+	}
+	if h.FailurePolicy == "" {
+		h.FailurePolicy = HookFailurePolicyFail
+	}
+}
+
+// defaultActions returns the actions of a rule that doesn't list any
+func (c *Config) defaultActions(rule Rule) []UntaintAction {
+	actions := []UntaintAction{UntaintActionUntaint}
+	if rule.Uncordon {
+		actions = append(actions, UntaintActionUncordon)
+	}
+	if len(rule.SetLabels) > 0 || len(rule.RemoveLabels) > 0 {
+		actions = append(actions, UntaintActionLabel)
+	}
+	if c.RecordUntaint {
+		actions = append(actions, UntaintActionAnnotate)
+	}
+	if rule.AWSLifecycleHook != "" {
+		actions = append(actions, UntaintActionCompleteLifecycle)
+	}
+	return append(actions, UntaintActionNotify)
+}
+
+// Validate checks that the configuration can be used by the reconciler. The
+// returned error lists every invalid field by its path in the YAML document.
+func (c *Config) Validate() error {
+	var errs field.ErrorList
+
+	errs = append(errs, ValidateProfile(field.NewPath("profile"), c.Profile)...)
+	rulesPath := field.NewPath("rules")
+	if len(c.Rules) == 0 {
+		errs = append(errs, field.Required(rulesPath, "at least one rule is required"))
+	}
+	seenTaints := make(map[string]bool)
+	for i, rule := range c.Rules {
+		rulePath := rulesPath.Index(i)
+		errs = append(errs, ValidatePreset(rulePath.Child("preset"), rule.Preset)...)
+		errs = append(errs, ValidateTaintKey(rulePath.Child("targetTaint"), rule.TargetTaint)...)
+		if rule.TargetTaint != "" && seenTaints[rule.TargetTaint] {
+			errs = append(errs, field.Duplicate(rulePath.Child("targetTaint"), rule.TargetTaint))
+		}
+		seenTaints[rule.TargetTaint] = true
+		errs = append(errs, ValidateWorkloadNames(rulePath.Child("ownedByNames"), rule.OwnedByNames)...)
+		errs = append(errs, ValidateTaintEffects(rulePath.Child("effects"), rule.Effects)...)
+		errs = append(errs, ValidateCSIDrivers(rulePath.Child("csiDrivers"), rule.CSIDrivers)...)
+		errs = append(errs, ValidateRequiredResources(rulePath.Child("requiredResources"), rule.RequiredResources)...)
+		errs = append(errs, ValidateResourceGates(rulePath.Child("resourceGates"), rule.ResourceGates)...)
+		errs = append(errs, ValidatePodProbe(rulePath.Child("probe"), rule.Probe)...)
+		errs = append(errs, ValidateCanary(rulePath.Child("canary"), rule.Canary)...)
+		if rule.RequeueInterval != nil && rule.RequeueInterval.Duration <= 0 {
+			errs = append(errs, field.Invalid(rulePath.Child("requeueInterval"), rule.RequeueInterval.Duration.String(),
+				"must be positive"))
+		}
+		if rule.MaxWait != nil && rule.MaxWait.Duration <= 0 {
+			errs = append(errs, field.Invalid(rulePath.Child("maxWait"), rule.MaxWait.Duration.String(),
+				"must be positive"))
+		}
+		errs = append(errs, ValidateMaxWaitAction(rulePath.Child("onMaxWait"), rule.OnMaxWait)...)
+		errs = append(errs, ValidateRemovalStages(rulePath.Child("stagedRemoval"), rule.StagedRemoval)...)
+		errs = append(errs, metav1validation.ValidateLabels(rule.SetLabels, rulePath.Child("setLabels"))...)
+		errs = append(errs, ValidateRemoveLabels(rulePath.Child("removeLabels"), rule.RemoveLabels, c.RemovalStrategy)...)
+		errs = append(errs, validateRuleActions(rulePath, rule)...)
+	}
+
+	if c.RequeueInterval.Duration < 0 {
+		errs = append(errs, field.Invalid(field.NewPath("requeueInterval"), c.RequeueInterval.Duration.String(),
+			"must not be negative"))
+	}
+	if c.MaxRequeueInterval.Duration < 0 {
+		errs = append(errs, field.Invalid(field.NewPath("maxRequeueInterval"), c.MaxRequeueInterval.Duration.String(),
+			"must not be negative"))
+	}
+	if c.MaxRestarts != nil && *c.MaxRestarts < 0 {
+		errs = append(errs, field.Invalid(field.NewPath("maxRestarts"), *c.MaxRestarts, "must not be negative"))
+	}
+	if c.RestartWindow.Duration < 0 {
+		errs = append(errs, field.Invalid(field.NewPath("restartWindow"), c.RestartWindow.Duration.String(),
+			"must not be negative"))
+	}
+	if c.MinNodeAge.Duration < 0 {
+		errs = append(errs, field.Invalid(field.NewPath("minNodeAge"), c.MinNodeAge.Duration.String(),
+			"must not be negative"))
+	}
+	if c.StuckNodeThreshold.Duration < 0 {
+		errs = append(errs, field.Invalid(field.NewPath("stuckNodeThreshold"), c.StuckNodeThreshold.Duration.String(),
+			"must not be negative"))
+	}
+	if c.MaxLeaseAge.Duration < 0 {
+		errs = append(errs, field.Invalid(field.NewPath("maxLeaseAge"), c.MaxLeaseAge.Duration.String(),
+			"must not be negative"))
+	}
+	if c.UntaintCooldown.Duration < 0 {
+		errs = append(errs, field.Invalid(field.NewPath("untaintCooldown"), c.UntaintCooldown.Duration.String(),
+			"must not be negative"))
+	}
+	if c.MaxConcurrentReconciles < 0 {
+		errs = append(errs, field.Invalid(field.NewPath("maxConcurrentReconciles"), c.MaxConcurrentReconciles,
+			"must not be negative"))
+	}
+	if c.MaxNodeUntaintsPerMinute < 0 {
+		errs = append(errs, field.Invalid(field.NewPath("maxNodeUntaintsPerMinute"), c.MaxNodeUntaintsPerMinute,
+			"must not be negative"))
+	}
+	if c.MinReadySeconds < 0 {
+		errs = append(errs, field.Invalid(field.NewPath("minReadySeconds"), c.MinReadySeconds,
+			"must not be negative"))
+	}
+	if c.RequeueJitter != nil && (*c.RequeueJitter < 0 || *c.RequeueJitter > 1) {
+		errs = append(errs, field.Invalid(field.NewPath("requeueJitter"), *c.RequeueJitter,
+			"must be between 0 and 1"))
+	}
+
+	errs = append(errs, ValidateReadinessMode(field.NewPath("readinessMode"), c.ReadinessMode)...)
+	errs = append(errs, ValidateNodeConditionType(field.NewPath("nodeConditionType"), c.NodeConditionType)...)
+	errs = append(errs, ValidateBlockingConditions(field.NewPath("blockingConditions"), c.BlockingConditions,
+		c.NodeConditionType)...)
+	errs = append(errs, ValidateUntaintRateLimit(field.NewPath("untaintRateLimit"), c.UntaintRateLimit)...)
+	errs = append(errs, ValidateUntaintWindows(field.NewPath("untaintWindows"), c.UntaintWindows)...)
+	errs = append(errs, ValidateRemovalStrategy(field.NewPath("removalStrategy"), c.RemovalStrategy)...)
+	errs = append(errs, ValidateHook(field.NewPath("preUntaintHook"), c.PreUntaintHook)...)
+	errs = append(errs, ValidateHook(field.NewPath("postUntaintHook"), c.PostUntaintHook)...)
+	errs = append(errs, ValidateNotifications(field.NewPath("notifications"), c.Notifications)...)
+
+	return errs.ToAggregate()
+}
+
+// ValidateTaintKey checks that key is a valid taint key, which follows the
+// same rules as a label key: an optional DNS subdomain prefix and a name
+func ValidateTaintKey(path *field.Path, key string) field.ErrorList {
+	if key == "" {
+		return field.ErrorList{field.Required(path, "a taint key is required")}
+	}
+	var errs field.ErrorList
+	for _, msg := range validation.IsQualifiedName(key) {
+		errs = append(errs, field.Invalid(path, key, msg))
+	}
+	return errs
+}
+
+// taintEffects are the effects a taint can have
+var taintEffects = []corev1.TaintEffect{
+	corev1.TaintEffectNoSchedule, corev1.TaintEffectPreferNoSchedule, corev1.TaintEffectNoExecute,
+}
+
+// ValidateTaintEffects checks that effects is a list of unique taint effects
+func ValidateTaintEffects(path *field.Path, effects []corev1.TaintEffect) field.ErrorList {
+	var errs field.ErrorList
+	for i, effect := range effects {
+		switch {
+		case !slices.Contains(taintEffects, effect):
+			errs = append(errs, field.NotSupported(path.Index(i), effect, taintEffects))
+		case slices.Contains(effects[:i], effect):
+			errs = append(errs, field.Duplicate(path.Index(i), effect))
+		}
+	}
+	return errs
+}
+
+// ValidateWorkloadNames checks that names is a non-empty list of unique,
+// non-empty workload names
+func ValidateWorkloadNames(path *field.Path, names []string) field.ErrorList {
+	if len(names) == 0 {
+		return field.ErrorList{field.Required(path, "at least one workload name is required")}
+	}
+	var errs field.ErrorList
+	seen := make(map[string]bool, len(names))
+	for i, name := range names {
+		switch {
+		case name == "":
+			errs = append(errs, field.Invalid(path.Index(i), name, "workload name must not be empty"))
+		case seen[name]:
+			errs = append(errs, field.Duplicate(path.Index(i), name))
+		}
+		seen[name] = true
+	}
+	return errs
+}
+
+// ValidateReadinessMode checks that mode is a known readiness mode
+func ValidateReadinessMode(path *field.Path, mode ReadinessMode) field.ErrorList {
+	switch mode {
+	case ReadinessModePodReady, ReadinessModeContainers:
+		return nil
+	default:
+		return field.ErrorList{field.NotSupported(path, mode,
+			[]ReadinessMode{ReadinessModePodReady, ReadinessModeContainers})}
+	}
+}
+
+// ValidateMaxWaitAction checks that action is a known maxWait action
+func ValidateMaxWaitAction(path *field.Path, action MaxWaitAction) field.ErrorList {
+	switch action {
+	case MaxWaitActionEvent, MaxWaitActionForceRemove:
+		return nil
+	default:
+		return field.ErrorList{field.NotSupported(path, action,
+			[]MaxWaitAction{MaxWaitActionEvent, MaxWaitActionForceRemove})}
+	}
+}
+
+// stageEffects are the effects a taint can be downgraded to, weakest first
+var stageEffects = []corev1.TaintEffect{corev1.TaintEffectPreferNoSchedule, corev1.TaintEffectNoSchedule}
+
+// ValidateRemovalStages checks that stages downgrade a taint to ever weaker
+// effects and dwell a positive time at each of them
+func ValidateRemovalStages(path *field.Path, stages []RemovalStage) field.ErrorList {
+	var errs field.ErrorList
+	previous := len(stageEffects)
+	for i, stage := range stages {
+		stagePath := path.Index(i)
+		strength := slices.Index(stageEffects, stage.Effect)
+		switch {
+		case strength < 0:
+			errs = append(errs, field.NotSupported(stagePath.Child("effect"), stage.Effect, stageEffects))
+		case strength >= previous:
+			errs = append(errs, field.Invalid(stagePath.Child("effect"), stage.Effect,
+				"must be weaker than the effect of the previous stage"))
+		}
+		if strength >= 0 {
+			previous = strength
+		}
+		if stage.Dwell.Duration <= 0 {
+			errs = append(errs, field.Invalid(stagePath.Child("dwell"), stage.Dwell.Duration.String(),
+				"must be positive"))
+		}
+	}
+	return errs
+}
+
+// ValidateUntaintActions checks that actions are unique known actions
+// including Untaint
+func ValidateUntaintActions(path *field.Path, actions []UntaintAction) field.ErrorList {
+	var errs field.ErrorList
+	seen := make(map[UntaintAction]bool, len(actions))
+	for i, action := range actions {
+		if !slices.Contains(untaintActions, action) {
+			errs = append(errs, field.NotSupported(path.Index(i), action, untaintActions))
+		}
+		if seen[action] {
+			errs = append(errs, field.Duplicate(path.Index(i), action))
+		}
+		seen[action] = true
+	}
+	if !seen[UntaintActionUntaint] {
+		errs = append(errs, field.Required(path, "must include Untaint"))
+	}
+	return errs
+}
+
+// validateRuleActions checks the actions of the rule at path, and that they
+// are consistent with the rule's uncordon and label settings
+func validateRuleActions(path *field.Path, rule Rule) field.ErrorList {
+	errs := ValidateUntaintActions(path.Child("actions"), rule.Actions)
+	seen := make(map[UntaintAction]bool, len(rule.Actions))
+	for _, action := range rule.Actions {
+		seen[action] = true
+	}
+	if rule.Uncordon && !seen[UntaintActionUncordon] {
+		errs = append(errs, field.Invalid(path.Child("uncordon"), rule.Uncordon, "requires the Uncordon action"))
+	}
+	hasLabels := len(rule.SetLabels) > 0 || len(rule.RemoveLabels) > 0
+	if hasLabels && !seen[UntaintActionLabel] {
+		errs = append(errs, field.Invalid(path.Child("setLabels"), rule.SetLabels, "requires the Label action"))
+	}
+	if !hasLabels && seen[UntaintActionLabel] {
+		errs = append(errs, field.Required(path.Child("setLabels"), "the Label action requires labels to set or remove"))
+	}
+	hookPath := path.Child("awsLifecycleHook")
+	switch {
+	case rule.AWSLifecycleHook != "" && !seen[UntaintActionCompleteLifecycle]:
+		errs = append(errs, field.Invalid(hookPath, rule.AWSLifecycleHook, "requires the CompleteLifecycleAction action"))
+	case rule.AWSLifecycleHook == "" && seen[UntaintActionCompleteLifecycle]:
+		errs = append(errs, field.Required(hookPath, "the CompleteLifecycleAction action requires a lifecycle hook"))
+	}
+	return append(errs, ValidateLifecycleHookName(hookPath, rule.AWSLifecycleHook)...)
+}
+
+// ValidateHook checks that hook, when set, calls an absolute http or https URL
+// with a positive timeout and a known failure policy
+func ValidateHook(path *field.Path, hook *Hook) field.ErrorList {
+	if hook == nil {
+		return nil
+	}
+	errs := validateWebhookURL(path.Child("url"), hook.URL)
+	if hook.Timeout.Duration <= 0 {
+		errs = append(errs, field.Invalid(path.Child("timeout"), hook.Timeout.Duration.String(), "must be positive"))
+	}
+	switch hook.FailurePolicy {
+	case HookFailurePolicyFail, HookFailurePolicyIgnore:
+	default:
+		errs = append(errs, field.NotSupported(path.Child("failurePolicy"), hook.FailurePolicy,
+			[]HookFailurePolicy{HookFailurePolicyFail, HookFailurePolicyIgnore}))
+	}
+	return errs
+}
+
+// lifecycleHookNamePattern matches the names AWS accepts for lifecycle hooks
+var lifecycleHookNamePattern = regexp.MustCompile(`^[A-Za-z0-9\-_/]{1,255}$`)
+
+// ValidateLifecycleHookName checks that name, when set, is a valid AWS Auto
+// Scaling lifecycle hook name
+func ValidateLifecycleHookName(path *field.Path, name string) field.ErrorList {
+	if name == "" || lifecycleHookNamePattern.MatchString(name) {
+		return nil
+	}
+	return field.ErrorList{field.Invalid(path, name,
+		"must be 1 to 255 letters, digits, hyphens, underscores and slashes")}
+}
+
+// validateWebhookURL checks that rawURL is an absolute http or https URL
+func validateWebhookURL(path *field.Path, rawURL string) field.ErrorList {
+	if rawURL == "" {
+		return field.ErrorList{field.Required(path, "a URL is required")}
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return field.ErrorList{field.Invalid(path, rawURL, err.Error())}
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return field.ErrorList{field.Invalid(path, rawURL, "must be an absolute http or https URL")}
+	}
+	return nil
+}
+
+// ValidateNodeConditionType checks that conditionType, when set, is a valid
+// condition type that doesn't collide with one kubelet maintains
+func ValidateNodeConditionType(path *field.Path, conditionType corev1.NodeConditionType) field.ErrorList {
+	if conditionType == "" {
+		return nil
+	}
+	var errs field.ErrorList
+	for _, msg := range validation.IsQualifiedName(string(conditionType)) {
+		errs = append(errs, field.Invalid(path, conditionType, msg))
+	}
+	if slices.Contains(kubeletConditionTypes, conditionType) {
+		errs = append(errs, field.Invalid(path, conditionType, "is maintained by kubelet"))
+	}
+	return errs
+}
+
+// kubeletConditionTypes are the node conditions kubelet and the network plugin report
+var kubeletConditionTypes = []corev1.NodeConditionType{
+	corev1.NodeReady, corev1.NodeMemoryPressure, corev1.NodeDiskPressure, corev1.NodePIDPressure,
+	corev1.NodeNetworkUnavailable,
+}
+
+// ValidateBlockingConditions checks that conditionTypes are unique valid
+// condition types a node can't be healthy with while they are True, so
+// neither Ready nor ownType, the condition the operator maintains itself
+func ValidateBlockingConditions(
+	path *field.Path,
+	conditionTypes []corev1.NodeConditionType,
+	ownType corev1.NodeConditionType,
+) field.ErrorList {
+	var errs field.ErrorList
+	seen := make(map[corev1.NodeConditionType]bool, len(conditionTypes))
+	for i, conditionType := range conditionTypes {
+		for _, msg := range validation.IsQualifiedName(string(conditionType)) {
+			errs = append(errs, field.Invalid(path.Index(i), conditionType, msg))
+		}
+		switch {
+		case conditionType == corev1.NodeReady:
+			errs = append(errs, field.Invalid(path.Index(i), conditionType, "is True on healthy nodes"))
+		case ownType != "" && conditionType == ownType:
+			errs = append(errs, field.Invalid(path.Index(i), conditionType, "is maintained by the operator"))
+		case seen[conditionType]:
+			errs = append(errs, field.Duplicate(path.Index(i), conditionType))
+		}
+		seen[conditionType] = true
+	}
+	return errs
+}
+
+// ValidateCSIDrivers checks that drivers are unique valid CSI driver names
+func ValidateCSIDrivers(path *field.Path, drivers []string) field.ErrorList {
+	var errs field.ErrorList
+	seen := make(map[string]bool, len(drivers))
+	for i, driver := range drivers {
+		for _, msg := range validation.IsDNS1123Subdomain(driver) {
+			errs = append(errs, field.Invalid(path.Index(i), driver, msg))
+		}
+		if len(driver) > csiDriverNameMaxLength {
+			errs = append(errs, field.TooLong(path.Index(i), driver, csiDriverNameMaxLength))
+		}
+		if seen[driver] {
+			errs = append(errs, field.Duplicate(path.Index(i), driver))
+		}
+		seen[driver] = true
+	}
+	return errs
+}
+
+// ValidateRequiredResources checks that resources name valid resources with
+// positive quantities
+func ValidateRequiredResources(path *field.Path, resources corev1.ResourceList) field.ErrorList {
+	var errs field.ErrorList
+	for name, quantity := range resources {
+		for _, msg := range validation.IsQualifiedName(string(name)) {
+			errs = append(errs, field.Invalid(path.Key(string(name)), name, msg))
+		}
+		if quantity.Sign() <= 0 {
+			errs = append(errs, field.Invalid(path.Key(string(name)), quantity.String(), "must be positive"))
+		}
+	}
+	return errs
+}
+
+// csiDriverNameMaxLength is the longest name the CSI spec allows a driver
+const csiDriverNameMaxLength = 63
+
+// ValidateRemoveLabels checks that keys are unique label keys that can be
+// removed with strategy. Server-side apply can only drop labels the operator
+// set itself, so removing labels requires one of the patch strategies.
+func ValidateRemoveLabels(path *field.Path, keys []string, strategy RemovalStrategy) field.ErrorList {
+	var errs field.ErrorList
+	if len(keys) > 0 && strategy == RemovalStrategyApply {
+		errs = append(errs, field.Forbidden(path, "labels can't be removed with the Apply removal strategy"))
+	}
+	seen := make(map[string]bool, len(keys))
+	for i, key := range keys {
+		errs = append(errs, metav1validation.ValidateLabelName(key, path.Index(i))...)
+		if seen[key] {
+			errs = append(errs, field.Duplicate(path.Index(i), key))
+		}
+		seen[key] = true
+	}
+	return errs
+}
+
+// ValidateRemovalStrategy checks that strategy is a known removal strategy
+func ValidateRemovalStrategy(path *field.Path, strategy RemovalStrategy) field.ErrorList {
+	switch strategy {
+	case RemovalStrategyPatch, RemovalStrategyApply, RemovalStrategyJSONPatch:
+		return nil
+	default:
+		return field.ErrorList{field.NotSupported(path, strategy,
+			[]RemovalStrategy{RemovalStrategyPatch, RemovalStrategyApply, RemovalStrategyJSONPatch})}
+	}
+}
+
+// ManagedEffects returns the effects of TargetTaint the rule manages: its
+// Effects plus the effects its staged removal downgrades the taint to. It
+// returns nil when the rule manages every effect.
+func (r Rule) ManagedEffects() []corev1.TaintEffect {
+	if len(r.Effects) == 0 {
+		return nil
+	}
+	effects := slices.Clone(r.Effects)
+	for _, stage := range r.StagedRemoval {
+		if !slices.Contains(effects, stage.Effect) {
+			effects = append(effects, stage.Effect)
+		}
+	}
+	return effects
+}
+
+// Manages reports whether taint is one of the entries managed by the rule
+func (r Rule) Manages(taint corev1.Taint) bool {
+	if taint.Key != r.TargetTaint {
+		return false
+	}
+	effects := r.ManagedEffects()
+	return effects == nil || slices.Contains(effects, taint.Effect)
+}
+
+// RequeueIntervalFor returns the requeue interval that applies to rule
+func (c *Config) RequeueIntervalFor(rule Rule) time.Duration {
+	if rule.RequeueInterval != nil {
+		return rule.RequeueInterval.Duration
+	}
+	return c.RequeueInterval.Duration
+}
+
+// Jitter adds the configured random jitter to a requeue interval
+func (c *Config) Jitter(interval time.Duration) time.Duration {
+	if c.RequeueJitter == nil || *c.RequeueJitter <= 0 {
+		return interval
+	}
+	return wait.Jitter(interval, *c.RequeueJitter)
+}
@@ -0,0 +1,792 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Config", func() {
+	Context("when parsing", func() {
+		It("should parse rules and apply defaults", func() {
+			cfg, err := Parse([]byte(`
+rules:
+  - targetTaint: example.com/not-ready
+    ownedByNames: [agent-a, agent-b]
+`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cfg.Rules).To(Equal([]Rule{{
+				TargetTaint:  "example.com/not-ready",
+				OwnedByNames: []string{"agent-a", "agent-b"},
+				OnMaxWait:    MaxWaitActionEvent,
+				Actions:      []UntaintAction{UntaintActionUntaint, UntaintActionNotify},
+			}}))
+			Expect(cfg.RequeueInterval.Duration).To(Equal(DefaultRequeueInterval))
+			Expect(cfg.RemovalStrategy).To(Equal(RemovalStrategyPatch))
+			Expect(cfg.ReadinessMode).To(Equal(ReadinessModePodReady))
+			Expect(cfg.MaxRestarts).To(BeNil())
+			Expect(cfg.RestartWindow.Duration).To(Equal(DefaultRestartWindow))
+			Expect(*cfg.RequeueJitter).To(Equal(DefaultRequeueJitter))
+			Expect(cfg.MaxRequeueInterval.Duration).To(Equal(DefaultMaxRequeueInterval))
+			Expect(cfg.StuckNodeThreshold.Duration).To(Equal(DefaultStuckNodeThreshold))
+		})
+
+		It("should parse tuning knobs", func() {
+			cfg, err := Parse([]byte(`
+requeueInterval: 10s
+rules:
+  - targetTaint: example.com/not-ready
+    ownedByNames: [agent-a]
+`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cfg.RequeueInterval.Duration).To(Equal(10 * time.Second))
+		})
+
+		It("should parse the stuck node threshold", func() {
+			cfg, err := Parse([]byte(`
+stuckNodeThreshold: 5m
+rules:
+  - targetTaint: example.com/not-ready
+    ownedByNames: [agent-a]
+`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cfg.StuckNodeThreshold.Duration).To(Equal(5 * time.Minute))
+
+			_, err = Parse([]byte(`
+stuckNodeThreshold: -5m
+rules:
+  - targetTaint: example.com/not-ready
+    ownedByNames: [agent-a]
+`))
+			Expect(err).To(MatchError(ContainSubstring("stuckNodeThreshold: Invalid value")))
+		})
+
+		It("should parse the reconcile concurrency", func() {
+			cfg, err := Parse([]byte(`
+maxConcurrentReconciles: 8
+rules:
+  - targetTaint: example.com/not-ready
+    ownedByNames: [agent-a]
+`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cfg.MaxConcurrentReconciles).To(Equal(int32(8)))
+
+			_, err = Parse([]byte(`
+maxConcurrentReconciles: -1
+rules:
+  - targetTaint: example.com/not-ready
+    ownedByNames: [agent-a]
+`))
+			Expect(err).To(MatchError(ContainSubstring("maxConcurrentReconciles: Invalid value")))
+		})
+
+		It("should let rules override the requeue interval", func() {
+			cfg, err := Parse([]byte(`
+requeueInterval: 1m
+requeueJitter: 0
+rules:
+  - targetTaint: example.com/not-ready
+    ownedByNames: [agent-a]
+    requeueInterval: 5s
+  - targetTaint: example.com/other
+    ownedByNames: [agent-b]
+`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cfg.RequeueIntervalFor(cfg.Rules[0])).To(Equal(5 * time.Second))
+			Expect(cfg.RequeueIntervalFor(cfg.Rules[1])).To(Equal(time.Minute))
+			Expect(cfg.Jitter(time.Minute)).To(Equal(time.Minute))
+		})
+
+		It("should jitter requeues by up to the configured fraction", func() {
+			jitter := 0.5
+			cfg := &Config{RequeueJitter: &jitter}
+			for range 20 {
+				Expect(cfg.Jitter(time.Minute)).To(And(
+					BeNumerically(">=", time.Minute),
+					BeNumerically("<=", 90*time.Second),
+				))
+			}
+		})
+
+		It("should not default the backoff cap below the requeue interval", func() {
+			cfg, err := Parse([]byte(`
+requeueInterval: 10m
+rules:
+  - targetTaint: example.com/not-ready
+    ownedByNames: [agent-a]
+`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cfg.MaxRequeueInterval.Duration).To(Equal(10 * time.Minute))
+		})
+
+		It("should reject unknown fields", func() {
+			_, err := Parse([]byte(`
+rules:
+  - targetTaint: example.com/not-ready
+    ownedByName: [agent-a]
+`))
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should reject a config without rules", func() {
+			_, err := Parse([]byte(`requeueInterval: 10s`))
+			Expect(err).To(MatchError(ContainSubstring("at least one rule")))
+		})
+
+		It("should reject a rule without workloads", func() {
+			_, err := Parse([]byte(`
+rules:
+  - targetTaint: example.com/not-ready
+`))
+			Expect(err).To(MatchError(ContainSubstring("rules[0].ownedByNames")))
+		})
+
+		It("should reject invalid taint keys", func() {
+			_, err := Parse([]byte(`
+rules:
+  - targetTaint: not a valid/taint key
+    ownedByNames: [agent-a]
+`))
+			Expect(err).To(MatchError(ContainSubstring("rules[0].targetTaint: Invalid value")))
+		})
+
+		It("should reject duplicate taints and workloads", func() {
+			_, err := Parse([]byte(`
+rules:
+  - targetTaint: example.com/not-ready
+    ownedByNames: [agent-a, agent-a]
+  - targetTaint: example.com/not-ready
+    ownedByNames: [agent-b]
+`))
+			Expect(err).To(MatchError(ContainSubstring(`rules[0].ownedByNames[1]: Duplicate value: "agent-a"`)))
+			Expect(err).To(MatchError(ContainSubstring(`rules[1].targetTaint: Duplicate value`)))
+		})
+
+		It("should reject removal stages that don't weaken the taint", func() {
+			_, err := Parse([]byte(`
+rules:
+  - targetTaint: example.com/not-ready
+    ownedByNames: [agent-a]
+    stagedRemoval:
+      - effect: PreferNoSchedule
+        dwell: 30s
+      - effect: NoSchedule
+        dwell: 0s
+      - effect: NoExecute
+        dwell: 1m
+`))
+			Expect(err).To(MatchError(ContainSubstring(
+				"rules[0].stagedRemoval[1].effect: Invalid value: \"NoSchedule\": must be weaker")))
+			Expect(err).To(MatchError(ContainSubstring("rules[0].stagedRemoval[1].dwell: Invalid value")))
+			Expect(err).To(MatchError(ContainSubstring("rules[0].stagedRemoval[2].effect: Unsupported value")))
+		})
+
+		It("should reject unknown and duplicate taint effects", func() {
+			_, err := Parse([]byte(`
+rules:
+  - targetTaint: example.com/not-ready
+    ownedByNames: [agent-a]
+    effects: [NoSchedule, NoSchedule, Evict]
+`))
+			Expect(err).To(MatchError(ContainSubstring(`rules[0].effects[1]: Duplicate value: "NoSchedule"`)))
+			Expect(err).To(MatchError(ContainSubstring("rules[0].effects[2]: Unsupported value")))
+		})
+
+		It("should reject invalid and duplicate CSI drivers", func() {
+			_, err := Parse([]byte(`
+rules:
+  - targetTaint: example.com/not-ready
+    ownedByNames: [agent-a]
+    csiDrivers: [ebs.csi.aws.com, ebs.csi.aws.com, EBS_CSI]
+`))
+			Expect(err).To(MatchError(ContainSubstring(`rules[0].csiDrivers[1]: Duplicate value: "ebs.csi.aws.com"`)))
+			Expect(err).To(MatchError(ContainSubstring(`rules[0].csiDrivers[2]: Invalid value: "EBS_CSI"`)))
+		})
+
+		It("should reject invalid required resources", func() {
+			_, err := Parse([]byte(`
+rules:
+  - targetTaint: example.com/not-ready
+    ownedByNames: [agent-a]
+    requiredResources:
+      nvidia.com/gpu: 0
+      "not valid": 1
+`))
+			Expect(err).To(MatchError(ContainSubstring(`rules[0].requiredResources[nvidia.com/gpu]: Invalid value: "0"`)))
+			Expect(err).To(MatchError(ContainSubstring(`rules[0].requiredResources[not valid]: Invalid value`)))
+		})
+
+		It("should reject invalid resource gates", func() {
+			_, err := Parse([]byte(`
+rules:
+  - targetTaint: example.com/not-ready
+    ownedByNames: [agent-a]
+    resourceGates:
+      - apiVersion: example.com/v1/beta
+        name: "{{ .Name"
+      - apiVersion: example.com/v1
+        kind: Workspace
+        name: "{{ .Name }}"
+        jsonPath: "{.status.phase"
+      - apiVersion: example.com/v1
+        kind: Workspace
+        name: "{{ .Name }}"
+        value: Ready
+`))
+			Expect(err).To(MatchError(ContainSubstring("rules[0].resourceGates[0].apiVersion: Invalid value")))
+			Expect(err).To(MatchError(ContainSubstring("rules[0].resourceGates[0].kind: Required value")))
+			Expect(err).To(MatchError(ContainSubstring("rules[0].resourceGates[0].name: Invalid value")))
+			Expect(err).To(MatchError(ContainSubstring("rules[0].resourceGates[1].jsonPath: Invalid value")))
+			Expect(err).To(MatchError(ContainSubstring("rules[0].resourceGates[2].value: Forbidden: requires jsonPath")))
+		})
+
+		It("should default and validate probes", func() {
+			cfg, err := Parse([]byte(`
+rules:
+  - targetTaint: example.com/not-ready
+    ownedByNames: [agent-a]
+    probe:
+      httpGet:
+        path: /healthz
+        port: health
+`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cfg.Rules[0].Probe.Timeout.Duration).To(Equal(DefaultProbeTimeout))
+			Expect(cfg.Rules[0].Probe.HTTPGet.Scheme).To(Equal(corev1.URISchemeHTTP))
+
+			_, err = Parse([]byte(`
+rules:
+  - targetTaint: example.com/not-ready
+    ownedByNames: [agent-a]
+    probe:
+      timeout: -1s
+  - targetTaint: example.com/other
+    ownedByNames: [agent-a]
+    probe:
+      httpGet:
+        port: 70000
+        scheme: FTP
+      grpc:
+        port: 9090
+  - targetTaint: example.com/third
+    ownedByNames: [agent-a]
+    probe:
+      httpGet:
+        port: 70000
+        scheme: FTP
+`))
+			Expect(err).To(MatchError(ContainSubstring("rules[0].probe.timeout: Invalid value")))
+			Expect(err).To(MatchError(ContainSubstring("rules[0].probe: Required value")))
+			Expect(err).To(MatchError(ContainSubstring("rules[1].probe: Forbidden: only one of httpGet and grpc")))
+			Expect(err).To(MatchError(ContainSubstring(`rules[2].probe.httpGet.port: Invalid value: "70000"`)))
+			Expect(err).To(MatchError(ContainSubstring("rules[2].probe.httpGet.scheme: Unsupported value")))
+		})
+
+		It("should default and validate canaries", func() {
+			cfg, err := Parse([]byte(`
+rules:
+  - targetTaint: example.com/not-ready
+    ownedByNames: [agent-a]
+    canary:
+      namespace: untaint-system
+`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cfg.Rules[0].Canary.Image).To(Equal(DefaultCanaryImage))
+
+			_, err = Parse([]byte(`
+rules:
+  - targetTaint: example.com/not-ready
+    ownedByNames: [agent-a]
+    canary:
+      probe:
+        grpc:
+          port: 0
+`))
+			Expect(err).To(MatchError(ContainSubstring("rules[0].canary.namespace: Required value")))
+			Expect(err).To(MatchError(ContainSubstring("rules[0].canary.probe.grpc.port: Invalid value")))
+		})
+
+		It("should render resource gates against the node", func() {
+			gate := ResourceGate{
+				APIVersion: "example.com/v1",
+				Kind:       "Workspace",
+				Namespace:  `{{ index .Labels "example.com/team" }}`,
+				Name:       "{{ .Name }}-workspace",
+				JSONPath:   "{.status.phase}",
+				Value:      "Ready",
+			}
+			node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{
+				Name:   "node",
+				Labels: map[string]string{"example.com/team": "platform"},
+			}}
+			namespace, name, err := gate.RenderName(node)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(namespace).To(Equal("platform"))
+			Expect(name).To(Equal("node-workspace"))
+			Expect(gate.GroupVersionKind().Group).To(Equal("example.com"))
+
+			ok, result, err := gate.Evaluate(map[string]interface{}{"status": map[string]interface{}{"phase": "Ready"}})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeTrue())
+			Expect(result).To(Equal("Ready"))
+			ok, _, err = gate.Evaluate(map[string]interface{}{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeFalse())
+		})
+
+		It("should reject invalid label changes", func() {
+			_, err := Parse([]byte(`
+rules:
+  - targetTaint: example.com/not-ready
+    ownedByNames: [agent-a]
+    setLabels:
+      "-invalid": "true"
+      example.com/ready: "not valid!"
+    removeLabels: [example.com/bootstrap, example.com/bootstrap]
+`))
+			Expect(err).To(MatchError(ContainSubstring("rules[0].setLabels: Invalid value: \"-invalid\"")))
+			Expect(err).To(MatchError(ContainSubstring("rules[0].setLabels: Invalid value: \"not valid!\"")))
+			Expect(err).To(MatchError(ContainSubstring("rules[0].removeLabels[1]: Duplicate value")))
+
+			_, err = Parse([]byte(`
+removalStrategy: Apply
+rules:
+  - targetTaint: example.com/not-ready
+    ownedByNames: [agent-a]
+    setLabels: {example.com/ready: "true"}
+    removeLabels: [example.com/bootstrap]
+`))
+			Expect(err).To(MatchError(ContainSubstring("rules[0].removeLabels: Forbidden")))
+			Expect(err).NotTo(MatchError(ContainSubstring("setLabels")))
+		})
+
+		It("should default actions from the rule's settings", func() {
+			cfg, err := Parse([]byte(`
+recordUntaint: true
+rules:
+  - targetTaint: example.com/not-ready
+    ownedByNames: [agent-a]
+    uncordon: true
+    setLabels: {example.com/ready: "true"}
+  - targetTaint: example.com/other
+    ownedByNames: [agent-b]
+    actions: [Notify, Untaint]
+`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cfg.Rules[0].Actions).To(Equal([]UntaintAction{
+				UntaintActionUntaint, UntaintActionUncordon, UntaintActionLabel, UntaintActionAnnotate,
+				UntaintActionNotify,
+			}))
+			Expect(cfg.Rules[1].Actions).To(Equal([]UntaintAction{UntaintActionNotify, UntaintActionUntaint}))
+		})
+
+		It("should reject invalid actions", func() {
+			_, err := Parse([]byte(`
+rules:
+  - targetTaint: example.com/not-ready
+    ownedByNames: [agent-a]
+    uncordon: true
+    actions: [Label, Notify, Notify, Page]
+`))
+			Expect(err).To(MatchError(ContainSubstring("rules[0].actions[2]: Duplicate value")))
+			Expect(err).To(MatchError(ContainSubstring("rules[0].actions[3]: Unsupported value")))
+			Expect(err).To(MatchError(ContainSubstring("rules[0].actions: Required value: must include Untaint")))
+			Expect(err).To(MatchError(ContainSubstring("rules[0].uncordon: Invalid value")))
+			Expect(err).To(MatchError(ContainSubstring("rules[0].setLabels: Required value")))
+		})
+
+		It("should reject invalid deadlines", func() {
+			_, err := Parse([]byte(`
+rules:
+  - targetTaint: example.com/not-ready
+    ownedByNames: [agent-a]
+    maxWait: 0s
+    onMaxWait: Evict
+`))
+			Expect(err).To(MatchError(ContainSubstring("rules[0].maxWait: Invalid value")))
+			Expect(err).To(MatchError(ContainSubstring("rules[0].onMaxWait: Unsupported value")))
+		})
+
+		It("should reject invalid readiness and removal settings", func() {
+			_, err := Parse([]byte(`
+removalStrategy: Replace
+readinessMode: Strict
+minReadySeconds: -1
+maxNodeUntaintsPerMinute: -1
+untaintCooldown: -1m
+maxRestarts: -1
+maxLeaseAge: -10s
+minNodeAge: -1m
+nodeConditionType: Ready
+rules:
+  - targetTaint: example.com/not-ready
+    ownedByNames: [agent-a]
+`))
+			Expect(err).To(MatchError(ContainSubstring("removalStrategy: Unsupported value")))
+			Expect(err).To(MatchError(ContainSubstring("readinessMode: Unsupported value")))
+			Expect(err).To(MatchError(ContainSubstring("minReadySeconds: Invalid value")))
+			Expect(err).To(MatchError(ContainSubstring("maxNodeUntaintsPerMinute: Invalid value")))
+			Expect(err).To(MatchError(ContainSubstring("untaintCooldown: Invalid value")))
+			Expect(err).To(MatchError(ContainSubstring("maxRestarts: Invalid value")))
+			Expect(err).To(MatchError(ContainSubstring("maxLeaseAge: Invalid value")))
+			Expect(err).To(MatchError(ContainSubstring("minNodeAge: Invalid value")))
+			Expect(err).To(MatchError(ContainSubstring(`nodeConditionType: Invalid value: "Ready": is maintained by kubelet`)))
+		})
+
+		It("should reject invalid blocking conditions", func() {
+			_, err := Parse([]byte(`
+nodeConditionType: example.com/Untainted
+blockingConditions: [KernelDeadlock, KernelDeadlock, Ready, example.com/Untainted, "not valid"]
+rules:
+  - targetTaint: example.com/not-ready
+    ownedByNames: [agent-a]
+`))
+			Expect(err).To(MatchError(ContainSubstring(`blockingConditions[1]: Duplicate value: "KernelDeadlock"`)))
+			Expect(err).To(MatchError(ContainSubstring(`blockingConditions[2]: Invalid value: "Ready"`)))
+			Expect(err).To(MatchError(ContainSubstring(`blockingConditions[3]: Invalid value: "example.com/Untainted"`)))
+			Expect(err).To(MatchError(ContainSubstring(`blockingConditions[4]: Invalid value: "not valid"`)))
+		})
+
+		It("should default and validate hooks", func() {
+			cfg, err := Parse([]byte(`
+preUntaintHook:
+  url: https://cmdb.example.com/untaint
+postUntaintHook:
+  url: http://orchestrator.example.com/hooks/untainted
+  timeout: 2s
+rules:
+  - targetTaint: example.com/not-ready
+    ownedByNames: [agent-a]
+`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cfg.PreUntaintHook).To(Equal(&Hook{
+				URL:           "https://cmdb.example.com/untaint",
+				Timeout:       metav1.Duration{Duration: DefaultHookTimeout},
+				FailurePolicy: HookFailurePolicyFail,
+			}))
+			Expect(cfg.PostUntaintHook.Timeout.Duration).To(Equal(2 * time.Second))
+
+			_, err = Parse([]byte(`
+preUntaintHook:
+  url: /untaint
+  timeout: -1s
+  failurePolicy: Retry
+postUntaintHook:
+  url: ""
+rules:
+  - targetTaint: example.com/not-ready
+    ownedByNames: [agent-a]
+`))
+			Expect(err).To(MatchError(ContainSubstring("preUntaintHook.url: Invalid value")))
+			Expect(err).To(MatchError(ContainSubstring("preUntaintHook.timeout: Invalid value")))
+			Expect(err).To(MatchError(ContainSubstring("preUntaintHook.failurePolicy: Unsupported value")))
+			Expect(err).To(MatchError(ContainSubstring("postUntaintHook.url: Required value")))
+		})
+
+		It("should default and validate notifications", func() {
+			cfg, err := Parse([]byte(`
+notifications:
+  url: https://hooks.slack.com/services/T000/B000/XXXX
+  stuckAfter: 15m
+rules:
+  - targetTaint: example.com/not-ready
+    ownedByNames: [agent-a]
+`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cfg.Notifications).To(Equal(&Notifications{
+				URL:             "https://hooks.slack.com/services/T000/B000/XXXX",
+				Format:          NotificationFormatSlack,
+				Timeout:         metav1.Duration{Duration: DefaultNotificationTimeout},
+				StuckAfter:      metav1.Duration{Duration: 15 * time.Minute},
+				StuckTemplate:   DefaultStuckTemplate,
+				UntaintTemplate: DefaultUntaintTemplate,
+			}))
+
+			_, err = Parse([]byte(`
+notifications:
+  url: hooks.slack.com
+  format: Teams
+  untaintTemplate: "{{.Node"
+rules:
+  - targetTaint: example.com/not-ready
+    ownedByNames: [agent-a]
+`))
+			Expect(err).To(MatchError(ContainSubstring("notifications.url: Invalid value")))
+			Expect(err).To(MatchError(ContainSubstring("notifications.format: Unsupported value")))
+			Expect(err).To(MatchError(ContainSubstring("notifications.stuckAfter: Required value")))
+			Expect(err).To(MatchError(ContainSubstring("notifications.untaintTemplate: Invalid value")))
+		})
+
+		It("should reject invalid untaint rate limits", func() {
+			_, err := Parse([]byte(`
+untaintRateLimit:
+  maxUntaints: 0
+  interval: -5m
+rules:
+  - targetTaint: example.com/not-ready
+    ownedByNames: [agent-a]
+`))
+			Expect(err).To(MatchError(ContainSubstring("untaintRateLimit.maxUntaints: Invalid value")))
+			Expect(err).To(MatchError(ContainSubstring("untaintRateLimit.interval: Invalid value")))
+		})
+
+		It("should default and validate untaint windows", func() {
+			cfg, err := Parse([]byte(`
+untaintWindows:
+  windows:
+    - schedule: "0 18 * * 5"
+      duration: 62h
+rules:
+  - targetTaint: example.com/not-ready
+    ownedByNames: [agent-a]
+`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cfg.UntaintWindows.Mode).To(Equal(WindowModePause))
+			Expect(cfg.UntaintWindows.TimeZone).To(Equal("UTC"))
+
+			_, err = Parse([]byte(`
+untaintWindows:
+  mode: Freeze
+  timeZone: Mars/Olympus_Mons
+  windows:
+    - schedule: "0 18 * *"
+      duration: 0s
+rules:
+  - targetTaint: example.com/not-ready
+    ownedByNames: [agent-a]
+`))
+			Expect(err).To(MatchError(ContainSubstring("untaintWindows.mode: Unsupported value")))
+			Expect(err).To(MatchError(ContainSubstring("untaintWindows.timeZone: Invalid value")))
+			Expect(err).To(MatchError(ContainSubstring("untaintWindows.windows[0].schedule: Invalid value")))
+			Expect(err).To(MatchError(ContainSubstring("untaintWindows.windows[0].duration: Invalid value")))
+		})
+
+		It("should pause untainting during untaint windows", func() {
+			windows := &UntaintWindows{
+				Mode:     WindowModePause,
+				TimeZone: "America/New_York",
+				Windows: []Window{{
+					Schedule: "0 18 * * 5",
+					Duration: metav1.Duration{Duration: 62 * time.Hour},
+				}},
+			}
+			newYork, err := time.LoadLocation("America/New_York")
+			Expect(err).NotTo(HaveOccurred())
+			// Saturday noon is within the weekend freeze, which ends Monday 8:00
+			blocked, after := windows.Blocked(time.Date(2025, 6, 7, 12, 0, 0, 0, newYork))
+			Expect(blocked).To(BeTrue())
+			Expect(after).To(Equal(44 * time.Hour))
+			// Thursday noon is outside of it, which starts Friday 18:00
+			blocked, after = windows.Blocked(time.Date(2025, 6, 5, 12, 0, 0, 0, newYork).UTC())
+			Expect(blocked).To(BeFalse())
+			Expect(after).To(Equal(30 * time.Hour))
+
+			windows.Mode = WindowModeAllow
+			blocked, after = windows.Blocked(time.Date(2025, 6, 5, 12, 0, 0, 0, newYork))
+			Expect(blocked).To(BeTrue())
+			Expect(after).To(Equal(30 * time.Hour))
+
+			blocked, _ = (*UntaintWindows)(nil).Blocked(time.Now())
+			Expect(blocked).To(BeFalse())
+		})
+
+		It("should complete the lifecycle action of rules naming a lifecycle hook", func() {
+			cfg, err := Parse([]byte(`
+rules:
+  - targetTaint: example.com/not-ready
+    ownedByNames: [agent-a]
+    awsLifecycleHook: node-ready
+`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cfg.Rules[0].Actions).To(Equal([]UntaintAction{
+				UntaintActionUntaint, UntaintActionCompleteLifecycle, UntaintActionNotify,
+			}))
+
+			_, err = Parse([]byte(`
+rules:
+  - targetTaint: example.com/not-ready
+    ownedByNames: [agent-a]
+    awsLifecycleHook: "node ready"
+    actions: [Untaint]
+  - targetTaint: example.com/other
+    ownedByNames: [agent-a]
+    actions: [Untaint, CompleteLifecycleAction]
+`))
+			Expect(err).To(MatchError(ContainSubstring(
+				`rules[0].awsLifecycleHook: Invalid value: "node ready": requires the CompleteLifecycleAction action`)))
+			Expect(err).To(MatchError(ContainSubstring(
+				`rules[0].awsLifecycleHook: Invalid value: "node ready": must be 1 to 255`)))
+			Expect(err).To(MatchError(ContainSubstring("rules[1].awsLifecycleHook: Required value")))
+		})
+
+		It("should fill rules in from their preset", func() {
+			cfg, err := Parse([]byte(`
+rules:
+  - preset: cilium
+  - preset: ebs-csi
+    ownedByNames: [my-ebs-csi-node]
+  - preset: cluster-autoscaler
+    ownedByNames: [agent-a]
+`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cfg.Rules[0].TargetTaint).To(Equal("node.cilium.io/agent-not-ready"))
+			Expect(cfg.Rules[0].OwnedByNames).To(Equal([]string{"cilium"}))
+			Expect(cfg.Rules[1].TargetTaint).To(Equal("ebs.csi.aws.com/agent-not-ready"))
+			Expect(cfg.Rules[1].OwnedByNames).To(Equal([]string{"my-ebs-csi-node"}))
+			Expect(cfg.Rules[1].CSIDrivers).To(Equal([]string{"ebs.csi.aws.com"}))
+			Expect(cfg.Rules[2].TargetTaint).To(Equal("ignore-taint.cluster-autoscaler.kubernetes.io/agent-not-ready"))
+		})
+
+		It("should reject unknown presets and presets without workloads", func() {
+			_, err := Parse([]byte(`
+rules:
+  - preset: flannel
+  - preset: cloud-provider
+`))
+			Expect(err).To(MatchError(ContainSubstring(`rules[0].preset: Unsupported value: "flannel"`)))
+			Expect(err).To(MatchError(ContainSubstring("rules[1].ownedByNames: Required value")))
+		})
+
+		It("should add the rules and workloads of the profile", func() {
+			cfg, err := Parse([]byte(`
+profile: eks
+rules:
+  - targetTaint: example.com/not-ready
+  - targetTaint: example.com/gpu-not-ready
+    ownedByNames: [nvidia-device-plugin-daemonset]
+`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cfg.Rules).To(HaveLen(3))
+			Expect(cfg.Rules[0].OwnedByNames).To(Equal([]string{"aws-node", "kube-proxy"}))
+			Expect(cfg.Rules[1].OwnedByNames).To(Equal([]string{"nvidia-device-plugin-daemonset"}))
+			Expect(cfg.Rules[2].Preset).To(Equal("ebs-csi"))
+			Expect(cfg.Rules[2].TargetTaint).To(Equal("ebs.csi.aws.com/agent-not-ready"))
+			Expect(cfg.Rules[2].OwnedByNames).To(Equal([]string{"ebs-csi-node"}))
+		})
+
+		It("should let rules override the rules of the profile", func() {
+			cfg, err := Parse([]byte(`
+profile: eks
+rules:
+  - targetTaint: ebs.csi.aws.com/agent-not-ready
+    ownedByNames: [my-ebs-csi-node]
+`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cfg.Rules).To(HaveLen(1))
+			Expect(cfg.Rules[0].OwnedByNames).To(Equal([]string{"my-ebs-csi-node"}))
+		})
+
+		It("should reject unknown profiles", func() {
+			_, err := Parse([]byte(`
+profile: openshift
+rules:
+  - targetTaint: example.com/not-ready
+    ownedByNames: [agent-a]
+`))
+			Expect(err).To(MatchError(ContainSubstring(`profile: Unsupported value: "openshift"`)))
+		})
+
+		It("should reject empty workload names", func() {
+			_, err := Parse([]byte(`
+rules:
+  - targetTaint: example.com/not-ready
+    ownedByNames: [agent-a, ""]
+`))
+			Expect(err).To(MatchError(ContainSubstring("rules[0].ownedByNames[1]")))
+		})
+	})
+
+	Context("when matching taints", func() {
+		It("should manage the selected effects and the effects of its stages", func() {
+			rule := Rule{
+				TargetTaint: "example.com/not-ready",
+				Effects:     []corev1.TaintEffect{corev1.TaintEffectNoExecute},
+				StagedRemoval: []RemovalStage{
+					{Effect: corev1.TaintEffectPreferNoSchedule, Dwell: metav1.Duration{Duration: time.Minute}},
+				},
+			}
+			Expect(rule.Manages(corev1.Taint{Key: "example.com/not-ready", Effect: corev1.TaintEffectNoExecute})).
+				To(BeTrue())
+			Expect(rule.Manages(corev1.Taint{Key: "example.com/not-ready", Effect: corev1.TaintEffectPreferNoSchedule})).
+				To(BeTrue())
+			Expect(rule.Manages(corev1.Taint{Key: "example.com/not-ready", Effect: corev1.TaintEffectNoSchedule})).
+				To(BeFalse())
+			Expect(rule.Manages(corev1.Taint{Key: "example.com/other", Effect: corev1.TaintEffectNoExecute})).
+				To(BeFalse())
+
+			rule.Effects = nil
+			Expect(rule.Manages(corev1.Taint{Key: "example.com/not-ready", Effect: corev1.TaintEffectNoSchedule})).
+				To(BeTrue())
+		})
+	})
+
+	Context("when watching a file", func() {
+		var (
+			path   string
+			store  *Store
+			cancel context.CancelFunc
+		)
+
+		writeConfig := func(workload string) {
+			Expect(os.WriteFile(path, []byte(`
+rules:
+  - targetTaint: example.com/not-ready
+    ownedByNames: [`+workload+`]
+`), 0o600)).To(Succeed())
+		}
+
+		BeforeEach(func() {
+			path = filepath.Join(GinkgoT().TempDir(), "config.yaml")
+			writeConfig("agent-a")
+
+			cfg, err := Load(path)
+			Expect(err).NotTo(HaveOccurred())
+			store = NewStore(cfg)
+
+			var ctx context.Context
+			ctx, cancel = context.WithCancel(context.Background())
+			watcher := &FileWatcher{Path: path, Store: store}
+			go func() {
+				defer GinkgoRecover()
+				Expect(watcher.Start(ctx)).To(Succeed())
+			}()
+		})
+
+		AfterEach(func() {
+			cancel()
+		})
+
+		It("should reload the config when the file changes", func() {
+			changes := store.Subscribe()
+			Eventually(func() []string {
+				writeConfig("agent-b")
+				return store.Get().Rules[0].OwnedByNames
+			}, "5s", "100ms").Should(Equal([]string{"agent-b"}))
+			Expect(changes).To(Receive())
+		})
+
+		It("should keep the previous config when the file is invalid", func() {
+			Expect(os.WriteFile(path, []byte("rules: []"), 0o600)).To(Succeed())
+			Consistently(func() []string {
+				return store.Get().Rules[0].OwnedByNames
+			}, "500ms", "100ms").Should(Equal([]string{"agent-a"}))
+			Eventually(func() error {
+				Expect(os.WriteFile(path, []byte("rules: []"), 0o600)).To(Succeed())
+				return store.Check(nil)
+			}, "5s", "100ms").Should(MatchError(ContainSubstring("reload failed")))
+			Expect(store.Get().Rules[0].OwnedByNames).To(Equal([]string{"agent-a"}))
+
+			// A valid file clears the failure
+			Eventually(func() error {
+				writeConfig("agent-b")
+				return store.Check(nil)
+			}, "5s", "100ms").Should(Succeed())
+		})
+	})
+})
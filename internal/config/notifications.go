@@ -0,0 +1,127 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"strings"
+	"text/template"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// DefaultNotificationTimeout is how long the notification webhook may take to answer
+const DefaultNotificationTimeout = 10 * time.Second
+
+const (
+	// DefaultStuckTemplate is the message posted when a node stayed blocked
+	// longer than the notifications' StuckAfter
+	DefaultStuckTemplate = "Node {{.Node}} has kept its target taints for {{.BlockedFor}}: {{.Message}}"
+	// DefaultUntaintTemplate is the message posted when target taints were
+	// removed from a node
+	DefaultUntaintTemplate = "Removed taints {{join .Taints \", \"}} from node {{.Node}}"
+)
+
+// NotificationFormat selects the body notifications are posted with
+type NotificationFormat string
+
+const (
+	// NotificationFormatSlack posts {"text": message}, as Slack incoming
+	// webhooks expect
+	NotificationFormatSlack NotificationFormat = "Slack"
+	// NotificationFormatJSON posts the message in "text" along with the
+	// details of the notification
+	NotificationFormatJSON NotificationFormat = "JSON"
+)
+
+// Notifications posts a message to Slack or a generic webhook when a node stays
+// blocked too long or has its taints removed, so on-call engineers don't have
+// to watch the logs
+type Notifications struct {
+	// URL is the http or https webhook to post to
+	URL string `json:"url"`
+	// Format selects the body of the posts
+	Format NotificationFormat `json:"format,omitempty"`
+	// Timeout is how long the webhook may take to answer
+	Timeout metav1.Duration `json:"timeout,omitempty"`
+	// StuckAfter, when set, posts once for a node that kept its target taints
+	// this long, until it is untainted
+	StuckAfter metav1.Duration `json:"stuckAfter,omitempty"`
+	// OnUntaint posts whenever target taints are removed from a node
+	OnUntaint bool `json:"onUntaint,omitempty"`
+	// StuckTemplate and UntaintTemplate are text/template messages, rendered
+	// with the details of the notification. join is available to join lists.
+	StuckTemplate   string `json:"stuckTemplate,omitempty"`
+	UntaintTemplate string `json:"untaintTemplate,omitempty"`
+}
+
+// Default fills in the unset fields of the notifications. It does nothing on nil.
+func (n *Notifications) Default() {
+	if n == nil {
+		return
+	}
+	if n.Format == "" {
+		n.Format = NotificationFormatSlack
+	}
+	if n.Timeout.Duration == 0 {
+		n.Timeout.Duration = DefaultNotificationTimeout
+	}
+	if n.StuckTemplate == "" {
+		n.StuckTemplate = DefaultStuckTemplate
+	}
+	if n.UntaintTemplate == "" {
+		n.UntaintTemplate = DefaultUntaintTemplate
+	}
+}
+
+// ParseNotificationTemplate parses a notification message template
+func ParseNotificationTemplate(name, text string) (*template.Template, error) {
+	return template.New(name).Funcs(template.FuncMap{"join": strings.Join}).Parse(text)
+}
+
+// ValidateNotifications checks that n, when set, posts to an absolute http or
+// https URL for at least one occasion, with valid templates
+func ValidateNotifications(path *field.Path, n *Notifications) field.ErrorList {
+	if n == nil {
+		return nil
+	}
+	errs := validateWebhookURL(path.Child("url"), n.URL)
+	switch n.Format {
+	case NotificationFormatSlack, NotificationFormatJSON:
+	default:
+		errs = append(errs, field.NotSupported(path.Child("format"), n.Format,
+			[]NotificationFormat{NotificationFormatSlack, NotificationFormatJSON}))
+	}
+	if n.Timeout.Duration <= 0 {
+		errs = append(errs, field.Invalid(path.Child("timeout"), n.Timeout.Duration.String(), "must be positive"))
+	}
+	if n.StuckAfter.Duration < 0 {
+		errs = append(errs, field.Invalid(path.Child("stuckAfter"), n.StuckAfter.Duration.String(),
+			"must not be negative"))
+	}
+	if n.StuckAfter.Duration == 0 && !n.OnUntaint {
+		errs = append(errs, field.Required(path.Child("stuckAfter"), "stuckAfter or onUntaint is required"))
+	}
+	if _, err := ParseNotificationTemplate("stuck", n.StuckTemplate); err != nil {
+		errs = append(errs, field.Invalid(path.Child("stuckTemplate"), n.StuckTemplate, err.Error()))
+	}
+	if _, err := ParseNotificationTemplate("untaint", n.UntaintTemplate); err != nil {
+		errs = append(errs, field.Invalid(path.Child("untaintTemplate"), n.UntaintTemplate, err.Error()))
+	}
+	return errs
+}
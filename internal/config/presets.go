@@ -0,0 +1,130 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"slices"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// Preset is a built-in rule for a well-known startup taint, selected by name
+// with a rule's preset field
+type Preset struct {
+	// TargetTaint is the taint key the preset manages
+	TargetTaint string
+	// OwnedByNames are the workloads the taint usually waits for. Rules using
+	// a preset without workloads must list their own.
+	OwnedByNames []string
+	// CSIDrivers are the CSI drivers that must be registered on the node
+	CSIDrivers []string
+	// Description is a short explanation shown in docs and errors
+	Description string
+}
+
+// presets are the built-in presets by name
+var presets = map[string]Preset{
+	"cilium": {
+		TargetTaint:  "node.cilium.io/agent-not-ready",
+		OwnedByNames: []string{"cilium"},
+		Description:  "Cilium agent",
+	},
+	"istio-cni": {
+		TargetTaint:  "cni.istio.io/not-ready",
+		OwnedByNames: []string{"istio-cni-node"},
+		Description:  "Istio CNI node agent",
+	},
+	"ebs-csi": {
+		TargetTaint:  "ebs.csi.aws.com/agent-not-ready",
+		OwnedByNames: []string{"ebs-csi-node"},
+		CSIDrivers:   []string{"ebs.csi.aws.com"},
+		Description:  "AWS EBS CSI node plugin",
+	},
+	"efs-csi": {
+		TargetTaint:  "efs.csi.aws.com/agent-not-ready",
+		OwnedByNames: []string{"efs-csi-node"},
+		CSIDrivers:   []string{"efs.csi.aws.com"},
+		Description:  "AWS EFS CSI node plugin",
+	},
+	"cloud-provider": {
+		TargetTaint: "node.cloudprovider.kubernetes.io/uninitialized",
+		Description: "external cloud provider initialization, for clusters where no cloud controller removes it",
+	},
+	"cluster-autoscaler": {
+		TargetTaint: "ignore-taint.cluster-autoscaler.kubernetes.io/agent-not-ready",
+		Description: "startup taint cluster-autoscaler ignores when simulating scale-ups",
+	},
+	"cluster-autoscaler-startup": {
+		TargetTaint: "startup-taint.cluster-autoscaler.kubernetes.io/agent-not-ready",
+		Description: "startup taint cluster-autoscaler treats as temporary",
+	},
+}
+
+// LookupPreset returns the built-in preset called name
+func LookupPreset(name string) (Preset, bool) {
+	preset, ok := presets[name]
+	return preset, ok
+}
+
+// PresetNames returns the names of the built-in presets, sorted
+func PresetNames() []string {
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// ParsePresets splits a comma-separated list of preset names, dropping
+// surrounding whitespace and empty entries
+func ParsePresets(value string) []string {
+	var names []string
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// applyPreset fills the target taint, workloads and CSI drivers of rule from
+// its preset, when they are unset
+func applyPreset(rule *Rule) {
+	preset, ok := presets[rule.Preset]
+	if !ok {
+		return
+	}
+	if rule.TargetTaint == "" {
+		rule.TargetTaint = preset.TargetTaint
+	}
+	if len(rule.OwnedByNames) == 0 {
+		rule.OwnedByNames = slices.Clone(preset.OwnedByNames)
+	}
+	if len(rule.CSIDrivers) == 0 {
+		rule.CSIDrivers = slices.Clone(preset.CSIDrivers)
+	}
+}
+
+// ValidatePreset checks that name, when set, is a built-in preset
+func ValidatePreset(path *field.Path, name string) field.ErrorList {
+	if _, ok := presets[name]; name == "" || ok {
+		return nil
+	}
+	return field.ErrorList{field.NotSupported(path, name, PresetNames())}
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// DefaultProbeTimeout is how long a probe may take to answer by default
+const DefaultProbeTimeout = time.Second
+
+// PodProbe is an HTTP or gRPC health check the operator sends to every ready
+// target pod itself, for agents whose readiness probe declares them ready
+// before they can serve
+type PodProbe struct {
+	// HTTPGet probes the pod like a kubelet HTTP probe: a response status
+	// from 200 to 399 passes
+	HTTPGet *corev1.HTTPGetAction `json:"httpGet,omitempty"`
+	// GRPC calls the gRPC health service of the pod, which must be SERVING
+	GRPC *corev1.GRPCAction `json:"grpc,omitempty"`
+	// Timeout is how long the pod may take to answer
+	Timeout metav1.Duration `json:"timeout,omitempty"`
+}
+
+// Default fills in the unset fields of a probe. It does nothing on a nil probe.
+func (p *PodProbe) Default() {
+	if p == nil {
+		return
+	}
+	if p.Timeout.Duration == 0 {
+		p.Timeout.Duration = DefaultProbeTimeout
+	}
+	if p.HTTPGet != nil && p.HTTPGet.Scheme == "" {
+		p.HTTPGet.Scheme = corev1.URISchemeHTTP
+	}
+}
+
+// ValidatePodProbe checks that probe, when set, is either an HTTP or a gRPC
+// probe of a valid port
+func ValidatePodProbe(path *field.Path, probe *PodProbe) field.ErrorList {
+	if probe == nil {
+		return nil
+	}
+	var errs field.ErrorList
+	if probe.Timeout.Duration <= 0 {
+		errs = append(errs, field.Invalid(path.Child("timeout"), probe.Timeout.Duration.String(), "must be positive"))
+	}
+	switch {
+	case probe.HTTPGet != nil && probe.GRPC != nil:
+		errs = append(errs, field.Forbidden(path, "only one of httpGet and grpc may be set"))
+	case probe.HTTPGet != nil:
+		httpPath := path.Child("httpGet")
+		errs = append(errs, validateProbePort(httpPath.Child("port"), probe.HTTPGet.Port)...)
+		switch probe.HTTPGet.Scheme {
+		case corev1.URISchemeHTTP, corev1.URISchemeHTTPS:
+		default:
+			errs = append(errs, field.NotSupported(httpPath.Child("scheme"), probe.HTTPGet.Scheme,
+				[]corev1.URIScheme{corev1.URISchemeHTTP, corev1.URISchemeHTTPS}))
+		}
+	case probe.GRPC != nil:
+		errs = append(errs, validateProbePort(path.Child("grpc", "port"), intstr.FromInt32(probe.GRPC.Port))...)
+	default:
+		errs = append(errs, field.Required(path, "one of httpGet and grpc must be set"))
+	}
+	return errs
+}
+
+// validateProbePort checks that port is a port number or the name of a
+// container port
+func validateProbePort(path *field.Path, port intstr.IntOrString) field.ErrorList {
+	var msgs []string
+	if port.Type == intstr.String {
+		msgs = validation.IsValidPortName(port.StrVal)
+	} else {
+		msgs = validation.IsValidPortNum(port.IntValue())
+	}
+	var errs field.ErrorList
+	for _, msg := range msgs {
+		errs = append(errs, field.Invalid(path, port.String(), msg))
+	}
+	return errs
+}
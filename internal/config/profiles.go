@@ -0,0 +1,100 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"slices"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// Profile holds the defaults for the nodes of a managed Kubernetes offering:
+// the rules for its bootstrap taints and the agent DaemonSets it runs on every
+// node
+type Profile struct {
+	// Presets are the rules added for the provider's bootstrap taints, unless
+	// the config has a rule for the same taint
+	Presets []string
+	// OwnedByNames are the agents gating rules that list no workloads of
+	// their own
+	OwnedByNames []string
+	// Description is a short explanation shown in docs and errors
+	Description string
+}
+
+// profiles are the built-in provider profiles by name
+var profiles = map[string]Profile{
+	"eks": {
+		Presets:      []string{"ebs-csi"},
+		OwnedByNames: []string{"aws-node", "kube-proxy"},
+		Description:  "Amazon EKS with the VPC CNI",
+	},
+	"gke": {
+		OwnedByNames: []string{"netd", "pdcsi-node"},
+		Description:  "Google Kubernetes Engine",
+	},
+	"aks": {
+		OwnedByNames: []string{"kube-proxy", "cloud-node-manager", "csi-azuredisk-node"},
+		Description:  "Azure Kubernetes Service",
+	},
+}
+
+// LookupProfile returns the built-in profile called name
+func LookupProfile(name string) (Profile, bool) {
+	profile, ok := profiles[name]
+	return profile, ok
+}
+
+// ProfileNames returns the names of the built-in profiles, sorted
+func ProfileNames() []string {
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// applyProfile adds the rules of the config's profile for the taints no rule
+// manages yet, and gates every rule without workloads on the profile's agents
+func (c *Config) applyProfile() {
+	profile, ok := profiles[c.Profile]
+	if !ok {
+		return
+	}
+	for _, name := range profile.Presets {
+		preset := presets[name]
+		if !slices.ContainsFunc(c.Rules, func(rule Rule) bool { return rule.TargetTaint == preset.TargetTaint }) {
+			rule := Rule{Preset: name}
+			applyPreset(&rule)
+			c.Rules = append(c.Rules, rule)
+		}
+	}
+	for i := range c.Rules {
+		if len(c.Rules[i].OwnedByNames) == 0 {
+			c.Rules[i].OwnedByNames = slices.Clone(profile.OwnedByNames)
+		}
+	}
+}
+
+// ValidateProfile checks that name, when set, is a built-in profile
+func ValidateProfile(path *field.Path, name string) field.ErrorList {
+	if _, ok := profiles[name]; name == "" || ok {
+		return nil
+	}
+	return field.ErrorList{field.NotSupported(path, name, ProfileNames())}
+}
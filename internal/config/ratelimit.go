@@ -0,0 +1,53 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// UntaintRateLimit caps how many nodes have their target taints removed per
+// interval, so a large batch of nodes finishing bootstrap at once doesn't
+// flood the scheduler and image registries
+type UntaintRateLimit struct {
+	// MaxUntaints is how many nodes may have target taints removed per interval
+	MaxUntaints int32 `json:"maxUntaints"`
+	// Interval is the sliding window the limit applies to
+	Interval metav1.Duration `json:"interval"`
+	// SpreadZones hands the untaints out round-robin across the
+	// topology.kubernetes.io/zone values of the waiting nodes, so capacity
+	// comes online evenly across zones
+	SpreadZones bool `json:"spreadZones,omitempty"`
+}
+
+// ValidateUntaintRateLimit checks that limit, when set, allows a positive
+// number of untaints per positive interval
+func ValidateUntaintRateLimit(path *field.Path, limit *UntaintRateLimit) field.ErrorList {
+	if limit == nil {
+		return nil
+	}
+	var errs field.ErrorList
+	if limit.MaxUntaints <= 0 {
+		errs = append(errs, field.Invalid(path.Child("maxUntaints"), limit.MaxUntaints, "must be positive"))
+	}
+	if limit.Interval.Duration <= 0 {
+		errs = append(errs, field.Invalid(path.Child("interval"), limit.Interval.Duration.String(),
+			"must be positive"))
+	}
+	return errs
+}
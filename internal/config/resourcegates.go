@@ -0,0 +1,143 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/util/jsonpath"
+)
+
+// ResourceGate requires an arbitrary resource, such as a custom resource a
+// platform creates for each node, to exist and report the node ready before a
+// rule's taint is removed
+type ResourceGate struct {
+	// APIVersion and Kind select the resource's kind
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	// Namespace is the namespace of a namespaced resource. Like Name, it is
+	// a Go template executed against the node, e.g. {{ .Name }}.
+	Namespace string `json:"namespace,omitempty"`
+	// Name is the name of the resource, as a Go template executed against
+	// the node
+	Name string `json:"name"`
+	// Condition, when set, is the type of a status condition of the resource
+	// that must be True
+	Condition string `json:"condition,omitempty"`
+	// JSONPath, when set, is a kubectl-style JSONPath expression, e.g.
+	// {.status.phase}, evaluated against the resource. Its result must equal
+	// Value, or be non-empty when Value is unset.
+	JSONPath string `json:"jsonPath,omitempty"`
+	// Value is the result JSONPath must produce
+	Value string `json:"value,omitempty"`
+}
+
+// GroupVersionKind returns the kind of the resource
+func (g ResourceGate) GroupVersionKind() schema.GroupVersionKind {
+	gv, _ := schema.ParseGroupVersion(g.APIVersion)
+	return gv.WithKind(g.Kind)
+}
+
+// RenderName returns the namespace and name of the resource for node, which
+// the templates are executed against
+func (g ResourceGate) RenderName(node any) (namespace, name string, err error) {
+	if namespace, err = renderTemplate(g.Namespace, node); err != nil {
+		return "", "", fmt.Errorf("namespace: %w", err)
+	}
+	if name, err = renderTemplate(g.Name, node); err != nil {
+		return "", "", fmt.Errorf("name: %w", err)
+	}
+	return namespace, name, nil
+}
+
+// Evaluate executes JSONPath against the content of the resource and reports
+// whether the result matches Value, along with the result
+func (g ResourceGate) Evaluate(content map[string]interface{}) (bool, string, error) {
+	path, err := parseJSONPath(g.JSONPath)
+	if err != nil {
+		return false, "", err
+	}
+	var result strings.Builder
+	if err := path.Execute(&result, content); err != nil {
+		return false, "", err
+	}
+	if g.Value == "" {
+		return result.Len() > 0, result.String(), nil
+	}
+	return result.String() == g.Value, result.String(), nil
+}
+
+// renderTemplate executes the Go template text against data
+func renderTemplate(text string, data any) (string, error) {
+	tmpl, err := template.New("").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var out strings.Builder
+	if err := tmpl.Execute(&out, data); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// parseJSONPath parses a kubectl-style JSONPath expression. Fields missing
+// from a resource produce an empty result rather than an error.
+func parseJSONPath(expression string) (*jsonpath.JSONPath, error) {
+	path := jsonpath.New("").AllowMissingKeys(true)
+	if err := path.Parse(expression); err != nil {
+		return nil, err
+	}
+	return path, nil
+}
+
+// ValidateResourceGates checks that gates select a kind and a name with
+// valid templates and expressions
+func ValidateResourceGates(path *field.Path, gates []ResourceGate) field.ErrorList {
+	var errs field.ErrorList
+	for i, gate := range gates {
+		gatePath := path.Index(i)
+		if gate.APIVersion == "" {
+			errs = append(errs, field.Required(gatePath.Child("apiVersion"), ""))
+		} else if _, err := schema.ParseGroupVersion(gate.APIVersion); err != nil {
+			errs = append(errs, field.Invalid(gatePath.Child("apiVersion"), gate.APIVersion, err.Error()))
+		}
+		if gate.Kind == "" {
+			errs = append(errs, field.Required(gatePath.Child("kind"), ""))
+		}
+		if gate.Name == "" {
+			errs = append(errs, field.Required(gatePath.Child("name"), ""))
+		}
+		if _, err := template.New("").Parse(gate.Namespace); err != nil {
+			errs = append(errs, field.Invalid(gatePath.Child("namespace"), gate.Namespace, err.Error()))
+		}
+		if _, err := template.New("").Parse(gate.Name); err != nil {
+			errs = append(errs, field.Invalid(gatePath.Child("name"), gate.Name, err.Error()))
+		}
+		if gate.JSONPath != "" {
+			if _, err := parseJSONPath(gate.JSONPath); err != nil {
+				errs = append(errs, field.Invalid(gatePath.Child("jsonPath"), gate.JSONPath, err.Error()))
+			}
+		} else if gate.Value != "" {
+			errs = append(errs, field.Forbidden(gatePath.Child("value"), "requires jsonPath"))
+		}
+	}
+	return errs
+}
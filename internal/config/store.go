@@ -0,0 +1,92 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// Store holds the active configuration and lets it be swapped at runtime
+type Store struct {
+	current atomic.Pointer[Config]
+
+	mu          sync.Mutex
+	subscribers []chan struct{}
+	// loadErr is why the latest reload failed, until a reload succeeds
+	loadErr error
+}
+
+// NewStore returns a Store serving cfg
+func NewStore(cfg *Config) *Store {
+	s := &Store{}
+	s.current.Store(cfg)
+	return s
+}
+
+// Get returns the active configuration. The returned value must not be modified.
+func (s *Store) Get() *Config {
+	return s.current.Load()
+}
+
+// Set replaces the active configuration and notifies subscribers
+func (s *Store) Set(cfg *Config) {
+	s.current.Store(cfg)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.loadErr = nil
+	for _, ch := range s.subscribers {
+		// Subscribers only need to know that something changed, so a pending
+		// notification is as good as a new one
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Subscribe returns a channel that receives a value whenever the configuration changes
+func (s *Store) Subscribe() <-chan struct{} {
+	ch := make(chan struct{}, 1)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.subscribers = append(s.subscribers, ch)
+	return ch
+}
+
+// LoadFailed records that reloading the configuration failed with err, so the
+// previous configuration is still active
+func (s *Store) LoadFailed(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.loadErr = err
+}
+
+// Check is a health check failing while the latest reload of the
+// configuration has failed
+func (s *Store) Check(_ *http.Request) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.loadErr != nil {
+		return fmt.Errorf("running on the previous configuration, reload failed: %w", s.loadErr)
+	}
+	return nil
+}
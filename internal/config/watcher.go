@@ -0,0 +1,94 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/fsnotify/fsnotify"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// FileWatcher reloads the configuration file into a Store whenever it changes
+type FileWatcher struct {
+	// Path is the configuration file to watch
+	Path string
+	// Store receives every successfully loaded configuration
+	Store *Store
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable. Every replica
+// keeps its configuration current so a new leader starts with the latest rules.
+func (w *FileWatcher) NeedLeaderElection() bool {
+	return false
+}
+
+// Start implements manager.Runnable and blocks until ctx is cancelled
+func (w *FileWatcher) Start(ctx context.Context) error {
+	log := log.FromContext(ctx).WithName("config-watcher").WithValues("path", w.Path)
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create file watcher: %w", err)
+	}
+	defer watcher.Close()
+
+	// Watch the parent directory rather than the file itself: editors and
+	// ConfigMap volume updates replace the file, which drops a direct watch
+	if err := watcher.Add(filepath.Dir(w.Path)); err != nil {
+		return fmt.Errorf("failed to watch config directory: %w", err)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if !w.isRelevant(event) {
+				continue
+			}
+			cfg, err := Load(w.Path)
+			if err != nil {
+				log.Error(err, "Failed to reload config, keeping previous configuration")
+				w.Store.LoadFailed(err)
+				continue
+			}
+			w.Store.Set(cfg)
+			log.Info("Reloaded config", "rules", len(cfg.Rules))
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			log.Error(err, "Config watcher error")
+		}
+	}
+}
+
+// isRelevant reports whether event may have changed the contents of the watched file
+func (w *FileWatcher) isRelevant(event fsnotify.Event) bool {
+	if !event.Has(fsnotify.Write) && !event.Has(fsnotify.Create) && !event.Has(fsnotify.Rename) {
+		return false
+	}
+	name := filepath.Base(event.Name)
+	// Kubernetes swaps the ..data symlink when a mounted ConfigMap changes
+	return name == filepath.Base(w.Path) || name == "..data"
+}
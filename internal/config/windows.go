@@ -0,0 +1,140 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"time"
+
+	"github.com/robfig/cron/v3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// WindowMode selects how the untaint windows apply
+type WindowMode string
+
+const (
+	// WindowModePause pauses untainting while a window is open
+	WindowModePause WindowMode = "Pause"
+	// WindowModeAllow only allows untainting while a window is open
+	WindowModeAllow WindowMode = "Allow"
+)
+
+// UntaintWindows are recurring windows during which untainting is paused,
+// or outside of which it is, so nodes provisioned during a change freeze
+// don't accept workloads until it ends
+type UntaintWindows struct {
+	// Mode selects whether untainting is paused during the windows or only
+	// allowed during them
+	Mode WindowMode `json:"mode,omitempty"`
+	// TimeZone is the IANA time zone the schedules are in. Defaults to UTC.
+	TimeZone string `json:"timeZone,omitempty"`
+	// Windows are the recurring windows
+	Windows []Window `json:"windows"`
+}
+
+// Window is a recurring window of time
+type Window struct {
+	// Schedule is a cron expression of when the window opens, in the
+	// five-field format of CronJobs
+	Schedule string `json:"schedule"`
+	// Duration is how long the window stays open
+	Duration metav1.Duration `json:"duration"`
+}
+
+// Default fills in the unset fields of the windows. It does nothing on nil windows.
+func (w *UntaintWindows) Default() {
+	if w == nil {
+		return
+	}
+	if w.Mode == "" {
+		w.Mode = WindowModePause
+	}
+	if w.TimeZone == "" {
+		w.TimeZone = "UTC"
+	}
+}
+
+// Blocked reports whether the windows block untainting at now, along with how
+// long until that may change. Nil windows never block.
+func (w *UntaintWindows) Blocked(now time.Time) (bool, time.Duration) {
+	if w == nil {
+		return false, 0
+	}
+	if location, err := time.LoadLocation(w.TimeZone); err == nil {
+		now = now.In(location)
+	}
+	var open bool
+	var change time.Duration
+	soonest := func(after time.Duration) {
+		if change == 0 || after < change {
+			change = after
+		}
+	}
+	for _, window := range w.Windows {
+		schedule, err := cron.ParseStandard(window.Schedule)
+		if err != nil {
+			continue
+		}
+		// The window is open when it last opened less than its duration ago
+		if start := schedule.Next(now.Add(-window.Duration.Duration)); !start.After(now) {
+			if !open {
+				change = 0
+			}
+			open = true
+			soonest(start.Add(window.Duration.Duration).Sub(now))
+		} else if !open {
+			soonest(schedule.Next(now).Sub(now))
+		}
+	}
+	if w.Mode == WindowModeAllow {
+		return !open, change
+	}
+	return open, change
+}
+
+// ValidateUntaintWindows checks that windows, when set, have a supported
+// mode, a known time zone and valid schedules
+func ValidateUntaintWindows(path *field.Path, windows *UntaintWindows) field.ErrorList {
+	if windows == nil {
+		return nil
+	}
+	var errs field.ErrorList
+	switch windows.Mode {
+	case WindowModePause, WindowModeAllow:
+	default:
+		errs = append(errs, field.NotSupported(path.Child("mode"), windows.Mode,
+			[]WindowMode{WindowModePause, WindowModeAllow}))
+	}
+	if _, err := time.LoadLocation(windows.TimeZone); err != nil {
+		errs = append(errs, field.Invalid(path.Child("timeZone"), windows.TimeZone, err.Error()))
+	}
+	if len(windows.Windows) == 0 {
+		errs = append(errs, field.Required(path.Child("windows"), ""))
+	}
+	for i, window := range windows.Windows {
+		windowPath := path.Child("windows").Index(i)
+		if _, err := cron.ParseStandard(window.Schedule); err != nil {
+			errs = append(errs, field.Invalid(windowPath.Child("schedule"), window.Schedule, err.Error()))
+		}
+		if window.Duration.Duration <= 0 {
+			errs = append(errs, field.Invalid(windowPath.Child("duration"), window.Duration.Duration.String(),
+				"must be positive"))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,250 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	untaintv1alpha1 "github.com/jslay88/generic-untaint-operator/api/v1alpha1"
+	"github.com/jslay88/generic-untaint-operator/internal/asg"
+	"github.com/jslay88/generic-untaint-operator/internal/config"
+)
+
+// actionContext is what an untaint action knows about the removal it is part of
+type actionContext struct {
+	node *corev1.Node
+	rule config.Rule
+	// pods are all pods on the node
+	pods []corev1.Pod
+	// forced is set when the taint is removed although its workloads are not ready
+	forced bool
+}
+
+// untaintAction is a step of the pipeline run, in the order the rule lists
+// them, for a rule whose taint is removed
+type untaintAction interface {
+	// prepare adds the changes the action makes to the node to the write
+	// removing the taint
+	prepare(ac actionContext, update *nodeEdit)
+	// complete runs once the write succeeded
+	complete(ctx context.Context, ac actionContext)
+}
+
+// untaintAction returns the implementation of action
+func (r *NodeReconciler) untaintAction(action config.UntaintAction) untaintAction {
+	switch action {
+	case config.UntaintActionUncordon:
+		return uncordonAction{}
+	case config.UntaintActionLabel:
+		return labelAction{}
+	case config.UntaintActionAnnotate:
+		return annotateAction{}
+	case config.UntaintActionNotify:
+		return notifyAction{r: r}
+	case config.UntaintActionCompleteLifecycle:
+		return lifecycleAction{r: r}
+	default:
+		return untaintTaintAction{}
+	}
+}
+
+// nodeEditAction is embedded by actions that only edit the node
+type nodeEditAction struct{}
+
+func (nodeEditAction) complete(context.Context, actionContext) {}
+
+// untaintTaintAction stands for the removal itself, whose taint edit the
+// reconciler already made
+type untaintTaintAction struct{ nodeEditAction }
+
+func (untaintTaintAction) prepare(actionContext, *nodeEdit) {}
+
+// uncordonAction clears spec.unschedulable
+type uncordonAction struct{ nodeEditAction }
+
+func (uncordonAction) prepare(ac actionContext, update *nodeEdit) {
+	update.uncordon = ac.node.Spec.Unschedulable
+}
+
+// labelAction sets and removes the rule's labels
+type labelAction struct{ nodeEditAction }
+
+func (labelAction) prepare(ac actionContext, update *nodeEdit) {
+	update.relabel(ac.rule)
+}
+
+// annotateAction records the removal in annotations on the node
+type annotateAction struct{ nodeEditAction }
+
+func (annotateAction) prepare(ac actionContext, update *nodeEdit) {
+	if update.record == nil {
+		update.record = &untaintRecord{}
+	}
+	update.record.taints = append(update.record.taints, ac.rule.TargetTaint)
+	if ac.forced {
+		update.record.forced = true
+		return
+	}
+	update.record.pods = append(update.record.pods, gatingPods(ac.pods, requiredWorkloads(ac.node, ac.rule))...)
+}
+
+// notifyAction emits an event on the node once the taint is removed
+type notifyAction struct {
+	r *NodeReconciler
+}
+
+func (notifyAction) prepare(actionContext, *nodeEdit) {}
+
+func (a notifyAction) complete(_ context.Context, ac actionContext) {
+	if ac.forced {
+		a.r.eventf(ac.node, corev1.EventTypeNormal, untaintv1alpha1.ReasonTaintRemoved,
+			"Removed taint %s after its maxWait although its workloads are not ready", ac.rule.TargetTaint)
+		return
+	}
+	pods := gatingPods(ac.pods, requiredWorkloads(ac.node, ac.rule))
+	slices.Sort(pods)
+	a.r.eventf(ac.node, corev1.EventTypeNormal, untaintv1alpha1.ReasonTaintRemoved,
+		"Removed taint %s, its workloads are ready: %s", ac.rule.TargetTaint, strings.Join(pods, ", "))
+}
+
+// LifecycleCompleter completes the cloud provider lifecycle action of the
+// instance backing a node
+type LifecycleCompleter interface {
+	// Complete continues the lifecycle action waiting on hookName and reports
+	// whether there was one to complete
+	Complete(ctx context.Context, node *corev1.Node, hookName string) (bool, error)
+}
+
+// lifecycleAction completes the lifecycle action of the instance backing the
+// node once the taint is removed
+type lifecycleAction struct {
+	r *NodeReconciler
+}
+
+func (lifecycleAction) prepare(actionContext, *nodeEdit) {}
+
+func (a lifecycleAction) complete(ctx context.Context, ac actionContext) {
+	hook := ac.rule.AWSLifecycleHook
+	if a.r.Lifecycle == nil {
+		log.FromContext(ctx).Error(nil, "No lifecycle completer is set up, skipping lifecycle action",
+			"node", ac.node.Name, "hook", hook)
+		return
+	}
+	a.r.completeLifecycle(ctx, ac.node, hook)
+}
+
+// lifecycleRetryInterval is how long a node waits to retry a lifecycle action
+// that failed to complete
+const lifecycleRetryInterval = 30 * time.Second
+
+// pendingLifecycles remembers the lifecycle actions of each node that failed
+// to complete, so the node is requeued until they do. The zero value is ready
+// to use.
+type pendingLifecycles struct {
+	mu    sync.Mutex
+	hooks map[string][]string
+}
+
+// add records that hook is pending on node, and reports whether it wasn't before
+func (p *pendingLifecycles) add(node, hook string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if slices.Contains(p.hooks[node], hook) {
+		return false
+	}
+	if p.hooks == nil {
+		p.hooks = make(map[string][]string)
+	}
+	p.hooks[node] = append(p.hooks[node], hook)
+	return true
+}
+
+// done drops hook from the actions pending on node
+func (p *pendingLifecycles) done(node, hook string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	hooks := slices.DeleteFunc(slices.Clone(p.hooks[node]), func(h string) bool { return h == hook })
+	if len(hooks) == 0 {
+		delete(p.hooks, node)
+		return
+	}
+	p.hooks[node] = hooks
+}
+
+// pending returns the hooks whose actions are pending on node
+func (p *pendingLifecycles) pending(node string) []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return slices.Clone(p.hooks[node])
+}
+
+// requeueAfter returns when to requeue node to retry its pending actions, or
+// zero when none are
+func (p *pendingLifecycles) requeueAfter(node string) time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.hooks[node]) == 0 {
+		return 0
+	}
+	return lifecycleRetryInterval
+}
+
+// forget drops the actions pending on node
+func (p *pendingLifecycles) forget(node string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.hooks, node)
+}
+
+// completeLifecycle completes the lifecycle action waiting on hook for node,
+// retrying transient errors a few times. An action that still fails is left
+// pending, for retryLifecycles to try again on a later reconcile.
+func (r *NodeReconciler) completeLifecycle(ctx context.Context, node *corev1.Node, hook string) {
+	log := log.FromContext(ctx)
+	transient := func(err error) bool {
+		return !errors.Is(err, asg.ErrNoInstanceID) && ctx.Err() == nil
+	}
+	var completed bool
+	err := retry.OnError(retry.DefaultRetry, transient, func() (err error) {
+		completed, err = r.Lifecycle.Complete(ctx, node, hook)
+		return err
+	})
+	switch {
+	case err != nil && transient(err):
+		log.Error(err, "Failed to complete lifecycle action, will retry", "node", node.Name, "hook", hook)
+		if r.lifecycles.add(node.Name, hook) {
+			r.eventf(node, corev1.EventTypeWarning, "LifecycleActionFailed",
+				"Failed to complete lifecycle action %s, will retry: %v", hook, err)
+		}
+		return
+	case err != nil:
+		log.Error(err, "Failed to complete lifecycle action", "node", node.Name, "hook", hook)
+		r.eventf(node, corev1.EventTypeWarning, "LifecycleActionFailed",
+			"Failed to complete lifecycle action %s: %v", hook, err)
+	case completed:
+		log.Info("Completed lifecycle action", "node", node.Name, "hook", hook)
+		r.eventf(node, corev1.EventTypeNormal, "LifecycleActionCompleted", "Completed lifecycle action %s", hook)
+	}
+	// Completed, or no longer waiting on the hook
+	r.lifecycles.done(node.Name, hook)
+}
+
+// retryLifecycles tries again to complete the lifecycle actions pending on
+// node, and returns when to requeue the node while some still are
+func (r *NodeReconciler) retryLifecycles(ctx context.Context, node *corev1.Node) time.Duration {
+	hooks := r.lifecycles.pending(node.Name)
+	if len(hooks) == 0 || r.Lifecycle == nil {
+		return 0
+	}
+	for _, hook := range hooks {
+		r.completeLifecycle(ctx, node, hook)
+	}
+	return r.lifecycles.requeueAfter(node.Name)
+}
@@ -0,0 +1,169 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/jslay88/generic-untaint-operator/internal/asg"
+	"github.com/jslay88/generic-untaint-operator/internal/config"
+)
+
+var _ = Describe("untaint actions", func() {
+	var (
+		recorder *record.FakeRecorder
+		r        *NodeReconciler
+		ac       actionContext
+	)
+
+	BeforeEach(func() {
+		recorder = record.NewFakeRecorder(10)
+		r = &NodeReconciler{Recorder: recorder}
+		ac = actionContext{
+			node: &corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: "node"},
+				Spec:       corev1.NodeSpec{Unschedulable: true},
+			},
+			rule: config.Rule{
+				TargetTaint:  "example.com/not-ready",
+				OwnedByNames: []string{"agent"},
+				SetLabels:    map[string]string{"example.com/ready": "true"},
+			},
+			pods: []corev1.Pod{{ObjectMeta: metav1.ObjectMeta{
+				Namespace:       "kube-system",
+				Name:            "agent-abc",
+				OwnerReferences: []metav1.OwnerReference{{Name: "agent"}},
+			}}},
+		}
+	})
+
+	It("should add the changes of each action to the write", func() {
+		update := nodeEdit{}
+		for _, action := range []config.UntaintAction{
+			config.UntaintActionUntaint, config.UntaintActionUncordon, config.UntaintActionLabel,
+			config.UntaintActionAnnotate, config.UntaintActionNotify,
+		} {
+			r.untaintAction(action).prepare(ac, &update)
+		}
+		Expect(update.uncordon).To(BeTrue())
+		Expect(update.setLabels).To(Equal(map[string]string{"example.com/ready": "true"}))
+		Expect(update.record).To(Equal(&untaintRecord{
+			taints: []string{"example.com/not-ready"},
+			pods:   []string{"kube-system/agent-abc"},
+		}))
+		Expect(recorder.Events).To(BeEmpty())
+	})
+
+	It("should only edit the node for the listed actions", func() {
+		update := nodeEdit{}
+		r.untaintAction(config.UntaintActionUntaint).prepare(ac, &update)
+		Expect(update).To(Equal(nodeEdit{}))
+	})
+
+	It("should notify once the taint is removed", func() {
+		r.untaintAction(config.UntaintActionNotify).complete(context.Background(), ac)
+		Expect(recorder.Events).To(Receive(And(
+			ContainSubstring("TaintRemoved"),
+			ContainSubstring("kube-system/agent-abc"),
+		)))
+
+		ac.forced = true
+		r.untaintAction(config.UntaintActionNotify).complete(context.Background(), ac)
+		Expect(recorder.Events).To(Receive(ContainSubstring("maxWait")))
+	})
+
+	It("should complete the lifecycle action once the taint is removed", func() {
+		lifecycle := &fakeLifecycle{completed: true}
+		r.Lifecycle = lifecycle
+		ac.rule.AWSLifecycleHook = "node-ready"
+		r.untaintAction(config.UntaintActionCompleteLifecycle).complete(context.Background(), ac)
+		Expect(lifecycle.hooks).To(Equal([]string{"node-ready"}))
+		Expect(recorder.Events).To(Receive(ContainSubstring("LifecycleActionCompleted")))
+
+		lifecycle.err = errors.New("access denied")
+		r.untaintAction(config.UntaintActionCompleteLifecycle).complete(context.Background(), ac)
+		Expect(recorder.Events).To(Receive(And(
+			ContainSubstring("LifecycleActionFailed"),
+			ContainSubstring("access denied"),
+		)))
+
+		lifecycle.err = nil
+		lifecycle.completed = false
+		r.untaintAction(config.UntaintActionCompleteLifecycle).complete(context.Background(), ac)
+		Expect(recorder.Events).To(BeEmpty())
+	})
+
+	It("should retry transient failures to complete the lifecycle action", func() {
+		lifecycle := &fakeLifecycle{completed: true, failures: 2}
+		r.Lifecycle = lifecycle
+		ac.rule.AWSLifecycleHook = "node-ready"
+		r.untaintAction(config.UntaintActionCompleteLifecycle).complete(context.Background(), ac)
+		Expect(lifecycle.hooks).To(HaveLen(3))
+		Expect(recorder.Events).To(Receive(ContainSubstring("LifecycleActionCompleted")))
+		Expect(r.lifecycles.requeueAfter("node")).To(BeZero())
+	})
+
+	It("should requeue the node until a failing lifecycle action completes", func() {
+		lifecycle := &fakeLifecycle{completed: true, err: errors.New("service unavailable")}
+		r.Lifecycle = lifecycle
+		ac.rule.AWSLifecycleHook = "node-ready"
+		r.untaintAction(config.UntaintActionCompleteLifecycle).complete(context.Background(), ac)
+		Expect(recorder.Events).To(Receive(ContainSubstring("LifecycleActionFailed")))
+		Expect(r.lifecycles.requeueAfter("node")).To(Equal(lifecycleRetryInterval))
+
+		// Still failing, without repeating the event
+		Expect(r.retryLifecycles(context.Background(), ac.node)).To(Equal(lifecycleRetryInterval))
+		Expect(recorder.Events).To(BeEmpty())
+
+		lifecycle.err = nil
+		Expect(r.retryLifecycles(context.Background(), ac.node)).To(BeZero())
+		Expect(recorder.Events).To(Receive(ContainSubstring("LifecycleActionCompleted")))
+		Expect(r.lifecycles.pending("node")).To(BeEmpty())
+	})
+
+	It("should stop retrying once the instance no longer waits on the hook", func() {
+		lifecycle := &fakeLifecycle{err: errors.New("service unavailable")}
+		r.Lifecycle = lifecycle
+		ac.rule.AWSLifecycleHook = "node-ready"
+		r.untaintAction(config.UntaintActionCompleteLifecycle).complete(context.Background(), ac)
+		Expect(r.lifecycles.pending("node")).To(Equal([]string{"node-ready"}))
+
+		lifecycle.err = nil
+		Expect(r.retryLifecycles(context.Background(), ac.node)).To(BeZero())
+		Expect(r.lifecycles.pending("node")).To(BeEmpty())
+	})
+
+	It("should not retry nodes without an EC2 instance", func() {
+		lifecycle := &fakeLifecycle{err: fmt.Errorf("%w: node node has no AWS provider ID", asg.ErrNoInstanceID)}
+		r.Lifecycle = lifecycle
+		ac.rule.AWSLifecycleHook = "node-ready"
+		r.untaintAction(config.UntaintActionCompleteLifecycle).complete(context.Background(), ac)
+		Expect(lifecycle.hooks).To(HaveLen(1))
+		Expect(recorder.Events).To(Receive(ContainSubstring("LifecycleActionFailed")))
+		Expect(r.lifecycles.requeueAfter("node")).To(BeZero())
+	})
+})
+
+// fakeLifecycle records the lifecycle hooks it is asked to complete, failing
+// the first failures calls with a throttling error
+type fakeLifecycle struct {
+	completed bool
+	err       error
+	failures  int
+	hooks     []string
+}
+
+func (f *fakeLifecycle) Complete(_ context.Context, _ *corev1.Node, hookName string) (bool, error) {
+	f.hooks = append(f.hooks, hookName)
+	if f.failures > 0 {
+		f.failures--
+		return false, errors.New("throttled")
+	}
+	return f.completed, f.err
+}
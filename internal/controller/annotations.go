@@ -0,0 +1,80 @@
+package controller
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// UntaintedByAnnotation names the operator that last removed target taints
+	// from the node
+	UntaintedByAnnotation = "untaint-operator.jslay88.github.io/untainted-by"
+	// UntaintedAtAnnotation is when target taints were last removed, in RFC 3339 format
+	UntaintedAtAnnotation = "untaint-operator.jslay88.github.io/untainted-at"
+	// UntaintedTaintsAnnotation is a comma-separated list of the taint keys
+	// last removed
+	UntaintedTaintsAnnotation = "untaint-operator.jslay88.github.io/untainted-taints"
+	// UntaintedPodsAnnotation is a comma-separated list of the namespace/name
+	// of the pods that satisfied the gate
+	UntaintedPodsAnnotation = "untaint-operator.jslay88.github.io/untainted-pods"
+	// UntaintReasonAnnotation is why the taints were removed
+	UntaintReasonAnnotation = "untaint-operator.jslay88.github.io/untaint-reason"
+)
+
+const (
+	// UntaintReasonWorkloadsReady means the required workloads were ready
+	UntaintReasonWorkloadsReady = "WorkloadsReady"
+	// UntaintReasonMaxWaitExceeded means a taint was force removed once it
+	// outlived its maxWait
+	UntaintReasonMaxWaitExceeded = "MaxWaitExceeded"
+)
+
+// untaintRecord collects what the untaint annotations record about a write
+// removing taints
+type untaintRecord struct {
+	taints []string
+	pods   []string
+	// forced is set when a taint was removed although its workloads are not ready
+	forced bool
+}
+
+// annotations returns the untaint annotations for a write made at the given time
+func (rec *untaintRecord) annotations(at time.Time) map[string]string {
+	reason := UntaintReasonWorkloadsReady
+	if rec.forced {
+		reason = UntaintReasonMaxWaitExceeded
+	}
+	return untaintAnnotations(rec.taints, rec.pods, reason, at)
+}
+
+// untaintAnnotations returns the annotations recording that taints were
+// removed at the given time because of reason, with pods satisfying the gate
+func untaintAnnotations(taints, pods []string, reason string, at time.Time) map[string]string {
+	taints = slices.Clone(taints)
+	slices.Sort(taints)
+	pods = slices.Clone(pods)
+	slices.Sort(pods)
+	return map[string]string{
+		UntaintedByAnnotation:     FieldManager,
+		UntaintedAtAnnotation:     at.UTC().Format(time.RFC3339),
+		UntaintedTaintsAnnotation: strings.Join(taints, ","),
+		UntaintedPodsAnnotation:   strings.Join(slices.Compact(pods), ","),
+		UntaintReasonAnnotation:   reason,
+	}
+}
+
+// gatingPods returns the namespace/name of the pods of the workloads that
+// were checked before removing a taint
+func gatingPods(pods []corev1.Pod, ownedByNames []string) []string {
+	workloads := newWorkloadSet(ownedByNames)
+	var names []string
+	for i := range pods {
+		if pods[i].DeletionTimestamp == nil && isOwnedBy(&pods[i], workloads) {
+			names = append(names, pods[i].Namespace+"/"+pods[i].Name)
+		}
+	}
+	return names
+}
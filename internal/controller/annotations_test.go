@@ -0,0 +1,43 @@
+package controller
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("untaint annotations", func() {
+	It("should record the removed taints and gating pods in a stable order", func() {
+		at := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+		annotations := untaintAnnotations(
+			[]string{"b.example.com/not-ready", "a.example.com/not-ready"},
+			[]string{"kube-system/agent-b", "kube-system/agent-a", "kube-system/agent-b"},
+			UntaintReasonWorkloadsReady, at,
+		)
+		Expect(annotations).To(Equal(map[string]string{
+			UntaintedByAnnotation:     FieldManager,
+			UntaintedAtAnnotation:     "2025-01-02T03:04:05Z",
+			UntaintedTaintsAnnotation: "a.example.com/not-ready,b.example.com/not-ready",
+			UntaintedPodsAnnotation:   "kube-system/agent-a,kube-system/agent-b",
+			UntaintReasonAnnotation:   UntaintReasonWorkloadsReady,
+		}))
+	})
+
+	It("should only list live pods of the required workloads", func() {
+		ownedBy := func(name, owner string) corev1.Pod {
+			return corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+				Namespace:       "kube-system",
+				Name:            name,
+				OwnerReferences: []metav1.OwnerReference{{Name: owner}},
+			}}
+		}
+		terminating := ownedBy("agent-old", "agent")
+		terminating.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+		pods := []corev1.Pod{ownedBy("agent-abc", "agent"), ownedBy("other-abc", "other"), terminating}
+
+		Expect(gatingPods(pods, []string{"agent"})).To(Equal([]string{"kube-system/agent-abc"}))
+	})
+})
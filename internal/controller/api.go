@@ -0,0 +1,190 @@
+package controller
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"time"
+
+	certutil "k8s.io/client-go/util/cert"
+	"sigs.k8s.io/controller-runtime/pkg/certwatcher"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+
+	"github.com/jslay88/generic-untaint-operator/internal/config"
+)
+
+// StatusAPIPrefix is the path prefix of the status API
+const StatusAPIPrefix = "/untaint/v1/"
+
+// statusAPIShutdownTimeout is how long the status API may take to finish the
+// requests in flight once stopped
+const statusAPIShutdownTimeout = 5 * time.Second
+
+// StatusAPI serves a read-only JSON API over HTTPS for platform dashboards:
+//
+//	GET /untaint/v1/config        the active configuration, with webhook URLs redacted
+//	GET /untaint/v1/nodes         the nodes keeping their target taints and why
+//	GET /untaint/v1/nodes/{name}  the same for one node
+//	GET /untaint/v1/actions       the latest taint changes, newest first
+//
+// Only the leader reconciles nodes, so on other replicas the nodes and
+// actions are empty.
+type StatusAPI struct {
+	// Addr is the address the API listens on
+	Addr string
+	// CertDir holds the tls.crt and tls.key to serve, reloaded when they
+	// change. A self-signed certificate is generated when unset.
+	CertDir string
+	// Reconciler is the node reconciler whose state is served
+	Reconciler *NodeReconciler
+	// Filter, when set, authenticates and authorizes every request
+	Filter metricsserver.Filter
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable so every
+// replica serves the API
+func (a *StatusAPI) NeedLeaderElection() bool {
+	return false
+}
+
+// Start implements manager.Runnable and serves the API until ctx is cancelled
+func (a *StatusAPI) Start(ctx context.Context) error {
+	log := log.FromContext(ctx).WithName("status-api")
+
+	handler := a.Reconciler.StatusAPIHandler()
+	if a.Filter != nil {
+		filtered, err := a.Filter(log, handler)
+		if err != nil {
+			return fmt.Errorf("failed to set up status API authentication: %w", err)
+		}
+		handler = filtered
+	}
+	tlsConfig, err := a.tlsConfig(ctx)
+	if err != nil {
+		return err
+	}
+	listener, err := tls.Listen("tcp", a.Addr, tlsConfig)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", a.Addr, err)
+	}
+
+	server := &http.Server{Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), statusAPIShutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Error(err, "Failed to shut down status API")
+		}
+	}()
+	log.Info("Serving status API", "addr", listener.Addr().String())
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// tlsConfig returns the TLS configuration serving the certificate of
+// CertDir, or a self-signed one
+func (a *StatusAPI) tlsConfig(ctx context.Context) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if a.CertDir == "" {
+		host, _, _ := net.SplitHostPort(a.Addr)
+		if host == "" {
+			host = "localhost"
+		}
+		certPEM, keyPEM, err := certutil.GenerateSelfSignedCertKey(host, nil, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate status API certificate: %w", err)
+		}
+		cert, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load status API certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+		return tlsConfig, nil
+	}
+
+	watcher, err := certwatcher.New(filepath.Join(a.CertDir, "tls.crt"), filepath.Join(a.CertDir, "tls.key"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load status API certificate: %w", err)
+	}
+	go func() {
+		if err := watcher.Start(ctx); err != nil {
+			log.FromContext(ctx).Error(err, "Failed to watch status API certificate")
+		}
+	}()
+	tlsConfig.GetCertificate = watcher.GetCertificate
+	return tlsConfig, nil
+}
+
+// StatusAPIHandler serves the routes of the status API
+func (r *NodeReconciler) StatusAPIHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+StatusAPIPrefix+"config", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, http.StatusOK, redactedConfig(r.currentConfig()))
+	})
+	mux.HandleFunc("GET "+StatusAPIPrefix+"nodes", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, http.StatusOK, r.blocked.list(time.Now()))
+	})
+	mux.HandleFunc("GET "+StatusAPIPrefix+"nodes/{name}", func(w http.ResponseWriter, req *http.Request) {
+		name := req.PathValue("name")
+		node, ok := r.blocked.get(name, time.Now())
+		if !ok {
+			writeJSON(w, http.StatusNotFound, apiError{Error: fmt.Sprintf("node %s keeps no target taints", name)})
+			return
+		}
+		writeJSON(w, http.StatusOK, node)
+	})
+	mux.HandleFunc("GET "+StatusAPIPrefix+"actions", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, http.StatusOK, r.actions.list())
+	})
+	return mux
+}
+
+// apiError is the body of failed status API requests
+type apiError struct {
+	Error string `json:"error"`
+}
+
+// writeJSON answers with body as JSON
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// redactedConfig returns a copy of cfg without the paths and queries of its
+// webhook URLs, which often carry credentials
+func redactedConfig(cfg *config.Config) *config.Config {
+	redacted := *cfg
+	for _, hook := range []**config.Hook{&redacted.PreUntaintHook, &redacted.PostUntaintHook} {
+		if *hook != nil {
+			copied := **hook
+			copied.URL = redactURL(copied.URL)
+			*hook = &copied
+		}
+	}
+	if redacted.Notifications != nil {
+		notifications := *redacted.Notifications
+		notifications.URL = redactURL(notifications.URL)
+		redacted.Notifications = &notifications
+	}
+	return &redacted
+}
+
+// redactURL keeps only the scheme and host of rawURL
+func redactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "redacted"
+	}
+	return (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/redacted"}).String()
+}
@@ -0,0 +1,140 @@
+package controller
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	untaintv1alpha1 "github.com/jslay88/generic-untaint-operator/api/v1alpha1"
+	"github.com/jslay88/generic-untaint-operator/internal/config"
+)
+
+var _ = Describe("status API", func() {
+	var (
+		r       *NodeReconciler
+		handler http.Handler
+	)
+
+	get := func(path string, body interface{}) int {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		Expect(recorder.Header().Get("Content-Type")).To(Equal("application/json"))
+		Expect(json.Unmarshal(recorder.Body.Bytes(), body)).To(Succeed())
+		return recorder.Code
+	}
+
+	BeforeEach(func() {
+		cfg := &config.Config{
+			Rules:          []config.Rule{{TargetTaint: "example.com/not-ready", OwnedByNames: []string{"agent"}}},
+			PreUntaintHook: &config.Hook{URL: "https://hooks.example.com/allow?token=secret"},
+			Notifications:  &config.Notifications{URL: "https://hooks.slack.com/services/T000/B000/XXXX"},
+		}
+		cfg.Default()
+		r = &NodeReconciler{Config: config.NewStore(cfg)}
+		handler = r.StatusAPIHandler()
+		DeferCleanup(func() { r.blocked.forget("node") })
+	})
+
+	It("should serve the config without webhook credentials", func() {
+		var cfg config.Config
+		Expect(get(StatusAPIPrefix+"config", &cfg)).To(Equal(http.StatusOK))
+		Expect(cfg.Rules).To(HaveLen(1))
+		Expect(cfg.PreUntaintHook.URL).To(Equal("https://hooks.example.com/redacted"))
+		Expect(cfg.Notifications.URL).To(Equal("https://hooks.slack.com/redacted"))
+		// The active config is left alone
+		Expect(r.currentConfig().PreUntaintHook.URL).To(Equal("https://hooks.example.com/allow?token=secret"))
+	})
+
+	It("should serve the gate evaluation of blocked nodes", func() {
+		r.blocked.set(BlockedNode{
+			Node:      "node",
+			Taints:    []string{"example.com/not-ready"},
+			Reason:    untaintv1alpha1.ReasonWaitingForWorkload,
+			Workloads: []string{"agent"},
+		}, time.Now(), 0)
+
+		var nodes []BlockedNode
+		Expect(get(StatusAPIPrefix+"nodes", &nodes)).To(Equal(http.StatusOK))
+		Expect(nodes).To(HaveLen(1))
+
+		var node BlockedNode
+		Expect(get(StatusAPIPrefix+"nodes/node", &node)).To(Equal(http.StatusOK))
+		Expect(node.Reason).To(Equal(untaintv1alpha1.ReasonWaitingForWorkload))
+		Expect(node.Workloads).To(Equal([]string{"agent"}))
+
+		var failure apiError
+		Expect(get(StatusAPIPrefix+"nodes/other", &failure)).To(Equal(http.StatusNotFound))
+		Expect(failure.Error).To(Equal("node other keeps no target taints"))
+	})
+
+	It("should serve the recent actions", func() {
+		r.recordActions(AuditRecord{Action: AuditTaintRemoved, Node: "node", Taint: "example.com/not-ready"})
+		var actions []AuditRecord
+		Expect(get(StatusAPIPrefix+"actions", &actions)).To(Equal(http.StatusOK))
+		Expect(actions).To(HaveLen(1))
+		Expect(actions[0].Action).To(Equal(AuditTaintRemoved))
+	})
+
+	It("should serve over TLS behind the filter", func() {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		addr := listener.Addr().String()
+		Expect(listener.Close()).To(Succeed())
+
+		api := &StatusAPI{
+			Addr:       addr,
+			Reconciler: r,
+			Filter: func(_ logr.Logger, next http.Handler) (http.Handler, error) {
+				return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+					if req.Header.Get("Authorization") != "Bearer token" {
+						http.Error(w, "Unauthorized", http.StatusUnauthorized)
+						return
+					}
+					next.ServeHTTP(w, req)
+				}), nil
+			},
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			Expect(api.Start(ctx)).To(Succeed())
+		}()
+		DeferCleanup(func() {
+			cancel()
+			Eventually(done).Should(BeClosed())
+		})
+
+		client := &http.Client{Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		}}
+		status := func(token string) func() (int, error) {
+			return func() (int, error) {
+				req, err := http.NewRequest(http.MethodGet, "https://"+addr+StatusAPIPrefix+"nodes", nil)
+				if err != nil {
+					return 0, err
+				}
+				if token != "" {
+					req.Header.Set("Authorization", "Bearer "+token)
+				}
+				resp, err := client.Do(req)
+				if err != nil {
+					return 0, err
+				}
+				defer resp.Body.Close()
+				return resp.StatusCode, nil
+			}
+		}
+		Eventually(status("token")).Should(Equal(http.StatusOK))
+		Expect(status("")()).To(Equal(http.StatusUnauthorized))
+	})
+})
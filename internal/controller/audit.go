@@ -0,0 +1,155 @@
+package controller
+
+import (
+	"encoding/json"
+	"io"
+	"slices"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	untaintv1alpha1 "github.com/jslay88/generic-untaint-operator/api/v1alpha1"
+	"github.com/jslay88/generic-untaint-operator/internal/config"
+)
+
+// The actions of audit records
+const (
+	// AuditTaintRemoved means the operator removed a target taint
+	AuditTaintRemoved = untaintv1alpha1.ReasonTaintRemoved
+	// AuditTaintDowngraded means the operator weakened the effect of a target
+	// taint during staged removal
+	AuditTaintDowngraded = untaintv1alpha1.ReasonTaintDowngraded
+	// AuditTaintReAdded means a target taint came back after the operator
+	// removed it
+	AuditTaintReAdded = untaintv1alpha1.ReasonReverted
+)
+
+// AuditRecord is a single line of the audit log, describing a change to a
+// target taint of a node and what the decision to make it was based on
+type AuditRecord struct {
+	Time   time.Time `json:"time"`
+	Action string    `json:"action"`
+	Node   string    `json:"node"`
+	Taint  string    `json:"taint"`
+	// Effect is the effect of a downgraded taint afterwards
+	Effect corev1.TaintEffect `json:"effect,omitempty"`
+	// Reason is why a removed taint was removed, WorkloadsReady or MaxWaitExceeded
+	Reason string `json:"reason,omitempty"`
+	// Workloads are the workloads the taint waited for
+	Workloads []string `json:"workloads,omitempty"`
+	// Pods are the namespace/name of the pods of those workloads
+	Pods []string `json:"pods,omitempty"`
+	// Waited is how long the taint was on the node
+	Waited string `json:"waited,omitempty"`
+	// ReadinessMode and RemovalStrategy are the settings the decision was made with
+	ReadinessMode   config.ReadinessMode   `json:"readinessMode,omitempty"`
+	RemovalStrategy config.RemovalStrategy `json:"removalStrategy,omitempty"`
+}
+
+// AuditLog writes an AuditRecord as a JSON line for every change made to a
+// target taint, for compliance review. All methods are safe to call on a nil
+// log, which records nothing.
+type AuditLog struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+}
+
+// NewAuditLog returns an audit log writing to w
+func NewAuditLog(w io.Writer) *AuditLog {
+	return &AuditLog{encoder: json.NewEncoder(w)}
+}
+
+// Write appends records to the log. Failures are only logged, since the
+// taints changed either way.
+func (a *AuditLog) Write(records ...AuditRecord) {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for i := range records {
+		if err := a.encoder.Encode(&records[i]); err != nil {
+			log.Log.WithName("audit").Error(err, "Failed to write audit record", "node", records[i].Node,
+				"taint", records[i].Taint)
+		}
+	}
+}
+
+// recentActionsSize is how many of the latest audit records the status API
+// serves
+const recentActionsSize = 100
+
+// recentActions keeps the latest audit records for the status API. The zero
+// value is ready to use.
+type recentActions struct {
+	mu      sync.Mutex
+	records []AuditRecord
+}
+
+// add appends records, dropping the oldest beyond recentActionsSize
+func (a *recentActions) add(records ...AuditRecord) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.records = append(a.records, records...)
+	if extra := len(a.records) - recentActionsSize; extra > 0 {
+		a.records = slices.Delete(a.records, 0, extra)
+	}
+}
+
+// list returns the kept records, newest first
+func (a *recentActions) list() []AuditRecord {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	records := slices.Clone(a.records)
+	slices.Reverse(records)
+	return records
+}
+
+// recordActions writes records to the audit log and keeps them for the
+// status API
+func (r *NodeReconciler) recordActions(records ...AuditRecord) {
+	r.Audit.Write(records...)
+	r.actions.add(records...)
+}
+
+// auditRecords returns the audit records of the taint edits of eval. They are
+// built before the edits are written, while the taints still tell how long
+// they were on the node.
+func (r *NodeReconciler) auditRecords(
+	node *corev1.Node,
+	pods []corev1.Pod,
+	rules []config.Rule,
+	eval *ruleEvaluation,
+	cfg *config.Config,
+	now time.Time,
+) []AuditRecord {
+	var records []AuditRecord
+	for _, rule := range rules {
+		edit, ok := eval.edits[rule.TargetTaint]
+		if !ok {
+			continue
+		}
+		record := AuditRecord{
+			Time:            now.UTC(),
+			Action:          AuditTaintRemoved,
+			Node:            node.Name,
+			Taint:           rule.TargetTaint,
+			Reason:          UntaintReasonWorkloadsReady,
+			Workloads:       requiredWorkloads(node, rule),
+			Waited:          now.Sub(r.ages.since(node, rule.TargetTaint, now)).Round(time.Second).String(),
+			ReadinessMode:   cfg.ReadinessMode,
+			RemovalStrategy: cfg.RemovalStrategy,
+		}
+		record.Pods = gatingPods(pods, record.Workloads)
+		slices.Sort(record.Pods)
+		if edit.downgradeTo != "" {
+			record.Action, record.Effect, record.Reason = AuditTaintDowngraded, edit.downgradeTo, ""
+		} else if slices.Contains(eval.forced, rule.TargetTaint) {
+			record.Reason = UntaintReasonMaxWaitExceeded
+		}
+		records = append(records, record)
+	}
+	return records
+}
@@ -0,0 +1,116 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/jslay88/generic-untaint-operator/internal/config"
+)
+
+var _ = Describe("audit log", func() {
+	var (
+		out   *bytes.Buffer
+		r     *NodeReconciler
+		node  *corev1.Node
+		pods  []corev1.Pod
+		rules []config.Rule
+		cfg   *config.Config
+		now   time.Time
+	)
+
+	pod := func(name, owner string) corev1.Pod {
+		return corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Namespace:       "kube-system",
+			Name:            name,
+			OwnerReferences: []metav1.OwnerReference{{Kind: "DaemonSet", Name: owner}},
+		}}
+	}
+
+	BeforeEach(func() {
+		out = &bytes.Buffer{}
+		r = &NodeReconciler{Audit: NewAuditLog(out)}
+		now = time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+		node = &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "node"},
+			Spec: corev1.NodeSpec{Taints: []corev1.Taint{{
+				Key:       "example.com/not-ready",
+				Effect:    corev1.TaintEffectNoSchedule,
+				TimeAdded: &metav1.Time{Time: now.Add(-90 * time.Second)},
+			}}},
+		}
+		pods = []corev1.Pod{pod("cni-b", "cni"), pod("cni-a", "cni"), pod("other", "other")}
+		rules = []config.Rule{
+			{TargetTaint: "example.com/not-ready", OwnedByNames: []string{"cni"}},
+			{TargetTaint: "example.com/untouched", OwnedByNames: []string{"other"}},
+		}
+		cfg = &config.Config{ReadinessMode: config.ReadinessModePodReady, RemovalStrategy: config.RemovalStrategyPatch}
+	})
+
+	It("should describe removed taints and what their removal was based on", func() {
+		eval := &ruleEvaluation{edits: taintEdits{"example.com/not-ready": {}}}
+		records := r.auditRecords(node, pods, rules, eval, cfg, now)
+		Expect(records).To(Equal([]AuditRecord{{
+			Time:            now,
+			Action:          AuditTaintRemoved,
+			Node:            "node",
+			Taint:           "example.com/not-ready",
+			Reason:          UntaintReasonWorkloadsReady,
+			Workloads:       []string{"cni"},
+			Pods:            []string{"kube-system/cni-a", "kube-system/cni-b"},
+			Waited:          "1m30s",
+			ReadinessMode:   config.ReadinessModePodReady,
+			RemovalStrategy: config.RemovalStrategyPatch,
+		}}))
+	})
+
+	It("should tell forced removals and downgrades apart", func() {
+		eval := &ruleEvaluation{edits: taintEdits{"example.com/not-ready": {}}, forced: []string{"example.com/not-ready"}}
+		records := r.auditRecords(node, pods, rules, eval, cfg, now)
+		Expect(records).To(HaveLen(1))
+		Expect(records[0].Reason).To(Equal(UntaintReasonMaxWaitExceeded))
+
+		eval = &ruleEvaluation{edits: taintEdits{
+			"example.com/not-ready": {downgradeTo: corev1.TaintEffectPreferNoSchedule},
+		}}
+		records = r.auditRecords(node, pods, rules, eval, cfg, now)
+		Expect(records).To(HaveLen(1))
+		Expect(records[0].Action).To(Equal(AuditTaintDowngraded))
+		Expect(records[0].Effect).To(Equal(corev1.TaintEffectPreferNoSchedule))
+		Expect(records[0].Reason).To(BeEmpty())
+	})
+
+	It("should write one JSON line per record", func() {
+		r.Audit.Write(
+			AuditRecord{Time: now, Action: AuditTaintRemoved, Node: "node", Taint: "example.com/not-ready"},
+			AuditRecord{Time: now, Action: AuditTaintReAdded, Node: "node", Taint: "example.com/not-ready"},
+		)
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		Expect(lines).To(HaveLen(2))
+		var record map[string]interface{}
+		Expect(json.Unmarshal([]byte(lines[1]), &record)).To(Succeed())
+		Expect(record).To(Equal(map[string]interface{}{
+			"time":   "2025-01-01T12:00:00Z",
+			"action": AuditTaintReAdded,
+			"node":   "node",
+			"taint":  "example.com/not-ready",
+		}))
+	})
+
+	It("should keep the latest actions, newest first, with or without an audit log", func() {
+		r.Audit = nil
+		for i := 0; i < recentActionsSize+5; i++ {
+			r.recordActions(AuditRecord{Time: now.Add(time.Duration(i) * time.Second), Action: AuditTaintRemoved})
+		}
+		actions := r.actions.list()
+		Expect(actions).To(HaveLen(recentActionsSize))
+		Expect(actions[0].Time).To(Equal(now.Add(time.Duration(recentActionsSize+4) * time.Second)))
+		Expect(actions[recentActionsSize-1].Time).To(Equal(now.Add(5 * time.Second)))
+	})
+})
@@ -0,0 +1,70 @@
+package controller
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// backoffState tracks how long a node has been blocked by the same pod state
+type backoffState struct {
+	fingerprint string
+	failures    int
+}
+
+// requeueBackoff doubles the requeue interval of a node each time it is found
+// blocked by unchanged pods, up to a cap. A change to any of the pods gating
+// the node resets it to the base interval. The zero value is ready to use.
+type requeueBackoff struct {
+	mu    sync.Mutex
+	nodes map[string]backoffState
+}
+
+// next returns the requeue interval for a blocked node and records the failure
+func (b *requeueBackoff) next(node, fingerprint string, base, maxInterval time.Duration) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.nodes == nil {
+		b.nodes = make(map[string]backoffState)
+	}
+	state := b.nodes[node]
+	if state.fingerprint != fingerprint {
+		state = backoffState{fingerprint: fingerprint}
+	}
+	interval := base
+	for i := 0; i < state.failures && interval < maxInterval; i++ {
+		interval *= 2
+	}
+	if interval > maxInterval {
+		// A rule interval above the cap is never shortened
+		interval = max(base, maxInterval)
+	}
+	state.failures++
+	b.nodes[node] = state
+	return interval
+}
+
+// reset forgets the failures recorded for node
+func (b *requeueBackoff) reset(node string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.nodes, node)
+}
+
+// podsFingerprint identifies the current state of the pods owned by the given
+// workloads. It changes whenever one of them is created, updated or deleted.
+func podsFingerprint(pods []corev1.Pod, ownedByNames []string) string {
+	workloads := newWorkloadSet(ownedByNames)
+	var versions []string
+	for _, pod := range pods {
+		if isOwnedBy(&pod, workloads) {
+			versions = append(versions, string(pod.UID)+"/"+pod.ResourceVersion)
+		}
+	}
+	slices.Sort(versions)
+	return strings.Join(versions, ",")
+}
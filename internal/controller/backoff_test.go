@@ -0,0 +1,60 @@
+package controller
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+var _ = Describe("requeueBackoff", func() {
+	It("should double the interval while the pods are unchanged, up to the cap", func() {
+		backoff := &requeueBackoff{}
+		var intervals []time.Duration
+		for range 6 {
+			intervals = append(intervals, backoff.next("node", "pod/1", 30*time.Second, 5*time.Minute))
+		}
+		Expect(intervals).To(Equal([]time.Duration{
+			30 * time.Second, time.Minute, 2 * time.Minute, 4 * time.Minute, 5 * time.Minute, 5 * time.Minute,
+		}))
+	})
+
+	It("should reset when the pods change or the node is reset", func() {
+		backoff := &requeueBackoff{}
+		backoff.next("node", "pod/1", 30*time.Second, 5*time.Minute)
+		Expect(backoff.next("node", "pod/1", 30*time.Second, 5*time.Minute)).To(Equal(time.Minute))
+		Expect(backoff.next("node", "pod/2", 30*time.Second, 5*time.Minute)).To(Equal(30 * time.Second))
+
+		backoff.next("node", "pod/2", 30*time.Second, 5*time.Minute)
+		backoff.reset("node")
+		Expect(backoff.next("node", "pod/2", 30*time.Second, 5*time.Minute)).To(Equal(30 * time.Second))
+	})
+
+	It("should never shorten an interval above the cap", func() {
+		backoff := &requeueBackoff{}
+		backoff.next("node", "", 10*time.Minute, 5*time.Minute)
+		Expect(backoff.next("node", "", 10*time.Minute, 5*time.Minute)).To(Equal(10 * time.Minute))
+	})
+
+	It("should fingerprint only the pods of the given workloads", func() {
+		pod := func(name, owner, resourceVersion string) corev1.Pod {
+			return corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+				Name:            name,
+				UID:             types.UID("uid-" + name),
+				ResourceVersion: resourceVersion,
+				OwnerReferences: []metav1.OwnerReference{{Name: owner}},
+			}}
+		}
+		pods := []corev1.Pod{pod("a", "agent", "1"), pod("b", "other", "2")}
+		before := podsFingerprint(pods, []string{"agent"})
+
+		pods[1].ResourceVersion = "3"
+		Expect(podsFingerprint(pods, []string{"agent"})).To(Equal(before))
+
+		pods[0].ResourceVersion = "4"
+		Expect(podsFingerprint(pods, []string{"agent"})).NotTo(Equal(before))
+	})
+})
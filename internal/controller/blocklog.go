@@ -0,0 +1,83 @@
+package controller
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// blockLogRepeat is how often a node kept blocked by the same block is logged
+// again at the default level
+const blockLogRepeat = 10 * time.Minute
+
+// loggedBlock is the last block logged for a node
+type loggedBlock struct {
+	reason, message string
+	at              time.Time
+	// suppressed counts the requeues since the block was last logged
+	suppressed int
+}
+
+// blockLogs remembers the last block logged for each node, so a node that
+// stays blocked for hours logs its block once per blockLogRepeat rather than
+// once per requeue. The zero value is ready to use.
+type blockLogs struct {
+	mu   sync.Mutex
+	last map[string]loggedBlock
+}
+
+// sample reports whether the block of node should be logged at the default
+// level at now, because it changed or was last logged blockLogRepeat ago, and
+// how many requeues were not logged since
+func (l *blockLogs) sample(node string, reason *blockReason, now time.Time) (bool, int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.last == nil {
+		l.last = make(map[string]loggedBlock)
+	}
+	previous, ok := l.last[node]
+	if ok && previous.reason == reason.reason && previous.message == reason.message &&
+		now.Sub(previous.at) < blockLogRepeat {
+		previous.suppressed++
+		l.last[node] = previous
+		return false, 0
+	}
+	l.last[node] = loggedBlock{reason: reason.reason, message: reason.message, at: now}
+	if !ok || previous.reason != reason.reason || previous.message != reason.message {
+		return true, 0
+	}
+	return true, previous.suppressed
+}
+
+// forget drops the block logged for node
+func (l *blockLogs) forget(node string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.last, node)
+}
+
+// logBlocked logs why node keeps its target taints. Repeats of the same block
+// are only logged at debug verbosity between the samples.
+func (r *NodeReconciler) logBlocked(
+	ctx context.Context,
+	node *corev1.Node,
+	reason *blockReason,
+	requeueAfter time.Duration,
+	now time.Time,
+) {
+	log := log.FromContext(ctx)
+	sampled, suppressed := r.blockLogs.sample(node.Name, reason, now)
+	if !sampled {
+		log = log.V(1)
+	}
+	keysAndValues := []interface{}{"node", node.Name, "reason", reason.reason, "message", reason.message,
+		"requeueAfter", requeueAfter}
+	if suppressed > 0 {
+		keysAndValues = append(keysAndValues, "unchangedRequeues", suppressed)
+	}
+	log.Info("Not all required pods are ready, requeueing", keysAndValues...)
+}
@@ -0,0 +1,121 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr/funcr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	untaintv1alpha1 "github.com/jslay88/generic-untaint-operator/api/v1alpha1"
+	"github.com/jslay88/generic-untaint-operator/internal/config"
+)
+
+var _ = Describe("block logs", func() {
+	var (
+		r       *NodeReconciler
+		node    *corev1.Node
+		unready *blockReason
+		now     time.Time
+	)
+
+	BeforeEach(func() {
+		r = &NodeReconciler{}
+		node = &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node"}}
+		unready = &blockReason{
+			reason:  untaintv1alpha1.ReasonWorkloadUnready,
+			message: "taint example.com/not-ready: pod kube-system/agent-abc is not ready",
+		}
+		now = time.Now()
+	})
+
+	It("should log an unchanged block once per interval", func() {
+		sampled, _ := r.blockLogs.sample(node.Name, unready, now)
+		Expect(sampled).To(BeTrue())
+		sampled, _ = r.blockLogs.sample(node.Name, unready, now.Add(30*time.Second))
+		Expect(sampled).To(BeFalse())
+		sampled, _ = r.blockLogs.sample(node.Name, unready, now.Add(time.Minute))
+		Expect(sampled).To(BeFalse())
+
+		sampled, suppressed := r.blockLogs.sample(node.Name, unready, now.Add(blockLogRepeat))
+		Expect(sampled).To(BeTrue())
+		Expect(suppressed).To(Equal(2))
+	})
+
+	It("should log a block again once it changed or was forgotten", func() {
+		r.blockLogs.sample(node.Name, unready, now)
+		sampled, suppressed := r.blockLogs.sample(node.Name, &blockReason{
+			reason:  untaintv1alpha1.ReasonWaitingForWorkload,
+			message: "taint example.com/not-ready: no pods of agent are running on the node",
+		}, now)
+		Expect(sampled).To(BeTrue())
+		Expect(suppressed).To(BeZero())
+
+		r.blockLogs.forget(node.Name)
+		sampled, _ = r.blockLogs.sample(node.Name, unready, now)
+		Expect(sampled).To(BeTrue())
+	})
+
+	It("should only log the repeats at debug verbosity", func() {
+		var lines []string
+		logger := funcr.New(func(_, args string) { lines = append(lines, args) }, funcr.Options{Verbosity: 0})
+		ctx := log.IntoContext(context.Background(), logger)
+
+		r.logBlocked(ctx, node, unready, 30*time.Second, now)
+		r.logBlocked(ctx, node, unready, 30*time.Second, now.Add(30*time.Second))
+		Expect(lines).To(HaveLen(1))
+		Expect(lines[0]).To(ContainSubstring(`"reason"="WorkloadUnready"`))
+		Expect(lines[0]).To(ContainSubstring(`"message"="taint example.com/not-ready: pod kube-system/agent-abc is not ready"`))
+
+		r.logBlocked(ctx, node, unready, 30*time.Second, now.Add(blockLogRepeat))
+		Expect(lines).To(HaveLen(2))
+		Expect(lines[1]).To(ContainSubstring(`"unchangedRequeues"=1`))
+	})
+})
+
+var _ = Describe("decision trace", func() {
+	pod := func(name, owner string, ready corev1.ConditionStatus) corev1.Pod {
+		return corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            name,
+				Namespace:       "kube-system",
+				OwnerReferences: []metav1.OwnerReference{{Kind: "ReplicaSet", Name: owner}},
+			},
+			Status: corev1.PodStatus{
+				Phase:      corev1.PodRunning,
+				Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: ready}},
+			},
+		}
+	}
+
+	It("should explain why each pod was counted or skipped at verbosity 2", func() {
+		var lines []string
+		logger := funcr.New(func(_, args string) { lines = append(lines, args) }, funcr.Options{Verbosity: 2})
+		ctx := log.IntoContext(context.Background(), logger)
+		cfg := &config.Config{}
+		cfg.Default()
+
+		terminating := pod("agent-old", "agent", corev1.ConditionTrue)
+		terminating.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+		pods := []corev1.Pod{pod("other", "unrelated", corev1.ConditionTrue), terminating,
+			pod("agent-new", "agent", corev1.ConditionTrue)}
+		reason, err := (&NodeReconciler{}).workloadsBlocked(ctx, pods, []string{"agent"}, cfg)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reason).To(BeNil())
+		Expect(lines).To(HaveLen(3))
+		Expect(lines[0]).To(ContainSubstring(`"pod"="other" "owners"=["unrelated"]`))
+		Expect(lines[1]).To(ContainSubstring(`"pod"="agent-old"`))
+		Expect(lines[2]).To(ContainSubstring(`"pod"="agent-new" "workloads"=["agent"]`))
+
+		lines = nil
+		logger = funcr.New(func(_, args string) { lines = append(lines, args) }, funcr.Options{Verbosity: 1})
+		_, err = (&NodeReconciler{}).workloadsBlocked(log.IntoContext(context.Background(), logger), pods,
+			[]string{"agent"}, cfg)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lines).To(BeEmpty())
+	})
+})
@@ -0,0 +1,198 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"slices"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	untaintv1alpha1 "github.com/jslay88/generic-untaint-operator/api/v1alpha1"
+	"github.com/jslay88/generic-untaint-operator/internal/config"
+)
+
+const (
+	// CanaryLabel marks the canary pods of the operator
+	CanaryLabel = "untaint-operator.jslay88.github.io/canary"
+	// CanaryNodeAnnotation and CanaryTaintAnnotation record the node and the
+	// target taint a canary pod was created for
+	CanaryNodeAnnotation  = "untaint-operator.jslay88.github.io/canary-node"
+	CanaryTaintAnnotation = "untaint-operator.jslay88.github.io/canary-taint"
+)
+
+// +kubebuilder:rbac:groups=core,resources=pods,verbs=create;delete
+
+// canaryName returns the name of the canary pod of taint on node. Node names
+// can be too long to derive a pod name from, so they are hashed.
+func canaryName(node, taint string) string {
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(node + "/" + taint))
+	return fmt.Sprintf("untaint-canary-%016x", hash.Sum64())
+}
+
+// canaryBlocked returns why the canary pod of rule doesn't prove yet that the
+// node can run workloads, creating it when it doesn't exist, or nil once it
+// is ready and passed its probe, deleting it. A failed canary is deleted, to
+// be created again on the next check.
+func (r *NodeReconciler) canaryBlocked(
+	ctx context.Context,
+	node *corev1.Node,
+	rule config.Rule,
+	cfg *config.Config,
+) (*blockReason, error) {
+	if rule.Canary == nil {
+		return nil, nil
+	}
+	key := types.NamespacedName{Namespace: rule.Canary.Namespace, Name: canaryName(node.Name, rule.TargetTaint)}
+	pod := &corev1.Pod{}
+	if err := r.canaryReader(key.Namespace).Get(ctx, key, pod); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("failed to get canary pod: %w", err)
+		}
+		if err := r.Create(ctx, newCanaryPod(key, node, rule, cfg)); client.IgnoreAlreadyExists(err) != nil {
+			return nil, fmt.Errorf("failed to create canary pod: %w", err)
+		}
+		log.FromContext(ctx).Info("Created canary pod", "node", node.Name, "pod", key)
+		return canaryReason(key, "was created"), nil
+	}
+
+	switch {
+	case pod.DeletionTimestamp != nil:
+		return canaryReason(key, "is terminating"), nil
+	case pod.Status.Phase == corev1.PodFailed || pod.Status.Phase == corev1.PodSucceeded:
+		if err := r.Delete(ctx, pod); client.IgnoreNotFound(err) != nil {
+			return nil, fmt.Errorf("failed to delete canary pod: %w", err)
+		}
+		return canaryReason(key, fmt.Sprintf("exited with phase %s and is recreated", pod.Status.Phase)), nil
+	case !hasPodCondition(pod, corev1.PodReady):
+		return canaryReason(key, fmt.Sprintf("is not ready, phase %s", pod.Status.Phase)), nil
+	}
+	if rule.Canary.Probe != nil {
+		if err := probePod(ctx, pod, rule.Canary.Probe); err != nil {
+			return canaryReason(key, fmt.Sprintf("failed its probe: %v", err)), nil
+		}
+	}
+
+	if err := r.Delete(ctx, pod); client.IgnoreNotFound(err) != nil {
+		return nil, fmt.Errorf("failed to delete canary pod: %w", err)
+	}
+	log.FromContext(ctx).Info("Canary pod proved the node can run workloads", "node", node.Name, "pod", key)
+	return nil, nil
+}
+
+// canaryReader returns the reader canary pods in namespace are fetched with:
+// the cache, unless pods aren't cached or the pod cache is restricted to a
+// selector not matching them or to other namespaces
+func (r *NodeReconciler) canaryReader(namespace string) client.Reader {
+	if r.UncachedPods {
+		return r.apiReader()
+	}
+	if r.PodSelector != nil && !r.PodSelector.Matches(labels.Set{CanaryLabel: "true"}) {
+		return r.apiReader()
+	}
+	if len(r.PodNamespaces) > 0 && !slices.Contains(r.PodNamespaces, namespace) {
+		return r.apiReader()
+	}
+	return r.Client
+}
+
+// canaryReason returns the reason a canary pod in the state described by
+// message blocks the taint
+func canaryReason(key types.NamespacedName, message string) *blockReason {
+	return &blockReason{
+		reason:  untaintv1alpha1.ReasonCanaryNotReady,
+		message: fmt.Sprintf("canary pod %s %s", key, message),
+	}
+}
+
+// deleteCanaries deletes the canary pods left on node by rules whose taint is
+// gone, e.g. because someone else removed it
+func (r *NodeReconciler) deleteCanaries(ctx context.Context, node *corev1.Node, cfg *config.Config) error {
+	for _, rule := range cfg.Rules {
+		if rule.Canary == nil {
+			continue
+		}
+		pod := &corev1.Pod{}
+		key := types.NamespacedName{Namespace: rule.Canary.Namespace, Name: canaryName(node.Name, rule.TargetTaint)}
+		if err := r.canaryReader(key.Namespace).Get(ctx, key, pod); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return fmt.Errorf("failed to get canary pod: %w", err)
+		}
+		if err := r.Delete(ctx, pod); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("failed to delete canary pod: %w", err)
+		}
+	}
+	return nil
+}
+
+// newCanaryPod returns the canary pod of rule for node. It is pinned to the
+// node through the scheduler, so scheduling is proven too, and tolerates the
+// taints of every rule so none of them keeps it off the node. The node owns
+// the pod so it is garbage collected along with it.
+func newCanaryPod(key types.NamespacedName, node *corev1.Node, rule config.Rule, cfg *config.Config) *corev1.Pod {
+	tolerations := make([]corev1.Toleration, 0, len(cfg.Rules))
+	for _, other := range cfg.Rules {
+		tolerations = append(tolerations, corev1.Toleration{Key: other.TargetTaint, Operator: corev1.TolerationOpExists})
+	}
+	resources := corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse("1m"),
+		corev1.ResourceMemory: resource.MustParse("8Mi"),
+	}
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: key.Namespace,
+			Name:      key.Name,
+			Labels:    map[string]string{CanaryLabel: "true"},
+			Annotations: map[string]string{
+				CanaryNodeAnnotation:  node.Name,
+				CanaryTaintAnnotation: rule.TargetTaint,
+			},
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: "v1",
+				Kind:       "Node",
+				Name:       node.Name,
+				UID:        node.UID,
+			}},
+		},
+		Spec: corev1.PodSpec{
+			Affinity: &corev1.Affinity{NodeAffinity: &corev1.NodeAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+					NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+						MatchFields: []corev1.NodeSelectorRequirement{{
+							Key:      "metadata.name",
+							Operator: corev1.NodeSelectorOpIn,
+							Values:   []string{node.Name},
+						}},
+					}},
+				},
+			}},
+			Tolerations:                   tolerations,
+			RestartPolicy:                 corev1.RestartPolicyNever,
+			TerminationGracePeriodSeconds: ptr.To[int64](0),
+			AutomountServiceAccountToken:  ptr.To(false),
+			EnableServiceLinks:            ptr.To(false),
+			Containers: []corev1.Container{{
+				Name:      "canary",
+				Image:     rule.Canary.Image,
+				Command:   rule.Canary.Command,
+				Args:      rule.Canary.Args,
+				Resources: corev1.ResourceRequirements{Requests: resources, Limits: resources},
+				SecurityContext: &corev1.SecurityContext{
+					AllowPrivilegeEscalation: ptr.To(false),
+					Capabilities:             &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}},
+				},
+			}},
+		},
+	}
+}
@@ -0,0 +1,120 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	untaintv1alpha1 "github.com/jslay88/generic-untaint-operator/api/v1alpha1"
+	"github.com/jslay88/generic-untaint-operator/internal/config"
+)
+
+var _ = Describe("Canary", func() {
+	var (
+		ctx  context.Context
+		r    *NodeReconciler
+		node *corev1.Node
+		rule config.Rule
+		cfg  *config.Config
+		key  client.ObjectKey
+	)
+
+	getCanary := func() *corev1.Pod {
+		pod := &corev1.Pod{}
+		Expect(r.Get(ctx, key, pod)).To(Succeed())
+		return pod
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		node = &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node", UID: "node-uid"}}
+		rule = config.Rule{
+			TargetTaint:  "example.com/not-ready",
+			OwnedByNames: []string{"agent"},
+			Canary:       &config.Canary{Namespace: "untaint-system", Image: config.DefaultCanaryImage},
+		}
+		cfg = &config.Config{Rules: []config.Rule{rule, {TargetTaint: "example.com/other"}}}
+		key = client.ObjectKey{Namespace: "untaint-system", Name: canaryName("node", "example.com/not-ready")}
+		r = &NodeReconciler{Client: fake.NewClientBuilder().WithStatusSubresource(&corev1.Pod{}).Build()}
+	})
+
+	It("should create a canary tolerating every target taint", func() {
+		reason, err := r.canaryBlocked(ctx, node, rule, cfg)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reason).NotTo(BeNil())
+		Expect(reason.reason).To(Equal(untaintv1alpha1.ReasonCanaryNotReady))
+
+		pod := getCanary()
+		Expect(pod.Spec.Tolerations).To(ConsistOf(
+			corev1.Toleration{Key: "example.com/not-ready", Operator: corev1.TolerationOpExists},
+			corev1.Toleration{Key: "example.com/other", Operator: corev1.TolerationOpExists},
+		))
+		Expect(pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms[0].
+			MatchFields[0].Values).To(Equal([]string{"node"}))
+		Expect(pod.OwnerReferences[0].UID).To(Equal(node.UID))
+		Expect(pod.Annotations).To(HaveKeyWithValue(CanaryTaintAnnotation, "example.com/not-ready"))
+
+		reason, err = r.canaryBlocked(ctx, node, rule, cfg)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reason.message).To(ContainSubstring("is not ready"))
+	})
+
+	It("should pass and delete the canary once it is ready", func() {
+		_, err := r.canaryBlocked(ctx, node, rule, cfg)
+		Expect(err).NotTo(HaveOccurred())
+		pod := getCanary()
+		pod.Status.Phase = corev1.PodRunning
+		pod.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}
+		Expect(r.Client.Status().Update(ctx, pod)).To(Succeed())
+
+		Expect(r.canaryBlocked(ctx, node, rule, cfg)).To(BeNil())
+		Expect(apierrors.IsNotFound(r.Get(ctx, key, &corev1.Pod{}))).To(BeTrue())
+	})
+
+	It("should recreate a failed canary", func() {
+		_, err := r.canaryBlocked(ctx, node, rule, cfg)
+		Expect(err).NotTo(HaveOccurred())
+		pod := getCanary()
+		pod.Status.Phase = corev1.PodFailed
+		Expect(r.Client.Status().Update(ctx, pod)).To(Succeed())
+
+		reason, err := r.canaryBlocked(ctx, node, rule, cfg)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reason.message).To(ContainSubstring("exited with phase Failed"))
+		Expect(apierrors.IsNotFound(r.Get(ctx, key, &corev1.Pod{}))).To(BeTrue())
+	})
+
+	It("should clean up canaries of taints that are gone", func() {
+		_, err := r.canaryBlocked(ctx, node, rule, cfg)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(r.deleteCanaries(ctx, node, cfg)).To(Succeed())
+		Expect(apierrors.IsNotFound(r.Get(ctx, key, &corev1.Pod{}))).To(BeTrue())
+	})
+
+	It("should read canaries outside the pod selector from the API server", func() {
+		canary := newCanaryPod(key, node, rule, cfg)
+		r.APIReader = fake.NewClientBuilder().WithObjects(canary).Build()
+		r.PodSelector = labels.SelectorFromSet(labels.Set{"app": "agent"})
+
+		reason, err := r.canaryBlocked(ctx, node, rule, cfg)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reason.message).To(ContainSubstring("is not ready"))
+	})
+
+	It("should read canaries outside the pod namespaces from the API server", func() {
+		canary := newCanaryPod(key, node, rule, cfg)
+		r.APIReader = fake.NewClientBuilder().WithObjects(canary).Build()
+		r.PodNamespaces = []string{"kube-system"}
+
+		reason, err := r.canaryBlocked(ctx, node, rule, cfg)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reason.message).To(ContainSubstring("is not ready"))
+	})
+})
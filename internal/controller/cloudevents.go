@@ -0,0 +1,189 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// CloudEventNodeUntainted is published once target taints were removed from a node
+	CloudEventNodeUntainted = "io.github.jslay88.untaint.NodeUntainted"
+	// CloudEventNodeBlocked is published when the reason a node keeps its
+	// target taints changes
+	CloudEventNodeBlocked = "io.github.jslay88.untaint.NodeBlocked"
+	// CloudEventNodeRetainted is published when a target taint the operator
+	// removed was put back on a node
+	CloudEventNodeRetainted = "io.github.jslay88.untaint.NodeRetainted"
+)
+
+// CloudEventSource is the source attribute of the published CloudEvents
+const CloudEventSource = "/" + FieldManager
+
+// DefaultCloudEventQueueSize is how many CloudEvents wait to be sent before
+// new ones are dropped
+const DefaultCloudEventQueueSize = 100
+
+// cloudEventTimeout is how long the sink may take to accept a CloudEvent
+const cloudEventTimeout = 10 * time.Second
+
+// NodeEventData is the JSON data of the published CloudEvents
+type NodeEventData struct {
+	// Node is the name of the node
+	Node string `json:"node"`
+	// Taints are the keys of the target taints the event is about
+	Taints []string `json:"taints,omitempty"`
+	// ForcedTaints are the taints among Taints removed once they outlived
+	// their maxWait, although their workloads are not ready
+	ForcedTaints []string `json:"forcedTaints,omitempty"`
+	// Pods are the namespace/name of the pods that satisfied the gate
+	Pods []string `json:"pods,omitempty"`
+	// Reason and Message explain why a node is blocked
+	Reason  string `json:"reason,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// CloudEvent is a published CloudEvent about a node
+type CloudEvent struct {
+	ID   string
+	Type string
+	Time time.Time
+	Data NodeEventData
+}
+
+// CloudEventSender delivers CloudEvents to a message bus such as a Kafka topic
+// or a NATS subject
+type CloudEventSender interface {
+	// Send delivers event, returning once the bus accepted it
+	Send(ctx context.Context, event CloudEvent) error
+	// Close flushes and closes the connection to the bus
+	Close() error
+}
+
+// CloudEventPublisher sends the untaint lifecycle of nodes to a CloudEvents
+// sink, such as a Knative broker or an Argo Events webhook, over HTTP in binary
+// content mode, and to any message buses. Events are sent in the background
+// so a slow sink never delays a reconcile, and dropped when too many are
+// waiting. All methods are safe to call on a nil publisher, which publishes
+// nothing.
+type CloudEventPublisher struct {
+	// SinkURL, when set, is where the events are POSTed
+	SinkURL string
+	// Client sends the events. Defaults to http.DefaultClient when unset.
+	Client *http.Client
+	// Buses also receive every event
+	Buses []CloudEventSender
+	// QueueSize is how many events may wait to be sent. Defaults to
+	// DefaultCloudEventQueueSize when unset.
+	QueueSize int
+
+	once  sync.Once
+	queue chan CloudEvent
+}
+
+// Publish queues a CloudEvent of the given type about the node in data
+func (p *CloudEventPublisher) Publish(eventType string, data NodeEventData) {
+	if p == nil {
+		return
+	}
+	event := CloudEvent{ID: string(uuid.NewUUID()), Type: eventType, Time: time.Now(), Data: data}
+	select {
+	case p.events() <- event:
+	default:
+		log.Log.WithName("cloudevents").Info("CloudEvent queue is full, dropping event",
+			"type", eventType, "node", data.Node)
+	}
+}
+
+// events returns the queue of events waiting to be sent
+func (p *CloudEventPublisher) events() chan CloudEvent {
+	p.once.Do(func() {
+		size := p.QueueSize
+		if size <= 0 {
+			size = DefaultCloudEventQueueSize
+		}
+		p.queue = make(chan CloudEvent, size)
+	})
+	return p.queue
+}
+
+// Start implements manager.Runnable and sends the queued events until ctx is
+// cancelled, then closes the buses
+func (p *CloudEventPublisher) Start(ctx context.Context) error {
+	log := log.FromContext(ctx).WithName("cloudevents")
+	defer func() {
+		for _, bus := range p.Buses {
+			if err := bus.Close(); err != nil {
+				log.Error(err, "Failed to close message bus")
+			}
+		}
+	}()
+
+	queue := p.events()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event := <-queue:
+			p.deliver(ctx, event)
+		}
+	}
+}
+
+// deliver sends event to the sink and every bus, logging the failures
+func (p *CloudEventPublisher) deliver(ctx context.Context, event CloudEvent) {
+	log := log.FromContext(ctx).WithName("cloudevents")
+	if p.SinkURL != "" {
+		if err := p.send(ctx, event); err != nil {
+			log.Error(err, "Failed to send CloudEvent", "sink", p.SinkURL, "type", event.Type,
+				"node", event.Data.Node)
+		}
+	}
+	for _, bus := range p.Buses {
+		if err := bus.Send(ctx, event); err != nil {
+			log.Error(err, "Failed to send CloudEvent to message bus", "type", event.Type, "node", event.Data.Node)
+		}
+	}
+}
+
+// send POSTs event to the sink
+func (p *CloudEventPublisher) send(ctx context.Context, event CloudEvent) error {
+	body, err := json.Marshal(event.Data)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, cloudEventTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.SinkURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Ce-Specversion", "1.0")
+	req.Header.Set("Ce-Id", event.ID)
+	req.Header.Set("Ce-Source", CloudEventSource)
+	req.Header.Set("Ce-Type", event.Type)
+	req.Header.Set("Ce-Subject", event.Data.Node)
+	req.Header.Set("Ce-Time", event.Time.UTC().Format(time.RFC3339Nano))
+
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("sink answered with status %s", resp.Status)
+	}
+	return nil
+}
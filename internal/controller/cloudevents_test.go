@@ -0,0 +1,124 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CloudEventPublisher", func() {
+	type received struct {
+		header http.Header
+		data   NodeEventData
+	}
+
+	var (
+		events    chan received
+		publisher *CloudEventPublisher
+	)
+
+	BeforeEach(func() {
+		events = make(chan received, 10)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			defer GinkgoRecover()
+			event := received{header: req.Header}
+			Expect(json.NewDecoder(req.Body).Decode(&event.data)).To(Succeed())
+			events <- event
+			w.WriteHeader(http.StatusAccepted)
+		}))
+		DeferCleanup(server.Close)
+		publisher = &CloudEventPublisher{SinkURL: server.URL}
+	})
+
+	It("should send events in binary content mode", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		DeferCleanup(cancel)
+		go func() {
+			defer GinkgoRecover()
+			Expect(publisher.Start(ctx)).To(Succeed())
+		}()
+
+		publisher.Publish(CloudEventNodeUntainted, NodeEventData{
+			Node:   "node",
+			Taints: []string{"example.com/not-ready"},
+			Pods:   []string{"kube-system/agent-abc"},
+		})
+		publisher.Publish(CloudEventNodeRetainted, NodeEventData{Node: "node", Taints: []string{"example.com/not-ready"}})
+
+		var event received
+		Eventually(events).Should(Receive(&event))
+		Expect(event.header.Get("Content-Type")).To(Equal("application/json"))
+		Expect(event.header.Get("Ce-Specversion")).To(Equal("1.0"))
+		Expect(event.header.Get("Ce-Type")).To(Equal(CloudEventNodeUntainted))
+		Expect(event.header.Get("Ce-Source")).To(Equal(CloudEventSource))
+		Expect(event.header.Get("Ce-Subject")).To(Equal("node"))
+		Expect(event.header.Get("Ce-Id")).NotTo(BeEmpty())
+		Expect(event.header.Get("Ce-Time")).NotTo(BeEmpty())
+		Expect(event.data).To(Equal(NodeEventData{
+			Node:   "node",
+			Taints: []string{"example.com/not-ready"},
+			Pods:   []string{"kube-system/agent-abc"},
+		}))
+
+		var next received
+		Eventually(events).Should(Receive(&next))
+		Expect(next.header.Get("Ce-Type")).To(Equal(CloudEventNodeRetainted))
+		Expect(next.header.Get("Ce-Id")).NotTo(Equal(event.header.Get("Ce-Id")))
+	})
+
+	It("should also send events to the message buses and close them when stopped", func() {
+		bus := &fakeBus{sent: make(chan CloudEvent, 10)}
+		publisher.SinkURL = ""
+		publisher.Buses = []CloudEventSender{bus}
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			Expect(publisher.Start(ctx)).To(Succeed())
+		}()
+
+		publisher.Publish(CloudEventNodeBlocked, NodeEventData{Node: "node", Reason: "WorkloadUnready"})
+		var event CloudEvent
+		Eventually(bus.sent).Should(Receive(&event))
+		Expect(event.Type).To(Equal(CloudEventNodeBlocked))
+		Expect(event.Data).To(Equal(NodeEventData{Node: "node", Reason: "WorkloadUnready"}))
+		Expect(events).NotTo(Receive())
+
+		cancel()
+		Eventually(done).Should(BeClosed())
+		Expect(bus.closed).To(BeTrue())
+	})
+
+	It("should drop events once the queue is full", func() {
+		publisher.QueueSize = 1
+		publisher.Publish(CloudEventNodeBlocked, NodeEventData{Node: "a"})
+		publisher.Publish(CloudEventNodeBlocked, NodeEventData{Node: "b"})
+		Expect(publisher.events()).To(HaveLen(1))
+	})
+
+	It("should publish nothing when nil", func() {
+		var nilPublisher *CloudEventPublisher
+		Expect(func() { nilPublisher.Publish(CloudEventNodeBlocked, NodeEventData{Node: "node"}) }).NotTo(Panic())
+	})
+})
+
+// fakeBus is a CloudEventSender recording the sent events
+type fakeBus struct {
+	sent   chan CloudEvent
+	closed bool
+}
+
+func (b *fakeBus) Send(_ context.Context, event CloudEvent) error {
+	b.sent <- event
+	return nil
+}
+
+func (b *fakeBus) Close() error {
+	b.closed = true
+	return nil
+}
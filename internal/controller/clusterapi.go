@@ -0,0 +1,206 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	untaintv1alpha1 "github.com/jslay88/generic-untaint-operator/api/v1alpha1"
+)
+
+// MachineGVK is the Cluster API Machine kind, read as unstructured so the
+// operator doesn't depend on a Cluster API release
+var MachineGVK = schema.GroupVersionKind{Group: "cluster.x-k8s.io", Version: "v1beta1", Kind: "Machine"}
+
+const (
+	// MachineAnnotation and ClusterNamespaceAnnotation are set by Cluster API
+	// on the nodes of its Machines
+	MachineAnnotation          = "cluster.x-k8s.io/machine"
+	ClusterNamespaceAnnotation = "cluster.x-k8s.io/cluster-namespace"
+	// SkipRemediationAnnotation keeps MachineHealthChecks from remediating a Machine
+	SkipRemediationAnnotation = "cluster.x-k8s.io/skip-remediation"
+)
+
+// MachineConditionType is the condition set on the Machine of a node,
+// mirroring whether its target taints were removed
+const MachineConditionType = "Untainted"
+
+// machineKey returns the namespace and name of the Machine backing node, and
+// whether the node has one
+func machineKey(node *corev1.Node) (types.NamespacedName, bool) {
+	name := node.Annotations[MachineAnnotation]
+	namespace := node.Annotations[ClusterNamespaceAnnotation]
+	return types.NamespacedName{Namespace: namespace, Name: name}, name != "" && namespace != ""
+}
+
+// +kubebuilder:rbac:groups=cluster.x-k8s.io,resources=machines,verbs=get;list;watch;patch
+// +kubebuilder:rbac:groups=cluster.x-k8s.io,resources=machines/status,verbs=patch
+
+// machine returns the Machine backing node, or nil when Cluster API support
+// is off or the node has no Machine
+func (r *NodeReconciler) machine(ctx context.Context, node *corev1.Node) (*unstructured.Unstructured, error) {
+	key, ok := machineKey(node)
+	if !r.ClusterAPI || !ok {
+		return nil, nil
+	}
+	machine := &unstructured.Unstructured{}
+	machine.SetGroupVersionKind(MachineGVK)
+	if err := r.Get(ctx, key, machine); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get Machine %s: %w", key, err)
+	}
+	return machine, nil
+}
+
+// setMachineCondition sets the Untainted condition of the Machine backing
+// node to False with reason while the node's target taints are blocked, or to
+// True once they are removed. With SkipRemediation, the Machine is also
+// annotated to skip remediation while the taints are blocked. Nothing is
+// written when the Machine is already up to date.
+func (r *NodeReconciler) setMachineCondition(
+	ctx context.Context,
+	node *corev1.Node,
+	untainted bool,
+	reason, message string,
+) error {
+	machine, err := r.machine(ctx, node)
+	if err != nil || machine == nil {
+		return err
+	}
+	if err := r.setSkipRemediation(ctx, machine, !untainted); err != nil {
+		return err
+	}
+
+	status := metav1.ConditionFalse
+	if untainted {
+		status = metav1.ConditionTrue
+	}
+	patch := client.MergeFromWithOptions(machine.DeepCopy(), client.MergeFromWithOptimisticLock{})
+	changed, err := setMachineConditionStatus(machine, status, reason, message, metav1.Now())
+	if err != nil || !changed {
+		return err
+	}
+	if err := r.Client.Status().Patch(ctx, machine, patch); err != nil {
+		return fmt.Errorf("failed to update Machine condition: %w", err)
+	}
+	return nil
+}
+
+// clearMachineCondition sets the Untainted condition of the Machine backing
+// node to True if it is False, for nodes whose target taints were removed by
+// someone else
+func (r *NodeReconciler) clearMachineCondition(ctx context.Context, node *corev1.Node) error {
+	machine, err := r.machine(ctx, node)
+	if err != nil || machine == nil {
+		return err
+	}
+	if machineConditionStatus(machine) != metav1.ConditionFalse {
+		return nil
+	}
+	return r.setMachineCondition(ctx, node, true, untaintv1alpha1.ReasonTaintRemoved, "")
+}
+
+// setSkipRemediation adds the skip-remediation annotation to machine when
+// skip is set and SkipRemediation is on, and removes it otherwise, unless
+// someone else put it there
+func (r *NodeReconciler) setSkipRemediation(ctx context.Context, machine *unstructured.Unstructured, skip bool) error {
+	annotations := machine.GetAnnotations()
+	value, present := annotations[SkipRemediationAnnotation]
+	skip = skip && r.SkipRemediation
+	if skip == present || (present && value != FieldManager) {
+		return nil
+	}
+
+	patch := client.MergeFrom(machine.DeepCopy())
+	if skip {
+		if annotations == nil {
+			annotations = make(map[string]string, 1)
+		}
+		annotations[SkipRemediationAnnotation] = FieldManager
+	} else {
+		delete(annotations, SkipRemediationAnnotation)
+	}
+	machine.SetAnnotations(annotations)
+	if err := r.Patch(ctx, machine, patch); err != nil {
+		return fmt.Errorf("failed to update Machine remediation annotation: %w", err)
+	}
+	return nil
+}
+
+// machineConditionStatus returns the status of the Untainted condition of
+// machine, or "" when it has none
+func machineConditionStatus(machine *unstructured.Unstructured) metav1.ConditionStatus {
+	conditions, _, _ := unstructured.NestedSlice(machine.Object, "status", "conditions")
+	for _, value := range conditions {
+		condition, ok := value.(map[string]interface{})
+		if ok && condition["type"] == MachineConditionType {
+			status, _ := condition["status"].(string)
+			return metav1.ConditionStatus(status)
+		}
+	}
+	return ""
+}
+
+// setMachineConditionStatus sets the Untainted condition in the Cluster API
+// conditions of machine, keeping the transition time while the status stays
+// the same, and reports whether anything changed
+func setMachineConditionStatus(
+	machine *unstructured.Unstructured,
+	status metav1.ConditionStatus,
+	reason, message string,
+	now metav1.Time,
+) (bool, error) {
+	conditions, _, err := unstructured.NestedSlice(machine.Object, "status", "conditions")
+	if err != nil {
+		return false, fmt.Errorf("machine %s has invalid conditions: %w", machine.GetName(), err)
+	}
+	condition := map[string]interface{}{
+		"type":               MachineConditionType,
+		"status":             string(status),
+		"lastTransitionTime": now.UTC().Format(time.RFC3339),
+	}
+	if status == metav1.ConditionFalse {
+		// Cluster API requires a severity on False conditions. Waiting for
+		// workloads is expected while a node bootstraps.
+		condition["severity"] = "Info"
+		condition["reason"] = reason
+		condition["message"] = message
+	}
+
+	if value, ok := machine.Object["status"]; ok && value == nil {
+		// A Machine not yet reconciled by Cluster API can have a null status
+		delete(machine.Object, "status")
+	}
+	index := len(conditions)
+	for i, value := range conditions {
+		existing, ok := value.(map[string]interface{})
+		if !ok || existing["type"] != MachineConditionType {
+			continue
+		}
+		if existing["status"] == condition["status"] && existing["reason"] == condition["reason"] &&
+			existing["message"] == condition["message"] {
+			return false, nil
+		}
+		if existing["status"] == condition["status"] {
+			condition["lastTransitionTime"] = existing["lastTransitionTime"]
+		}
+		index = i
+		break
+	}
+	if index == len(conditions) {
+		conditions = append(conditions, condition)
+	} else {
+		conditions[index] = condition
+	}
+	return true, unstructured.SetNestedSlice(machine.Object, conditions, "status", "conditions")
+}
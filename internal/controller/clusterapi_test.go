@@ -0,0 +1,105 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	untaintv1alpha1 "github.com/jslay88/generic-untaint-operator/api/v1alpha1"
+)
+
+var _ = Describe("Cluster API", func() {
+	var (
+		ctx  context.Context
+		r    *NodeReconciler
+		node *corev1.Node
+	)
+
+	getMachine := func() *unstructured.Unstructured {
+		machine := &unstructured.Unstructured{}
+		machine.SetGroupVersionKind(MachineGVK)
+		Expect(r.Get(ctx, client.ObjectKey{Namespace: "capi", Name: "machine"}, machine)).To(Succeed())
+		return machine
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		node = &corev1.Node{ObjectMeta: metav1.ObjectMeta{
+			Name: "node",
+			Annotations: map[string]string{
+				MachineAnnotation:          "machine",
+				ClusterNamespaceAnnotation: "capi",
+			},
+		}}
+		machine := &unstructured.Unstructured{}
+		machine.SetGroupVersionKind(MachineGVK)
+		machine.SetNamespace("capi")
+		machine.SetName("machine")
+		r = &NodeReconciler{
+			Client:          fake.NewClientBuilder().WithObjects(machine).WithStatusSubresource(machine).Build(),
+			ClusterAPI:      true,
+			SkipRemediation: true,
+		}
+	})
+
+	It("should report the untaint progress on the node's Machine", func() {
+		Expect(r.setMachineCondition(ctx, node, false, untaintv1alpha1.ReasonWorkloadUnready,
+			"pod kube-system/agent-abc is not ready")).To(Succeed())
+		machine := getMachine()
+		Expect(machineConditionStatus(machine)).To(Equal(metav1.ConditionFalse))
+		Expect(machine.GetAnnotations()).To(HaveKeyWithValue(SkipRemediationAnnotation, FieldManager))
+
+		Expect(r.clearMachineCondition(ctx, node)).To(Succeed())
+		machine = getMachine()
+		Expect(machineConditionStatus(machine)).To(Equal(metav1.ConditionTrue))
+		Expect(machine.GetAnnotations()).NotTo(HaveKey(SkipRemediationAnnotation))
+	})
+
+	It("should keep a skip-remediation annotation set by someone else", func() {
+		machine := getMachine()
+		machine.SetAnnotations(map[string]string{SkipRemediationAnnotation: ""})
+		Expect(r.Update(ctx, machine)).To(Succeed())
+
+		Expect(r.setMachineCondition(ctx, node, true, untaintv1alpha1.ReasonTaintRemoved, "")).To(Succeed())
+		Expect(getMachine().GetAnnotations()).To(HaveKeyWithValue(SkipRemediationAnnotation, ""))
+	})
+
+	It("should only skip remediation when asked to", func() {
+		r.SkipRemediation = false
+		Expect(r.setMachineCondition(ctx, node, false, untaintv1alpha1.ReasonWorkloadUnready, "waiting")).To(Succeed())
+		machine := getMachine()
+		Expect(machineConditionStatus(machine)).To(Equal(metav1.ConditionFalse))
+		Expect(machine.GetAnnotations()).NotTo(HaveKey(SkipRemediationAnnotation))
+	})
+
+	It("should leave nodes without a Machine alone", func() {
+		delete(node.Annotations, MachineAnnotation)
+		Expect(r.setMachineCondition(ctx, node, false, untaintv1alpha1.ReasonWorkloadUnready, "waiting")).To(Succeed())
+		Expect(machineConditionStatus(getMachine())).To(BeEmpty())
+	})
+
+	It("should keep the transition time while the status stays the same", func() {
+		machine := &unstructured.Unstructured{Object: map[string]interface{}{}}
+		before := metav1.NewTime(metav1.Now().Add(-5 * time.Minute))
+		changed, err := setMachineConditionStatus(machine, metav1.ConditionFalse, "A", "a", before)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changed).To(BeTrue())
+		changed, err = setMachineConditionStatus(machine, metav1.ConditionFalse, "A", "a", metav1.Now())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changed).To(BeFalse())
+		changed, err = setMachineConditionStatus(machine, metav1.ConditionFalse, "B", "b", metav1.Now())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changed).To(BeTrue())
+		conditions, _, _ := unstructured.NestedSlice(machine.Object, "status", "conditions")
+		Expect(conditions).To(HaveLen(1))
+		Expect(conditions[0]).To(HaveKeyWithValue("lastTransitionTime", before.UTC().Format(time.RFC3339)))
+		Expect(conditions[0]).To(HaveKeyWithValue("severity", "Info"))
+	})
+})
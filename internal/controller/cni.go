@@ -0,0 +1,189 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	untaintv1alpha1 "github.com/jslay88/generic-untaint-operator/api/v1alpha1"
+)
+
+// CNIProvider names a network plugin whose per-node custom resources report
+// whether the node's datapath is programmed
+type CNIProvider string
+
+const (
+	// CNICilium requires the node's CiliumNode to exist without an IPAM error
+	CNICilium CNIProvider = "Cilium"
+	// CNICalico requires a confirmed Calico IPAM block affinity for the node
+	CNICalico CNIProvider = "Calico"
+)
+
+var (
+	// CiliumNodeGVK is the Cilium CiliumNode kind, read as unstructured so the
+	// operator doesn't depend on a Cilium release
+	CiliumNodeGVK = schema.GroupVersionKind{Group: "cilium.io", Version: "v2", Kind: "CiliumNode"}
+	// BlockAffinityGVK is the Calico BlockAffinity kind, through which Calico
+	// IPAM assigns address blocks to nodes
+	BlockAffinityGVK = schema.GroupVersionKind{Group: "crd.projectcalico.org", Version: "v1", Kind: "BlockAffinity"}
+)
+
+// blockAffinityNodeField indexes BlockAffinities by the name of their node
+const blockAffinityNodeField = "spec.node"
+
+// +kubebuilder:rbac:groups=cilium.io,resources=ciliumnodes,verbs=get;list;watch
+// +kubebuilder:rbac:groups=crd.projectcalico.org,resources=blockaffinities,verbs=get;list;watch
+
+// cniNotReady returns why the custom resources of the configured CNI don't
+// report the node's datapath ready yet, or nil when they do or no CNI is set.
+// The agent pod can turn ready before the datapath is programmed.
+func (r *NodeReconciler) cniNotReady(ctx context.Context, node *corev1.Node) (*blockReason, error) {
+	switch r.CNI {
+	case CNICilium:
+		ciliumNode := newUnstructured(CiliumNodeGVK)
+		if err := r.Get(ctx, types.NamespacedName{Name: node.Name}, ciliumNode); err != nil {
+			if apierrors.IsNotFound(err) {
+				return &blockReason{reason: untaintv1alpha1.ReasonCNINotReady, message: "node has no CiliumNode"}, nil
+			}
+			return nil, fmt.Errorf("failed to get CiliumNode: %w", err)
+		}
+		return ciliumNodeNotReady(ciliumNode), nil
+	case CNICalico:
+		affinities := &unstructured.UnstructuredList{}
+		affinities.SetGroupVersionKind(BlockAffinityGVK.GroupVersion().WithKind(BlockAffinityGVK.Kind + "List"))
+		if err := r.List(ctx, affinities, client.MatchingFields{blockAffinityNodeField: node.Name}); err != nil {
+			return nil, fmt.Errorf("failed to list BlockAffinities: %w", err)
+		}
+		return blockAffinitiesNotReady(affinities.Items), nil
+	}
+	return nil, nil
+}
+
+// ciliumNodeNotReady returns why ciliumNode doesn't report the node ready,
+// or nil when the Cilium operator reported no IPAM error for it
+func ciliumNodeNotReady(ciliumNode *unstructured.Unstructured) *blockReason {
+	if message, _, _ := unstructured.NestedString(ciliumNode.Object, "status", "ipam", "operator-status",
+		"error"); message != "" {
+		return &blockReason{
+			reason:  untaintv1alpha1.ReasonCNINotReady,
+			message: fmt.Sprintf("CiliumNode reports an IPAM error: %s", message),
+		}
+	}
+	return nil
+}
+
+// blockAffinitiesNotReady returns why affinities don't report the node ready,
+// or nil when one of them is confirmed, meaning Calico IPAM assigned the node
+// an address block
+func blockAffinitiesNotReady(affinities []unstructured.Unstructured) *blockReason {
+	for _, affinity := range affinities {
+		if state, _, _ := unstructured.NestedString(affinity.Object, "spec", "state"); state == "confirmed" {
+			return nil
+		}
+	}
+	return &blockReason{
+		reason:  untaintv1alpha1.ReasonCNINotReady,
+		message: "node has no confirmed Calico IPAM block",
+	}
+}
+
+// newUnstructured returns an empty unstructured object of kind gvk
+func newUnstructured(gvk schema.GroupVersionKind) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(gvk)
+	return obj
+}
+
+// setupCNI adds the watches on the custom resources of the configured CNI to
+// bldr, so a node is re-evaluated as soon as its datapath is reported ready
+func (r *NodeReconciler) setupCNI(mgr ctrl.Manager, bldr *builder.Builder) (*builder.Builder, error) {
+	switch r.CNI {
+	case "":
+		return bldr, nil
+	case CNICilium:
+		return bldr.Watches(
+			newUnstructured(CiliumNodeGVK),
+			handler.EnqueueRequestsFromMapFunc(r.selectedNodes(mgr.GetCache(),
+				func(_ context.Context, obj client.Object) []reconcile.Request {
+					return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: obj.GetName()}}}
+				})),
+			builder.WithPredicates(cniChangedPredicate("status", "ipam", "operator-status")),
+		), nil
+	case CNICalico:
+		if err := mgr.GetFieldIndexer().IndexField(
+			context.Background(),
+			newUnstructured(BlockAffinityGVK),
+			blockAffinityNodeField,
+			func(obj client.Object) []string {
+				if name := blockAffinityNode(obj); name != "" {
+					return []string{name}
+				}
+				return nil
+			},
+		); err != nil {
+			return nil, err
+		}
+		return bldr.Watches(
+			newUnstructured(BlockAffinityGVK),
+			handler.EnqueueRequestsFromMapFunc(r.selectedNodes(mgr.GetCache(),
+				func(_ context.Context, obj client.Object) []reconcile.Request {
+					if name := blockAffinityNode(obj); name != "" {
+						return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: name}}}
+					}
+					return nil
+				})),
+			builder.WithPredicates(cniChangedPredicate("spec", "state")),
+		), nil
+	}
+	return nil, fmt.Errorf("unsupported CNI %q, must be %s or %s", r.CNI, CNICilium, CNICalico)
+}
+
+// blockAffinityNode returns the name of the node of a BlockAffinity
+func blockAffinityNode(obj client.Object) string {
+	affinity, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return ""
+	}
+	name, _, _ := unstructured.NestedString(affinity.Object, "spec", "node")
+	return name
+}
+
+// cniChangedPredicate passes CNI resources when they are created and when the
+// field at path, which reports the node's datapath state, changes
+func cniChangedPredicate(path ...string) predicate.Funcs {
+	nested := func(obj client.Object) interface{} {
+		u, ok := obj.(*unstructured.Unstructured)
+		if !ok {
+			return nil
+		}
+		value, _, _ := unstructured.NestedFieldNoCopy(u.Object, path...)
+		return value
+	}
+	return predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool {
+			return true
+		},
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			return !equality.Semantic.DeepEqual(nested(e.ObjectOld), nested(e.ObjectNew))
+		},
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			return false
+		},
+		GenericFunc: func(e event.GenericEvent) bool {
+			return false
+		},
+	}
+}
@@ -0,0 +1,100 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	untaintv1alpha1 "github.com/jslay88/generic-untaint-operator/api/v1alpha1"
+)
+
+var _ = Describe("CNI", func() {
+	var (
+		ctx  context.Context
+		node *corev1.Node
+	)
+
+	blockAffinity := func(name, nodeName, state string) *unstructured.Unstructured {
+		affinity := newUnstructured(BlockAffinityGVK)
+		affinity.SetName(name)
+		Expect(unstructured.SetNestedField(affinity.Object, nodeName, "spec", "node")).To(Succeed())
+		Expect(unstructured.SetNestedField(affinity.Object, state, "spec", "state")).To(Succeed())
+		return affinity
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		node = &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node"}}
+	})
+
+	It("should not check anything without a CNI", func() {
+		r := &NodeReconciler{Client: fake.NewClientBuilder().Build()}
+		Expect(r.cniNotReady(ctx, node)).To(BeNil())
+	})
+
+	It("should require a healthy CiliumNode", func() {
+		ciliumNode := newUnstructured(CiliumNodeGVK)
+		ciliumNode.SetName("node")
+		r := &NodeReconciler{Client: fake.NewClientBuilder().WithObjects(ciliumNode).Build(), CNI: CNICilium}
+		Expect(r.cniNotReady(ctx, node)).To(BeNil())
+
+		Expect(unstructured.SetNestedField(ciliumNode.Object, "no more IPs available", "status", "ipam",
+			"operator-status", "error")).To(Succeed())
+		reason := ciliumNodeNotReady(ciliumNode)
+		Expect(reason).NotTo(BeNil())
+		Expect(reason.reason).To(Equal(untaintv1alpha1.ReasonCNINotReady))
+		Expect(reason.message).To(ContainSubstring("no more IPs available"))
+
+		node.Name = "other"
+		reason, err := r.cniNotReady(ctx, node)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reason).NotTo(BeNil())
+		Expect(reason.message).To(Equal("node has no CiliumNode"))
+	})
+
+	It("should require a confirmed Calico block affinity", func() {
+		r := &NodeReconciler{
+			Client: fake.NewClientBuilder().
+				WithObjects(
+					blockAffinity("node-10-0-0-0-26", "node", "pending"),
+					blockAffinity("other-10-0-0-64-26", "other", "confirmed"),
+				).
+				WithIndex(newUnstructured(BlockAffinityGVK), blockAffinityNodeField, func(obj client.Object) []string {
+					return []string{blockAffinityNode(obj)}
+				}).
+				Build(),
+			CNI: CNICalico,
+		}
+		reason, err := r.cniNotReady(ctx, node)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reason).NotTo(BeNil())
+		Expect(reason.reason).To(Equal(untaintv1alpha1.ReasonCNINotReady))
+
+		node.Name = "other"
+		Expect(r.cniNotReady(ctx, node)).To(BeNil())
+	})
+
+	It("should only pass updates to the datapath state", func() {
+		pending := blockAffinity("node-10-0-0-0-26", "node", "pending")
+		confirmed := blockAffinity("node-10-0-0-0-26", "node", "confirmed")
+		relabeled := pending.DeepCopy()
+		relabeled.SetLabels(map[string]string{"example.com/team": "network"})
+
+		predicate := cniChangedPredicate("spec", "state")
+		Expect(predicate.Update(event.UpdateEvent{ObjectOld: pending, ObjectNew: confirmed})).To(BeTrue())
+		Expect(predicate.Update(event.UpdateEvent{ObjectOld: pending, ObjectNew: relabeled})).To(BeFalse())
+	})
+
+	It("should reject unsupported CNIs", func() {
+		r := &NodeReconciler{CNI: "Flannel"}
+		_, err := r.setupCNI(nil, nil)
+		Expect(err).To(MatchError(ContainSubstring(`unsupported CNI "Flannel"`)))
+	})
+})
@@ -0,0 +1,76 @@
+package controller
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	"github.com/jslay88/generic-untaint-operator/internal/config"
+)
+
+// ConfigMapReconciler loads the operator configuration from a ConfigMap and
+// publishes it to a config.Store whenever the ConfigMap changes
+type ConfigMapReconciler struct {
+	client.Client
+	// ConfigMap is the namespace and name of the ConfigMap holding the configuration
+	ConfigMap types.NamespacedName
+	// Key is the data key holding the YAML configuration
+	Key string
+	// Store receives every successfully parsed configuration
+	Store *config.Store
+}
+
+// +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch,namespace=system
+
+// Reconcile parses the watched ConfigMap and swaps the active configuration.
+// Invalid or missing configuration keeps the previous rules in place.
+func (r *ConfigMapReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	cm := &corev1.ConfigMap{}
+	if err := r.Get(ctx, req.NamespacedName, cm); err != nil {
+		if apierrors.IsNotFound(err) {
+			log.Info("Config ConfigMap not found, keeping previous configuration")
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+
+	cfg, err := config.FromConfigMap(cm, r.Key)
+	if err != nil {
+		// Retrying won't fix a bad document; the next edit will trigger a reconcile
+		log.Error(err, "Invalid config in ConfigMap, keeping previous configuration")
+		r.Store.LoadFailed(err)
+		return ctrl.Result{}, nil
+	}
+
+	r.Store.Set(cfg)
+	log.Info("Loaded config from ConfigMap", "rules", len(cfg.Rules))
+	return ctrl.Result{}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ConfigMapReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	isConfigMap := predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		return obj.GetNamespace() == r.ConfigMap.Namespace && obj.GetName() == r.ConfigMap.Name
+	})
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("configmap").
+		For(&corev1.ConfigMap{}, builder.WithPredicates(isConfigMap, predicate.ResourceVersionChangedPredicate{})).
+		WithOptions(controller.Options{
+			// Every replica keeps its configuration current so a new leader
+			// starts with the latest rules
+			NeedLeaderElection: ptr.To(false),
+		}).
+		Complete(r)
+}
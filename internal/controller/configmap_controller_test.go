@@ -0,0 +1,77 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/jslay88/generic-untaint-operator/internal/config"
+)
+
+var _ = Describe("ConfigMapReconciler", func() {
+	var (
+		ctx        context.Context
+		reconciler *ConfigMapReconciler
+		cm         *corev1.ConfigMap
+		initial    *config.Config
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+
+		initial = &config.Config{
+			Rules: []config.Rule{{TargetTaint: "test-taint", OwnedByNames: []string{"test-daemonset"}}},
+		}
+		initial.Default()
+
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "untaint-config",
+				Namespace: "default",
+			},
+			Data: map[string]string{
+				config.DefaultConfigMapKey: `
+rules:
+  - targetTaint: other-taint
+    ownedByNames: [other-daemonset]
+`,
+			},
+		}
+		Expect(k8sClient.Create(ctx, cm)).To(Succeed())
+
+		reconciler = &ConfigMapReconciler{
+			Client:    k8sClient,
+			ConfigMap: types.NamespacedName{Namespace: cm.Namespace, Name: cm.Name},
+			Key:       config.DefaultConfigMapKey,
+			Store:     config.NewStore(initial),
+		}
+	})
+
+	AfterEach(func() {
+		Expect(k8sClient.Delete(ctx, cm)).To(Succeed())
+	})
+
+	It("should load rules from the ConfigMap", func() {
+		_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: reconciler.ConfigMap})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reconciler.Store.Get().Rules).To(Equal([]config.Rule{
+			{TargetTaint: "other-taint", OwnedByNames: []string{"other-daemonset"}},
+		}))
+		Expect(reconciler.Store.Check(nil)).To(Succeed())
+	})
+
+	It("should keep the previous config when the ConfigMap is invalid", func() {
+		cm.Data[config.DefaultConfigMapKey] = "rules: []"
+		Expect(k8sClient.Update(ctx, cm)).To(Succeed())
+
+		_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: reconciler.ConfigMap})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reconciler.Store.Get()).To(BeIdenticalTo(initial))
+		Expect(reconciler.Store.Check(nil)).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,59 @@
+package controller
+
+import (
+	"sync"
+	"time"
+)
+
+// nodeTaintKey identifies a taint on a node
+type nodeTaintKey struct {
+	node  string
+	taint string
+}
+
+// untaintCooldown remembers when each target taint was last removed from each
+// node. The zero value is ready to use.
+type untaintCooldown struct {
+	mu          sync.Mutex
+	untaintedAt map[nodeTaintKey]time.Time
+}
+
+// record notes that taint was removed from node at the given time
+func (c *untaintCooldown) record(node, taint string, at time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.untaintedAt == nil {
+		c.untaintedAt = make(map[nodeTaintKey]time.Time)
+	}
+	c.untaintedAt[nodeTaintKey{node: node, taint: taint}] = at
+}
+
+// remaining returns how much of period is left since taint was last removed
+// from node, or zero when the cooldown is over
+func (c *untaintCooldown) remaining(node, taint string, period time.Duration, now time.Time) time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := nodeTaintKey{node: node, taint: taint}
+	at, ok := c.untaintedAt[key]
+	if !ok {
+		return 0
+	}
+	if left := period - now.Sub(at); left > 0 {
+		return left
+	}
+	delete(c.untaintedAt, key)
+	return 0
+}
+
+// forget drops everything recorded for node
+func (c *untaintCooldown) forget(node string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key := range c.untaintedAt {
+		if key.node == node {
+			delete(c.untaintedAt, key)
+		}
+	}
+}
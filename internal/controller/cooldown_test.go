@@ -0,0 +1,33 @@
+package controller
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("untaintCooldown", func() {
+	It("should report the time left until the cooldown of a taint ends", func() {
+		cooldown := &untaintCooldown{}
+		now := time.Now()
+		Expect(cooldown.remaining("node", "taint", time.Minute, now)).To(BeZero())
+
+		cooldown.record("node", "taint", now)
+		Expect(cooldown.remaining("node", "taint", time.Minute, now.Add(20*time.Second))).
+			To(Equal(40 * time.Second))
+		Expect(cooldown.remaining("node", "other-taint", time.Minute, now)).To(BeZero())
+		Expect(cooldown.remaining("node", "taint", time.Minute, now.Add(time.Minute))).To(BeZero())
+	})
+
+	It("should forget every taint of a node", func() {
+		cooldown := &untaintCooldown{}
+		now := time.Now()
+		cooldown.record("node", "taint", now)
+		cooldown.record("other-node", "taint", now)
+
+		cooldown.forget("node")
+		Expect(cooldown.remaining("node", "taint", time.Minute, now)).To(BeZero())
+		Expect(cooldown.remaining("other-node", "taint", time.Minute, now)).To(Equal(time.Minute))
+	})
+})
@@ -0,0 +1,98 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	untaintv1alpha1 "github.com/jslay88/generic-untaint-operator/api/v1alpha1"
+)
+
+// +kubebuilder:rbac:groups=storage.k8s.io,resources=csinodes,verbs=get;list;watch
+
+// csiDriversBlocked returns why the CSI drivers a rule requires are not all
+// registered in the node's CSINode yet, or nil when they are. Kubelet creates
+// the CSINode, named after the node, once the first driver registers.
+func (r *NodeReconciler) csiDriversBlocked(
+	ctx context.Context,
+	node *corev1.Node,
+	drivers []string,
+) (*blockReason, error) {
+	if len(drivers) == 0 {
+		return nil, nil
+	}
+	csiNode := &storagev1.CSINode{}
+	if err := r.Get(ctx, types.NamespacedName{Name: node.Name}, csiNode); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("failed to get CSINode: %w", err)
+		}
+		csiNode = &storagev1.CSINode{}
+	}
+	return missingCSIDriver(csiNode, drivers), nil
+}
+
+// missingCSIDriver returns why the first of drivers not registered in csiNode
+// blocks the taint, or nil when all of them are
+func missingCSIDriver(csiNode *storagev1.CSINode, drivers []string) *blockReason {
+	for _, name := range drivers {
+		if !slices.ContainsFunc(csiNode.Spec.Drivers, func(driver storagev1.CSINodeDriver) bool {
+			return driver.Name == name
+		}) {
+			return &blockReason{
+				reason:  untaintv1alpha1.ReasonCSIDriverNotRegistered,
+				message: fmt.Sprintf("CSI driver %s is not registered on the node", name),
+			}
+		}
+	}
+	return nil
+}
+
+// csiNodeToNode maps a CSINode to the node of the same name
+func csiNodeToNode(_ context.Context, obj client.Object) []reconcile.Request {
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: obj.GetName()}}}
+}
+
+// csiNodeChangedPredicate passes CSINodes when they are created and when the
+// set of drivers registered in them changes
+func csiNodeChangedPredicate() predicate.Funcs {
+	return predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool {
+			return true
+		},
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldNode, ok := e.ObjectOld.(*storagev1.CSINode)
+			if !ok {
+				return false
+			}
+			newNode, ok := e.ObjectNew.(*storagev1.CSINode)
+			if !ok {
+				return false
+			}
+			return !slices.Equal(csiDriverNames(oldNode), csiDriverNames(newNode))
+		},
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			return false
+		},
+		GenericFunc: func(e event.GenericEvent) bool {
+			return false
+		},
+	}
+}
+
+// csiDriverNames returns the names of the drivers registered in csiNode
+func csiDriverNames(csiNode *storagev1.CSINode) []string {
+	names := make([]string, 0, len(csiNode.Spec.Drivers))
+	for _, driver := range csiNode.Spec.Drivers {
+		names = append(names, driver.Name)
+	}
+	return names
+}
@@ -0,0 +1,58 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	untaintv1alpha1 "github.com/jslay88/generic-untaint-operator/api/v1alpha1"
+)
+
+var _ = Describe("CSI drivers", func() {
+	var (
+		ctx  context.Context
+		node *corev1.Node
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		node = &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node"}}
+	})
+
+	It("should pass once every required driver is registered", func() {
+		csiNode := &storagev1.CSINode{
+			ObjectMeta: metav1.ObjectMeta{Name: "node"},
+			Spec: storagev1.CSINodeSpec{Drivers: []storagev1.CSINodeDriver{
+				{Name: "ebs.csi.aws.com", NodeID: "i-0123456789abcdef0"},
+			}},
+		}
+		r := &NodeReconciler{Client: fake.NewClientBuilder().WithObjects(csiNode).Build()}
+
+		reason, err := r.csiDriversBlocked(ctx, node, []string{"ebs.csi.aws.com"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reason).To(BeNil())
+
+		reason, err = r.csiDriversBlocked(ctx, node, []string{"ebs.csi.aws.com", "efs.csi.aws.com"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reason).NotTo(BeNil())
+		Expect(reason.reason).To(Equal(untaintv1alpha1.ReasonCSIDriverNotRegistered))
+		Expect(reason.message).To(ContainSubstring("efs.csi.aws.com"))
+	})
+
+	It("should block while the node has no CSINode", func() {
+		r := &NodeReconciler{Client: fake.NewClientBuilder().Build()}
+		reason, err := r.csiDriversBlocked(ctx, node, []string{"ebs.csi.aws.com"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reason).NotTo(BeNil())
+
+		// Rules without CSI drivers don't look for one
+		reason, err = r.csiDriversBlocked(ctx, node, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reason).To(BeNil())
+	})
+})
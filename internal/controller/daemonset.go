@@ -0,0 +1,50 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// podTemplateGenerationLabel records the template generation a DaemonSet pod
+// was created from. The DaemonSet controller sets it alongside
+// controller-revision-hash, and unlike the hash it can be compared without
+// looking up the DaemonSet's ControllerRevisions.
+const podTemplateGenerationLabel = "pod-template-generation"
+
+// isOutdatedDaemonSetPod reports whether pod belongs to a DaemonSet that is
+// rolling out a newer template than the one the pod was created from. Pods of
+// other workloads, and DaemonSets using the OnDelete strategy, whose pods are
+// only replaced by hand, are never outdated.
+func (r *NodeReconciler) isOutdatedDaemonSetPod(ctx context.Context, pod *corev1.Pod) (bool, error) {
+	var owner string
+	for _, ref := range pod.OwnerReferences {
+		if ref.Kind == "DaemonSet" && ref.Controller != nil && *ref.Controller {
+			owner = ref.Name
+			break
+		}
+	}
+	podGeneration, ok := pod.Labels[podTemplateGenerationLabel]
+	if owner == "" || !ok {
+		return false, nil
+	}
+
+	ds := &appsv1.DaemonSet{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: pod.Namespace, Name: owner}, ds); err != nil {
+		if apierrors.IsNotFound(err) {
+			// The pod is orphaned and will be garbage collected
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to get daemonset %s/%s: %w", pod.Namespace, owner, err)
+	}
+	if ds.Spec.UpdateStrategy.Type == appsv1.OnDeleteDaemonSetStrategyType {
+		return false, nil
+	}
+	// The API server bumps this annotation each time the template changes
+	dsGeneration, ok := ds.Annotations[appsv1.DeprecatedTemplateGeneration]
+	return ok && dsGeneration != podGeneration, nil
+}
@@ -0,0 +1,72 @@
+package controller
+
+import (
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// taintAges remembers when the operator first saw each target taint on each
+// node, for taints that don't record when they were added, and which taints
+// outlived their maxWait. The zero value is ready to use.
+type taintAges struct {
+	mu        sync.Mutex
+	firstSeen map[nodeTaintKey]time.Time
+	exceeded  map[nodeTaintKey]struct{}
+}
+
+// since returns when taint was added to node: the taint's own timeAdded when
+// set, which Kubernetes only records for NoExecute taints, and otherwise when
+// the operator first saw it
+func (a *taintAges) since(node *corev1.Node, taint string, now time.Time) time.Time {
+	for _, t := range node.Spec.Taints {
+		if t.Key == taint && t.TimeAdded != nil {
+			return t.TimeAdded.Time
+		}
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.firstSeen == nil {
+		a.firstSeen = make(map[nodeTaintKey]time.Time)
+	}
+	key := nodeTaintKey{node: node.Name, taint: taint}
+	if at, ok := a.firstSeen[key]; ok {
+		return at
+	}
+	a.firstSeen[key] = now
+	return now
+}
+
+// exceed records that taint on node outlived its maxWait, and reports whether
+// it is the first time since the taint was added
+func (a *taintAges) exceed(node, taint string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.exceeded == nil {
+		a.exceeded = make(map[nodeTaintKey]struct{})
+	}
+	key := nodeTaintKey{node: node, taint: taint}
+	if _, ok := a.exceeded[key]; ok {
+		return false
+	}
+	a.exceeded[key] = struct{}{}
+	return true
+}
+
+// forget drops the age of taint on node, or of every taint of node when taint is empty
+func (a *taintAges) forget(node, taint string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for key := range a.firstSeen {
+		if key.node == node && (taint == "" || key.taint == taint) {
+			delete(a.firstSeen, key)
+		}
+	}
+	for key := range a.exceeded {
+		if key.node == node && (taint == "" || key.taint == taint) {
+			delete(a.exceeded, key)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package controller
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("taintAges", func() {
+	It("should prefer the time the taint was added", func() {
+		added := metav1.NewTime(time.Now().Add(-time.Hour).Truncate(time.Second))
+		node := &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "node"},
+			Spec: corev1.NodeSpec{Taints: []corev1.Taint{
+				{Key: "noexecute", Effect: corev1.TaintEffectNoExecute, TimeAdded: &added},
+				{Key: "noschedule", Effect: corev1.TaintEffectNoSchedule},
+			}},
+		}
+		ages := &taintAges{}
+		now := time.Now()
+		Expect(ages.since(node, "noexecute", now)).To(Equal(added.Time))
+
+		// Taints without timeAdded count from when they were first seen
+		Expect(ages.since(node, "noschedule", now)).To(Equal(now))
+		Expect(ages.since(node, "noschedule", now.Add(time.Minute))).To(Equal(now))
+
+		ages.forget("node", "noschedule")
+		Expect(ages.since(node, "noschedule", now.Add(time.Minute))).To(Equal(now.Add(time.Minute)))
+	})
+
+	It("should report a taint outliving its maxWait once until it is forgotten", func() {
+		ages := &taintAges{}
+		Expect(ages.exceed("node", "taint")).To(BeTrue())
+		Expect(ages.exceed("node", "taint")).To(BeFalse())
+		Expect(ages.exceed("node", "other")).To(BeTrue())
+
+		ages.forget("node", "taint")
+		Expect(ages.exceed("node", "taint")).To(BeTrue())
+		Expect(ages.exceed("node", "other")).To(BeFalse())
+
+		ages.forget("node", "")
+		Expect(ages.exceed("node", "other")).To(BeTrue())
+	})
+})
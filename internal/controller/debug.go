@@ -0,0 +1,80 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jslay88/generic-untaint-operator/internal/config"
+)
+
+// BlockedNodesPath is where the blocked nodes debug endpoint is served
+const BlockedNodesPath = "/debug/blocked"
+
+// BlockedNode is a node keeping its target taints, as listed by the blocked
+// nodes debug endpoint
+type BlockedNode struct {
+	Node string `json:"node"`
+	// Taints are the target taints the node keeps
+	Taints []string `json:"taints"`
+	// Reason and Message explain why the node is blocked
+	Reason  string `json:"reason"`
+	Message string `json:"message,omitempty"`
+	// Workloads are the workloads that are missing or not ready, when the
+	// node waits for workloads
+	Workloads []string `json:"workloads,omitempty"`
+	// Since is when the node was first seen blocked, and BlockedFor how long
+	// ago that was
+	Since      time.Time `json:"since"`
+	BlockedFor string    `json:"blockedFor"`
+}
+
+// list returns the blocked nodes, longest blocked first
+func (b *blockedNodes) list(now time.Time) []BlockedNode {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	nodes := make([]BlockedNode, 0, len(b.nodes))
+	for _, node := range b.nodes {
+		node.BlockedFor = now.Sub(node.Since).Round(time.Second).String()
+		nodes = append(nodes, node)
+	}
+	slices.SortFunc(nodes, func(a, b BlockedNode) int {
+		if c := a.Since.Compare(b.Since); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Node, b.Node)
+	})
+	return nodes
+}
+
+// get returns the blocked node named node, and whether it is blocked
+func (b *blockedNodes) get(node string, now time.Time) (BlockedNode, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	blocked, ok := b.nodes[node]
+	blocked.BlockedFor = now.Sub(blocked.Since).Round(time.Second).String()
+	return blocked, ok
+}
+
+// BlockedNodesHandler serves the nodes the reconciler keeps blocked as JSON,
+// to triage stuck nodes without going through the logs. Only the leader
+// reconciles, so other replicas serve an empty list.
+func (r *NodeReconciler) BlockedNodesHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(r.blocked.list(time.Now()))
+	})
+}
+
+// ruleTaints returns the target taints of rules
+func ruleTaints(rules []config.Rule) []string {
+	taints := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		taints = append(taints, rule.TargetTaint)
+	}
+	return taints
+}
@@ -0,0 +1,75 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	untaintv1alpha1 "github.com/jslay88/generic-untaint-operator/api/v1alpha1"
+)
+
+var _ = Describe("blocked nodes debug endpoint", func() {
+	var (
+		r   *NodeReconciler
+		now time.Time
+	)
+
+	BeforeEach(func() {
+		r = &NodeReconciler{}
+		now = time.Now().Truncate(time.Second)
+		DeferCleanup(func() {
+			r.blocked.forget("a")
+			r.blocked.forget("b")
+		})
+	})
+
+	It("should list blocked nodes longest blocked first", func() {
+		r.blocked.set(BlockedNode{Node: "b", Reason: untaintv1alpha1.ReasonWaitingForWorkload}, now.Add(-time.Minute), 0)
+		r.blocked.set(BlockedNode{Node: "a", Reason: untaintv1alpha1.ReasonWaitingForWorkload}, now, 0)
+		// A new reason keeps when the node was first blocked
+		r.blocked.set(BlockedNode{
+			Node:      "b",
+			Taints:    []string{"example.com/not-ready"},
+			Reason:    untaintv1alpha1.ReasonWorkloadUnready,
+			Message:   "taint example.com/not-ready: pod kube-system/agent-abc is not ready",
+			Workloads: []string{"agent"},
+		}, now, 0)
+
+		Expect(r.blocked.list(now.Add(30 * time.Second))).To(Equal([]BlockedNode{
+			{
+				Node:       "b",
+				Taints:     []string{"example.com/not-ready"},
+				Reason:     untaintv1alpha1.ReasonWorkloadUnready,
+				Message:    "taint example.com/not-ready: pod kube-system/agent-abc is not ready",
+				Workloads:  []string{"agent"},
+				Since:      now.Add(-time.Minute),
+				BlockedFor: "1m30s",
+			},
+			{Node: "a", Reason: untaintv1alpha1.ReasonWaitingForWorkload, Since: now, BlockedFor: "30s"},
+		}))
+
+		r.blocked.forget("b")
+		Expect(r.blocked.list(now)).To(HaveLen(1))
+	})
+
+	It("should serve the blocked nodes as JSON", func() {
+		recorder := httptest.NewRecorder()
+		r.BlockedNodesHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, BlockedNodesPath, nil))
+		Expect(recorder.Header().Get("Content-Type")).To(Equal("application/json"))
+		Expect(recorder.Body.String()).To(MatchJSON("[]"))
+
+		r.blocked.set(BlockedNode{Node: "a", Reason: untaintv1alpha1.ReasonNodeNotReady}, now, 0)
+		recorder = httptest.NewRecorder()
+		r.BlockedNodesHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, BlockedNodesPath, nil))
+		var nodes []map[string]interface{}
+		Expect(json.Unmarshal(recorder.Body.Bytes(), &nodes)).To(Succeed())
+		Expect(nodes).To(HaveLen(1))
+		Expect(nodes[0]).To(HaveKeyWithValue("node", "a"))
+		Expect(nodes[0]).To(HaveKeyWithValue("reason", untaintv1alpha1.ReasonNodeNotReady))
+		Expect(nodes[0]).To(HaveKey("blockedFor"))
+	})
+})
@@ -0,0 +1,148 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/segmentio/kafka-go"
+)
+
+// structuredContentType is the content type of CloudEvents sent to message
+// buses in structured content mode
+const structuredContentType = "application/cloudevents+json"
+
+// structuredCloudEvent is the JSON envelope of a CloudEvent in structured
+// content mode, carrying its attributes next to its data
+type structuredCloudEvent struct {
+	SpecVersion     string        `json:"specversion"`
+	ID              string        `json:"id"`
+	Source          string        `json:"source"`
+	Type            string        `json:"type"`
+	Subject         string        `json:"subject"`
+	Time            string        `json:"time"`
+	DataContentType string        `json:"datacontenttype"`
+	Data            NodeEventData `json:"data"`
+}
+
+// marshalStructured encodes event in structured content mode
+func marshalStructured(event CloudEvent) ([]byte, error) {
+	return json.Marshal(structuredCloudEvent{
+		SpecVersion:     "1.0",
+		ID:              event.ID,
+		Source:          CloudEventSource,
+		Type:            event.Type,
+		Subject:         event.Data.Node,
+		Time:            event.Time.UTC().Format(time.RFC3339Nano),
+		DataContentType: "application/json",
+		Data:            event.Data,
+	})
+}
+
+// NewCloudEventBus returns the sender for a message bus URL, either
+// kafka://broker[,broker...]/topic or nats://server/subject (tls:// for NATS
+// over TLS)
+func NewCloudEventBus(busURL string) (CloudEventSender, error) {
+	u, err := url.Parse(busURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid message bus URL: %w", err)
+	}
+	destination := strings.TrimPrefix(u.Path, "/")
+	if u.Host == "" || destination == "" {
+		return nil, fmt.Errorf("message bus URL %q needs a host and a topic or subject", busURL)
+	}
+	switch u.Scheme {
+	case "kafka":
+		return NewKafkaSender(strings.Split(u.Host, ","), destination), nil
+	case "nats", "tls":
+		server := *u
+		server.Path = ""
+		return NewNATSSender(server.String(), destination)
+	}
+	return nil, fmt.Errorf("unsupported message bus %q, must be kafka, nats or tls", u.Scheme)
+}
+
+// KafkaSender publishes CloudEvents to a Kafka topic in structured content
+// mode, keyed by node name so the events of a node stay in order
+type KafkaSender struct {
+	writer *kafka.Writer
+}
+
+// NewKafkaSender returns a sender publishing to topic on brokers
+func NewKafkaSender(brokers []string, topic string) *KafkaSender {
+	return &KafkaSender{writer: &kafka.Writer{
+		Addr:         kafka.TCP(brokers...),
+		Topic:        topic,
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireOne,
+		WriteTimeout: cloudEventTimeout,
+	}}
+}
+
+// Send implements CloudEventSender
+func (k *KafkaSender) Send(ctx context.Context, event CloudEvent) error {
+	value, err := marshalStructured(event)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, cloudEventTimeout)
+	defer cancel()
+	return k.writer.WriteMessages(ctx, kafka.Message{
+		Key:     []byte(event.Data.Node),
+		Value:   value,
+		Headers: []kafka.Header{{Key: "content-type", Value: []byte(structuredContentType)}},
+	})
+}
+
+// Close implements CloudEventSender
+func (k *KafkaSender) Close() error {
+	return k.writer.Close()
+}
+
+// NATSSender publishes CloudEvents to a NATS subject in structured content mode
+type NATSSender struct {
+	conn    *nats.Conn
+	subject string
+}
+
+// NewNATSSender returns a sender publishing to subject on server. The
+// connection is retried in the background, so an unreachable server only
+// fails the sends.
+func NewNATSSender(server, subject string) (*NATSSender, error) {
+	conn, err := nats.Connect(server,
+		nats.Name(FieldManager),
+		nats.RetryOnFailedConnect(true),
+		nats.MaxReconnects(-1),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+	return &NATSSender{conn: conn, subject: subject}, nil
+}
+
+// Send implements CloudEventSender, returning once the server received event
+func (n *NATSSender) Send(ctx context.Context, event CloudEvent) error {
+	data, err := marshalStructured(event)
+	if err != nil {
+		return err
+	}
+	msg := nats.NewMsg(n.subject)
+	msg.Header.Set("Content-Type", structuredContentType)
+	msg.Data = data
+	if err := n.conn.PublishMsg(msg); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, cloudEventTimeout)
+	defer cancel()
+	return n.conn.FlushWithContext(ctx)
+}
+
+// Close implements CloudEventSender
+func (n *NATSSender) Close() error {
+	n.conn.Close()
+	return nil
+}
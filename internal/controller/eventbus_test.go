@@ -0,0 +1,61 @@
+package controller
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("message buses", func() {
+	It("should encode events in structured content mode", func() {
+		data, err := marshalStructured(CloudEvent{
+			ID:   "id",
+			Type: CloudEventNodeUntainted,
+			Time: time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC),
+			Data: NodeEventData{Node: "node", Taints: []string{"example.com/not-ready"}},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(data).To(MatchJSON(`{
+			"specversion": "1.0",
+			"id": "id",
+			"source": "/generic-untaint-operator",
+			"type": "io.github.jslay88.untaint.NodeUntainted",
+			"subject": "node",
+			"time": "2025-01-01T12:00:00Z",
+			"datacontenttype": "application/json",
+			"data": {"node": "node", "taints": ["example.com/not-ready"]}
+		}`))
+	})
+
+	It("should pick the bus from the URL", func() {
+		bus, err := NewCloudEventBus("kafka://kafka-0:9092,kafka-1:9092/node-events")
+		Expect(err).NotTo(HaveOccurred())
+		kafka, ok := bus.(*KafkaSender)
+		Expect(ok).To(BeTrue())
+		Expect(kafka.writer.Addr.String()).To(Equal("kafka-0:9092,kafka-1:9092"))
+		Expect(kafka.writer.Topic).To(Equal("node-events"))
+		Expect(bus.Close()).To(Succeed())
+
+		// The connection is retried in the background, so an unreachable
+		// server doesn't fail
+		bus, err = NewCloudEventBus("nats://127.0.0.1:1/untaint.events")
+		Expect(err).NotTo(HaveOccurred())
+		nats, ok := bus.(*NATSSender)
+		Expect(ok).To(BeTrue())
+		Expect(nats.subject).To(Equal("untaint.events"))
+		Expect(bus.Close()).To(Succeed())
+	})
+
+	It("should reject unusable bus URLs", func() {
+		for _, busURL := range []string{
+			"kafka://kafka-0:9092",
+			"kafka:///node-events",
+			"amqp://rabbitmq/node-events",
+			"://",
+		} {
+			_, err := NewCloudEventBus(busURL)
+			Expect(err).To(HaveOccurred(), busURL)
+		}
+	})
+})
@@ -0,0 +1,57 @@
+package controller
+
+import (
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// blockEvents remembers the last block reported on each node, so a node that
+// stays blocked for the same reason gets a single event rather than one per
+// requeue. The zero value is ready to use.
+type blockEvents struct {
+	mu   sync.Mutex
+	last map[string]blockReason
+}
+
+// changed records reason as the current block of node and reports whether it
+// differs from the one recorded before
+func (e *blockEvents) changed(node string, reason *blockReason) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.last == nil {
+		e.last = make(map[string]blockReason)
+	}
+	previous, ok := e.last[node]
+	if ok && previous.reason == reason.reason && previous.message == reason.message {
+		return false
+	}
+	e.last[node] = blockReason{reason: reason.reason, message: reason.message}
+	return true
+}
+
+// forget drops the block recorded for node
+func (e *blockEvents) forget(node string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	delete(e.last, node)
+}
+
+// deferredEvent emits a warning event on node naming what keeps its target
+// taints in place, publishes it as a NodeBlocked CloudEvent and counts the
+// workloads blocking it, unless the same block was reported already
+func (r *NodeReconciler) deferredEvent(node *corev1.Node, reason *blockReason) {
+	if !r.blockEvents.changed(node.Name, reason) {
+		return
+	}
+	for _, workload := range reason.workloads {
+		blockingWorkloadTotal.WithLabelValues(workload).Inc()
+	}
+	r.eventf(node, corev1.EventTypeWarning, reason.reason, "Untaint deferred, %s", reason.message)
+	r.CloudEvents.Publish(CloudEventNodeBlocked, NodeEventData{
+		Node:    node.Name,
+		Reason:  reason.reason,
+		Message: reason.message,
+	})
+}
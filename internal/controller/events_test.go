@@ -0,0 +1,92 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	untaintv1alpha1 "github.com/jslay88/generic-untaint-operator/api/v1alpha1"
+	"github.com/jslay88/generic-untaint-operator/internal/config"
+)
+
+var _ = Describe("deferred events", func() {
+	var (
+		recorder *record.FakeRecorder
+		r        *NodeReconciler
+		node     *corev1.Node
+		unready  *blockReason
+	)
+
+	BeforeEach(func() {
+		recorder = record.NewFakeRecorder(10)
+		r = &NodeReconciler{Recorder: recorder}
+		node = &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node"}}
+		unready = &blockReason{
+			reason:  untaintv1alpha1.ReasonWorkloadUnready,
+			message: "taint example.com/not-ready: pod kube-system/agent-abc is not ready",
+		}
+	})
+
+	It("should name the blocking pod once per block", func() {
+		r.deferredEvent(node, unready)
+		Expect(recorder.Events).To(Receive(Equal("Warning WorkloadUnready Untaint deferred, " +
+			"taint example.com/not-ready: pod kube-system/agent-abc is not ready")))
+
+		r.deferredEvent(node, unready)
+		Expect(recorder.Events).NotTo(Receive())
+	})
+
+	It("should report a block again once it changed or was forgotten", func() {
+		r.deferredEvent(node, unready)
+		Expect(recorder.Events).To(Receive())
+
+		r.deferredEvent(node, &blockReason{
+			reason:  untaintv1alpha1.ReasonWaitingForWorkload,
+			message: "taint example.com/not-ready: no pods of agent are running on the node",
+		})
+		Expect(recorder.Events).To(Receive(HavePrefix("Warning WaitingForWorkload")))
+
+		r.blockEvents.forget(node.Name)
+		r.deferredEvent(node, unready)
+		Expect(recorder.Events).To(Receive(HavePrefix("Warning WorkloadUnready")))
+	})
+
+	It("should track nodes separately", func() {
+		r.deferredEvent(node, unready)
+		r.deferredEvent(&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "other"}}, unready)
+		Expect(recorder.Events).To(HaveLen(2))
+	})
+
+	It("should count the blocking workloads once per block", func() {
+		const workload = "events-test-agent"
+		before := testutil.ToFloat64(blockingWorkloadTotal.WithLabelValues(workload))
+		unready.workloads = []string{workload}
+		r.deferredEvent(node, unready)
+		r.deferredEvent(node, unready)
+		Expect(testutil.ToFloat64(blockingWorkloadTotal.WithLabelValues(workload))).To(Equal(before + 1))
+	})
+})
+
+var _ = Describe("workloadsBlocked", func() {
+	It("should name the workload whose pod blocks the taint", func() {
+		pod := corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Namespace:       "kube-system",
+			Name:            "agent-abc",
+			OwnerReferences: []metav1.OwnerReference{{Kind: "DaemonSet", Name: "agent"}},
+		}}
+		r := &NodeReconciler{}
+		reason, err := r.workloadsBlocked(context.Background(), []corev1.Pod{pod}, []string{"agent", "other"},
+			&config.Config{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reason.workloads).To(Equal([]string{"agent"}))
+
+		reason, err = r.workloadsBlocked(context.Background(), nil, []string{"agent", "other"}, &config.Config{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reason.workloads).To(Equal([]string{"agent", "other"}))
+	})
+})
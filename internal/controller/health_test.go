@@ -0,0 +1,42 @@
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/cache/informertest"
+)
+
+var _ = Describe("health checks", func() {
+	It("should fail the API server check while the API server is unreachable", func() {
+		status := http.StatusOK
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			Expect(req.URL.Path).To(Equal("/version"))
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(status)
+			_, _ = w.Write([]byte(`{"major":"1","minor":"31"}`))
+		}))
+		DeferCleanup(server.Close)
+
+		check, err := APIServerCheck(&rest.Config{Host: server.URL}, server.Client())
+		Expect(err).NotTo(HaveOccurred())
+		req := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+		Expect(check(req)).To(Succeed())
+
+		status = http.StatusServiceUnavailable
+		Expect(check(req)).To(MatchError(ContainSubstring("API server is unreachable")))
+	})
+
+	It("should fail the cache check until the informers synced", func() {
+		synced := false
+		check := CacheSyncCheck(&informertest.FakeInformers{Synced: &synced})
+		req := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+		Expect(check(req)).To(MatchError("informer caches are not synced"))
+
+		synced = true
+		Expect(check(req)).To(Succeed())
+	})
+})
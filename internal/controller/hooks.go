@@ -0,0 +1,174 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	untaintv1alpha1 "github.com/jslay88/generic-untaint-operator/api/v1alpha1"
+	"github.com/jslay88/generic-untaint-operator/internal/config"
+)
+
+const (
+	// HookPhasePreUntaint is the phase of a request asking whether the taints
+	// may be removed
+	HookPhasePreUntaint = "PreUntaint"
+	// HookPhasePostUntaint is the phase of a request reporting that the taints
+	// were removed
+	HookPhasePostUntaint = "PostUntaint"
+)
+
+// maxHookResponseSize bounds how much of a hook's answer is read
+const maxHookResponseSize = 64 << 10
+
+// HookRequest is the JSON body POSTed to the untaint hooks
+type HookRequest struct {
+	// Phase is HookPhasePreUntaint or HookPhasePostUntaint
+	Phase string `json:"phase"`
+	// Node is the name of the node
+	Node string `json:"node"`
+	// Taints are the keys of the target taints being removed
+	Taints []string `json:"taints"`
+	// ForcedTaints are the taints among Taints removed once they outlived
+	// their maxWait, although their workloads are not ready
+	ForcedTaints []string `json:"forcedTaints,omitempty"`
+	// Pods are the namespace/name of the pods that satisfied the gate
+	Pods []string `json:"pods,omitempty"`
+}
+
+// HookResponse is the JSON body the pre-untaint hook answers with
+type HookResponse struct {
+	// Allowed is whether the taints may be removed
+	Allowed bool `json:"allowed"`
+	// Message explains a veto
+	Message string `json:"message,omitempty"`
+}
+
+// hookClient returns the client the hook requests are sent with
+func (r *NodeReconciler) hookClient() *http.Client {
+	if r.HookClient != nil {
+		return r.HookClient
+	}
+	return http.DefaultClient
+}
+
+// newHookRequest describes the taints of eval removed from node, leaving out
+// the ones only being downgraded
+func newHookRequest(node *corev1.Node, pods []corev1.Pod, rules []config.Rule, eval *ruleEvaluation) HookRequest {
+	request := HookRequest{Node: node.Name}
+	for _, rule := range rules {
+		if edit, ok := eval.edits[rule.TargetTaint]; !ok || edit.downgradeTo != "" {
+			continue
+		}
+		request.Taints = append(request.Taints, rule.TargetTaint)
+		if slices.Contains(eval.forced, rule.TargetTaint) {
+			request.ForcedTaints = append(request.ForcedTaints, rule.TargetTaint)
+			continue
+		}
+		request.Pods = append(request.Pods, gatingPods(pods, requiredWorkloads(node, rule))...)
+	}
+	slices.Sort(request.Pods)
+	request.Pods = slices.Compact(request.Pods)
+	return request
+}
+
+// preUntaintVeto asks the pre-untaint hook whether the taints of request may
+// be removed. It returns why they must stay, or nil when they may go or no
+// hook is configured.
+func (r *NodeReconciler) preUntaintVeto(ctx context.Context, request HookRequest, cfg *config.Config) *blockReason {
+	hook := cfg.PreUntaintHook
+	if hook == nil || len(request.Taints) == 0 {
+		return nil
+	}
+	request.Phase = HookPhasePreUntaint
+	response := HookResponse{}
+	err := r.callHook(ctx, hook, request, &response)
+	switch {
+	case err != nil && hook.FailurePolicy == config.HookFailurePolicyIgnore:
+		log.FromContext(ctx).Error(err, "Pre-untaint hook failed, removing taints anyway", "node", request.Node)
+		return nil
+	case err != nil:
+		return &blockReason{
+			reason:  untaintv1alpha1.ReasonUntaintVetoed,
+			message: fmt.Sprintf("pre-untaint hook failed: %v", err),
+		}
+	case !response.Allowed:
+		message := "pre-untaint hook vetoed the removal"
+		if response.Message != "" {
+			message += ": " + response.Message
+		}
+		return &blockReason{reason: untaintv1alpha1.ReasonUntaintVetoed, message: message}
+	default:
+		return nil
+	}
+}
+
+// vetoRemoval keeps the taints the pre-untaint hook vetoed, dropping their
+// edits from eval and blocking the node with reason
+func vetoRemoval(rules []config.Rule, request HookRequest, eval *ruleEvaluation, reason *blockReason,
+	cfg *config.Config) {
+	for _, rule := range rules {
+		if !slices.Contains(request.Taints, rule.TargetTaint) {
+			continue
+		}
+		delete(eval.edits, rule.TargetTaint)
+		if interval := cfg.RequeueIntervalFor(rule); eval.requeueAfter == 0 || interval < eval.requeueAfter {
+			eval.requeueAfter = interval
+		}
+	}
+	if eval.blocked == nil {
+		eval.blocked = reason
+	}
+}
+
+// postUntaint tells the post-untaint hook that the taints of request were
+// removed. Failures are only logged, since the taints are gone either way.
+func (r *NodeReconciler) postUntaint(ctx context.Context, request HookRequest, cfg *config.Config) {
+	if cfg.PostUntaintHook == nil || len(request.Taints) == 0 {
+		return
+	}
+	request.Phase = HookPhasePostUntaint
+	if err := r.callHook(ctx, cfg.PostUntaintHook, request, nil); err != nil {
+		log.FromContext(ctx).Error(err, "Post-untaint hook failed", "node", request.Node)
+	}
+}
+
+// callHook POSTs request to hook and decodes its answer into response, when
+// set. Any status other than 2xx is an error.
+func (r *NodeReconciler) callHook(ctx context.Context, hook *config.Hook, request HookRequest,
+	response *HookResponse) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, hook.Timeout.Duration)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := r.hookClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("hook answered with status %s", resp.Status)
+	}
+	if response == nil {
+		return nil
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxHookResponseSize)).Decode(response); err != nil {
+		return fmt.Errorf("failed to decode hook response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	untaintv1alpha1 "github.com/jslay88/generic-untaint-operator/api/v1alpha1"
+	"github.com/jslay88/generic-untaint-operator/internal/config"
+)
+
+var _ = Describe("untaint hooks", func() {
+	var (
+		server   *httptest.Server
+		requests chan HookRequest
+		status   int
+		answer   string
+		r        *NodeReconciler
+		cfg      *config.Config
+		request  HookRequest
+	)
+
+	BeforeEach(func() {
+		requests = make(chan HookRequest, 10)
+		status, answer = http.StatusOK, `{"allowed": true}`
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			defer GinkgoRecover()
+			received := HookRequest{}
+			Expect(json.NewDecoder(req.Body).Decode(&received)).To(Succeed())
+			requests <- received
+			w.WriteHeader(status)
+			_, _ = w.Write([]byte(answer))
+		}))
+		DeferCleanup(server.Close)
+
+		r = &NodeReconciler{}
+		cfg = &config.Config{
+			PreUntaintHook:  &config.Hook{URL: server.URL},
+			PostUntaintHook: &config.Hook{URL: server.URL},
+		}
+		cfg.Default()
+		request = HookRequest{Node: "node", Taints: []string{"example.com/not-ready"}}
+	})
+
+	It("should describe the taints being removed", func() {
+		node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node"}}
+		pods := []corev1.Pod{
+			{ObjectMeta: metav1.ObjectMeta{
+				Namespace: "kube-system", Name: "agent-abc",
+				OwnerReferences: []metav1.OwnerReference{{Name: "agent"}},
+			}},
+			{ObjectMeta: metav1.ObjectMeta{
+				Namespace: "kube-system", Name: "other-abc",
+				OwnerReferences: []metav1.OwnerReference{{Name: "other"}},
+			}},
+		}
+		rules := []config.Rule{
+			{TargetTaint: "example.com/not-ready", OwnedByNames: []string{"agent"}},
+			{TargetTaint: "example.com/staged", OwnedByNames: []string{"agent"}},
+			{TargetTaint: "example.com/forced", OwnedByNames: []string{"other"}},
+		}
+		eval := &ruleEvaluation{
+			edits: taintEdits{
+				"example.com/not-ready": {},
+				"example.com/staged":    {downgradeTo: corev1.TaintEffectPreferNoSchedule},
+				"example.com/forced":    {},
+			},
+			forced: []string{"example.com/forced"},
+		}
+		Expect(newHookRequest(node, pods, rules, eval)).To(Equal(HookRequest{
+			Node:         "node",
+			Taints:       []string{"example.com/not-ready", "example.com/forced"},
+			ForcedTaints: []string{"example.com/forced"},
+			Pods:         []string{"kube-system/agent-abc"},
+		}))
+	})
+
+	It("should let the pre-untaint hook allow the removal", func() {
+		Expect(r.preUntaintVeto(context.Background(), request, cfg)).To(BeNil())
+		Expect(<-requests).To(Equal(HookRequest{
+			Phase:  HookPhasePreUntaint,
+			Node:   "node",
+			Taints: []string{"example.com/not-ready"},
+		}))
+	})
+
+	It("should let the pre-untaint hook veto the removal", func() {
+		answer = `{"allowed": false, "message": "node is not registered in the CMDB"}`
+		Expect(r.preUntaintVeto(context.Background(), request, cfg)).To(Equal(&blockReason{
+			reason:  untaintv1alpha1.ReasonUntaintVetoed,
+			message: "pre-untaint hook vetoed the removal: node is not registered in the CMDB",
+		}))
+	})
+
+	It("should apply the failure policy when the pre-untaint hook fails", func() {
+		status = http.StatusInternalServerError
+		reason := r.preUntaintVeto(context.Background(), request, cfg)
+		Expect(reason).NotTo(BeNil())
+		Expect(reason.reason).To(Equal(untaintv1alpha1.ReasonUntaintVetoed))
+		Expect(reason.message).To(ContainSubstring("500"))
+
+		cfg.PreUntaintHook.FailurePolicy = config.HookFailurePolicyIgnore
+		Expect(r.preUntaintVeto(context.Background(), request, cfg)).To(BeNil())
+	})
+
+	It("should time out a slow pre-untaint hook", func() {
+		slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			select {
+			case <-req.Context().Done():
+			case <-time.After(200 * time.Millisecond):
+			}
+		}))
+		DeferCleanup(slow.Close)
+		cfg.PreUntaintHook = &config.Hook{
+			URL:           slow.URL,
+			Timeout:       metav1.Duration{Duration: 10 * time.Millisecond},
+			FailurePolicy: config.HookFailurePolicyFail,
+		}
+		reason := r.preUntaintVeto(context.Background(), request, cfg)
+		Expect(reason).NotTo(BeNil())
+		Expect(reason.message).To(ContainSubstring("deadline exceeded"))
+	})
+
+	It("should keep vetoed taints on the node", func() {
+		rules := []config.Rule{
+			{TargetTaint: "example.com/not-ready", RequeueInterval: &metav1.Duration{Duration: time.Minute}},
+			{TargetTaint: "example.com/staged"},
+		}
+		eval := &ruleEvaluation{edits: taintEdits{
+			"example.com/not-ready": {},
+			"example.com/staged":    {downgradeTo: corev1.TaintEffectPreferNoSchedule},
+		}}
+		reason := &blockReason{reason: untaintv1alpha1.ReasonUntaintVetoed}
+		vetoRemoval(rules, request, eval, reason, cfg)
+		Expect(eval.edits).To(HaveKey("example.com/staged"))
+		Expect(eval.edits).NotTo(HaveKey("example.com/not-ready"))
+		Expect(eval.blocked).To(BeIdenticalTo(reason))
+		Expect(eval.requeueAfter).To(Equal(time.Minute))
+	})
+
+	It("should tell the post-untaint hook about the removal", func() {
+		r.postUntaint(context.Background(), request, cfg)
+		Expect(<-requests).To(HaveField("Phase", HookPhasePostUntaint))
+
+		// Nothing is sent when no taint was removed
+		r.postUntaint(context.Background(), HookRequest{Node: "node"}, cfg)
+		Consistently(requests, 50*time.Millisecond).ShouldNot(Receive())
+	})
+})
@@ -0,0 +1,40 @@
+package controller
+
+import (
+	"sync"
+	"time"
+)
+
+// inFlightRetry is how soon a reconcile that found its node already being
+// reconciled is retried
+const inFlightRetry = time.Second
+
+// nodeLocks tracks the nodes currently being reconciled, so concurrent workers
+// never both try to remove taints from, or emit events for, the same node. The
+// zero value is ready to use.
+type nodeLocks struct {
+	mu    sync.Mutex
+	nodes map[string]struct{}
+}
+
+// tryLock claims node and reports whether it wasn't already claimed
+func (l *nodeLocks) tryLock(node string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.nodes[node]; ok {
+		return false
+	}
+	if l.nodes == nil {
+		l.nodes = make(map[string]struct{})
+	}
+	l.nodes[node] = struct{}{}
+	return true
+}
+
+// unlock releases a node claimed by tryLock
+func (l *nodeLocks) unlock(node string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.nodes, node)
+}
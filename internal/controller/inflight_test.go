@@ -0,0 +1,40 @@
+package controller
+
+import (
+	"sync"
+	"sync/atomic"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("nodeLocks", func() {
+	It("should let only one worker claim a node at a time", func() {
+		locks := &nodeLocks{}
+		Expect(locks.tryLock("node-a")).To(BeTrue())
+		Expect(locks.tryLock("node-a")).To(BeFalse())
+
+		// Other nodes are unaffected
+		Expect(locks.tryLock("node-b")).To(BeTrue())
+
+		locks.unlock("node-a")
+		Expect(locks.tryLock("node-a")).To(BeTrue())
+	})
+
+	It("should hand a node to a single concurrent worker", func() {
+		locks := &nodeLocks{}
+		var claimed atomic.Int32
+		var wg sync.WaitGroup
+		for range 16 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if locks.tryLock("node") {
+					claimed.Add(1)
+				}
+			}()
+		}
+		wg.Wait()
+		Expect(claimed.Load()).To(Equal(int32(1)))
+	})
+})
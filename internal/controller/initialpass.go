@@ -0,0 +1,130 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// defaultCacheSyncTimeout is how long controllers wait for their caches to
+// sync unless the manager sets another timeout, as in controller-runtime
+const defaultCacheSyncTimeout = 2 * time.Minute
+
+// initialPass tracks the first pass over the nodes that carried a target taint
+// when the controller started, so the operator only reports ready once none
+// of them was left unreconciled. The zero value is ready to use.
+type initialPass struct {
+	mu sync.Mutex
+	// listed is set once the tainted nodes were listed
+	listed bool
+	// pending are the listed nodes not reconciled yet
+	pending map[string]struct{}
+	// reconciled are the nodes reconciled before the tainted nodes were listed
+	reconciled map[string]struct{}
+}
+
+// start records nodes as the tainted nodes to reconcile, less those already
+// reconciled
+func (p *initialPass) start(nodes []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.pending = make(map[string]struct{}, len(nodes))
+	for _, node := range nodes {
+		if _, ok := p.reconciled[node]; !ok {
+			p.pending[node] = struct{}{}
+		}
+	}
+	p.reconciled = nil
+	p.listed = true
+}
+
+// done records that node was reconciled
+func (p *initialPass) done(node string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.listed {
+		delete(p.pending, node)
+		return
+	}
+	if p.reconciled == nil {
+		p.reconciled = make(map[string]struct{})
+	}
+	p.reconciled[node] = struct{}{}
+}
+
+// remaining reports whether the tainted nodes were listed yet, and how many of
+// them are still to be reconciled
+func (p *initialPass) remaining() (bool, int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.listed, len(p.pending)
+}
+
+// startInitialPass lists the nodes carrying a target taint once the informers
+// of c synced, to track the first pass over them, and queues them right away,
+// so nodes that became ready while no replica was leading are untainted
+// without waiting for an event about them. It must be the controller's last
+// source: the controller only starts its workers once it returned, so a new
+// leader never acts on partially synced caches or before the sweep is queued.
+// Like the controller's own sources, it fails when the informers don't sync
+// within syncTimeout.
+func (r *NodeReconciler) startInitialPass(c cache.Cache, syncTimeout time.Duration) source.Func {
+	return func(ctx context.Context, queue workqueue.TypedRateLimitingInterface[reconcile.Request]) error {
+		log := log.FromContext(ctx)
+		syncCtx, cancel := context.WithTimeout(ctx, syncTimeout)
+		defer cancel()
+		if !c.WaitForCacheSync(syncCtx) {
+			if ctx.Err() != nil {
+				// The controller is stopping
+				return nil
+			}
+			return fmt.Errorf("caches did not sync within %s for the initial pass", syncTimeout)
+		}
+		tainted, err := r.listTaintedNodes(ctx)
+		if err != nil {
+			log.Error(err, "Failed to list nodes for the initial pass")
+			// Don't keep the operator unready for good over it
+			r.initialPass.start(nil)
+			return nil
+		}
+		r.initialPass.start(tainted)
+		for _, name := range tainted {
+			queue.Add(reconcile.Request{NamespacedName: types.NamespacedName{Name: name}})
+		}
+		log.Info("Queued the tainted nodes for the initial pass", "nodes", len(tainted))
+		return nil
+	}
+}
+
+// InitialPassCheck returns a health check failing until every node that
+// carried a target taint when the controller started was reconciled once.
+// It passes while elected isn't closed, since replicas waiting for leadership
+// don't reconcile nodes.
+func (r *NodeReconciler) InitialPassCheck(elected <-chan struct{}) healthz.Checker {
+	return func(_ *http.Request) error {
+		if !isClosed(elected) {
+			return nil
+		}
+		listed, pending := r.initialPass.remaining()
+		if !listed {
+			return errors.New("tainted nodes are not listed yet")
+		}
+		if pending > 0 {
+			return fmt.Errorf("%d tainted nodes are not reconciled yet", pending)
+		}
+		return nil
+	}
+}
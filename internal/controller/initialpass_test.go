@@ -0,0 +1,99 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/cache/informertest"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("initial pass", func() {
+	var (
+		r       *NodeReconciler
+		elected chan struct{}
+		req     *http.Request
+	)
+
+	BeforeEach(func() {
+		r = &NodeReconciler{}
+		elected = make(chan struct{})
+		req = httptest.NewRequest(http.MethodGet, "/readyz", nil)
+	})
+
+	It("should pass while waiting for leadership", func() {
+		Expect(r.InitialPassCheck(elected)(req)).To(Succeed())
+	})
+
+	It("should fail until every tainted node was reconciled once", func() {
+		close(elected)
+		check := r.InitialPassCheck(elected)
+		Expect(check(req)).To(MatchError("tainted nodes are not listed yet"))
+
+		r.initialPass.start([]string{"node-a", "node-b"})
+		Expect(check(req)).To(MatchError("2 tainted nodes are not reconciled yet"))
+
+		r.initialPass.done("node-a")
+		r.initialPass.done("node-a")
+		Expect(check(req)).To(MatchError("1 tainted nodes are not reconciled yet"))
+
+		r.initialPass.done("node-b")
+		Expect(check(req)).To(Succeed())
+	})
+
+	It("should count nodes reconciled before they were listed", func() {
+		close(elected)
+		r.initialPass.done("node-a")
+		r.initialPass.start([]string{"node-a"})
+		Expect(r.InitialPassCheck(elected)(req)).To(Succeed())
+	})
+
+	It("should queue the tainted nodes once the cache synced", func() {
+		const taint = "initialpass.test/not-ready"
+		tainted := &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "tainted"},
+			Spec:       corev1.NodeSpec{Taints: []corev1.Taint{{Key: taint, Effect: corev1.TaintEffectNoSchedule}}},
+		}
+		untainted := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "untainted"}}
+		r.Client = fake.NewClientBuilder().WithObjects(tainted, untainted).Build()
+		r.TargetTaint = taint
+		r.OwnedByNames = []string{"agent"}
+		queue := workqueue.NewTypedRateLimitingQueue(workqueue.DefaultTypedControllerRateLimiter[reconcile.Request]())
+		DeferCleanup(queue.ShutDown)
+		ctx, cancel := context.WithCancel(context.Background())
+		DeferCleanup(cancel)
+
+		// The nodes are queued by the time the controller starts its workers
+		Expect(r.startInitialPass(&informertest.FakeInformers{}, time.Minute)(ctx, queue)).To(Succeed())
+		Expect(queue.Len()).To(Equal(1))
+		item, _ := queue.Get()
+		Expect(item.Name).To(Equal("tainted"))
+		listed, pending := r.initialPass.remaining()
+		Expect(listed).To(BeTrue())
+		Expect(pending).To(Equal(1))
+	})
+
+	It("should fail when the caches don't sync in time", func() {
+		queue := workqueue.NewTypedRateLimitingQueue(workqueue.DefaultTypedControllerRateLimiter[reconcile.Request]())
+		DeferCleanup(queue.ShutDown)
+		informers := &informertest.FakeInformers{Synced: ptr.To(false)}
+
+		err := r.startInitialPass(informers, time.Millisecond)(context.Background(), queue)
+		Expect(err).To(MatchError(ContainSubstring("caches did not sync within 1ms")))
+		Expect(queue.Len()).To(BeZero())
+
+		// Unless the controller is stopping
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		Expect(r.startInitialPass(informers, time.Minute)(ctx, queue)).To(Succeed())
+	})
+})
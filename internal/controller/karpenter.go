@@ -0,0 +1,337 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	untaintv1alpha1 "github.com/jslay88/generic-untaint-operator/api/v1alpha1"
+	"github.com/jslay88/generic-untaint-operator/internal/config"
+)
+
+var (
+	// NodeClaimGVK is the Karpenter NodeClaim kind, read as unstructured so
+	// the operator doesn't depend on a Karpenter release
+	NodeClaimGVK = schema.GroupVersionKind{Group: "karpenter.sh", Version: "v1", Kind: "NodeClaim"}
+	// NodePoolGVK is the Karpenter NodePool kind
+	NodePoolGVK = schema.GroupVersionKind{Group: "karpenter.sh", Version: "v1", Kind: "NodePool"}
+)
+
+// NodeClaimConditionType is the condition set on the NodeClaim of a node,
+// mirroring whether its target taints were removed
+const NodeClaimConditionType = "Untainted"
+
+// nodeClaimNodeNameField indexes NodeClaims by the name of their node
+const nodeClaimNodeNameField = "status.nodeName"
+
+// newNodeClaim returns an empty unstructured NodeClaim
+func newNodeClaim() *unstructured.Unstructured {
+	claim := &unstructured.Unstructured{}
+	claim.SetGroupVersionKind(NodeClaimGVK)
+	return claim
+}
+
+// newNodePool returns an empty unstructured NodePool
+func newNodePool() *unstructured.Unstructured {
+	pool := &unstructured.Unstructured{}
+	pool.SetGroupVersionKind(NodePoolGVK)
+	return pool
+}
+
+// +kubebuilder:rbac:groups=karpenter.sh,resources=nodeclaims,verbs=get;list;watch
+// +kubebuilder:rbac:groups=karpenter.sh,resources=nodeclaims/status,verbs=patch
+
+// nodeClaim returns the NodeClaim that launched node, or nil when Karpenter
+// support is off or the node wasn't launched by Karpenter
+func (r *NodeReconciler) nodeClaim(ctx context.Context, node *corev1.Node) (*unstructured.Unstructured, error) {
+	if !r.Karpenter {
+		return nil, nil
+	}
+	claims := &unstructured.UnstructuredList{}
+	claims.SetGroupVersionKind(NodeClaimGVK.GroupVersion().WithKind(NodeClaimGVK.Kind + "List"))
+	if err := r.List(ctx, claims, client.MatchingFields{nodeClaimNodeNameField: node.Name}); err != nil {
+		return nil, fmt.Errorf("failed to list NodeClaims: %w", err)
+	}
+	if len(claims.Items) == 0 {
+		return nil, nil
+	}
+	return &claims.Items[0], nil
+}
+
+// setNodeClaimCondition sets the Untainted condition of the node's NodeClaim
+// to False with reason while the node's target taints are blocked, or to True
+// once they are removed. Nothing is written when the condition already
+// matches. A conflicting write by Karpenter is retried on the next reconcile.
+func (r *NodeReconciler) setNodeClaimCondition(
+	ctx context.Context,
+	node *corev1.Node,
+	untainted bool,
+	reason, message string,
+) error {
+	claim, err := r.nodeClaim(ctx, node)
+	if err != nil || claim == nil {
+		return err
+	}
+	return r.patchNodeClaimCondition(ctx, claim, untainted, reason, message)
+}
+
+// clearNodeClaimCondition sets the Untainted condition of the node's
+// NodeClaim to True if it is False, for nodes whose target taints were
+// removed by someone else
+func (r *NodeReconciler) clearNodeClaimCondition(ctx context.Context, node *corev1.Node) error {
+	claim, err := r.nodeClaim(ctx, node)
+	if err != nil || claim == nil {
+		return err
+	}
+	conditions, err := nodeClaimConditions(claim)
+	if err != nil {
+		return err
+	}
+	if !meta.IsStatusConditionFalse(conditions, NodeClaimConditionType) {
+		return nil
+	}
+	return r.patchNodeClaimCondition(ctx, claim, true, untaintv1alpha1.ReasonTaintRemoved, "")
+}
+
+// patchNodeClaimCondition writes the Untainted condition to claim
+func (r *NodeReconciler) patchNodeClaimCondition(
+	ctx context.Context,
+	claim *unstructured.Unstructured,
+	untainted bool,
+	reason, message string,
+) error {
+	conditions, err := nodeClaimConditions(claim)
+	if err != nil {
+		return err
+	}
+	status := metav1.ConditionFalse
+	if untainted {
+		status = metav1.ConditionTrue
+	}
+	if message == "" {
+		// metav1.Condition requires a message
+		message = "target taints were removed"
+	}
+	if !meta.SetStatusCondition(&conditions, metav1.Condition{
+		Type:               NodeClaimConditionType,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: claim.GetGeneration(),
+	}) {
+		return nil
+	}
+
+	// Karpenter rewrites the whole conditions list, so only write on top of
+	// the version that was read
+	patch := client.MergeFromWithOptions(claim.DeepCopy(), client.MergeFromWithOptimisticLock{})
+	values := make([]interface{}, 0, len(conditions))
+	for _, condition := range conditions {
+		value, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&condition)
+		if err != nil {
+			return err
+		}
+		values = append(values, value)
+	}
+	if err := unstructured.SetNestedSlice(claim.Object, values, "status", "conditions"); err != nil {
+		return err
+	}
+	if err := r.Client.Status().Patch(ctx, claim, patch); err != nil {
+		return fmt.Errorf("failed to update NodeClaim condition: %w", err)
+	}
+	return nil
+}
+
+// nodeClaimConditions returns the status conditions of claim
+func nodeClaimConditions(claim *unstructured.Unstructured) ([]metav1.Condition, error) {
+	values, _, err := unstructured.NestedSlice(claim.Object, "status", "conditions")
+	if err != nil {
+		return nil, fmt.Errorf("NodeClaim %s has invalid conditions: %w", claim.GetName(), err)
+	}
+	conditions := make([]metav1.Condition, 0, len(values)+1)
+	for _, value := range values {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("NodeClaim %s has an invalid condition: %v", claim.GetName(), value)
+		}
+		condition := metav1.Condition{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(object, &condition); err != nil {
+			return nil, fmt.Errorf("NodeClaim %s has an invalid condition: %w", claim.GetName(), err)
+		}
+		conditions = append(conditions, condition)
+	}
+	return conditions, nil
+}
+
+// nodeClaimNodeName returns the name of the node launched for the NodeClaim obj
+func nodeClaimNodeName(obj client.Object) string {
+	claim, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return ""
+	}
+	name, _, _ := unstructured.NestedString(claim.Object, "status", "nodeName")
+	return name
+}
+
+// nodeClaimToNode maps a NodeClaim to the node it launched
+func nodeClaimToNode(_ context.Context, obj client.Object) []reconcile.Request {
+	name := nodeClaimNodeName(obj)
+	if name == "" {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: name}}}
+}
+
+// nodeClaimChangedPredicate passes NodeClaims once they are linked to their
+// node, which usually happens after the node registered with its startup
+// taints and was first reconciled
+func nodeClaimChangedPredicate() predicate.Funcs {
+	return predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool {
+			return nodeClaimNodeName(e.Object) != ""
+		},
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			return nodeClaimNodeName(e.ObjectOld) != nodeClaimNodeName(e.ObjectNew)
+		},
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			return false
+		},
+		GenericFunc: func(e event.GenericEvent) bool {
+			return false
+		},
+	}
+}
+
+// NodePoolReconciler checks the startupTaints declared on Karpenter NodePools
+// against the configured rules. Karpenter only considers a node initialized
+// once its startup taints are gone, so a startup taint no rule manages leaves
+// every node of the pool stuck; it is reported with a StartupTaintUnmanaged
+// warning event on the NodePool.
+type NodePoolReconciler struct {
+	client.Client
+	// Config supplies the taint rules the startup taints are checked against
+	Config *config.Store
+	// Recorder, when set, receives the events emitted on NodePools
+	Recorder record.EventRecorder
+}
+
+// +kubebuilder:rbac:groups=karpenter.sh,resources=nodepools,verbs=get;list;watch
+
+// Reconcile reports the startup taints of a NodePool that no rule manages
+func (r *NodePoolReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	pool := newNodePool()
+	if err := r.Get(ctx, req.NamespacedName, pool); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	taints, err := startupTaints(pool)
+	if err != nil {
+		log.Error(err, "Ignoring NodePool with invalid startupTaints", "nodePool", pool.GetName())
+		return ctrl.Result{}, nil
+	}
+	unmanaged := unmanagedTaints(taints, r.Config.Get().Rules)
+	if len(unmanaged) == 0 {
+		return ctrl.Result{}, nil
+	}
+	log.Info("NodePool declares startup taints no rule manages", "nodePool", pool.GetName(), "taints", unmanaged)
+	if r.Recorder != nil {
+		r.Recorder.Eventf(pool, corev1.EventTypeWarning, "StartupTaintUnmanaged",
+			"Startup taints %s are not managed by any rule, nodes of this NodePool will not initialize",
+			strings.Join(unmanaged, ", "))
+	}
+	return ctrl.Result{}, nil
+}
+
+// startupTaints returns the startupTaints of the node template of pool
+func startupTaints(pool *unstructured.Unstructured) ([]corev1.Taint, error) {
+	values, _, err := unstructured.NestedSlice(pool.Object, "spec", "template", "spec", "startupTaints")
+	if err != nil {
+		return nil, err
+	}
+	taints := make([]corev1.Taint, 0, len(values))
+	for _, value := range values {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid startup taint: %v", value)
+		}
+		taint := corev1.Taint{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(object, &taint); err != nil {
+			return nil, err
+		}
+		taints = append(taints, taint)
+	}
+	return taints, nil
+}
+
+// unmanagedTaints returns the key:effect of the taints none of rules manages
+func unmanagedTaints(taints []corev1.Taint, rules []config.Rule) []string {
+	var unmanaged []string
+	for _, taint := range taints {
+		managed := false
+		for _, rule := range rules {
+			managed = managed || rule.Manages(taint)
+		}
+		if !managed {
+			unmanaged = append(unmanaged, fmt.Sprintf("%s:%s", taint.Key, taint.Effect))
+		}
+	}
+	return unmanaged
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *NodePoolReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("nodepool").
+		For(newNodePool(), builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		// Check every NodePool again when the rules change
+		WatchesRawSource(source.Func(r.enqueueAllOnConfigChange)).
+		Complete(r)
+}
+
+// enqueueAllOnConfigChange queues every NodePool for reconciliation each time
+// the configuration store reports a change
+func (r *NodePoolReconciler) enqueueAllOnConfigChange(
+	ctx context.Context,
+	queue workqueue.TypedRateLimitingInterface[reconcile.Request],
+) error {
+	changes := r.Config.Subscribe()
+	go func() {
+		log := log.FromContext(ctx)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-changes:
+				pools := &unstructured.UnstructuredList{}
+				pools.SetGroupVersionKind(NodePoolGVK.GroupVersion().WithKind(NodePoolGVK.Kind + "List"))
+				if err := r.List(ctx, pools); err != nil {
+					log.Error(err, "Failed to list NodePools after config change")
+					continue
+				}
+				for _, pool := range pools.Items {
+					queue.Add(reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&pool)})
+				}
+			}
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,114 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	untaintv1alpha1 "github.com/jslay88/generic-untaint-operator/api/v1alpha1"
+	"github.com/jslay88/generic-untaint-operator/internal/config"
+)
+
+var _ = Describe("Karpenter", func() {
+	var (
+		ctx  context.Context
+		r    *NodeReconciler
+		node *corev1.Node
+	)
+
+	claimConditions := func() []metav1.Condition {
+		claim := newNodeClaim()
+		Expect(r.Get(ctx, client.ObjectKey{Name: "claim"}, claim)).To(Succeed())
+		conditions, err := nodeClaimConditions(claim)
+		Expect(err).NotTo(HaveOccurred())
+		return conditions
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		node = &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node"}}
+		claim := newNodeClaim()
+		claim.SetName("claim")
+		Expect(unstructured.SetNestedField(claim.Object, "node", "status", "nodeName")).To(Succeed())
+		Expect(unstructured.SetNestedSlice(claim.Object, []interface{}{map[string]interface{}{
+			"type":               "Launched",
+			"status":             "True",
+			"reason":             "Launched",
+			"message":            "",
+			"lastTransitionTime": "2025-01-01T00:00:00Z",
+		}}, "status", "conditions")).To(Succeed())
+
+		scheme := runtime.NewScheme()
+		Expect(corev1.AddToScheme(scheme)).To(Succeed())
+		r = &NodeReconciler{
+			Client: fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithObjects(claim).
+				WithStatusSubresource(claim).
+				WithIndex(newNodeClaim(), nodeClaimNodeNameField, func(obj client.Object) []string {
+					return []string{nodeClaimNodeName(obj)}
+				}).
+				Build(),
+			Karpenter: true,
+		}
+	})
+
+	It("should report the untaint progress on the node's NodeClaim", func() {
+		Expect(r.setNodeClaimCondition(ctx, node, false, untaintv1alpha1.ReasonWorkloadUnready,
+			"pod kube-system/agent-abc is not ready")).To(Succeed())
+		conditions := claimConditions()
+		Expect(meta.IsStatusConditionTrue(conditions, "Launched")).To(BeTrue())
+		condition := meta.FindStatusCondition(conditions, NodeClaimConditionType)
+		Expect(condition).NotTo(BeNil())
+		Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+		Expect(condition.Reason).To(Equal(untaintv1alpha1.ReasonWorkloadUnready))
+
+		Expect(r.clearNodeClaimCondition(ctx, node)).To(Succeed())
+		Expect(meta.IsStatusConditionTrue(claimConditions(), NodeClaimConditionType)).To(BeTrue())
+	})
+
+	It("should leave NodeClaims alone without Karpenter support", func() {
+		r.Karpenter = false
+		Expect(r.setNodeClaimCondition(ctx, node, true, untaintv1alpha1.ReasonTaintRemoved, "")).To(Succeed())
+		Expect(meta.FindStatusCondition(claimConditions(), NodeClaimConditionType)).To(BeNil())
+	})
+
+	It("should not fail for nodes without a NodeClaim", func() {
+		node.Name = "other"
+		Expect(r.setNodeClaimCondition(ctx, node, true, untaintv1alpha1.ReasonTaintRemoved, "")).To(Succeed())
+		Expect(r.clearNodeClaimCondition(ctx, node)).To(Succeed())
+	})
+
+	It("should warn about NodePool startup taints no rule manages", func() {
+		pool := newNodePool()
+		pool.SetName("default")
+		Expect(unstructured.SetNestedSlice(pool.Object, []interface{}{
+			map[string]interface{}{"key": "example.com/not-ready", "effect": "NoSchedule"},
+			map[string]interface{}{"key": "example.com/cni-not-ready", "effect": "NoExecute"},
+		}, "spec", "template", "spec", "startupTaints")).To(Succeed())
+		recorder := record.NewFakeRecorder(10)
+		pools := &NodePoolReconciler{
+			Client:   fake.NewClientBuilder().WithObjects(pool).Build(),
+			Config:   config.NewStore(&config.Config{Rules: []config.Rule{{TargetTaint: "example.com/not-ready"}}}),
+			Recorder: recorder,
+		}
+
+		_, err := pools.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKey{Name: "default"}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(recorder.Events).To(Receive(And(
+			ContainSubstring("StartupTaintUnmanaged"),
+			ContainSubstring("example.com/cni-not-ready:NoExecute"),
+			Not(ContainSubstring("example.com/not-ready:")),
+		)))
+	})
+})
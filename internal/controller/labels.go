@@ -0,0 +1,80 @@
+package controller
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/jslay88/generic-untaint-operator/internal/config"
+)
+
+// relabel adds the label changes of rule to the edit
+func (e *nodeEdit) relabel(rule config.Rule) {
+	for key, value := range rule.SetLabels {
+		if e.setLabels == nil {
+			e.setLabels = make(map[string]string, len(rule.SetLabels))
+		}
+		e.setLabels[key] = value
+	}
+	e.removeLabels = append(e.removeLabels, rule.RemoveLabels...)
+}
+
+// relabels reports whether the edit changes any label of a node with labels
+func (e nodeEdit) relabels(labels map[string]string) bool {
+	for key, value := range e.setLabels {
+		if current, ok := labels[key]; !ok || current != value {
+			return true
+		}
+	}
+	for _, key := range e.removeLabels {
+		_, set := e.setLabels[key]
+		if _, ok := labels[key]; ok && !set {
+			return true
+		}
+	}
+	return false
+}
+
+// labelPatchOps builds the JSON patch operations making the label changes of
+// edit to a node with labels, in a stable order
+func labelPatchOps(labels map[string]string, edit nodeEdit) []jsonPatchOp {
+	return metadataPatchOps("/metadata/labels", labels, edit.setLabels, edit.removeLabels)
+}
+
+// metadataPatchOps builds the JSON patch operations setting and removing
+// entries of the string map at path, whose current content is current. Keys
+// that are both set and removed are set.
+func metadataPatchOps(path string, current, set map[string]string, remove []string) []jsonPatchOp {
+	var ops []jsonPatchOp
+	for _, key := range remove {
+		if _, ok := set[key]; ok {
+			continue
+		}
+		if _, ok := current[key]; ok {
+			ops = append(ops, jsonPatchOp{Op: "remove", Path: metadataKeyPath(path, key)})
+		}
+	}
+	if len(set) == 0 {
+		return ops
+	}
+	if current == nil {
+		return append(ops, jsonPatchOp{Op: "add", Path: path, Value: set})
+	}
+
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		if value, ok := current[key]; !ok || value != set[key] {
+			ops = append(ops, jsonPatchOp{Op: "add", Path: metadataKeyPath(path, key), Value: set[key]})
+		}
+	}
+	return ops
+}
+
+// metadataKeyPath returns the JSON pointer to the entry key of the map at
+// path, escaping the key as RFC 6901 requires
+func metadataKeyPath(path, key string) string {
+	return path + "/" + strings.NewReplacer("~", "~0", "/", "~1").Replace(key)
+}
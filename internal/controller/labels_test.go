@@ -0,0 +1,52 @@
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/jslay88/generic-untaint-operator/internal/config"
+)
+
+var _ = Describe("node labels", func() {
+	edit := nodeEdit{}
+	edit.relabel(config.Rule{
+		SetLabels:    map[string]string{"example.com/ready": "true", "example.com/pool": "a"},
+		RemoveLabels: []string{"example.com/bootstrap", "example.com/pool"},
+	})
+
+	It("should set and remove labels, preferring labels that are set", func() {
+		node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{
+			"example.com/bootstrap": "pending",
+			"kubernetes.io/os":      "linux",
+		}}}
+		Expect(edit.relabels(node.Labels)).To(BeTrue())
+
+		edit.applyTo(node)
+		Expect(node.Labels).To(Equal(map[string]string{
+			"example.com/ready": "true",
+			"example.com/pool":  "a",
+			"kubernetes.io/os":  "linux",
+		}))
+		Expect(edit.relabels(node.Labels)).To(BeFalse())
+	})
+
+	It("should set labels on a node without any", func() {
+		node := &corev1.Node{}
+		edit.applyTo(node)
+		Expect(node.Labels).To(HaveKeyWithValue("example.com/ready", "true"))
+
+		Expect(labelPatchOps(nil, edit)).To(Equal([]jsonPatchOp{
+			{Op: "add", Path: "/metadata/labels", Value: edit.setLabels},
+		}))
+	})
+
+	It("should only patch the labels that change", func() {
+		labels := map[string]string{"example.com/bootstrap": "pending", "example.com/ready": "true"}
+		Expect(labelPatchOps(labels, edit)).To(Equal([]jsonPatchOp{
+			{Op: "remove", Path: "/metadata/labels/example.com~1bootstrap"},
+			{Op: "add", Path: "/metadata/labels/example.com~1pool", Value: "a"},
+		}))
+	})
+})
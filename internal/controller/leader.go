@@ -0,0 +1,116 @@
+package controller
+
+import (
+	"context"
+	"os"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// DefaultLeaderStatusInterval is how often the leader election lease is read
+// when LeaderStatus.Interval is unset
+const DefaultLeaderStatusInterval = 15 * time.Second
+
+// LeaderStatus keeps the leader metrics up to date on every replica, so HA
+// deployments can alert on leadership churn. Without leader election the
+// replica always leads, under its hostname.
+type LeaderStatus struct {
+	// Reader reads the leader election lease, uncached
+	Reader client.Reader
+	// Lease is the leader election lease. Leader election is disabled when
+	// its name is empty.
+	Lease types.NamespacedName
+	// Elected is closed once this replica became the leader
+	Elected <-chan struct{}
+	// Interval is how often the lease is read. Defaults to
+	// DefaultLeaderStatusInterval when unset.
+	Interval time.Duration
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable so every
+// replica reports whether it leads
+func (s *LeaderStatus) NeedLeaderElection() bool {
+	return false
+}
+
+// Start implements manager.Runnable and updates the leader metrics until ctx
+// is cancelled
+func (s *LeaderStatus) Start(ctx context.Context) error {
+	log := log.FromContext(ctx).WithName("leader-status")
+
+	interval := s.Interval
+	if interval <= 0 {
+		interval = DefaultLeaderStatusInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	elected := s.Elected
+	for {
+		leading := isClosed(s.Elected)
+		if err := s.update(ctx, leading); err != nil {
+			log.Error(err, "Failed to read the leader election lease", "lease", s.Lease)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		case <-elected:
+			// Report the new leadership right away, then only on ticks
+			elected = nil
+		}
+	}
+}
+
+// update sets the leader metrics from the leader election lease
+func (s *LeaderStatus) update(ctx context.Context, leading bool) error {
+	if s.Lease.Name == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return err
+		}
+		setLeader(hostname, leading, 0)
+		return nil
+	}
+
+	lease := &coordinationv1.Lease{}
+	if err := s.Reader.Get(ctx, s.Lease, lease); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	var identity string
+	if lease.Spec.HolderIdentity != nil {
+		identity = *lease.Spec.HolderIdentity
+	}
+	var transitions int32
+	if lease.Spec.LeaseTransitions != nil {
+		transitions = *lease.Spec.LeaseTransitions
+	}
+	setLeader(identity, leading, transitions)
+	return nil
+}
+
+// setLeader reports identity as the current leader, whether this replica is
+// it, and how often leadership changed hands
+func setLeader(identity string, leading bool, transitions int32) {
+	leaderGauge.Reset()
+	value := 0.0
+	if leading {
+		value = 1
+	}
+	leaderGauge.WithLabelValues(identity).Set(value)
+	leaderTransitionsGauge.Set(float64(transitions))
+}
+
+// isClosed reports whether ch is closed
+func isClosed(ch <-chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,58 @@
+package controller
+
+import (
+	"context"
+	"os"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("leader metrics", func() {
+	lease := types.NamespacedName{Namespace: "generic-untaint-operator-system", Name: "leader"}
+
+	AfterEach(func() {
+		leaderGauge.Reset()
+		leaderTransitionsGauge.Set(0)
+	})
+
+	It("should report the leader of the lease", func() {
+		status := &LeaderStatus{
+			Reader: fake.NewClientBuilder().WithObjects(&coordinationv1.Lease{
+				ObjectMeta: metav1.ObjectMeta{Namespace: lease.Namespace, Name: lease.Name},
+				Spec: coordinationv1.LeaseSpec{
+					HolderIdentity:   ptr.To("replica-b_1234"),
+					LeaseTransitions: ptr.To[int32](3),
+				},
+			}).Build(),
+			Lease: lease,
+		}
+		Expect(status.update(context.Background(), false)).To(Succeed())
+		Expect(testutil.CollectAndCount(leaderGauge)).To(Equal(1))
+		Expect(testutil.ToFloat64(leaderGauge.WithLabelValues("replica-b_1234"))).To(BeZero())
+		Expect(testutil.ToFloat64(leaderTransitionsGauge)).To(Equal(3.0))
+
+		Expect(status.update(context.Background(), true)).To(Succeed())
+		Expect(testutil.ToFloat64(leaderGauge.WithLabelValues("replica-b_1234"))).To(Equal(1.0))
+	})
+
+	It("should wait for the lease to be created", func() {
+		status := &LeaderStatus{Reader: fake.NewClientBuilder().Build(), Lease: lease}
+		Expect(status.update(context.Background(), false)).To(Succeed())
+		Expect(testutil.CollectAndCount(leaderGauge)).To(BeZero())
+	})
+
+	It("should lead under the hostname without leader election", func() {
+		hostname, err := os.Hostname()
+		Expect(err).NotTo(HaveOccurred())
+		status := &LeaderStatus{}
+		Expect(status.update(context.Background(), true)).To(Succeed())
+		Expect(testutil.ToFloat64(leaderGauge.WithLabelValues(hostname))).To(Equal(1.0))
+	})
+})
@@ -0,0 +1,58 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+
+	untaintv1alpha1 "github.com/jslay88/generic-untaint-operator/api/v1alpha1"
+	"github.com/jslay88/generic-untaint-operator/internal/config"
+)
+
+// +kubebuilder:rbac:groups=coordination.k8s.io,resources=leases,verbs=get
+
+// kubeletLeaseStale returns why the node's kubelet Lease rules out removing its
+// taints, or nil when it was renewed within the configured maximum age. The
+// Lease is read from the API server, since caching every node's heartbeat
+// would churn the cache.
+func (r *NodeReconciler) kubeletLeaseStale(ctx context.Context, node *corev1.Node, cfg *config.Config,
+	now time.Time) (*blockReason, error) {
+	if cfg.MaxLeaseAge.Duration <= 0 {
+		return nil, nil
+	}
+
+	lease := &coordinationv1.Lease{}
+	err := r.apiReader().Get(ctx, types.NamespacedName{Namespace: corev1.NamespaceNodeLease, Name: node.Name}, lease)
+	if apierrors.IsNotFound(err) {
+		return &blockReason{
+			reason:  untaintv1alpha1.ReasonKubeletLeaseStale,
+			message: "node has no kubelet lease",
+		}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get kubelet lease: %w", err)
+	}
+	return leaseStale(lease, cfg.MaxLeaseAge.Duration, now), nil
+}
+
+// leaseStale returns why lease was not renewed within maxAge, or nil when it was
+func leaseStale(lease *coordinationv1.Lease, maxAge time.Duration, now time.Time) *blockReason {
+	if lease.Spec.RenewTime == nil {
+		return &blockReason{
+			reason:  untaintv1alpha1.ReasonKubeletLeaseStale,
+			message: "kubelet lease was never renewed",
+		}
+	}
+	if age := now.Sub(lease.Spec.RenewTime.Time); age > maxAge {
+		return &blockReason{
+			reason:  untaintv1alpha1.ReasonKubeletLeaseStale,
+			message: fmt.Sprintf("kubelet lease was last renewed %s ago", age.Round(time.Second)),
+		}
+	}
+	return nil
+}
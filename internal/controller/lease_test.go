@@ -0,0 +1,35 @@
+package controller
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	untaintv1alpha1 "github.com/jslay88/generic-untaint-operator/api/v1alpha1"
+)
+
+var _ = Describe("leaseStale", func() {
+	now := time.Now()
+	renewedAt := func(at time.Time) *coordinationv1.Lease {
+		renewTime := metav1.NewMicroTime(at)
+		return &coordinationv1.Lease{Spec: coordinationv1.LeaseSpec{RenewTime: &renewTime}}
+	}
+
+	It("should pass a recently renewed lease", func() {
+		Expect(leaseStale(renewedAt(now.Add(-10*time.Second)), 40*time.Second, now)).To(BeNil())
+	})
+
+	It("should block on a lease renewed too long ago", func() {
+		reason := leaseStale(renewedAt(now.Add(-time.Minute)), 40*time.Second, now)
+		Expect(reason).NotTo(BeNil())
+		Expect(reason.reason).To(Equal(untaintv1alpha1.ReasonKubeletLeaseStale))
+		Expect(reason.message).To(ContainSubstring("1m0s ago"))
+	})
+
+	It("should block on a lease that was never renewed", func() {
+		Expect(leaseStale(&coordinationv1.Lease{}, 40*time.Second, now)).NotTo(BeNil())
+	})
+})
@@ -0,0 +1,304 @@
+package controller
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	untaintv1alpha1 "github.com/jslay88/generic-untaint-operator/api/v1alpha1"
+	"github.com/jslay88/generic-untaint-operator/internal/config"
+)
+
+// taintReaddedTotal counts target taints put back on a node after the
+// operator removed them
+var taintReaddedTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "untaint_operator_taint_readded_total",
+		Help: "Number of times a target taint was re-added to a node after the operator removed it",
+	},
+	[]string{"taint"},
+)
+
+// blockedNodesGauge counts the nodes still carrying a target taint by why
+// they are blocked, so alerts can fire when nodes pile up on one reason
+var blockedNodesGauge = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "untaint_operator_blocked_nodes",
+		Help: "Number of nodes still carrying a target taint, by the reason they are blocked",
+	},
+	[]string{"reason"},
+)
+
+// blockingWorkloadTotal counts, per configured workload, how often its pods
+// started holding the target taints of a node in place
+var blockingWorkloadTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "untaint_operator_blocking_workload_total",
+		Help: "Number of times the pods of a workload blocked removing the target taints of a node",
+	},
+	[]string{"workload"},
+)
+
+// untaintDurationSeconds observes how long after a node was created each of
+// its target taints was removed, measuring node bootstrap latency
+var untaintDurationSeconds = prometheus.NewHistogramVec(
+	prometheus.HistogramOpts{
+		Name: "untaint_operator_untaint_duration_seconds",
+		Help: "Time from node creation until the operator removed a target taint",
+		// 5s up to about 43m
+		Buckets: prometheus.ExponentialBuckets(5, 2, 10),
+	},
+	[]string{"taint"},
+)
+
+// taintCarryDurationSeconds summarizes how long nodes carried each target
+// taint until the operator removed it, so node readiness SLOs can be read off
+// its quantiles
+var taintCarryDurationSeconds = prometheus.NewSummaryVec(
+	prometheus.SummaryOpts{
+		Name:       "untaint_operator_taint_carry_duration_seconds",
+		Help:       "Time a node carried a target taint until the operator removed it",
+		Objectives: map[float64]float64{0.5: 0.05, 0.95: 0.01, 0.99: 0.001},
+	},
+	[]string{"taint"},
+)
+
+// stuckNodesGauge counts the nodes blocked for longer than the stuck node
+// threshold, by why they are blocked
+var stuckNodesGauge = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "untaint_operator_stuck_nodes",
+		Help: "Number of nodes blocked for longer than the stuck node threshold, by the reason they are blocked",
+	},
+	[]string{"reason"},
+)
+
+// reconcileErrorsTotal counts the failures met while reconciling nodes by
+// reason, so noisy failure modes show up on dashboards
+var reconcileErrorsTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "untaint_operator_reconcile_errors_total",
+		Help: "Number of failures while reconciling nodes, by reason",
+	},
+	[]string{"reason"},
+)
+
+// nodeUpdateConflictsTotal counts the writes to a node that conflicted with
+// another change to it, by removal strategy, to show when the operator fights
+// kubelet, autoscalers or other controllers over spec.taints
+var nodeUpdateConflictsTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "untaint_operator_node_update_conflicts_total",
+		Help: "Number of node writes that conflicted with another change to the node, by removal strategy",
+	},
+	[]string{"strategy"},
+)
+
+// nodeUpdateRetriesTotal counts the writes to a node retried against a fresh
+// read of the node after a conflict, by removal strategy
+var nodeUpdateRetriesTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "untaint_operator_node_update_retries_total",
+		Help: "Number of node writes retried after a conflict, by removal strategy",
+	},
+	[]string{"strategy"},
+)
+
+// nodeUntaintThrottledTotal counts the untaints held back because their node
+// reached its cap of untaint attempts per minute
+var nodeUntaintThrottledTotal = prometheus.NewCounter(
+	prometheus.CounterOpts{
+		Name: "untaint_operator_node_untaint_throttled_total",
+		Help: "Number of untaints held back because their node was untainted too often within a minute",
+	},
+)
+
+// nodeWriteWaitSeconds adds up the time node writes waited for the node write
+// rate limit, which shows when the limit holds the operator back
+var nodeWriteWaitSeconds = prometheus.NewCounter(
+	prometheus.CounterOpts{
+		Name: "untaint_operator_node_write_wait_seconds_total",
+		Help: "Total time node writes waited for the node write rate limit",
+	},
+)
+
+// reconcileWaitSeconds adds up the time reconciles waited for the reconcile
+// rate limit, which shows when a burst of queued nodes was spread out
+var reconcileWaitSeconds = prometheus.NewCounter(
+	prometheus.CounterOpts{
+		Name: "untaint_operator_reconcile_wait_seconds_total",
+		Help: "Total time reconciles waited for the reconcile rate limit",
+	},
+)
+
+// nodeWritesTotal counts the writes made to nodes, by removal strategy. All
+// changes made to a node in one reconcile share a single write.
+var nodeWritesTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "untaint_operator_node_writes_total",
+		Help: "Number of writes made to nodes, including retries, by removal strategy",
+	},
+	[]string{"strategy"},
+)
+
+// leaderGauge is 1 on the replica leading and 0 on the others, labelled with
+// the identity of the current leader
+var leaderGauge = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "untaint_operator_leader",
+		Help: "Whether this replica is the leader (1) or not (0), by the identity of the current leader",
+	},
+	[]string{"identity"},
+)
+
+// leaderTransitionsGauge mirrors the transitions recorded on the leader
+// election lease
+var leaderTransitionsGauge = prometheus.NewGauge(
+	prometheus.GaugeOpts{
+		Name: "untaint_operator_leader_transitions",
+		Help: "Number of times leadership changed hands, as recorded on the leader election lease",
+	},
+)
+
+// The reasons of reconcileErrorsTotal
+const (
+	// errorListPods means listing the pods of a node failed
+	errorListPods = "ListPodsFailed"
+	// errorNodeConflict means a node changed while the operator wrote to it
+	errorNodeConflict = untaintv1alpha1.ReasonUpdateConflict
+	// errorNodeNotFound means a node was deleted before the operator wrote it
+	errorNodeNotFound = "NodeNotFound"
+	// errorNodeUpdate means writing to a node failed for any other reason
+	errorNodeUpdate = "NodeUpdateFailed"
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		taintReaddedTotal,
+		blockedNodesGauge,
+		blockingWorkloadTotal,
+		untaintDurationSeconds,
+		taintCarryDurationSeconds,
+		stuckNodesGauge,
+		reconcileErrorsTotal,
+		nodeUpdateConflictsTotal,
+		nodeUpdateRetriesTotal,
+		nodeWritesTotal,
+		nodeWriteWaitSeconds,
+		reconcileWaitSeconds,
+		nodeUntaintThrottledTotal,
+		leaderGauge,
+		leaderTransitionsGauge,
+	)
+}
+
+// countError counts a reconcile failure for reason
+func countError(reason string) {
+	reconcileErrorsTotal.WithLabelValues(reason).Inc()
+}
+
+// nodeConflict reports whether err is a conflict writing a node with
+// strategy, counting it
+func nodeConflict(strategy config.RemovalStrategy, err error) bool {
+	if !apierrors.IsConflict(err) {
+		return false
+	}
+	countConflict(strategy)
+	return true
+}
+
+// countConflict counts a conflict writing a node with strategy
+func countConflict(strategy config.RemovalStrategy) {
+	countError(errorNodeConflict)
+	nodeUpdateConflictsTotal.WithLabelValues(string(strategy)).Inc()
+}
+
+// countRetry counts a node write with strategy retried after a conflict
+func countRetry(strategy config.RemovalStrategy) {
+	nodeUpdateRetriesTotal.WithLabelValues(string(strategy)).Inc()
+}
+
+// countWrite counts a node write with strategy
+func countWrite(strategy config.RemovalStrategy) {
+	nodeWritesTotal.WithLabelValues(string(strategy)).Inc()
+}
+
+// observeUntaint records how long after node was created taint was removed
+// at now
+func observeUntaint(node *corev1.Node, taint string, now time.Time) {
+	if node.CreationTimestamp.IsZero() {
+		return
+	}
+	untaintDurationSeconds.WithLabelValues(taint).Observe(now.Sub(node.CreationTimestamp.Time).Seconds())
+}
+
+// observeTaintCarry records that a taint added at since was removed at now
+func observeTaintCarry(taint string, since, now time.Time) {
+	taintCarryDurationSeconds.WithLabelValues(taint).Observe(now.Sub(since).Seconds())
+}
+
+// blockedNodes tracks why each blocked node is blocked and since when, for the
+// blocked nodes debug endpoint, and keeps the blocked and stuck nodes gauges in
+// step. The zero value is ready to use.
+type blockedNodes struct {
+	mu    sync.Mutex
+	nodes map[string]BlockedNode
+	// stuck are the blocked nodes counted by the stuck nodes gauge
+	stuck map[string]struct{}
+}
+
+// set records that the node of block is blocked as described at now,
+// keeping when it was first blocked, and counts it as stuck once it has been
+// blocked for stuckAfter
+func (b *blockedNodes) set(block BlockedNode, now time.Time, stuckAfter time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	previous, ok := b.nodes[block.Node]
+	block.Since = now
+	if ok {
+		block.Since = previous.Since
+		if previous.Reason != block.Reason {
+			blockedNodesGauge.WithLabelValues(previous.Reason).Dec()
+		}
+	}
+	if !ok || previous.Reason != block.Reason {
+		blockedNodesGauge.WithLabelValues(block.Reason).Inc()
+	}
+	if b.nodes == nil {
+		b.nodes = make(map[string]BlockedNode)
+		b.stuck = make(map[string]struct{})
+	}
+	b.nodes[block.Node] = block
+
+	_, wasStuck := b.stuck[block.Node]
+	if wasStuck {
+		stuckNodesGauge.WithLabelValues(previous.Reason).Dec()
+		delete(b.stuck, block.Node)
+	}
+	if stuckAfter > 0 && now.Sub(block.Since) >= stuckAfter {
+		stuckNodesGauge.WithLabelValues(block.Reason).Inc()
+		b.stuck[block.Node] = struct{}{}
+	}
+}
+
+// forget records that node is no longer blocked
+func (b *blockedNodes) forget(node string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	previous, ok := b.nodes[node]
+	if !ok {
+		return
+	}
+	blockedNodesGauge.WithLabelValues(previous.Reason).Dec()
+	if _, stuck := b.stuck[node]; stuck {
+		stuckNodesGauge.WithLabelValues(previous.Reason).Dec()
+		delete(b.stuck, node)
+	}
+	delete(b.nodes, node)
+}
@@ -0,0 +1,219 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	untaintv1alpha1 "github.com/jslay88/generic-untaint-operator/api/v1alpha1"
+	"github.com/jslay88/generic-untaint-operator/internal/config"
+)
+
+var _ = Describe("blockedNodes", func() {
+	const (
+		waiting = "metrics.test/Waiting"
+		paused  = "metrics.test/Paused"
+	)
+	count := func(reason string) float64 {
+		return testutil.ToFloat64(blockedNodesGauge.WithLabelValues(reason))
+	}
+
+	It("should count each blocked node under its current reason", func() {
+		blocked := &blockedNodes{}
+		now := time.Now()
+		blocked.set(BlockedNode{Node: "a", Reason: waiting}, now, 0)
+		blocked.set(BlockedNode{Node: "a", Reason: waiting}, now, 0)
+		blocked.set(BlockedNode{Node: "b", Reason: waiting}, now, 0)
+		Expect(count(waiting)).To(Equal(2.0))
+
+		blocked.set(BlockedNode{Node: "a", Reason: paused}, now, 0)
+		Expect(count(waiting)).To(Equal(1.0))
+		Expect(count(paused)).To(Equal(1.0))
+
+		blocked.forget("a")
+		blocked.forget("b")
+		blocked.forget("c")
+		Expect(count(waiting)).To(BeZero())
+		Expect(count(paused)).To(BeZero())
+	})
+
+	It("should count nodes blocked beyond the threshold as stuck", func() {
+		stuck := func(reason string) float64 {
+			return testutil.ToFloat64(stuckNodesGauge.WithLabelValues(reason))
+		}
+		blocked := &blockedNodes{}
+		now := time.Now()
+		blocked.set(BlockedNode{Node: "a", Reason: waiting}, now, time.Hour)
+		blocked.set(BlockedNode{Node: "a", Reason: waiting}, now.Add(30*time.Minute), time.Hour)
+		Expect(stuck(waiting)).To(BeZero())
+
+		blocked.set(BlockedNode{Node: "a", Reason: waiting}, now.Add(time.Hour), time.Hour)
+		blocked.set(BlockedNode{Node: "a", Reason: waiting}, now.Add(2*time.Hour), time.Hour)
+		Expect(stuck(waiting)).To(Equal(1.0))
+
+		blocked.set(BlockedNode{Node: "a", Reason: paused}, now.Add(3*time.Hour), time.Hour)
+		Expect(stuck(waiting)).To(BeZero())
+		Expect(stuck(paused)).To(Equal(1.0))
+
+		blocked.forget("a")
+		Expect(stuck(paused)).To(BeZero())
+	})
+})
+
+var _ = Describe("observeTaintCarry", func() {
+	It("should observe how long the taint was carried", func() {
+		const taint = "metrics.test/carried"
+		summary := func() *dto.Summary {
+			metric := &dto.Metric{}
+			observer := taintCarryDurationSeconds.WithLabelValues(taint).(prometheus.Summary)
+			Expect(observer.Write(metric)).To(Succeed())
+			return metric.GetSummary()
+		}
+		now := time.Now()
+		observeTaintCarry(taint, now.Add(-2*time.Minute), now)
+		Expect(summary().GetSampleCount()).To(Equal(uint64(1)))
+		Expect(summary().GetSampleSum()).To(Equal(120.0))
+		Expect(summary().GetQuantile()).To(HaveLen(3))
+	})
+})
+
+var _ = Describe("observeUntaint", func() {
+	It("should observe the time since the node was created", func() {
+		const taint = "metrics.test/not-ready"
+		histogram := func() *dto.Histogram {
+			metric := &dto.Metric{}
+			observer := untaintDurationSeconds.WithLabelValues(taint).(prometheus.Histogram)
+			Expect(observer.Write(metric)).To(Succeed())
+			return metric.GetHistogram()
+		}
+		created := time.Now().Add(-90 * time.Second)
+		observeUntaint(&corev1.Node{}, taint, time.Now())
+		Expect(histogram().GetSampleCount()).To(BeZero())
+
+		observeUntaint(&corev1.Node{ObjectMeta: metav1.ObjectMeta{CreationTimestamp: metav1.NewTime(created)}},
+			taint, created.Add(90*time.Second))
+		Expect(histogram().GetSampleCount()).To(Equal(uint64(1)))
+		Expect(histogram().GetSampleSum()).To(Equal(90.0))
+	})
+})
+
+var _ = Describe("reconcileErrorsTotal", func() {
+	count := func(reason string) float64 {
+		return testutil.ToFloat64(reconcileErrorsTotal.WithLabelValues(reason))
+	}
+	edit := nodeEdit{taints: taintEdits{"example.com/not-ready": {}}}
+	cfg := &config.Config{RemovalStrategy: config.RemovalStrategyPatch}
+
+	It("should count each conflict writing a node", func() {
+		node := &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "node"},
+			Spec:       corev1.NodeSpec{Taints: []corev1.Taint{{Key: "example.com/not-ready", Effect: "NoSchedule"}}},
+		}
+		conflicts := 0
+		r := &NodeReconciler{Client: fake.NewClientBuilder().WithObjects(node).WithInterceptorFuncs(interceptor.Funcs{
+			Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch,
+				opts ...client.PatchOption) error {
+				if conflicts < 2 {
+					conflicts++
+					return apierrors.NewConflict(schema.GroupResource{Resource: "nodes"}, obj.GetName(), nil)
+				}
+				return c.Patch(ctx, obj, patch, opts...)
+			},
+		}).Build()}
+		before := count(errorNodeConflict)
+		conflictsBefore := testutil.ToFloat64(nodeUpdateConflictsTotal.WithLabelValues(string(config.RemovalStrategyPatch)))
+		retriesBefore := testutil.ToFloat64(nodeUpdateRetriesTotal.WithLabelValues(string(config.RemovalStrategyPatch)))
+		Expect(r.updateNode(context.Background(), node.DeepCopy(), edit, cfg)).To(Succeed())
+		Expect(count(errorNodeConflict)).To(Equal(before + 2))
+		Expect(testutil.ToFloat64(nodeUpdateConflictsTotal.WithLabelValues(string(config.RemovalStrategyPatch)))).
+			To(Equal(conflictsBefore + 2))
+		Expect(testutil.ToFloat64(nodeUpdateRetriesTotal.WithLabelValues(string(config.RemovalStrategyPatch)))).
+			To(Equal(retriesBefore + 2))
+	})
+
+	It("should report conflicts outlasting the retries", func() {
+		node := &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "node"},
+			Spec:       corev1.NodeSpec{Taints: []corev1.Taint{{Key: "example.com/not-ready", Effect: "NoSchedule"}}},
+		}
+		recorder := record.NewFakeRecorder(10)
+		r := &NodeReconciler{Recorder: recorder, Client: fake.NewClientBuilder().WithObjects(node).WithInterceptorFuncs(
+			interceptor.Funcs{
+				Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch,
+					opts ...client.PatchOption) error {
+					return apierrors.NewConflict(schema.GroupResource{Resource: "nodes"}, obj.GetName(), nil)
+				},
+			}).Build()}
+		Expect(r.updateNode(context.Background(), node.DeepCopy(), edit, cfg)).NotTo(Succeed())
+		Expect(recorder.Events).To(Receive(HavePrefix("Warning " + untaintv1alpha1.ReasonUpdateConflict)))
+	})
+
+	It("should count nodes deleted before they were written", func() {
+		r := &NodeReconciler{Client: fake.NewClientBuilder().Build()}
+		before := count(errorNodeNotFound)
+		node := &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "gone", ResourceVersion: "1"},
+			Spec:       corev1.NodeSpec{Taints: []corev1.Taint{{Key: "example.com/not-ready", Effect: "NoSchedule"}}},
+		}
+		Expect(r.updateNode(context.Background(), node, edit, cfg)).NotTo(Succeed())
+		Expect(count(errorNodeNotFound)).To(Equal(before + 1))
+	})
+})
+
+var _ = Describe("nodeWritesTotal", func() {
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node", Labels: map[string]string{"example.com/pending": "true"}},
+		Spec: corev1.NodeSpec{Taints: []corev1.Taint{
+			{Key: "example.com/not-ready", Effect: "NoSchedule"},
+			{Key: "example.com/agent-not-ready", Effect: "NoExecute"},
+			{Key: "example.com/kept", Effect: "NoSchedule"},
+		}},
+	}
+	edit := nodeEdit{
+		taints:         taintEdits{"example.com/not-ready": {}, "example.com/agent-not-ready": {}},
+		setLabels:      map[string]string{"example.com/ready": "true"},
+		removeLabels:   []string{"example.com/pending"},
+		setAnnotations: map[string]string{"example.com/untainted-at": "now"},
+	}
+
+	for _, strategy := range []config.RemovalStrategy{config.RemovalStrategyPatch, config.RemovalStrategyJSONPatch} {
+		It("should make all changes to a node in a single "+string(strategy)+" write", func() {
+			patches := 0
+			r := &NodeReconciler{Client: fake.NewClientBuilder().WithObjects(node.DeepCopy()).WithInterceptorFuncs(
+				interceptor.Funcs{
+					Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch,
+						opts ...client.PatchOption) error {
+						patches++
+						return c.Patch(ctx, obj, patch, opts...)
+					},
+				}).Build()}
+			before := testutil.ToFloat64(nodeWritesTotal.WithLabelValues(string(strategy)))
+			updated := &corev1.Node{}
+			Expect(r.Get(context.Background(), client.ObjectKeyFromObject(node), updated)).To(Succeed())
+			cfg := &config.Config{RemovalStrategy: strategy}
+			Expect(r.updateNode(context.Background(), updated, edit, cfg)).To(Succeed())
+			Expect(patches).To(Equal(1))
+			Expect(testutil.ToFloat64(nodeWritesTotal.WithLabelValues(string(strategy)))).To(Equal(before + 1))
+			Expect(updated.Spec.Taints).To(ConsistOf(HaveField("Key", "example.com/kept")))
+			Expect(updated.Labels).To(Equal(map[string]string{"example.com/ready": "true"}))
+			Expect(updated.Annotations).To(HaveKeyWithValue("example.com/untainted-at", "now"))
+
+			// Once made, the same edit doesn't write again
+			Expect(r.updateNode(context.Background(), updated, edit, cfg)).To(Succeed())
+			Expect(patches).To(Equal(1))
+		})
+	}
+})
@@ -3,15 +3,44 @@ package controller
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"slices"
+	"strings"
 	"time"
 
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/flowcontrol"
+	"k8s.io/client-go/util/workqueue"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	untaintv1alpha1 "github.com/jslay88/generic-untaint-operator/api/v1alpha1"
+	"github.com/jslay88/generic-untaint-operator/internal/config"
+)
+
+const (
+	// RequiredWorkloadsAnnotation replaces the configured workload names for a
+	// node with a comma-separated list of names
+	RequiredWorkloadsAnnotation = "untaint-operator.jslay88.github.io/required-workloads"
+	// AdditionalWorkloadsAnnotation adds a comma-separated list of workload names
+	// to the ones required for a node
+	AdditionalWorkloadsAnnotation = "untaint-operator.jslay88.github.io/additional-workloads"
 )
 
 // NodeReconciler reconciles a Node object
@@ -22,136 +51,1018 @@ type NodeReconciler struct {
 	TargetTaint string
 	// OwnedByNames is a list of workload names to check for readiness
 	OwnedByNames []string
+	// Config supplies the taint rules at runtime. When set, it takes precedence
+	// over TargetTaint and OwnedByNames.
+	Config *config.Store
+	// Status, when set, receives the outcome of every reconcile
+	Status *PolicyStatusReporter
+	// APIReader reads nodes straight from the API server when retrying a write
+	// that conflicted. Defaults to the client when unset.
+	APIReader client.Reader
+	// Recorder, when set, receives the events emitted on nodes
+	Recorder record.EventRecorder
+	// Lifecycle completes the lifecycle actions of the CompleteLifecycleAction
+	// untaint action
+	Lifecycle LifecycleCompleter
+	// CloudEvents, when set, receives the untaint lifecycle of every node
+	CloudEvents *CloudEventPublisher
+	// Notifier, when set, posts the configured notifications
+	Notifier *Notifier
+	// Audit, when set, records every change made to a target taint
+	Audit *AuditLog
+	// Karpenter reports the untaint progress of nodes launched by Karpenter as
+	// a condition of their NodeClaim. Requires the Karpenter CRDs.
+	Karpenter bool
+	// ClusterAPI reports the untaint progress of nodes backed by a Cluster API
+	// Machine as a condition of the Machine
+	ClusterAPI bool
+	// SkipRemediation, together with ClusterAPI, keeps MachineHealthChecks
+	// from remediating the Machine of a node while its target taints are blocked
+	SkipRemediation bool
+	// CNI, when set, additionally requires the custom resources of that
+	// network plugin to report the node's datapath ready. Requires its CRDs.
+	CNI CNIProvider
+	// HookClient sends the requests to the untaint hooks. Defaults to
+	// http.DefaultClient when unset.
+	HookClient *http.Client
+	// MaxConcurrentReconciles is how many nodes are reconciled in parallel.
+	// Defaults to 1 when unset.
+	MaxConcurrentReconciles int
+	// NodeSelector, when set, is the label selector the node cache is
+	// restricted to. Events of other objects on the nodes it doesn't match are
+	// dropped before they reach the work queue.
+	NodeSelector labels.Selector
+	// PodSelector, when set, is the label selector the pod cache is restricted
+	// to. Canary pods it doesn't match are read from the API server instead.
+	PodSelector labels.Selector
+	// PodNamespaces, when set, are the namespaces the pod cache is restricted
+	// to. Canary pods outside of them are read from the API server instead.
+	PodNamespaces []string
+	// ShardCount, when above 1, splits the nodes into that many shards, of
+	// which this replica only reconciles the one numbered ShardIndex, counting
+	// from 0
+	ShardCount int
+	ShardIndex int
+	// ResyncPeriod, when set, is how often every node carrying a target taint
+	// is queued again even if no event fired for it
+	ResyncPeriod time.Duration
+	// PrioritizeNewNodes reconciles the most recently created nodes first
+	// rather than in the order they were queued
+	PrioritizeNewNodes bool
+	// UncachedPods lists the pods of each reconciled node from the API server,
+	// a page at a time, instead of watching and caching every pod. Pod changes
+	// are then only noticed on the next requeue.
+	UncachedPods bool
+	// NodeWriteLimiter, when set, caps the rate of the writes made to nodes
+	// across all workers
+	NodeWriteLimiter flowcontrol.RateLimiter
+	// ReconcileLimiter, when set, caps the rate of reconciles across all
+	// workers, spreading out the burst of queued nodes a relist can cause
+	ReconcileLimiter flowcontrol.RateLimiter
+
+	inFlight     nodeLocks
+	untaints     untaintLimiter
+	nodeAttempts nodeUntaintAttempts
+	blocked      blockedNodes
+	actions      recentActions
+	backoff      requeueBackoff
+	cooldown     untaintCooldown
+	restarts     restartTracker
+	ages         taintAges
+	stages       stageTimes
+	removed      removedTaints
+	blockEvents  blockEvents
+	blockLogs    blockLogs
+	stuck        stuckNotices
+	lifecycles   pendingLifecycles
+	initialPass  initialPass
+}
+
+// apiReader returns the reader used to fetch the latest version of a node
+func (r *NodeReconciler) apiReader() client.Reader {
+	if r.APIReader != nil {
+		return r.APIReader
+	}
+	return r.Client
 }
 
-// +kubebuilder:rbac:groups=core,resources=nodes,verbs=get;list;watch;update;patch
+// blockReason explains why a rule's taint can't be removed from a node yet
+type blockReason struct {
+	reason  string
+	message string
+	// retryAfter, when set, is when the block is known to clear unless the
+	// pods change in the meantime
+	retryAfter time.Duration
+	// workloads, when set, are the configured workloads whose pods hold the
+	// taint in place
+	workloads []string
+	// paused is set when untainting is paused, which no maxWait overrides
+	paused bool
+}
+
+// currentConfig returns the configuration to use for a single reconcile
+func (r *NodeReconciler) currentConfig() *config.Config {
+	if r.Config != nil {
+		return r.Config.Get()
+	}
+	cfg := &config.Config{
+		Rules: []config.Rule{{TargetTaint: r.TargetTaint, OwnedByNames: r.OwnedByNames}},
+	}
+	cfg.Default()
+	return cfg
+}
+
+// +kubebuilder:rbac:groups=core,resources=nodes,verbs=get;list;watch;patch
 // +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch
+// +kubebuilder:rbac:groups=apps,resources=daemonsets,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
 func (r *NodeReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := log.FromContext(ctx)
-	node := &corev1.Node{}
 
+	// Nodes of other shards are queued by pod and config changes, but left
+	// to their own replicas
+	if !r.ownsNode(req.Name) {
+		return ctrl.Result{}, nil
+	}
+	if err := r.waitReconcile(ctx); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Never work on the same node from two workers at once, or both could
+	// remove its taints and emit the same events
+	if !r.inFlight.tryLock(req.Name) {
+		log.Info("Node is already being reconciled, retrying")
+		return ctrl.Result{RequeueAfter: inFlightRetry}, nil
+	}
+	defer r.inFlight.unlock(req.Name)
+	defer r.initialPass.done(req.Name)
+
+	node := &corev1.Node{}
 	if err := r.Get(ctx, req.NamespacedName, node); err != nil {
+		// Deleted nodes are queued to drop what is tracked for them
+		if apierrors.IsNotFound(err) {
+			r.backoff.reset(req.Name)
+			r.cooldown.forget(req.Name)
+			r.removed.forget(req.Name)
+			r.nodeAttempts.forget(req.Name)
+			r.blockEvents.forget(req.Name)
+			r.blockLogs.forget(req.Name)
+			r.stuck.forget(req.Name)
+			r.blocked.forget(req.Name)
+			r.lifecycles.forget(req.Name)
+			r.ages.forget(req.Name, "")
+			r.stages.forget(req.Name, "")
+		}
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
-	// Check if the node has our target taint
-	hasTargetTaint := false
+	cfg := r.currentConfig()
+	now := time.Now()
+	r.reportReadded(ctx, node, cfg)
+	activeRules, coolingTaint, coolingDown := r.activeRules(node, cfg, now)
+
+	if len(activeRules) == 0 && coolingDown == 0 {
+		// Node doesn't have any of our target taints, no need to reconcile
+		r.backoff.reset(node.Name)
+		r.blockEvents.forget(node.Name)
+		r.blockLogs.forget(node.Name)
+		r.stuck.forget(node.Name)
+		r.blocked.forget(node.Name)
+		r.Status.ClearBlocked(node.Name)
+		if err := r.deleteCanaries(ctx, node, cfg); err != nil {
+			return ctrl.Result{}, err
+		}
+		// Lifecycle actions that failed to complete once the taints were
+		// removed are tried again until they do
+		return ctrl.Result{RequeueAfter: r.retryLifecycles(ctx, node)}, r.clearNodeCondition(ctx, node, cfg)
+	}
+
+	// Get all pods on this node
+	pods, err := r.listNodePods(ctx, node.Name)
+	if err != nil {
+		countError(errorListPods)
+		return ctrl.Result{}, fmt.Errorf("failed to list pods: %w", err)
+	}
+
+	// The node's own health gates every rule alike
+	nodeBlock, err := r.nodeBlocked(ctx, node, cfg, now)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	eval, err := r.evaluateRules(ctx, node, pods, activeRules, nodeBlock, cfg, now)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if len(eval.edits) > 0 {
+		if err := r.removeTaints(ctx, node, pods, activeRules, eval, cfg, now); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	blocked, staging, requeueAfter := eval.blocked, eval.staging, eval.requeueAfter
+
+	if blocked == nil && coolingDown == 0 && staging == 0 {
+		r.backoff.reset(node.Name)
+		r.blockEvents.forget(node.Name)
+		r.blockLogs.forget(node.Name)
+		r.stuck.forget(node.Name)
+		r.blocked.forget(node.Name)
+		r.Status.SetUntainted(node.Name)
+		return ctrl.Result{RequeueAfter: r.lifecycles.requeueAfter(node.Name)},
+			r.setNodeCondition(ctx, node, cfg, true, untaintv1alpha1.ReasonTaintRemoved, "")
+	}
+	if blocked == nil && staging > 0 {
+		// The workloads are ready, step the taint down once it dwelled long enough
+		message := fmt.Sprintf("taint %s is being removed in stages", eval.stagingTaint)
+		r.blocked.set(BlockedNode{Node: node.Name, Taints: ruleTaints(activeRules),
+			Reason: untaintv1alpha1.ReasonTaintDowngraded, Message: message}, now, cfg.StuckNodeThreshold.Duration)
+		r.Status.SetBlocked(node.Name, untaintv1alpha1.ReasonTaintDowngraded, message)
+		if coolingDown > 0 && coolingDown < staging {
+			staging = coolingDown
+		}
+		return ctrl.Result{RequeueAfter: staging},
+			r.setNodeCondition(ctx, node, cfg, false, untaintv1alpha1.ReasonTaintDowngraded, message)
+	}
+	if blocked == nil {
+		// Only taints in their cooldown are left, look at them again once it ends
+		log.Info("Ignoring target taint re-added during its cooldown", "node", node.Name,
+			"taint", coolingTaint, "remaining", coolingDown)
+		message := fmt.Sprintf("taint %s was re-added within %s of being removed", coolingTaint,
+			cfg.UntaintCooldown.Duration)
+		r.blocked.set(BlockedNode{Node: node.Name, Taints: []string{coolingTaint},
+			Reason: untaintv1alpha1.ReasonCoolingDown, Message: message}, now, cfg.StuckNodeThreshold.Duration)
+		r.Status.SetBlocked(node.Name, untaintv1alpha1.ReasonCoolingDown, message)
+		return ctrl.Result{RequeueAfter: coolingDown},
+			r.setNodeCondition(ctx, node, cfg, false, untaintv1alpha1.ReasonCoolingDown, message)
+	}
+	r.blocked.set(BlockedNode{Node: node.Name, Taints: ruleTaints(activeRules), Reason: blocked.reason,
+		Message: blocked.message, Workloads: blocked.workloads}, now, cfg.StuckNodeThreshold.Duration)
+	r.Status.SetBlocked(node.Name, blocked.reason, blocked.message)
+	r.deferredEvent(node, blocked)
+	r.notifyStuck(ctx, node, activeRules, blocked, cfg, now)
+	if err := r.setNodeCondition(ctx, node, cfg, false, blocked.reason, blocked.message); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Not all pods are ready yet, requeue. Back off while the pods gating the
+	// node stay unchanged, since re-checking them can't change the outcome.
+	fingerprint := podsFingerprint(pods, eval.blockingWorkloads)
+	requeueAfter = r.backoff.next(node.Name, fingerprint, requeueAfter, cfg.MaxRequeueInterval.Duration)
+	// Come back as soon as a stabilizing pod has been ready long enough, a
+	// cooldown ends or a staged taint is due for its next step
+	for _, after := range []time.Duration{eval.retryAfter, coolingDown, staging} {
+		if after > 0 && after < requeueAfter {
+			requeueAfter = after
+		}
+	}
+	r.logBlocked(ctx, node, blocked, requeueAfter, now)
+	return ctrl.Result{RequeueAfter: cfg.Jitter(requeueAfter)}, nil
+}
+
+// nodeBlocked returns why a pause or the node's own health rules out removing
+// any of its taints, or nil when every node check passes
+func (r *NodeReconciler) nodeBlocked(
+	ctx context.Context,
+	node *corev1.Node,
+	cfg *config.Config,
+	now time.Time,
+) (*blockReason, error) {
+	if reason := untaintPaused(node, cfg, now); reason != nil {
+		return reason, nil
+	}
+	if reason := nodeNotReady(node, cfg, now); reason != nil {
+		return reason, nil
+	}
+	if reason, err := r.kubeletLeaseStale(ctx, node, cfg, now); err != nil || reason != nil {
+		return reason, err
+	}
+	return r.cniNotReady(ctx, node)
+}
+
+// ruleBlocked returns why rule can't be untainted yet, or nil when its
+// workloads are ready and the node provides what the rule requires. The
+// cheap checks run first, the probes and the canary pod last.
+func (r *NodeReconciler) ruleBlocked(
+	ctx context.Context,
+	node *corev1.Node,
+	pods []corev1.Pod,
+	workloads []string,
+	rule config.Rule,
+	cfg *config.Config,
+) (*blockReason, error) {
+	if reason, err := r.workloadsBlocked(ctx, pods, workloads, cfg); err != nil || reason != nil {
+		return reason, err
+	}
+	if reason := missingResources(node, rule.RequiredResources); reason != nil {
+		return reason, nil
+	}
+	if reason, err := r.csiDriversBlocked(ctx, node, rule.CSIDrivers); err != nil || reason != nil {
+		return reason, err
+	}
+	if reason, err := r.resourceGatesBlocked(ctx, node, rule.ResourceGates); err != nil || reason != nil {
+		return reason, err
+	}
+	if reason := probesFailed(ctx, pods, workloads, rule.Probe); reason != nil {
+		return reason, nil
+	}
+	return r.canaryBlocked(ctx, node, rule, cfg)
+}
+
+// activeRules returns the rules whose taint is present on node, leaving out
+// taints re-added while their cooldown is running. The cooldown ending first
+// is returned along with its taint.
+func (r *NodeReconciler) activeRules(
+	node *corev1.Node,
+	cfg *config.Config,
+	now time.Time,
+) (rules []config.Rule, coolingTaint string, coolingDown time.Duration) {
+	for _, rule := range cfg.Rules {
+		if !hasRuleTaint(node, rule) {
+			r.ages.forget(node.Name, rule.TargetTaint)
+			r.stages.forget(node.Name, rule.TargetTaint)
+			continue
+		}
+		remaining := r.cooldown.remaining(node.Name, rule.TargetTaint, cfg.UntaintCooldown.Duration, now)
+		if remaining > 0 {
+			if coolingDown == 0 || remaining < coolingDown {
+				coolingTaint, coolingDown = rule.TargetTaint, remaining
+			}
+			continue
+		}
+		// Start the clock on the taint as soon as it is seen, for the taint
+		// carry duration of taints removed right away
+		r.ages.since(node, rule.TargetTaint, now)
+		rules = append(rules, rule)
+	}
+	return rules, coolingTaint, coolingDown
+}
+
+// ruleEvaluation is the outcome of checking the active rules of a node
+type ruleEvaluation struct {
+	// edits are the changes to make to the taints of ready rules, and of
+	// blocked rules whose taint is force removed
+	edits taintEdits
+	// forced are the taints removed although their workloads are not ready
+	forced []string
+	// staging is when the next staged taint is due for its next step
+	stagingTaint string
+	staging      time.Duration
+	// blocked is why the first blocked rule can't be untainted yet
+	blocked *blockReason
+	// requeueAfter is the soonest requeue interval of the blocked rules
+	requeueAfter time.Duration
+	// retryAfter is the soonest a block is known to clear
+	retryAfter        time.Duration
+	blockingWorkloads []string
+}
+
+// evaluateRules checks the workloads and node requirements of every active
+// rule. nodeBlock, when set, blocks every rule alike.
+func (r *NodeReconciler) evaluateRules(
+	ctx context.Context,
+	node *corev1.Node,
+	pods []corev1.Pod,
+	rules []config.Rule,
+	nodeBlock *blockReason,
+	cfg *config.Config,
+	now time.Time,
+) (*ruleEvaluation, error) {
+	eval := &ruleEvaluation{edits: make(taintEdits)}
+	for _, rule := range rules {
+		workloads := requiredWorkloads(node, rule)
+		var reason *blockReason
+		if nodeBlock != nil {
+			block := *nodeBlock
+			reason = &block
+		} else {
+			var err error
+			if reason, err = r.ruleBlocked(ctx, node, pods, workloads, rule, cfg); err != nil {
+				return nil, err
+			}
+		}
+		if reason == nil {
+			log.FromContext(ctx).V(2).Info("Rule is satisfied", "node", node.Name, "taint", rule.TargetTaint,
+				"workloads", workloads)
+			edit, dwell := r.stageEdit(node, rule, now)
+			if edit != nil {
+				eval.edits[rule.TargetTaint] = *edit
+			}
+			if dwell > 0 && (eval.staging == 0 || dwell < eval.staging) {
+				eval.stagingTaint, eval.staging = rule.TargetTaint, dwell
+			}
+			continue
+		}
+		log.FromContext(ctx).V(2).Info("Rule is blocked", "node", node.Name, "taint", rule.TargetTaint,
+			"workloads", workloads, "reason", reason.reason, "message", reason.message)
+		if rule.MaxWait != nil {
+			waited := now.Sub(r.ages.since(node, rule.TargetTaint, now))
+			if remaining := rule.MaxWait.Duration - waited; remaining > 0 {
+				// Come back when the deadline passes
+				if reason.retryAfter == 0 || remaining < reason.retryAfter {
+					reason.retryAfter = remaining
+				}
+			} else if !reason.paused && r.maxWaitExceeded(node, rule, reason, waited) {
+				eval.edits[rule.TargetTaint] = taintEdit{effects: rule.ManagedEffects()}
+				eval.forced = append(eval.forced, rule.TargetTaint)
+				continue
+			}
+		}
+		if eval.blocked == nil {
+			eval.blocked = reason
+			eval.blocked.message = fmt.Sprintf("taint %s: %s", rule.TargetTaint, reason.message)
+		}
+		eval.blockingWorkloads = append(eval.blockingWorkloads, workloads...)
+		// Requeue for the soonest of the blocked rules
+		if interval := cfg.RequeueIntervalFor(rule); eval.requeueAfter == 0 || interval < eval.requeueAfter {
+			eval.requeueAfter = interval
+		}
+		if reason.retryAfter > 0 && (eval.retryAfter == 0 || reason.retryAfter < eval.retryAfter) {
+			eval.retryAfter = reason.retryAfter
+		}
+	}
+	return eval, nil
+}
+
+// removeTaints writes the taint edits of eval to node, together with the
+// changes the actions of the rules whose taint is removed make to it, then
+// completes those actions and updates the trackers. The per-node cap, the
+// untaint rate limit and the pre-untaint hook can hold the removals back
+// first, leaving eval blocked.
+func (r *NodeReconciler) removeTaints(
+	ctx context.Context,
+	node *corev1.Node,
+	pods []corev1.Pod,
+	rules []config.Rule,
+	eval *ruleEvaluation,
+	cfg *config.Config,
+	now time.Time,
+) error {
+	log := log.FromContext(ctx)
+
+	request := newHookRequest(node, pods, rules, eval)
+	if reason := r.throttleNode(node, request, cfg, now); reason != nil {
+		log.Info("Node was untainted too often, keeping the target taints", "node", node.Name,
+			"taints", request.Taints, "retryAfter", reason.retryAfter)
+		vetoRemoval(rules, request, eval, reason, cfg)
+		if eval.retryAfter == 0 || reason.retryAfter < eval.retryAfter {
+			eval.retryAfter = reason.retryAfter
+		}
+		request = HookRequest{}
+		if len(eval.edits) == 0 {
+			return nil
+		}
+	}
+	if reason := r.reserveUntaint(node, request, cfg, now); reason != nil {
+		log.Info("Untaint rate limit reached, keeping the target taints", "node", node.Name,
+			"taints", request.Taints, "retryAfter", reason.retryAfter)
+		r.releaseNodeAttempt(request, cfg, now)
+		vetoRemoval(rules, request, eval, reason, cfg)
+		if eval.retryAfter == 0 || reason.retryAfter < eval.retryAfter {
+			eval.retryAfter = reason.retryAfter
+		}
+		request = HookRequest{}
+		if len(eval.edits) == 0 {
+			return nil
+		}
+	}
+	if reason := r.preUntaintVeto(ctx, request, cfg); reason != nil {
+		log.Info("Pre-untaint hook kept the target taints", "node", node.Name, "taints", request.Taints,
+			"reason", reason.message)
+		r.releaseUntaint(request, cfg, now)
+		r.releaseNodeAttempt(request, cfg, now)
+		vetoRemoval(rules, request, eval, reason, cfg)
+		request = HookRequest{}
+		if len(eval.edits) == 0 {
+			return nil
+		}
+	}
+
+	update := nodeEdit{taints: eval.edits}
+	var removals []actionContext
+	for _, rule := range rules {
+		if edit, ok := eval.edits[rule.TargetTaint]; !ok || edit.downgradeTo != "" {
+			continue
+		}
+		ac := actionContext{node: node, rule: rule, pods: pods, forced: slices.Contains(eval.forced, rule.TargetTaint)}
+		for _, action := range rule.Actions {
+			r.untaintAction(action).prepare(ac, &update)
+		}
+		removals = append(removals, ac)
+	}
+	if update.record != nil {
+		update.setAnnotations = update.record.annotations(now)
+	}
+	relabels := update.relabels(node.Labels)
+	audit := r.auditRecords(node, pods, rules, eval, cfg, now)
+	removedFrom := node.ResourceVersion
+	if err := r.updateNode(ctx, node, update, cfg); err != nil {
+		r.releaseUntaint(request, cfg, now)
+		r.releaseNodeAttempt(request, cfg, now)
+		return err
+	}
+	r.recordActions(audit...)
+	if update.uncordon {
+		log.Info("Uncordoned node", "node", node.Name)
+	}
+	if relabels {
+		log.Info("Relabeled node", "node", node.Name, "set", update.setLabels, "removed", update.removeLabels)
+	}
+
+	for taint, edit := range eval.edits {
+		if edit.downgradeTo != "" {
+			log.Info("Downgraded target taint on node", "node", node.Name, "taint", taint, "effect", edit.downgradeTo)
+			continue
+		}
+		log.Info("Removed target taint from node", "node", node.Name, "taint", taint,
+			"reason", untaintv1alpha1.ReasonTaintRemoved)
+		observeUntaint(node, taint, now)
+		observeTaintCarry(taint, r.ages.since(node, taint, now), now)
+		r.ages.forget(node.Name, taint)
+		r.removed.record(node.Name, taint, removedFrom)
+		if cfg.UntaintCooldown.Duration > 0 {
+			r.cooldown.record(node.Name, taint, now)
+		}
+	}
+	for _, ac := range removals {
+		for _, action := range ac.rule.Actions {
+			r.untaintAction(action).complete(ctx, ac)
+		}
+	}
+	r.postUntaint(ctx, request, cfg)
+	if len(request.Taints) > 0 {
+		r.notifyUntainted(ctx, request, cfg)
+		r.CloudEvents.Publish(CloudEventNodeUntainted, NodeEventData{
+			Node:         node.Name,
+			Taints:       request.Taints,
+			ForcedTaints: request.ForcedTaints,
+			Pods:         request.Pods,
+		})
+	}
+	return nil
+}
+
+// maxWaitExceeded takes the rule's maxWait action for a taint that waited too
+// long for its workloads, and reports whether the taint must be removed anyway.
+// The warning of a taint kept in place is only emitted once while it stays.
+func (r *NodeReconciler) maxWaitExceeded(
+	node *corev1.Node,
+	rule config.Rule,
+	reason *blockReason,
+	waited time.Duration,
+) bool {
+	waited = waited.Round(time.Second)
+	if rule.OnMaxWait == config.MaxWaitActionForceRemove {
+		r.eventf(node, corev1.EventTypeWarning, "TaintForceRemoved",
+			"Removing taint %s after waiting %s for its workloads: %s", rule.TargetTaint, waited, reason.message)
+		return true
+	}
+	if r.ages.exceed(node.Name, rule.TargetTaint) {
+		r.eventf(node, corev1.EventTypeWarning, "UntaintDeadlineExceeded",
+			"Taint %s has been waiting %s for its workloads: %s", rule.TargetTaint, waited, reason.message)
+	}
+	return false
+}
+
+// eventf emits an event on node when a recorder is set
+func (r *NodeReconciler) eventf(node *corev1.Node, eventType, reason, messageFmt string, args ...interface{}) {
+	if r.Recorder != nil {
+		r.Recorder.Eventf(node, eventType, reason, messageFmt, args...)
+	}
+}
+
+// reportReadded reports the target taints the operator removed from node that
+// are back on it
+func (r *NodeReconciler) reportReadded(ctx context.Context, node *corev1.Node, cfg *config.Config) {
+	for _, rule := range cfg.Rules {
+		if hasRuleTaint(node, rule) && r.removed.readded(node.Name, rule.TargetTaint, node.ResourceVersion) {
+			r.taintReadded(ctx, node, rule.TargetTaint)
+		}
+	}
+}
+
+// taintReadded reports that taint came back on node after it was removed. The
+// node is reconciled again like any newly tainted node.
+func (r *NodeReconciler) taintReadded(ctx context.Context, node *corev1.Node, taint string) {
+	log.FromContext(ctx).Info("Target taint was re-added after it was removed",
+		"node", node.Name, "taint", taint, "reason", untaintv1alpha1.ReasonReverted)
+	taintReaddedTotal.WithLabelValues(taint).Inc()
+	r.recordActions(AuditRecord{Time: time.Now().UTC(), Action: AuditTaintReAdded, Node: node.Name, Taint: taint})
+	r.CloudEvents.Publish(CloudEventNodeRetainted, NodeEventData{Node: node.Name, Taints: []string{taint}})
+	r.eventf(node, corev1.EventTypeWarning, untaintv1alpha1.ReasonReverted,
+		"Taint %s was re-added after the operator removed it, re-evaluating workload readiness", taint)
+}
+
+// hasRuleTaint reports whether the node carries a taint managed by rule
+func hasRuleTaint(node *corev1.Node, rule config.Rule) bool {
+	return slices.ContainsFunc(node.Spec.Taints, rule.Manages)
+}
+
+// hasTaint reports whether the node carries a taint with the given key
+func hasTaint(node *corev1.Node, key string) bool {
 	for _, taint := range node.Spec.Taints {
-		if taint.Key == r.TargetTaint {
-			hasTargetTaint = true
-			break
+		if taint.Key == key {
+			return true
 		}
 	}
+	return false
+}
 
-	if !hasTargetTaint {
-		// Node doesn't have our target taint, no need to reconcile
-		return ctrl.Result{}, nil
+// requiredWorkloads returns the workload names gating rule on node, applying
+// the per-node override annotations
+func requiredWorkloads(node *corev1.Node, rule config.Rule) []string {
+	names := rule.OwnedByNames
+	// An empty override is ignored rather than leaving the node with nothing to wait for
+	if override := splitAnnotationList(node.Annotations[RequiredWorkloadsAnnotation]); len(override) > 0 {
+		names = override
+	}
+	if additional := splitAnnotationList(node.Annotations[AdditionalWorkloadsAnnotation]); len(additional) > 0 {
+		names = append(slices.Clone(names), additional...)
 	}
+	return names
+}
 
-	// Get all pods on this node
-	pods := &corev1.PodList{}
-	if err := r.List(ctx, pods, client.MatchingFields{"spec.nodeName": node.Name}); err != nil {
-		return ctrl.Result{}, fmt.Errorf("failed to list pods: %w", err)
+// splitAnnotationList splits a comma-separated annotation value, dropping
+// surrounding whitespace and empty entries
+func splitAnnotationList(value string) []string {
+	var names []string
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
 	}
+	return names
+}
+
+// workloadsBlocked checks that the node runs at least one pod owned by the
+// given workloads and that all such pods are ready and up to date, ignoring
+// terminating pods. It returns nil when they are, and the reason the taint
+// must stay otherwise.
+func (r *NodeReconciler) workloadsBlocked(
+	ctx context.Context,
+	pods []corev1.Pod,
+	ownedByNames []string,
+	cfg *config.Config,
+) (*blockReason, error) {
+	log := log.FromContext(ctx)
+	// The decision trace explains why each pod was counted or skipped
+	trace := log.V(2)
+
+	workloads := newWorkloadSet(ownedByNames)
 
 	// Check if all required pods are ready
-	allPodsReady := true
 	hasTargetPods := false
-	for _, pod := range pods.Items {
+	var terminating *corev1.Pod
+	for _, pod := range pods {
 		// Skip pods that aren't owned by our target workloads
-		isTargetPod := false
-		for _, owner := range pod.OwnerReferences {
-			for _, targetName := range r.OwnedByNames {
-				if owner.Name == targetName {
-					isTargetPod = true
-					hasTargetPods = true
-					break
-				}
-			}
-			if isTargetPod {
-				break
+		if !isOwnedBy(&pod, workloads) {
+			trace.Info("Skipping pod not owned by a required workload", "pod", pod.Name,
+				"owners", ownerNames(&pod), "workloads", ownedByNames)
+			continue
+		}
+		// A terminating pod can still report Ready for a moment, but it is about
+		// to go away, so only its replacement counts
+		if pod.DeletionTimestamp != nil {
+			trace.Info("Skipping terminating pod", "pod", pod.Name, "deletionTimestamp", pod.DeletionTimestamp)
+			if terminating == nil {
+				terminating = &pod
 			}
+			continue
 		}
+		hasTargetPods = true
 
-		if !isTargetPod {
-			continue
+		// Track restarts before checking readiness, so the history also covers
+		// the time the pod spends crash looping
+		if cfg.MaxRestarts != nil {
+			container, restarts := r.restarts.observe(&pod, cfg.RestartWindow.Duration, time.Now())
+			if restarts > *cfg.MaxRestarts {
+				log.V(1).Info("Pod is restarting too often, requeueing", "pod", pod.Name, "container", container,
+					"restarts", restarts)
+				return &blockReason{
+					reason: untaintv1alpha1.ReasonWorkloadUnready,
+					message: fmt.Sprintf("pod %s/%s is not ready: container %s restarted %d times in the last %s",
+						pod.Namespace, pod.Name, container, restarts, cfg.RestartWindow.Duration),
+					workloads: owners(&pod, workloads),
+				}, nil
+			}
 		}
 
 		// Check if pod is ready
-		podReady := false
-		for _, condition := range pod.Status.Conditions {
-			if condition.Type == corev1.PodReady && condition.Status == corev1.ConditionTrue {
-				podReady = true
-				break
+		if notReady, detail := podNotReady(&pod, cfg); notReady {
+			log.V(1).Info("Pod is not ready, requeueing", "pod", pod.Name, "phase", pod.Status.Phase, "detail", detail,
+				"finalizers", pod.Finalizers)
+			message := fmt.Sprintf("pod %s/%s is not ready", pod.Namespace, pod.Name)
+			if detail != "" {
+				message += ": " + detail
 			}
+			return &blockReason{
+				reason:    untaintv1alpha1.ReasonWorkloadUnready,
+				message:   message,
+				workloads: owners(&pod, workloads),
+			}, nil
+		}
+		minReady := time.Duration(cfg.MinReadySeconds) * time.Second
+		if remaining := minReady - readyFor(&pod, time.Now()); remaining > 0 {
+			log.V(1).Info("Pod has not been ready long enough, requeueing", "pod", pod.Name, "remaining", remaining)
+			return &blockReason{
+				reason: untaintv1alpha1.ReasonWorkloadStabilizing,
+				message: fmt.Sprintf("pod %s/%s has been ready for less than %ds",
+					pod.Namespace, pod.Name, cfg.MinReadySeconds),
+				retryAfter: remaining,
+				workloads:  owners(&pod, workloads),
+			}, nil
 		}
 
-		if !podReady {
-			log.Info("Pod is not ready, requeueing", "pod", pod.Name, "podStatus", pod.Status, "finalizers", pod.Finalizers)
-			allPodsReady = false
-			break
+		// During a rolling update the old pod is ready while its replacement is
+		// still starting, so it must not satisfy the check
+		outdated, err := r.isOutdatedDaemonSetPod(ctx, &pod)
+		if err != nil {
+			return nil, err
+		}
+		if outdated {
+			log.V(1).Info("Pod runs an outdated DaemonSet revision, requeueing", "pod", pod.Name)
+			return &blockReason{
+				reason:    untaintv1alpha1.ReasonWorkloadOutdated,
+				message:   fmt.Sprintf("pod %s/%s runs an outdated revision of its DaemonSet", pod.Namespace, pod.Name),
+				workloads: owners(&pod, workloads),
+			}, nil
 		}
+		trace.Info("Counting ready pod", "pod", pod.Name, "workloads", owners(&pod, workloads))
 	}
 
-	if allPodsReady && hasTargetPods {
-		// Remove the target taint
-		newTaints := make([]corev1.Taint, 0)
-		for _, taint := range node.Spec.Taints {
-			if taint.Key != r.TargetTaint {
-				newTaints = append(newTaints, taint)
-			}
-		}
-		node.Spec.Taints = newTaints
+	if !hasTargetPods && terminating != nil {
+		return &blockReason{
+			reason: untaintv1alpha1.ReasonWaitingForWorkload,
+			message: fmt.Sprintf("pod %s/%s is terminating and has no replacement yet",
+				terminating.Namespace, terminating.Name),
+			workloads: owners(terminating, workloads),
+		}, nil
+	}
+	if !hasTargetPods {
+		return &blockReason{
+			reason:    untaintv1alpha1.ReasonWaitingForWorkload,
+			message:   fmt.Sprintf("no pods of %s are running on the node", strings.Join(ownedByNames, ", ")),
+			workloads: ownedByNames,
+		}, nil
+	}
+	return nil, nil
+}
 
-		if err := r.Update(ctx, node); err != nil {
-			return ctrl.Result{}, fmt.Errorf("failed to update node: %w", err)
+// workloadSet holds the names of the required workloads, so the owners of
+// every pod on a node are matched against them in constant time
+type workloadSet map[string]struct{}
+
+// newWorkloadSet returns the set of the named workloads
+func newWorkloadSet(names []string) workloadSet {
+	workloads := make(workloadSet, len(names))
+	for _, name := range names {
+		workloads[name] = struct{}{}
+	}
+	return workloads
+}
+
+// isOwnedBy reports whether pod has an owner named after one of the workloads
+func isOwnedBy(pod *corev1.Pod, workloads workloadSet) bool {
+	for _, owner := range pod.OwnerReferences {
+		if _, ok := workloads[owner.Name]; ok {
+			return true
 		}
+	}
+	return false
+}
 
-		log.Info("Removed target taint from node", "node", node.Name)
-		return ctrl.Result{}, nil
+// ownerNames returns the names of all owners of pod
+func ownerNames(pod *corev1.Pod) []string {
+	names := make([]string, 0, len(pod.OwnerReferences))
+	for _, owner := range pod.OwnerReferences {
+		names = append(names, owner.Name)
 	}
+	return names
+}
 
-	// Not all pods are ready yet, requeue
-	log.Info("Not all required pods are ready, requeueing", "node", node.Name)
-	return ctrl.Result{RequeueAfter: 30 * time.Second}, nil
+// owners returns the workloads of the set that own pod
+func owners(pod *corev1.Pod, workloads workloadSet) []string {
+	var names []string
+	for _, owner := range pod.OwnerReferences {
+		if _, ok := workloads[owner.Name]; ok {
+			names = append(names, owner.Name)
+		}
+	}
+	return names
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *NodeReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	// Create an index for pods by node name
-	if err := mgr.GetFieldIndexer().IndexField(
-		context.Background(),
-		&corev1.Pod{},
-		"spec.nodeName",
-		func(obj client.Object) []string {
-			pod := obj.(*corev1.Pod)
-			if pod.Spec.NodeName == "" {
+	if !r.UncachedPods {
+		if err := mgr.GetFieldIndexer().IndexField(
+			context.Background(),
+			&corev1.Pod{},
+			podNodeNameField,
+			func(obj client.Object) []string {
+				pod := obj.(*corev1.Pod)
+				if pod.Spec.NodeName == "" {
+					return nil
+				}
+				return []string{pod.Spec.NodeName}
+			},
+		); err != nil {
+			return err
+		}
+	}
+
+	if r.Karpenter {
+		if err := mgr.GetFieldIndexer().IndexField(
+			context.Background(),
+			newNodeClaim(),
+			nodeClaimNodeNameField,
+			func(obj client.Object) []string {
+				if name := nodeClaimNodeName(obj); name != "" {
+					return []string{name}
+				}
 				return nil
-			}
-			return []string{pod.Spec.NodeName}
-		},
-	); err != nil {
+			},
+		); err != nil {
+			return err
+		}
+	}
+
+	// Reconciles read DaemonSets to tell outdated pods apart. Registering
+	// their informer up front has it synced along with the others before the
+	// first reconcile, rather than on the first read.
+	if _, err := mgr.GetCache().GetInformer(context.Background(), &appsv1.DaemonSet{}); err != nil {
+		return err
+	}
+
+	options := controller.Options{MaxConcurrentReconciles: r.MaxConcurrentReconciles}
+	if r.PrioritizeNewNodes {
+		options.NewQueue = newNewestFirstQueue(mgr.GetCache())
+	}
+	bldr := ctrl.NewControllerManagedBy(mgr).
+		WithOptions(options).
+		For(&corev1.Node{}, builder.WithPredicates(r.shardPredicate(), r.nodePredicate())).
+		// Re-evaluate a node as soon as a CSI driver registers on it
+		Watches(
+			&storagev1.CSINode{},
+			handler.EnqueueRequestsFromMapFunc(r.selectedNodes(mgr.GetCache(), csiNodeToNode)),
+			builder.WithPredicates(csiNodeChangedPredicate()),
+		)
+
+	if !r.UncachedPods {
+		// Likewise as soon as one of its pods changes, so the taint is removed
+		// when the last required pod turns ready rather than on the next requeue
+		bldr = bldr.Watches(
+			&corev1.Pod{},
+			handler.EnqueueRequestsFromMapFunc(r.selectedNodes(mgr.GetCache(), podToNode)),
+			builder.WithPredicates(podChangedPredicate()),
+		)
+	}
+	if r.Karpenter {
+		// A NodeClaim is usually linked to its node after the node registered,
+		// so report the progress on it as soon as it is
+		bldr = bldr.Watches(
+			newNodeClaim(),
+			handler.EnqueueRequestsFromMapFunc(r.selectedNodes(mgr.GetCache(), nodeClaimToNode)),
+			builder.WithPredicates(nodeClaimChangedPredicate()),
+		)
+	}
+	bldr, err := r.setupCNI(mgr, bldr)
+	if err != nil {
 		return err
 	}
+	if r.Config != nil {
+		// Re-evaluate every node when the rules change, since nodes that already
+		// carry a newly configured taint will not produce a create event
+		bldr = bldr.WatchesRawSource(source.Func(r.enqueueAllOnConfigChange))
+	}
+	if r.ResyncPeriod > 0 {
+		bldr = bldr.WatchesRawSource(r.resyncTaintedNodes(r.ResyncPeriod))
+	}
+	// Last, so the informers of every other source are registered by the time
+	// it waits for them to sync
+	syncTimeout := mgr.GetControllerOptions().CacheSyncTimeout
+	if syncTimeout == 0 {
+		syncTimeout = defaultCacheSyncTimeout
+	}
+	bldr = bldr.WatchesRawSource(r.startInitialPass(mgr.GetCache(), syncTimeout))
+
+	return bldr.Complete(r)
+}
 
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&corev1.Node{}).
-		WithEventFilter(predicate.Funcs{
-			CreateFunc: func(e event.CreateEvent) bool {
+// nodePredicate passes new and deleted nodes, updates that add one of the
+// target taints to an existing node, e.g. when a remediation tool re-taints
+// it, and updates to the node conditions or allocatable resources gating a
+// tainted node
+func (r *NodeReconciler) nodePredicate() predicate.Funcs {
+	return predicate.Funcs{
+		// Nodes registering without a target taint are never queued, unless the
+		// node condition still reports taints someone else removed meanwhile
+		CreateFunc: func(e event.CreateEvent) bool {
+			node, ok := e.Object.(*corev1.Node)
+			if !ok {
+				return false
+			}
+			cfg := r.currentConfig()
+			if cfg.NodeConditionType != "" && nodeConditionStatus(node, cfg.NodeConditionType) == corev1.ConditionFalse {
 				return true
-			},
-			DeleteFunc: func(e event.DeleteEvent) bool {
+			}
+			return slices.ContainsFunc(cfg.Rules, func(rule config.Rule) bool { return hasRuleTaint(node, rule) })
+		},
+		// Deleted nodes are reconciled once to forget them
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			return true
+		},
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldNode, ok := e.ObjectOld.(*corev1.Node)
+			if !ok {
 				return false
-			},
-			UpdateFunc: func(e event.UpdateEvent) bool {
+			}
+			newNode, ok := e.ObjectNew.(*corev1.Node)
+			if !ok {
 				return false
-			},
-			GenericFunc: func(e event.GenericEvent) bool {
+			}
+			cfg := r.currentConfig()
+			gateChanged := nodeGateChanged(oldNode, newNode, cfg)
+			changed := false
+			for _, rule := range cfg.Rules {
+				if !hasRuleTaint(newNode, rule) {
+					continue
+				}
+				if hasRuleTaint(oldNode, rule) {
+					// A tainted node waiting on its own conditions or resources is
+					// re-evaluated as soon as they change
+					changed = changed || gateChanged || allocatableChanged(oldNode, newNode, rule.RequiredResources)
+					continue
+				}
+				changed = true
+			}
+			return changed
+		},
+		GenericFunc: func(e event.GenericEvent) bool {
+			return false
+		},
+	}
+}
+
+// podToNode maps a pod to the node it is scheduled on
+func podToNode(_ context.Context, obj client.Object) []reconcile.Request {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok || pod.Spec.NodeName == "" {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: pod.Spec.NodeName}}}
+}
+
+// podChangedPredicate passes pod events that can change the readiness outcome
+// of the pod's node: new and deleted pods, and updates to a pod's scheduling,
+// deletion or status
+func podChangedPredicate() predicate.Funcs {
+	return predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldPod, ok := e.ObjectOld.(*corev1.Pod)
+			if !ok {
 				return false
-			},
-		}).
-		Complete(r)
+			}
+			newPod, ok := e.ObjectNew.(*corev1.Pod)
+			if !ok {
+				return false
+			}
+			return oldPod.Spec.NodeName != newPod.Spec.NodeName ||
+				!oldPod.DeletionTimestamp.Equal(newPod.DeletionTimestamp) ||
+				!equality.Semantic.DeepEqual(oldPod.Status, newPod.Status)
+		},
+		GenericFunc: func(e event.GenericEvent) bool {
+			return false
+		},
+	}
+}
+
+// enqueueAllOnConfigChange queues every node for reconciliation each time the
+// configuration store reports a change
+func (r *NodeReconciler) enqueueAllOnConfigChange(
+	ctx context.Context,
+	queue workqueue.TypedRateLimitingInterface[reconcile.Request],
+) error {
+	changes := r.Config.Subscribe()
+	go func() {
+		log := log.FromContext(ctx)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-changes:
+				nodes := &corev1.NodeList{}
+				if err := r.List(ctx, nodes); err != nil {
+					log.Error(err, "Failed to list nodes after config change")
+					continue
+				}
+				for _, node := range nodes.Items {
+					queue.Add(reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&node)})
+				}
+			}
+		}
+	}()
+	return nil
 }
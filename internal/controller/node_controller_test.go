@@ -7,13 +7,21 @@ import (
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	gomegatypes "github.com/onsi/gomega/types"
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	untaintv1alpha1 "github.com/jslay88/generic-untaint-operator/api/v1alpha1"
+	"github.com/jslay88/generic-untaint-operator/internal/config"
 )
 
 func cleanupPod(ctx context.Context, k8sClient client.Client, pod *corev1.Pod) {
@@ -29,6 +37,53 @@ func cleanupPod(ctx context.Context, k8sClient client.Client, pod *corev1.Pod) {
 	}, "10s", "2s").Should(BeTrue(), "Pod was not deleted within timeout period")
 }
 
+// beJitteredRequeue matches a requeue after base plus up to the default jitter
+func beJitteredRequeue(base time.Duration) gomegatypes.GomegaMatcher {
+	maxJitter := time.Duration(float64(base) * config.DefaultRequeueJitter)
+	return And(BeNumerically(">=", base), BeNumerically("<=", base+maxJitter))
+}
+
+// createReadyPod creates a pod on nodeName owned by the named DaemonSet and marks it ready
+func createReadyPod(ctx context.Context, k8sClient client.Client, name, nodeName, owner string) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+			OwnerReferences: []metav1.OwnerReference{
+				{
+					APIVersion: "apps/v1",
+					Kind:       "DaemonSet",
+					Name:       owner,
+					UID:        "test-uid",
+				},
+			},
+		},
+		Spec: corev1.PodSpec{
+			NodeName: nodeName,
+			Containers: []corev1.Container{
+				{
+					Name:  "test-container",
+					Image: "busybox",
+				},
+			},
+		},
+	}
+	Expect(k8sClient.Create(ctx, pod)).To(Succeed())
+
+	podPatch := pod.DeepCopy()
+	podPatch.Status = corev1.PodStatus{
+		Phase: corev1.PodRunning,
+		Conditions: []corev1.PodCondition{
+			{
+				Type:   corev1.PodReady,
+				Status: corev1.ConditionTrue,
+			},
+		},
+	}
+	Expect(k8sClient.Status().Patch(ctx, podPatch, client.MergeFrom(pod))).To(Succeed())
+	return podPatch
+}
+
 var _ = Describe("NodeReconciler", func() {
 	var (
 		ctx        context.Context
@@ -121,7 +176,7 @@ var _ = Describe("NodeReconciler", func() {
 				NamespacedName: types.NamespacedName{Name: node.Name},
 			})
 			Expect(err).NotTo(HaveOccurred())
-			Expect(result.RequeueAfter).To(Equal(30 * time.Second))
+			Expect(result.RequeueAfter).To(beJitteredRequeue(30 * time.Second))
 
 			// Verify taint still exists
 			updatedNode := &corev1.Node{}
@@ -169,7 +224,7 @@ var _ = Describe("NodeReconciler", func() {
 				NamespacedName: types.NamespacedName{Name: node.Name},
 			})
 			Expect(err).NotTo(HaveOccurred())
-			Expect(result.RequeueAfter).To(Equal(30 * time.Second))
+			Expect(result.RequeueAfter).To(beJitteredRequeue(30 * time.Second))
 
 			// Verify taint still exists
 			updatedNode := &corev1.Node{}
@@ -255,7 +310,7 @@ var _ = Describe("NodeReconciler", func() {
 				NamespacedName: types.NamespacedName{Name: node.Name},
 			})
 			Expect(err).NotTo(HaveOccurred())
-			Expect(result.RequeueAfter).To(Equal(30 * time.Second))
+			Expect(result.RequeueAfter).To(beJitteredRequeue(30 * time.Second))
 
 			// Update second pod status to ready
 			pod2Patch := pod2.DeepCopy()
@@ -329,7 +384,7 @@ var _ = Describe("NodeReconciler", func() {
 				NamespacedName: types.NamespacedName{Name: node.Name},
 			})
 			Expect(err).NotTo(HaveOccurred())
-			Expect(result.RequeueAfter).To(Equal(30 * time.Second))
+			Expect(result.RequeueAfter).To(beJitteredRequeue(30 * time.Second))
 
 			// Verify taint still exists
 			updatedNode := &corev1.Node{}
@@ -415,7 +470,7 @@ var _ = Describe("NodeReconciler", func() {
 				NamespacedName: types.NamespacedName{Name: node.Name},
 			})
 			Expect(err).NotTo(HaveOccurred())
-			Expect(result.RequeueAfter).To(Equal(30 * time.Second))
+			Expect(result.RequeueAfter).To(beJitteredRequeue(30 * time.Second))
 
 			// Verify taint still exists
 			updatedNode := &corev1.Node{}
@@ -426,5 +481,666 @@ var _ = Describe("NodeReconciler", func() {
 				Effect: corev1.TaintEffectNoSchedule,
 			}))
 		})
+		It("should only remove taints whose rule is satisfied when using config rules", func() {
+			node.Spec.Taints = append(node.Spec.Taints, corev1.Taint{
+				Key:    "second-taint",
+				Value:  "true",
+				Effect: corev1.TaintEffectNoSchedule,
+			})
+			Expect(k8sClient.Update(ctx, node)).To(Succeed())
+
+			cfg := &config.Config{
+				Rules: []config.Rule{
+					{TargetTaint: "test-taint", OwnedByNames: []string{"test-daemonset"}},
+					{TargetTaint: "second-taint", OwnedByNames: []string{"second-daemonset"}},
+				},
+			}
+			cfg.Default()
+			reconciler.Config = config.NewStore(cfg)
+
+			pod := createReadyPod(ctx, k8sClient, "test-pod-rules", node.Name, "test-daemonset")
+			defer cleanupPod(ctx, k8sClient, pod)
+
+			// Reconcile the node - only the first rule is satisfied
+			result, err := reconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: node.Name},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(beJitteredRequeue(30 * time.Second))
+
+			updatedNode := &corev1.Node{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: node.Name}, updatedNode)).To(Succeed())
+			Expect(updatedNode.Spec.Taints).To(ConsistOf(corev1.Taint{
+				Key:    "second-taint",
+				Value:  "true",
+				Effect: corev1.TaintEffectNoSchedule,
+			}))
+		})
+
+		It("should use the workloads from the required-workloads annotation", func() {
+			node.Annotations = map[string]string{RequiredWorkloadsAnnotation: "override-daemonset"}
+			Expect(k8sClient.Update(ctx, node)).To(Succeed())
+
+			pod := createReadyPod(ctx, k8sClient, "test-pod-override", node.Name, "override-daemonset")
+			defer cleanupPod(ctx, k8sClient, pod)
+
+			result, err := reconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: node.Name},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal(reconcile.Result{}))
+
+			updatedNode := &corev1.Node{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: node.Name}, updatedNode)).To(Succeed())
+			Expect(updatedNode.Spec.Taints).To(BeEmpty())
+		})
+
+		It("should wait for the workloads from the additional-workloads annotation", func() {
+			node.Annotations = map[string]string{AdditionalWorkloadsAnnotation: "extra-daemonset"}
+			Expect(k8sClient.Update(ctx, node)).To(Succeed())
+
+			pod := createReadyPod(ctx, k8sClient, "test-pod-additional", node.Name, "test-daemonset")
+			defer cleanupPod(ctx, k8sClient, pod)
+
+			extraPod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-pod-extra",
+					Namespace: "default",
+					OwnerReferences: []metav1.OwnerReference{
+						{
+							APIVersion: "apps/v1",
+							Kind:       "DaemonSet",
+							Name:       "extra-daemonset",
+							UID:        "extra-uid",
+						},
+					},
+				},
+				Spec: corev1.PodSpec{
+					NodeName: node.Name,
+					Containers: []corev1.Container{
+						{
+							Name:  "test-container",
+							Image: "busybox",
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, extraPod)).To(Succeed())
+			defer cleanupPod(ctx, k8sClient, extraPod)
+
+			result, err := reconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: node.Name},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(beJitteredRequeue(30 * time.Second))
+
+			updatedNode := &corev1.Node{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: node.Name}, updatedNode)).To(Succeed())
+			Expect(updatedNode.Spec.Taints).To(HaveLen(1))
+		})
+	})
+
+	Context("when removing taints with server-side apply", func() {
+		It("should remove the taint and take ownership of spec.taints", func() {
+			cfg := &config.Config{
+				Rules:           []config.Rule{{TargetTaint: "test-taint", OwnedByNames: []string{"test-daemonset"}}},
+				RemovalStrategy: config.RemovalStrategyApply,
+				ForceApply:      true,
+			}
+			cfg.Default()
+			reconciler.Config = config.NewStore(cfg)
+
+			pod := createReadyPod(ctx, k8sClient, "test-pod-apply", node.Name, "test-daemonset")
+			defer cleanupPod(ctx, k8sClient, pod)
+
+			result, err := reconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: node.Name},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal(reconcile.Result{}))
+
+			updatedNode := &corev1.Node{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: node.Name}, updatedNode)).To(Succeed())
+			Expect(updatedNode.Spec.Taints).To(BeEmpty())
+			Expect(updatedNode.ManagedFields).To(ContainElement(HaveField("Manager", FieldManager)))
+		})
+	})
+
+	Context("when the node changes while removing taints", func() {
+		It("should retry against the latest version of the node", func() {
+			stale := &corev1.Node{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: node.Name}, stale)).To(Succeed())
+
+			// Another actor adds a taint after we read the node
+			current := stale.DeepCopy()
+			current.Spec.Taints = append(current.Spec.Taints, corev1.Taint{
+				Key:    "concurrent-taint",
+				Effect: corev1.TaintEffectNoSchedule,
+			})
+			Expect(k8sClient.Update(ctx, current)).To(Succeed())
+
+			Expect(reconciler.patchNode(ctx, stale, nodeEdit{taints: taintEdits{"test-taint": {}}})).To(Succeed())
+
+			updatedNode := &corev1.Node{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: node.Name}, updatedNode)).To(Succeed())
+			Expect(updatedNode.Spec.Taints).To(ConsistOf(HaveField("Key", "concurrent-taint")))
+		})
+
+		It("should not remove the wrong entry when the taints array shifted", func() {
+			stale := &corev1.Node{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: node.Name}, stale)).To(Succeed())
+
+			// Another actor inserts a taint in front of ours after we read the node
+			current := stale.DeepCopy()
+			current.Spec.Taints = append([]corev1.Taint{{
+				Key:    "concurrent-taint",
+				Effect: corev1.TaintEffectNoSchedule,
+			}}, current.Spec.Taints...)
+			Expect(k8sClient.Update(ctx, current)).To(Succeed())
+
+			Expect(reconciler.jsonPatchNode(ctx, stale, nodeEdit{taints: taintEdits{"test-taint": {}}})).To(Succeed())
+
+			updatedNode := &corev1.Node{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: node.Name}, updatedNode)).To(Succeed())
+			Expect(updatedNode.Spec.Taints).To(ConsistOf(HaveField("Key", "concurrent-taint")))
+		})
+
+		It("should test each removed taint before removing it, highest index first", func() {
+			taints := []corev1.Taint{{Key: "a"}, {Key: "b"}, {Key: "c"}}
+			ops := taintPatchOps(taints, taintEdits{"a": {}, "c": {}})
+			Expect(ops).To(Equal([]jsonPatchOp{
+				{Op: "test", Path: "/spec/taints/2", Value: &taints[2]},
+				{Op: "remove", Path: "/spec/taints/2"},
+				{Op: "test", Path: "/spec/taints/0", Value: &taints[0]},
+				{Op: "remove", Path: "/spec/taints/0"},
+			}))
+		})
+	})
+
+	Context("when required pods are terminating", func() {
+		It("should not count a terminating pod that still reports ready", func() {
+			readyPod := func(name string) corev1.Pod {
+				return corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:            name,
+						Namespace:       "default",
+						OwnerReferences: []metav1.OwnerReference{{Name: "test-daemonset"}},
+					},
+					Status: corev1.PodStatus{
+						Phase:      corev1.PodRunning,
+						Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+					},
+				}
+			}
+			old := readyPod("old-pod")
+			old.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+
+			reason, err := reconciler.workloadsBlocked(ctx, []corev1.Pod{old}, []string{"test-daemonset"}, reconciler.currentConfig())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(reason).NotTo(BeNil())
+			Expect(reason.reason).To(Equal(untaintv1alpha1.ReasonWaitingForWorkload))
+			Expect(reason.message).To(ContainSubstring("default/old-pod is terminating"))
+
+			replacement := readyPod("new-pod")
+			reason, err = reconciler.workloadsBlocked(ctx, []corev1.Pod{old, replacement}, []string{"test-daemonset"}, reconciler.currentConfig())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(reason).To(BeNil())
+		})
+	})
+
+	Context("when a rule selects taint effects", func() {
+		It("should leave entries with other effects in place", func() {
+			node.Spec.Taints = append(node.Spec.Taints, corev1.Taint{
+				Key:    "test-taint",
+				Effect: corev1.TaintEffectNoExecute,
+			})
+			Expect(k8sClient.Update(ctx, node)).To(Succeed())
+			pod := createReadyPod(ctx, k8sClient, "test-pod-effects", node.Name, "test-daemonset")
+			defer cleanupPod(ctx, k8sClient, pod)
+
+			cfg := &config.Config{Rules: []config.Rule{{
+				TargetTaint:  "test-taint",
+				Effects:      []corev1.TaintEffect{corev1.TaintEffectNoSchedule},
+				OwnedByNames: []string{"test-daemonset"},
+			}}}
+			cfg.Default()
+			reconciler.Config = config.NewStore(cfg)
+
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: node.Name},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			updatedNode := &corev1.Node{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: node.Name}, updatedNode)).To(Succeed())
+			Expect(updatedNode.Spec.Taints).To(ConsistOf(And(
+				HaveField("Key", "test-taint"),
+				HaveField("Effect", corev1.TaintEffectNoExecute),
+			)))
+		})
+	})
+
+	Context("when a rule uncordons the node", func() {
+		DescribeTable("should clear spec.unschedulable together with the taint",
+			func(strategy config.RemovalStrategy) {
+				node.Spec.Unschedulable = true
+				Expect(k8sClient.Update(ctx, node)).To(Succeed())
+				pod := createReadyPod(ctx, k8sClient, "test-pod-uncordon", node.Name, "test-daemonset")
+				defer cleanupPod(ctx, k8sClient, pod)
+
+				cfg := &config.Config{
+					Rules: []config.Rule{{
+						TargetTaint:  "test-taint",
+						OwnedByNames: []string{"test-daemonset"},
+						Uncordon:     true,
+					}},
+					RemovalStrategy: strategy,
+					ForceApply:      true,
+				}
+				cfg.Default()
+				reconciler.Config = config.NewStore(cfg)
+
+				_, err := reconciler.Reconcile(ctx, reconcile.Request{
+					NamespacedName: types.NamespacedName{Name: node.Name},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				updatedNode := &corev1.Node{}
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: node.Name}, updatedNode)).To(Succeed())
+				Expect(updatedNode.Spec.Taints).To(BeEmpty())
+				Expect(updatedNode.Spec.Unschedulable).To(BeFalse())
+			},
+			Entry("with a merge patch", config.RemovalStrategyPatch),
+			Entry("with a JSON patch", config.RemovalStrategyJSONPatch),
+			Entry("with server-side apply", config.RemovalStrategyApply),
+		)
+
+		It("should leave the node cordoned while the taint stays", func() {
+			node.Spec.Unschedulable = true
+			Expect(k8sClient.Update(ctx, node)).To(Succeed())
+
+			cfg := &config.Config{Rules: []config.Rule{{
+				TargetTaint:  "test-taint",
+				OwnedByNames: []string{"test-daemonset"},
+				Uncordon:     true,
+			}}}
+			cfg.Default()
+			reconciler.Config = config.NewStore(cfg)
+
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: node.Name},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			updatedNode := &corev1.Node{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: node.Name}, updatedNode)).To(Succeed())
+			Expect(updatedNode.Spec.Unschedulable).To(BeTrue())
+		})
+	})
+
+	Context("when a rule relabels the node", func() {
+		DescribeTable("should change the labels together with the taint",
+			func(strategy config.RemovalStrategy) {
+				node.Labels = map[string]string{"example.com/bootstrap": "pending"}
+				Expect(k8sClient.Update(ctx, node)).To(Succeed())
+				pod := createReadyPod(ctx, k8sClient, "test-pod-labels", node.Name, "test-daemonset")
+				defer cleanupPod(ctx, k8sClient, pod)
+
+				cfg := &config.Config{
+					Rules: []config.Rule{{
+						TargetTaint:  "test-taint",
+						OwnedByNames: []string{"test-daemonset"},
+						SetLabels:    map[string]string{"example.com/ready": "true"},
+						RemoveLabels: []string{"example.com/bootstrap"},
+					}},
+					RemovalStrategy: strategy,
+				}
+				cfg.Default()
+				reconciler.Config = config.NewStore(cfg)
+
+				_, err := reconciler.Reconcile(ctx, reconcile.Request{
+					NamespacedName: types.NamespacedName{Name: node.Name},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				updatedNode := &corev1.Node{}
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: node.Name}, updatedNode)).To(Succeed())
+				Expect(updatedNode.Spec.Taints).To(BeEmpty())
+				Expect(updatedNode.Labels).To(HaveKeyWithValue("example.com/ready", "true"))
+				Expect(updatedNode.Labels).NotTo(HaveKey("example.com/bootstrap"))
+			},
+			Entry("with a merge patch", config.RemovalStrategyPatch),
+			Entry("with a JSON patch", config.RemovalStrategyJSONPatch),
+		)
+	})
+
+	Context("when untaints are recorded", func() {
+		It("should annotate the node with the removal and its gating pods", func() {
+			pod := createReadyPod(ctx, k8sClient, "test-pod-record", node.Name, "test-daemonset")
+			defer cleanupPod(ctx, k8sClient, pod)
+
+			cfg := &config.Config{
+				Rules:         []config.Rule{{TargetTaint: "test-taint", OwnedByNames: []string{"test-daemonset"}}},
+				RecordUntaint: true,
+			}
+			cfg.Default()
+			reconciler.Config = config.NewStore(cfg)
+
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: node.Name},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			updatedNode := &corev1.Node{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: node.Name}, updatedNode)).To(Succeed())
+			Expect(updatedNode.Spec.Taints).To(BeEmpty())
+			Expect(updatedNode.Annotations).To(HaveKeyWithValue(UntaintedByAnnotation, FieldManager))
+			Expect(updatedNode.Annotations).To(HaveKeyWithValue(UntaintedTaintsAnnotation, "test-taint"))
+			Expect(updatedNode.Annotations).To(HaveKeyWithValue(UntaintedPodsAnnotation, pod.Namespace+"/"+pod.Name))
+			Expect(updatedNode.Annotations).To(HaveKeyWithValue(UntaintReasonAnnotation, UntaintReasonWorkloadsReady))
+			Expect(updatedNode.Annotations).To(HaveKey(UntaintedAtAnnotation))
+		})
+	})
+
+	Context("when a node condition is configured", func() {
+		It("should report the gate status on the node", func() {
+			cfg := &config.Config{
+				Rules:             []config.Rule{{TargetTaint: "test-taint", OwnedByNames: []string{"test-daemonset"}}},
+				NodeConditionType: "AgentsReady",
+			}
+			cfg.Default()
+			reconciler.Config = config.NewStore(cfg)
+
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: node.Name},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			updatedNode := &corev1.Node{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: node.Name}, updatedNode)).To(Succeed())
+			Expect(updatedNode.Status.Conditions).To(ContainElement(And(
+				HaveField("Type", corev1.NodeConditionType("AgentsReady")),
+				HaveField("Status", corev1.ConditionFalse),
+				HaveField("Reason", untaintv1alpha1.ReasonWaitingForWorkload),
+			)))
+
+			pod := createReadyPod(ctx, k8sClient, "test-pod-condition", node.Name, "test-daemonset")
+			defer cleanupPod(ctx, k8sClient, pod)
+
+			_, err = reconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: node.Name},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: node.Name}, updatedNode)).To(Succeed())
+			Expect(updatedNode.Spec.Taints).To(BeEmpty())
+			Expect(updatedNode.Status.Conditions).To(ContainElement(And(
+				HaveField("Type", corev1.NodeConditionType("AgentsReady")),
+				HaveField("Status", corev1.ConditionTrue),
+				HaveField("Reason", untaintv1alpha1.ReasonTaintRemoved),
+			)))
+		})
+	})
+
+	Context("when removing a taint in stages", func() {
+		It("should downgrade the taint before removing it", func() {
+			node.Spec.Taints[0].Effect = corev1.TaintEffectNoExecute
+			Expect(k8sClient.Update(ctx, node)).To(Succeed())
+			pod := createReadyPod(ctx, k8sClient, "test-pod-staged", node.Name, "test-daemonset")
+			defer cleanupPod(ctx, k8sClient, pod)
+
+			cfg := &config.Config{Rules: []config.Rule{{
+				TargetTaint:  "test-taint",
+				OwnedByNames: []string{"test-daemonset"},
+				StagedRemoval: []config.RemovalStage{
+					{Effect: corev1.TaintEffectNoSchedule, Dwell: metav1.Duration{Duration: time.Minute}},
+				},
+			}}}
+			cfg.Default()
+			reconciler.Config = config.NewStore(cfg)
+
+			result, err := reconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: node.Name},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(time.Minute))
+
+			updatedNode := &corev1.Node{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: node.Name}, updatedNode)).To(Succeed())
+			Expect(updatedNode.Spec.Taints).To(ConsistOf(And(
+				HaveField("Key", "test-taint"),
+				HaveField("Effect", corev1.TaintEffectNoSchedule),
+			)))
+		})
+	})
+
+	Context("when a taint outlives its maxWait", func() {
+		var recorder *record.FakeRecorder
+
+		BeforeEach(func() {
+			added := metav1.NewTime(time.Now().Add(-2 * time.Minute))
+			node.Spec.Taints = []corev1.Taint{{Key: "test-taint", Effect: corev1.TaintEffectNoExecute, TimeAdded: &added}}
+			Expect(k8sClient.Update(ctx, node)).To(Succeed())
+
+			recorder = record.NewFakeRecorder(10)
+			reconciler.Recorder = recorder
+		})
+
+		reconcileWith := func(action config.MaxWaitAction) {
+			cfg := &config.Config{Rules: []config.Rule{{
+				TargetTaint:  "test-taint",
+				OwnedByNames: []string{"test-daemonset"},
+				MaxWait:      &metav1.Duration{Duration: time.Minute},
+				OnMaxWait:    action,
+			}}}
+			cfg.Default()
+			reconciler.Config = config.NewStore(cfg)
+
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: node.Name},
+			})
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		It("should keep the taint and emit a warning event by default", func() {
+			reconcileWith(config.MaxWaitActionEvent)
+
+			updatedNode := &corev1.Node{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: node.Name}, updatedNode)).To(Succeed())
+			Expect(hasTaint(updatedNode, "test-taint")).To(BeTrue())
+			Expect(recorder.Events).To(Receive(HavePrefix("Warning UntaintDeadlineExceeded Taint test-taint")))
+
+			// The warning isn't repeated while the taint stays
+			reconcileWith(config.MaxWaitActionEvent)
+			Expect(recorder.Events).NotTo(Receive())
+		})
+
+		It("should remove the taint when configured to force it", func() {
+			reconcileWith(config.MaxWaitActionForceRemove)
+
+			updatedNode := &corev1.Node{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: node.Name}, updatedNode)).To(Succeed())
+			Expect(hasTaint(updatedNode, "test-taint")).To(BeFalse())
+			Expect(recorder.Events).To(Receive(HavePrefix("Warning TaintForceRemoved Removing taint test-taint")))
+		})
+	})
+
+	Context("when an untaint cooldown is configured", func() {
+		It("should ignore a taint re-added during the cooldown", func() {
+			pod := createReadyPod(ctx, k8sClient, "test-pod-cooldown", node.Name, "test-daemonset")
+			defer cleanupPod(ctx, k8sClient, pod)
+
+			cfg := reconciler.currentConfig()
+			cfg.UntaintCooldown = metav1.Duration{Duration: time.Minute}
+			reconciler.Config = config.NewStore(cfg)
+
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: node.Name},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			// Another controller puts the taint back
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: node.Name}, node)).To(Succeed())
+			Expect(node.Spec.Taints).To(BeEmpty())
+			node.Spec.Taints = []corev1.Taint{{Key: "test-taint", Effect: corev1.TaintEffectNoSchedule}}
+			Expect(k8sClient.Update(ctx, node)).To(Succeed())
+
+			result, err := reconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: node.Name},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(And(BeNumerically(">", 0), BeNumerically("<=", time.Minute)))
+
+			updatedNode := &corev1.Node{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: node.Name}, updatedNode)).To(Succeed())
+			Expect(hasTaint(updatedNode, "test-taint")).To(BeTrue())
+		})
+	})
+
+	Context("when a minimum ready time is configured", func() {
+		It("should keep the taint until the pod has been ready long enough", func() {
+			pod := createReadyPod(ctx, k8sClient, "test-pod-stabilizing", node.Name, "test-daemonset")
+			defer cleanupPod(ctx, k8sClient, pod)
+			pod.Status.Conditions[0].LastTransitionTime = metav1.NewTime(time.Now().Add(-50 * time.Second))
+
+			cfg := reconciler.currentConfig()
+			cfg.MinReadySeconds = 60
+			reason, err := reconciler.workloadsBlocked(ctx, []corev1.Pod{*pod}, []string{"test-daemonset"}, cfg)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(reason).NotTo(BeNil())
+			Expect(reason.reason).To(Equal(untaintv1alpha1.ReasonWorkloadStabilizing))
+			Expect(reason.retryAfter).To(BeNumerically("~", 10*time.Second, time.Second))
+
+			pod.Status.Conditions[0].LastTransitionTime = metav1.NewTime(time.Now().Add(-time.Minute))
+			reason, err = reconciler.workloadsBlocked(ctx, []corev1.Pod{*pod}, []string{"test-daemonset"}, cfg)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(reason).To(BeNil())
+		})
+	})
+
+	Context("when a required DaemonSet is rolling out", func() {
+		It("should wait for the pod of the current template generation", func() {
+			labels := map[string]string{"app": "test-daemonset"}
+			ds := &appsv1.DaemonSet{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-daemonset", Namespace: "default"},
+				Spec: appsv1.DaemonSetSpec{
+					Selector: &metav1.LabelSelector{MatchLabels: labels},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{Labels: labels},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{{Name: "test-container", Image: "busybox"}},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, ds)).To(Succeed())
+			defer func() { Expect(k8sClient.Delete(ctx, ds)).To(Succeed()) }()
+
+			// Roll out a new template, bumping the template generation to 2
+			ds.Spec.Template.Spec.Containers[0].Image = "busybox:latest"
+			Expect(k8sClient.Update(ctx, ds)).To(Succeed())
+			Expect(ds.Annotations).To(HaveKeyWithValue(appsv1.DeprecatedTemplateGeneration, "2"))
+
+			pod := createReadyPod(ctx, k8sClient, "test-pod-outdated", node.Name, "test-daemonset")
+			defer cleanupPod(ctx, k8sClient, pod)
+			pod.Labels = map[string]string{podTemplateGenerationLabel: "1"}
+			pod.OwnerReferences[0].UID = ds.UID
+			pod.OwnerReferences[0].Controller = ptr.To(true)
+			Expect(k8sClient.Update(ctx, pod)).To(Succeed())
+
+			reason, err := reconciler.workloadsBlocked(ctx, []corev1.Pod{*pod}, []string{"test-daemonset"}, reconciler.currentConfig())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(reason).NotTo(BeNil())
+			Expect(reason.reason).To(Equal(untaintv1alpha1.ReasonWorkloadOutdated))
+
+			pod.Labels[podTemplateGenerationLabel] = "2"
+			Expect(k8sClient.Update(ctx, pod)).To(Succeed())
+			Eventually(func() error {
+				_, err := reconciler.Reconcile(ctx, reconcile.Request{
+					NamespacedName: types.NamespacedName{Name: node.Name},
+				})
+				if err != nil {
+					return err
+				}
+				updatedNode := &corev1.Node{}
+				if err := k8sClient.Get(ctx, types.NamespacedName{Name: node.Name}, updatedNode); err != nil {
+					return err
+				}
+				if hasTaint(updatedNode, "test-taint") {
+					return fmt.Errorf("node is still tainted")
+				}
+				return nil
+			}, "10s", "1s").Should(Succeed())
+		})
+	})
+
+	Context("when requeueing blocked nodes", func() {
+		It("should use the shortest interval of the blocked rules without jitter", func() {
+			node.Spec.Taints = append(node.Spec.Taints, corev1.Taint{
+				Key:    "second-taint",
+				Effect: corev1.TaintEffectNoSchedule,
+			})
+			Expect(k8sClient.Update(ctx, node)).To(Succeed())
+
+			noJitter := 0.0
+			cfg := &config.Config{
+				Rules: []config.Rule{
+					{TargetTaint: "test-taint", OwnedByNames: []string{"test-daemonset"}},
+					{
+						TargetTaint:     "second-taint",
+						OwnedByNames:    []string{"second-daemonset"},
+						RequeueInterval: &metav1.Duration{Duration: 5 * time.Second},
+					},
+				},
+				RequeueInterval: metav1.Duration{Duration: time.Minute},
+				RequeueJitter:   &noJitter,
+			}
+			cfg.Default()
+			reconciler.Config = config.NewStore(cfg)
+
+			result, err := reconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: node.Name},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(5 * time.Second))
+		})
+	})
+
+	Context("when watching pods", func() {
+		It("should map pods to their node", func() {
+			pod := &corev1.Pod{Spec: corev1.PodSpec{NodeName: "test-node"}}
+			Expect(podToNode(ctx, pod)).To(ConsistOf(reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: "test-node"},
+			}))
+			Expect(podToNode(ctx, &corev1.Pod{})).To(BeEmpty())
+		})
+
+		It("should only pass pod updates that can change readiness", func() {
+			oldPod := &corev1.Pod{Spec: corev1.PodSpec{NodeName: "test-node"}}
+
+			relabeled := oldPod.DeepCopy()
+			relabeled.Labels = map[string]string{"foo": "bar"}
+			Expect(podChangedPredicate().Update(event.UpdateEvent{ObjectOld: oldPod, ObjectNew: relabeled})).To(BeFalse())
+
+			ready := oldPod.DeepCopy()
+			ready.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}
+			Expect(podChangedPredicate().Update(event.UpdateEvent{ObjectOld: oldPod, ObjectNew: ready})).To(BeTrue())
+		})
+	})
+
+	Context("when watching nodes", func() {
+		It("should only pass node updates that add a target taint", func() {
+			untainted := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "test-node"}}
+			tainted := untainted.DeepCopy()
+			tainted.Spec.Taints = []corev1.Taint{{Key: "test-taint", Effect: corev1.TaintEffectNoSchedule}}
+			otherTaint := untainted.DeepCopy()
+			otherTaint.Spec.Taints = []corev1.Taint{{Key: "other-taint", Effect: corev1.TaintEffectNoSchedule}}
+
+			predicate := reconciler.nodePredicate()
+			Expect(predicate.Update(event.UpdateEvent{ObjectOld: untainted, ObjectNew: tainted})).To(BeTrue())
+			Expect(predicate.Update(event.UpdateEvent{ObjectOld: tainted, ObjectNew: tainted})).To(BeFalse())
+			Expect(predicate.Update(event.UpdateEvent{ObjectOld: tainted, ObjectNew: untainted})).To(BeFalse())
+			Expect(predicate.Update(event.UpdateEvent{ObjectOld: untainted, ObjectNew: otherTaint})).To(BeFalse())
+		})
 	})
 })
@@ -0,0 +1,25 @@
+package controller
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+// TransformNode is a cache transform keeping only the parts of a node the
+// operator reads: its metadata less managed fields, its spec, and the
+// allocatable resources and conditions of its status. The images, volumes
+// and system info reported by kubelet dominate the size of a node, so nodes
+// read from the cache must only be written with patches computed against
+// themselves.
+func TransformNode(obj interface{}) (interface{}, error) {
+	node, ok := obj.(*corev1.Node)
+	if !ok {
+		// Tombstones of deleted nodes are passed as they are
+		return obj, nil
+	}
+	node.ManagedFields = nil
+	node.Status = corev1.NodeStatus{
+		Allocatable: node.Status.Allocatable,
+		Conditions:  node.Status.Conditions,
+	}
+	return node, nil
+}
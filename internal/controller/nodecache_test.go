@@ -0,0 +1,53 @@
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+var _ = Describe("TransformNode", func() {
+	It("should keep only what the operator reads", func() {
+		allocatable := corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("4")}
+		conditions := []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}}
+		node := &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:          "node",
+				Labels:        map[string]string{"pool": "gpu"},
+				Annotations:   map[string]string{"example.com/untainted-at": "now"},
+				ManagedFields: []metav1.ManagedFieldsEntry{{Manager: "kubelet"}},
+			},
+			Spec: corev1.NodeSpec{
+				ProviderID:    "aws:///us-east-1a/i-0123",
+				Unschedulable: true,
+				Taints:        []corev1.Taint{{Key: "example.com/not-ready", Effect: corev1.TaintEffectNoSchedule}},
+			},
+			Status: corev1.NodeStatus{
+				Capacity:    allocatable,
+				Allocatable: allocatable,
+				Conditions:  conditions,
+				Images:      []corev1.ContainerImage{{Names: []string{"registry.example.com/agent:v1"}}},
+				NodeInfo:    corev1.NodeSystemInfo{KubeletVersion: "v1.31.0"},
+				Addresses:   []corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: "10.0.0.1"}},
+			},
+		}
+		spec := *node.Spec.DeepCopy()
+
+		obj, err := TransformNode(node)
+		Expect(err).NotTo(HaveOccurred())
+		stripped := obj.(*corev1.Node)
+		Expect(stripped.Labels).To(HaveKey("pool"))
+		Expect(stripped.Annotations).To(HaveKey("example.com/untainted-at"))
+		Expect(stripped.ManagedFields).To(BeNil())
+		Expect(stripped.Spec).To(Equal(spec))
+		Expect(stripped.Status).To(Equal(corev1.NodeStatus{Allocatable: allocatable, Conditions: conditions}))
+	})
+
+	It("should pass other objects through", func() {
+		tombstone := cache.DeletedFinalStateUnknown{Key: "node"}
+		Expect(TransformNode(tombstone)).To(Equal(tombstone))
+	})
+})
@@ -0,0 +1,106 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	untaintv1alpha1 "github.com/jslay88/generic-untaint-operator/api/v1alpha1"
+	"github.com/jslay88/generic-untaint-operator/internal/config"
+)
+
+// +kubebuilder:rbac:groups=core,resources=nodes/status,verbs=patch
+
+// setNodeCondition sets the configured node condition, and the condition of
+// the node's NodeClaim or Machine when Karpenter or Cluster API support is
+// on, to False with reason while the node's target taints are blocked, or to
+// True once they are removed. Nothing is written when the condition already
+// matches.
+func (r *NodeReconciler) setNodeCondition(
+	ctx context.Context,
+	node *corev1.Node,
+	cfg *config.Config,
+	untainted bool,
+	reason, message string,
+) error {
+	if err := r.setNodeClaimCondition(ctx, node, untainted, reason, message); err != nil {
+		return err
+	}
+	if err := r.setMachineCondition(ctx, node, untainted, reason, message); err != nil {
+		return err
+	}
+	if cfg.NodeConditionType == "" {
+		return nil
+	}
+	status := corev1.ConditionFalse
+	if untainted {
+		status = corev1.ConditionTrue
+	}
+	conditions, changed := updateNodeCondition(node.Status.Conditions, corev1.NodeCondition{
+		Type:    cfg.NodeConditionType,
+		Status:  status,
+		Reason:  reason,
+		Message: message,
+	}, metav1.Now())
+	if !changed {
+		return nil
+	}
+
+	// A strategic merge patch merges conditions by type, leaving the ones
+	// kubelet keeps updating untouched
+	patch := client.StrategicMergeFrom(node.DeepCopy())
+	node.Status.Conditions = conditions
+	if err := r.waitNodeWrite(ctx); err != nil {
+		return err
+	}
+	if err := r.Client.Status().Patch(ctx, node, patch); err != nil {
+		return fmt.Errorf("failed to update node condition: %w", err)
+	}
+	return nil
+}
+
+// clearNodeCondition sets the configured node condition, and the condition
+// of the node's NodeClaim or Machine, to True if it is False, for nodes whose
+// target taints were removed by someone else
+func (r *NodeReconciler) clearNodeCondition(ctx context.Context, node *corev1.Node, cfg *config.Config) error {
+	if err := r.clearNodeClaimCondition(ctx, node); err != nil {
+		return err
+	}
+	if err := r.clearMachineCondition(ctx, node); err != nil {
+		return err
+	}
+	if nodeConditionStatus(node, cfg.NodeConditionType) != corev1.ConditionFalse {
+		return nil
+	}
+	return r.setNodeCondition(ctx, node, cfg, true, untaintv1alpha1.ReasonTaintRemoved, "")
+}
+
+// updateNodeCondition returns conditions with condition set, keeping the
+// transition time while the status stays the same, and whether anything changed
+func updateNodeCondition(
+	conditions []corev1.NodeCondition,
+	condition corev1.NodeCondition,
+	now metav1.Time,
+) ([]corev1.NodeCondition, bool) {
+	condition.LastHeartbeatTime = now
+	condition.LastTransitionTime = now
+	for i, existing := range conditions {
+		if existing.Type != condition.Type {
+			continue
+		}
+		if existing.Status == condition.Status && existing.Reason == condition.Reason &&
+			existing.Message == condition.Message {
+			return conditions, false
+		}
+		if existing.Status == condition.Status {
+			condition.LastTransitionTime = existing.LastTransitionTime
+		}
+		updated := append([]corev1.NodeCondition(nil), conditions...)
+		updated[i] = condition
+		return updated, true
+	}
+	return append(append([]corev1.NodeCondition(nil), conditions...), condition), true
+}
@@ -0,0 +1,54 @@
+package controller
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("updateNodeCondition", func() {
+	earlier := metav1.NewTime(time.Now().Add(-time.Hour).Truncate(time.Second))
+	now := metav1.NewTime(time.Now().Truncate(time.Second))
+	ready := corev1.NodeCondition{Type: corev1.NodeReady, Status: corev1.ConditionTrue}
+	blocked := corev1.NodeCondition{
+		Type:               "AgentsReady",
+		Status:             corev1.ConditionFalse,
+		Reason:             "WorkloadUnready",
+		Message:            "pod kube-system/agent is not ready",
+		LastHeartbeatTime:  earlier,
+		LastTransitionTime: earlier,
+	}
+
+	It("should add the condition next to the existing ones", func() {
+		conditions, changed := updateNodeCondition([]corev1.NodeCondition{ready}, blocked, now)
+		Expect(changed).To(BeTrue())
+		Expect(conditions).To(HaveLen(2))
+		Expect(conditions[0]).To(Equal(ready))
+		Expect(conditions[1].LastTransitionTime).To(Equal(now))
+	})
+
+	It("should leave a matching condition alone", func() {
+		existing := []corev1.NodeCondition{ready, blocked}
+		conditions, changed := updateNodeCondition(existing, blocked, now)
+		Expect(changed).To(BeFalse())
+		Expect(conditions).To(Equal(existing))
+	})
+
+	It("should keep the transition time while the status stays the same", func() {
+		stabilizing := blocked
+		stabilizing.Reason = "WorkloadStabilizing"
+		conditions, changed := updateNodeCondition([]corev1.NodeCondition{blocked}, stabilizing, now)
+		Expect(changed).To(BeTrue())
+		Expect(conditions[0].Reason).To(Equal("WorkloadStabilizing"))
+		Expect(conditions[0].LastTransitionTime).To(Equal(earlier))
+		Expect(conditions[0].LastHeartbeatTime).To(Equal(now))
+
+		untainted := corev1.NodeCondition{Type: "AgentsReady", Status: corev1.ConditionTrue, Reason: "TaintRemoved"}
+		conditions, changed = updateNodeCondition(conditions, untainted, now)
+		Expect(changed).To(BeTrue())
+		Expect(conditions[0].LastTransitionTime).To(Equal(now))
+	})
+})
@@ -0,0 +1,84 @@
+package controller
+
+import (
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	untaintv1alpha1 "github.com/jslay88/generic-untaint-operator/api/v1alpha1"
+	"github.com/jslay88/generic-untaint-operator/internal/config"
+)
+
+// nodeNotReady returns why the node's age or own conditions rule out removing
+// its taints, or nil when the configured node checks pass
+func nodeNotReady(node *corev1.Node, cfg *config.Config, now time.Time) *blockReason {
+	if cfg.MinNodeAge.Duration > 0 {
+		age := now.Sub(node.CreationTimestamp.Time)
+		if remaining := cfg.MinNodeAge.Duration - age; remaining > 0 {
+			return &blockReason{
+				reason:     untaintv1alpha1.ReasonNodeTooNew,
+				message:    fmt.Sprintf("node was created %s ago, less than %s", age.Round(time.Second), cfg.MinNodeAge.Duration),
+				retryAfter: remaining,
+			}
+		}
+	}
+	if cfg.RequireNodeReady {
+		if status := nodeConditionStatus(node, corev1.NodeReady); status != corev1.ConditionTrue {
+			return &blockReason{
+				reason:  untaintv1alpha1.ReasonNodeNotReady,
+				message: fmt.Sprintf("node Ready condition is %s", status),
+			}
+		}
+	}
+	if cfg.RequireNetworkAvailable {
+		// Only some network plugins report the condition, so a missing one passes
+		if status := nodeConditionStatus(node, corev1.NodeNetworkUnavailable); status == corev1.ConditionTrue {
+			return &blockReason{
+				reason:  untaintv1alpha1.ReasonNodeNotReady,
+				message: "node network is unavailable",
+			}
+		}
+	}
+	for _, conditionType := range cfg.BlockingConditions {
+		if nodeConditionStatus(node, conditionType) == corev1.ConditionTrue {
+			return &blockReason{
+				reason:  untaintv1alpha1.ReasonNodeProblem,
+				message: fmt.Sprintf("node condition %s is True", conditionType),
+			}
+		}
+	}
+	return nil
+}
+
+// nodeConditionStatus returns the status of the node condition, or Unknown
+// when the node doesn't report it
+func nodeConditionStatus(node *corev1.Node, conditionType corev1.NodeConditionType) corev1.ConditionStatus {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == conditionType {
+			return condition.Status
+		}
+	}
+	return corev1.ConditionUnknown
+}
+
+// nodeGateChanged reports whether an update to a node paused or resumed it, or
+// changed one of the conditions cfg checks before its taints are removed
+func nodeGateChanged(oldNode, newNode *corev1.Node, cfg *config.Config) bool {
+	if nodePaused(oldNode) != nodePaused(newNode) {
+		return true
+	}
+	var conditionTypes []corev1.NodeConditionType
+	if cfg.RequireNodeReady {
+		conditionTypes = append(conditionTypes, corev1.NodeReady)
+	}
+	if cfg.RequireNetworkAvailable {
+		conditionTypes = append(conditionTypes, corev1.NodeNetworkUnavailable)
+	}
+	for _, conditionType := range append(conditionTypes, cfg.BlockingConditions...) {
+		if nodeConditionStatus(oldNode, conditionType) != nodeConditionStatus(newNode, conditionType) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,86 @@
+package controller
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	untaintv1alpha1 "github.com/jslay88/generic-untaint-operator/api/v1alpha1"
+	"github.com/jslay88/generic-untaint-operator/internal/config"
+)
+
+var _ = Describe("nodeNotReady", func() {
+	withConditions := func(conditions ...corev1.NodeCondition) *corev1.Node {
+		return &corev1.Node{Status: corev1.NodeStatus{Conditions: conditions}}
+	}
+	ready := corev1.NodeCondition{Type: corev1.NodeReady, Status: corev1.ConditionTrue}
+	notReady := corev1.NodeCondition{Type: corev1.NodeReady, Status: corev1.ConditionFalse}
+	networkDown := corev1.NodeCondition{Type: corev1.NodeNetworkUnavailable, Status: corev1.ConditionTrue}
+	networkUp := corev1.NodeCondition{Type: corev1.NodeNetworkUnavailable, Status: corev1.ConditionFalse}
+	kernelDeadlock := corev1.NodeCondition{Type: "KernelDeadlock", Status: corev1.ConditionTrue}
+	kernelHealthy := corev1.NodeCondition{Type: "KernelDeadlock", Status: corev1.ConditionFalse}
+
+	It("should not check the node unless configured", func() {
+		Expect(nodeNotReady(withConditions(notReady, networkDown), &config.Config{}, time.Now())).To(BeNil())
+	})
+
+	It("should require the node Ready condition", func() {
+		cfg := &config.Config{RequireNodeReady: true}
+		Expect(nodeNotReady(withConditions(ready), cfg, time.Now())).To(BeNil())
+
+		reason := nodeNotReady(withConditions(notReady), cfg, time.Now())
+		Expect(reason).NotTo(BeNil())
+		Expect(reason.reason).To(Equal(untaintv1alpha1.ReasonNodeNotReady))
+		Expect(reason.message).To(ContainSubstring("False"))
+
+		// A node that never reported Ready is not ready either
+		Expect(nodeNotReady(withConditions(), cfg, time.Now()).message).To(ContainSubstring("Unknown"))
+	})
+
+	It("should require the network to be available when reported", func() {
+		cfg := &config.Config{RequireNetworkAvailable: true}
+		Expect(nodeNotReady(withConditions(networkUp), cfg, time.Now())).To(BeNil())
+		Expect(nodeNotReady(withConditions(), cfg, time.Now())).To(BeNil())
+		Expect(nodeNotReady(withConditions(networkDown), cfg, time.Now())).NotTo(BeNil())
+	})
+
+	It("should hold back nodes younger than the minimum age", func() {
+		cfg := &config.Config{MinNodeAge: metav1.Duration{Duration: 5 * time.Minute}}
+		now := time.Now()
+		node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{CreationTimestamp: metav1.NewTime(now.Add(-time.Minute))}}
+
+		reason := nodeNotReady(node, cfg, now)
+		Expect(reason).NotTo(BeNil())
+		Expect(reason.reason).To(Equal(untaintv1alpha1.ReasonNodeTooNew))
+		Expect(reason.retryAfter).To(Equal(4 * time.Minute))
+
+		Expect(nodeNotReady(node, cfg, now.Add(4*time.Minute))).To(BeNil())
+	})
+
+	It("should block on the configured problem conditions", func() {
+		cfg := &config.Config{BlockingConditions: []corev1.NodeConditionType{"KernelDeadlock", "ReadonlyFilesystem"}}
+		Expect(nodeNotReady(withConditions(kernelHealthy), cfg, time.Now())).To(BeNil())
+		// A condition node-problem-detector doesn't report passes
+		Expect(nodeNotReady(withConditions(), cfg, time.Now())).To(BeNil())
+
+		reason := nodeNotReady(withConditions(kernelDeadlock), cfg, time.Now())
+		Expect(reason).NotTo(BeNil())
+		Expect(reason.reason).To(Equal(untaintv1alpha1.ReasonNodeProblem))
+		Expect(reason.message).To(ContainSubstring("KernelDeadlock"))
+	})
+
+	It("should notice when a gating condition changes", func() {
+		cfg := &config.Config{RequireNodeReady: true, RequireNetworkAvailable: true}
+		Expect(nodeGateChanged(withConditions(notReady), withConditions(ready), cfg)).To(BeTrue())
+		Expect(nodeGateChanged(withConditions(ready, networkDown), withConditions(ready, networkUp), cfg)).To(BeTrue())
+		Expect(nodeGateChanged(withConditions(ready), withConditions(ready), cfg)).To(BeFalse())
+		Expect(nodeGateChanged(withConditions(kernelDeadlock), withConditions(kernelHealthy), cfg)).To(BeFalse())
+
+		cfg = &config.Config{BlockingConditions: []corev1.NodeConditionType{"KernelDeadlock"}}
+		Expect(nodeGateChanged(withConditions(kernelDeadlock), withConditions(kernelHealthy), cfg)).To(BeTrue())
+		Expect(nodeGateChanged(withConditions(notReady), withConditions(ready), cfg)).To(BeFalse())
+	})
+})
@@ -0,0 +1,32 @@
+package controller
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// selectedNodes wraps mapper, when NodeSelector is set, to drop the requests
+// for nodes missing from the node cache c, which only holds the selected
+// nodes, so the events of pods and other objects on the rest of the nodes
+// never reach the work queue
+func (r *NodeReconciler) selectedNodes(c client.Reader, mapper handler.MapFunc) handler.MapFunc {
+	if r.NodeSelector == nil {
+		return mapper
+	}
+	return func(ctx context.Context, obj client.Object) []reconcile.Request {
+		requests := mapper(ctx, obj)
+		selected := requests[:0]
+		for _, req := range requests {
+			if err := c.Get(ctx, req.NamespacedName, &corev1.Node{}); apierrors.IsNotFound(err) {
+				continue
+			}
+			selected = append(selected, req)
+		}
+		return selected
+	}
+}
@@ -0,0 +1,38 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("selectedNodes", func() {
+	selected := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "selected"}}
+	c := fake.NewClientBuilder().WithObjects(selected).Build()
+	mapper := func(context.Context, client.Object) []reconcile.Request {
+		return []reconcile.Request{
+			{NamespacedName: types.NamespacedName{Name: "selected"}},
+			{NamespacedName: types.NamespacedName{Name: "unselected"}},
+		}
+	}
+
+	It("should only map to the nodes in the cache", func() {
+		r := &NodeReconciler{NodeSelector: labels.SelectorFromSet(labels.Set{"pool": "gpu"})}
+		Expect(r.selectedNodes(c, mapper)(context.Background(), &corev1.Pod{})).To(ConsistOf(
+			reconcile.Request{NamespacedName: types.NamespacedName{Name: "selected"}},
+		))
+	})
+
+	It("should map to every node without a node selector", func() {
+		r := &NodeReconciler{}
+		Expect(r.selectedNodes(c, mapper)(context.Background(), &corev1.Pod{})).To(HaveLen(2))
+	})
+})
@@ -0,0 +1,269 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/jslay88/generic-untaint-operator/internal/config"
+)
+
+const (
+	// NotificationNodeStuck is the event of a notification about a node that
+	// kept its target taints longer than the configured threshold
+	NotificationNodeStuck = "NodeStuck"
+	// NotificationTaintRemoved is the event of a notification about target
+	// taints removed from a node
+	NotificationTaintRemoved = "TaintRemoved"
+)
+
+// NotificationData is what the notification templates are rendered with, and
+// what the JSON format posts along with the message
+type NotificationData struct {
+	// Event is NotificationNodeStuck or NotificationTaintRemoved
+	Event string `json:"event"`
+	// Node is the name of the node
+	Node string `json:"node"`
+	// Taints are the keys of the target taints removed from the node
+	Taints []string `json:"taints,omitempty"`
+	// Pods are the namespace/name of the pods that satisfied the gate
+	Pods []string `json:"pods,omitempty"`
+	// Reason and Message explain why a stuck node keeps its taints
+	Reason  string `json:"reason,omitempty"`
+	Message string `json:"message,omitempty"`
+	// BlockedFor is how long a stuck node has kept its taints
+	BlockedFor string `json:"blockedFor,omitempty"`
+}
+
+// stuckNotices remembers the nodes a stuck notification was posted for, so
+// each is only posted once until the node is untainted. The zero value is
+// ready to use.
+type stuckNotices struct {
+	mu       sync.Mutex
+	notified map[string]struct{}
+}
+
+// first records that node is stuck and reports whether it wasn't recorded before
+func (s *stuckNotices) first(node string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.notified[node]; ok {
+		return false
+	}
+	if s.notified == nil {
+		s.notified = make(map[string]struct{})
+	}
+	s.notified[node] = struct{}{}
+	return true
+}
+
+// forget drops the stuck notice recorded for node
+func (s *stuckNotices) forget(node string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.notified, node)
+}
+
+// notifyStuck posts a stuck notification for node once the oldest of the
+// rules' taints waited longer than the configured threshold
+func (r *NodeReconciler) notifyStuck(
+	ctx context.Context,
+	node *corev1.Node,
+	rules []config.Rule,
+	blocked *blockReason,
+	cfg *config.Config,
+	now time.Time,
+) {
+	n := cfg.Notifications
+	if n == nil || n.StuckAfter.Duration <= 0 {
+		return
+	}
+	var waited time.Duration
+	for _, rule := range rules {
+		waited = max(waited, now.Sub(r.ages.since(node, rule.TargetTaint, now)))
+	}
+	if waited < n.StuckAfter.Duration || !r.stuck.first(node.Name) {
+		return
+	}
+	err := r.notify(n, n.StuckTemplate, NotificationData{
+		Event:      NotificationNodeStuck,
+		Node:       node.Name,
+		Reason:     blocked.reason,
+		Message:    blocked.message,
+		BlockedFor: waited.Round(time.Second).String(),
+	})
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Failed to render stuck node notification", "node", node.Name)
+	}
+}
+
+// notifyUntainted posts a notification about the taints of request removed
+// from its node
+func (r *NodeReconciler) notifyUntainted(ctx context.Context, request HookRequest, cfg *config.Config) {
+	n := cfg.Notifications
+	if n == nil || !n.OnUntaint {
+		return
+	}
+	err := r.notify(n, n.UntaintTemplate, NotificationData{
+		Event:  NotificationTaintRemoved,
+		Node:   request.Node,
+		Taints: request.Taints,
+		Pods:   request.Pods,
+	})
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Failed to render untaint notification", "node", request.Node)
+	}
+}
+
+// notify renders text with data in the configured format and queues it to be
+// posted by the Notifier
+func (r *NodeReconciler) notify(n *config.Notifications, text string, data NotificationData) error {
+	tmpl, err := config.ParseNotificationTemplate(data.Event, text)
+	if err != nil {
+		return err
+	}
+	message := &strings.Builder{}
+	if err := tmpl.Execute(message, data); err != nil {
+		return fmt.Errorf("failed to render notification: %w", err)
+	}
+
+	var body []byte
+	if n.Format == config.NotificationFormatJSON {
+		body, err = json.Marshal(struct {
+			Text string `json:"text"`
+			NotificationData
+		}{Text: message.String(), NotificationData: data})
+	} else {
+		body, err = json.Marshal(map[string]string{"text": message.String()})
+	}
+	if err != nil {
+		return err
+	}
+	r.Notifier.post(notification{
+		url:     n.URL,
+		timeout: n.Timeout.Duration,
+		body:    body,
+		event:   data.Event,
+		node:    data.Node,
+	})
+	return nil
+}
+
+// DefaultNotificationQueueSize is how many notifications wait to be posted
+// before new ones are dropped
+const DefaultNotificationQueueSize = 100
+
+// notificationRetry is how many times, and how far apart, a failed
+// notification is posted again
+var notificationRetry = wait.Backoff{Steps: 5, Duration: time.Second, Factor: 2, Jitter: 0.1}
+
+// notification is a rendered notification waiting to be posted
+type notification struct {
+	url     string
+	timeout time.Duration
+	body    []byte
+	event   string
+	node    string
+}
+
+// Notifier posts the notifications to the configured webhook. Notifications
+// are posted in the background so a slow webhook never delays a reconcile,
+// posted again with backoff when they fail, and dropped when too many are
+// waiting. All methods are safe to call on a nil notifier, which posts
+// nothing.
+type Notifier struct {
+	// Client sends the notifications. Defaults to http.DefaultClient when unset.
+	Client *http.Client
+	// QueueSize is how many notifications may wait to be posted. Defaults to
+	// DefaultNotificationQueueSize when unset.
+	QueueSize int
+
+	once  sync.Once
+	queue chan notification
+}
+
+// post queues item to be posted
+func (n *Notifier) post(item notification) {
+	if n == nil {
+		return
+	}
+	select {
+	case n.notifications() <- item:
+	default:
+		log.Log.WithName("notifications").Info("Notification queue is full, dropping notification",
+			"event", item.event, "node", item.node)
+	}
+}
+
+// notifications returns the queue of notifications waiting to be posted
+func (n *Notifier) notifications() chan notification {
+	n.once.Do(func() {
+		size := n.QueueSize
+		if size <= 0 {
+			size = DefaultNotificationQueueSize
+		}
+		n.queue = make(chan notification, size)
+	})
+	return n.queue
+}
+
+// Start implements manager.Runnable and posts the queued notifications until
+// ctx is cancelled
+func (n *Notifier) Start(ctx context.Context) error {
+	queue := n.notifications()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case item := <-queue:
+			n.deliver(ctx, item)
+		}
+	}
+}
+
+// deliver posts item, again with backoff while it fails, logging the failure
+// once it gives up
+func (n *Notifier) deliver(ctx context.Context, item notification) {
+	err := retry.OnError(notificationRetry, func(error) bool { return ctx.Err() == nil }, func() error {
+		return n.send(ctx, item)
+	})
+	if err != nil {
+		log.FromContext(ctx).WithName("notifications").Error(err, "Failed to post notification",
+			"event", item.event, "node", item.node)
+	}
+}
+
+// send POSTs item to its webhook
+func (n *Notifier) send(ctx context.Context, item notification) error {
+	ctx, cancel := context.WithTimeout(ctx, item.timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, item.url, bytes.NewReader(item.body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	client := n.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook answered with status %s", resp.Status)
+	}
+	return nil
+}
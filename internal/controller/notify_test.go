@@ -0,0 +1,152 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	untaintv1alpha1 "github.com/jslay88/generic-untaint-operator/api/v1alpha1"
+	"github.com/jslay88/generic-untaint-operator/internal/config"
+)
+
+var _ = Describe("notifications", func() {
+	var (
+		posts   chan map[string]interface{}
+		failing atomic.Int32
+		r       *NodeReconciler
+		cfg     *config.Config
+		node    *corev1.Node
+		rules   []config.Rule
+		blocked *blockReason
+		now     time.Time
+	)
+
+	BeforeEach(func() {
+		posts = make(chan map[string]interface{}, 10)
+		failing.Store(0)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			defer GinkgoRecover()
+			body := map[string]interface{}{}
+			Expect(json.NewDecoder(req.Body).Decode(&body)).To(Succeed())
+			posts <- body
+			if failing.Add(-1) >= 0 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+		}))
+		DeferCleanup(server.Close)
+
+		retryBackoff := notificationRetry
+		notificationRetry = wait.Backoff{Steps: 3, Duration: 10 * time.Millisecond}
+		DeferCleanup(func() { notificationRetry = retryBackoff })
+
+		notifier := &Notifier{}
+		ctx, cancel := context.WithCancel(context.Background())
+		DeferCleanup(cancel)
+		go func() {
+			defer GinkgoRecover()
+			Expect(notifier.Start(ctx)).To(Succeed())
+		}()
+		r = &NodeReconciler{Notifier: notifier}
+		cfg = &config.Config{Notifications: &config.Notifications{
+			URL:        server.URL,
+			StuckAfter: metav1.Duration{Duration: 10 * time.Minute},
+			OnUntaint:  true,
+		}}
+		cfg.Default()
+		now = time.Now()
+		added := metav1.NewTime(now.Add(-15 * time.Minute))
+		node = &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "node"},
+			Spec: corev1.NodeSpec{Taints: []corev1.Taint{{
+				Key: "example.com/not-ready", Effect: corev1.TaintEffectNoExecute, TimeAdded: &added,
+			}}},
+		}
+		rules = []config.Rule{{TargetTaint: "example.com/not-ready"}}
+		blocked = &blockReason{
+			reason:  untaintv1alpha1.ReasonWorkloadUnready,
+			message: "taint example.com/not-ready: pod kube-system/agent-abc is not ready",
+		}
+	})
+
+	It("should post once for a node stuck beyond the threshold", func() {
+		r.notifyStuck(context.Background(), node, rules, blocked, cfg, now)
+		Eventually(posts).Should(Receive(Equal(map[string]interface{}{
+			"text": "Node node has kept its target taints for 15m0s: " +
+				"taint example.com/not-ready: pod kube-system/agent-abc is not ready",
+		})))
+
+		r.notifyStuck(context.Background(), node, rules, blocked, cfg, now)
+		Consistently(posts, 100*time.Millisecond).ShouldNot(Receive())
+
+		r.stuck.forget(node.Name)
+		r.notifyStuck(context.Background(), node, rules, blocked, cfg, now)
+		Eventually(posts).Should(Receive())
+	})
+
+	It("should not post before the threshold", func() {
+		r.notifyStuck(context.Background(), node, rules, blocked, cfg, now.Add(-10*time.Minute))
+		Consistently(posts, 100*time.Millisecond).ShouldNot(Receive())
+	})
+
+	It("should retry a failed post in the background", func() {
+		failing.Store(2)
+		r.notifyStuck(context.Background(), node, rules, blocked, cfg, now)
+		for range 3 {
+			Eventually(posts).Should(Receive())
+		}
+		Consistently(posts, 100*time.Millisecond).ShouldNot(Receive())
+
+		// The reconciles don't post it again
+		r.notifyStuck(context.Background(), node, rules, blocked, cfg, now)
+		Consistently(posts, 100*time.Millisecond).ShouldNot(Receive())
+	})
+
+	It("should give up after the retries", func() {
+		failing.Store(10)
+		r.notifyUntainted(context.Background(), HookRequest{Node: "node", Taints: []string{"example.com/not-ready"}}, cfg)
+		for range notificationRetry.Steps {
+			Eventually(posts).Should(Receive())
+		}
+		Consistently(posts, 100*time.Millisecond).ShouldNot(Receive())
+	})
+
+	It("should not post without a notifier", func() {
+		r.Notifier = nil
+		r.notifyUntainted(context.Background(), HookRequest{Node: "node", Taints: []string{"example.com/not-ready"}}, cfg)
+		Consistently(posts, 100*time.Millisecond).ShouldNot(Receive())
+	})
+
+	It("should post the details with the JSON format and custom templates", func() {
+		cfg.Notifications.Format = config.NotificationFormatJSON
+		cfg.Notifications.UntaintTemplate = `{{.Node}} is open for scheduling ({{join .Pods ", "}})`
+		r.notifyUntainted(context.Background(), HookRequest{
+			Node:   "node",
+			Taints: []string{"example.com/not-ready"},
+			Pods:   []string{"kube-system/agent-abc", "kube-system/cni-xyz"},
+		}, cfg)
+		Eventually(posts).Should(Receive(Equal(map[string]interface{}{
+			"text":   "node is open for scheduling (kube-system/agent-abc, kube-system/cni-xyz)",
+			"event":  NotificationTaintRemoved,
+			"node":   "node",
+			"taints": []interface{}{"example.com/not-ready"},
+			"pods":   []interface{}{"kube-system/agent-abc", "kube-system/cni-xyz"},
+		})))
+	})
+
+	It("should only post what is enabled", func() {
+		cfg.Notifications.OnUntaint = false
+		r.notifyUntainted(context.Background(), HookRequest{Node: "node", Taints: []string{"example.com/not-ready"}}, cfg)
+		cfg.Notifications.StuckAfter = metav1.Duration{}
+		r.notifyStuck(context.Background(), node, rules, blocked, cfg, now)
+		Consistently(posts, 100*time.Millisecond).ShouldNot(Receive())
+	})
+})
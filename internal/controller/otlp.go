@@ -0,0 +1,73 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	otelprometheus "go.opentelemetry.io/contrib/bridges/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// OTLPExporter periodically pushes the operator's Prometheus metrics to an
+// OTLP gRPC endpoint, such as an OpenTelemetry collector, for setups that
+// don't scrape Prometheus. It runs on every replica, like the metrics endpoint.
+type OTLPExporter struct {
+	// EndpointURL is the URL of the OTLP gRPC endpoint. An http URL connects
+	// without TLS.
+	EndpointURL string
+	// Interval is how often metrics are pushed. Defaults to the
+	// OTEL_METRIC_EXPORT_INTERVAL environment variable, or a minute, when unset.
+	Interval time.Duration
+	// Gatherer provides the metrics. Defaults to the controller-runtime
+	// registry when unset.
+	Gatherer prometheus.Gatherer
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable
+func (e *OTLPExporter) NeedLeaderElection() bool {
+	return false
+}
+
+// Start implements manager.Runnable and pushes metrics until ctx is cancelled,
+// pushing them a last time on the way out
+func (e *OTLPExporter) Start(ctx context.Context) error {
+	exporter, err := otlpmetricgrpc.New(ctx, otlpmetricgrpc.WithEndpointURL(e.EndpointURL))
+	if err != nil {
+		return fmt.Errorf("failed to create OTLP exporter: %w", err)
+	}
+	gatherer := e.Gatherer
+	if gatherer == nil {
+		gatherer = metrics.Registry
+	}
+	options := []sdkmetric.PeriodicReaderOption{
+		sdkmetric.WithProducer(otelprometheus.NewMetricProducer(otelprometheus.WithGatherer(gatherer))),
+	}
+	if e.Interval > 0 {
+		options = append(options, sdkmetric.WithInterval(e.Interval))
+	}
+	reader := sdkmetric.NewPeriodicReader(exporter, options...)
+	attributes := []attribute.KeyValue{attribute.String("service.name", FieldManager)}
+	if hostname, err := os.Hostname(); err == nil {
+		attributes = append(attributes, attribute.String("service.instance.id", hostname))
+	}
+	provider := sdkmetric.NewMeterProvider(
+		sdkmetric.WithReader(reader),
+		sdkmetric.WithResource(resource.NewSchemaless(attributes...)),
+	)
+
+	<-ctx.Done()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := provider.Shutdown(shutdownCtx); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to push the last metrics to the OTLP endpoint")
+	}
+	return nil
+}
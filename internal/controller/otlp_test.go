@@ -0,0 +1,61 @@
+package controller
+
+import (
+	"context"
+	"net"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	collectormetrics "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"google.golang.org/grpc"
+)
+
+// metricsCollector is an OTLP metrics endpoint handing the received metric
+// names to a channel
+type metricsCollector struct {
+	collectormetrics.UnimplementedMetricsServiceServer
+	names chan string
+}
+
+func (c *metricsCollector) Export(
+	_ context.Context,
+	request *collectormetrics.ExportMetricsServiceRequest,
+) (*collectormetrics.ExportMetricsServiceResponse, error) {
+	for _, resourceMetrics := range request.ResourceMetrics {
+		for _, scopeMetrics := range resourceMetrics.ScopeMetrics {
+			for _, metric := range scopeMetrics.Metrics {
+				c.names <- metric.Name
+			}
+		}
+	}
+	return &collectormetrics.ExportMetricsServiceResponse{}, nil
+}
+
+var _ = Describe("OTLPExporter", func() {
+	It("should push the gathered metrics to the endpoint", func() {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		collector := &metricsCollector{names: make(chan string, 100)}
+		server := grpc.NewServer()
+		collectormetrics.RegisterMetricsServiceServer(server, collector)
+		go func() { _ = server.Serve(listener) }()
+		DeferCleanup(server.Stop)
+
+		registry := prometheus.NewRegistry()
+		counter := prometheus.NewCounter(prometheus.CounterOpts{Name: "otlp_test_total", Help: "Test counter"})
+		registry.MustRegister(counter)
+		counter.Inc()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error)
+		exporter := &OTLPExporter{EndpointURL: "http://" + listener.Addr().String(), Gatherer: registry}
+		Expect(exporter.NeedLeaderElection()).To(BeFalse())
+		go func() { done <- exporter.Start(ctx) }()
+
+		// Stopping pushes the metrics a last time
+		cancel()
+		Eventually(done).Should(Receive(BeNil()))
+		Expect(collector.names).To(Receive(Equal("otlp_test_total")))
+	})
+})
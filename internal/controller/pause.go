@@ -0,0 +1,38 @@
+package controller
+
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	untaintv1alpha1 "github.com/jslay88/generic-untaint-operator/api/v1alpha1"
+	"github.com/jslay88/generic-untaint-operator/internal/config"
+)
+
+// PausedAnnotation pauses untainting of a node while set to "true"
+const PausedAnnotation = "untaint-operator.jslay88.github.io/paused"
+
+// nodePaused reports whether the node is annotated to pause untainting
+func nodePaused(node *corev1.Node) bool {
+	return node.Annotations[PausedAnnotation] == "true"
+}
+
+// untaintPaused returns why a global or per-node pause or the untaint windows
+// rule out removing taints from node at now, or nil when nothing pauses it
+func untaintPaused(node *corev1.Node, cfg *config.Config, now time.Time) *blockReason {
+	if cfg.Paused {
+		return &blockReason{
+			reason:  untaintv1alpha1.ReasonUntaintPaused,
+			message: "untainting is paused",
+			paused:  true,
+		}
+	}
+	if nodePaused(node) {
+		return &blockReason{
+			reason:  untaintv1alpha1.ReasonUntaintPaused,
+			message: "node is annotated to pause untainting",
+			paused:  true,
+		}
+	}
+	return untaintWindowClosed(cfg.UntaintWindows, now)
+}
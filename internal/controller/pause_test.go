@@ -0,0 +1,72 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	untaintv1alpha1 "github.com/jslay88/generic-untaint-operator/api/v1alpha1"
+	"github.com/jslay88/generic-untaint-operator/internal/config"
+)
+
+var _ = Describe("untaintPaused", func() {
+	var node *corev1.Node
+
+	BeforeEach(func() {
+		added := metav1.NewTime(time.Now().Add(-2 * time.Hour))
+		node = &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "node"},
+			Spec: corev1.NodeSpec{Taints: []corev1.Taint{
+				{Key: "example.com/not-ready", Effect: corev1.TaintEffectNoSchedule, TimeAdded: &added},
+			}},
+		}
+	})
+
+	It("should not pause untainting unless asked to", func() {
+		Expect(untaintPaused(node, &config.Config{}, time.Now())).To(BeNil())
+	})
+
+	It("should pause untainting globally", func() {
+		reason := untaintPaused(node, &config.Config{Paused: true}, time.Now())
+		Expect(reason).NotTo(BeNil())
+		Expect(reason.reason).To(Equal(untaintv1alpha1.ReasonUntaintPaused))
+		Expect(reason.message).To(Equal("untainting is paused"))
+	})
+
+	It("should pause untainting of annotated nodes", func() {
+		node.Annotations = map[string]string{PausedAnnotation: "true"}
+		reason := untaintPaused(node, &config.Config{}, time.Now())
+		Expect(reason).NotTo(BeNil())
+		Expect(reason.message).To(ContainSubstring("annotated"))
+
+		node.Annotations[PausedAnnotation] = "false"
+		Expect(untaintPaused(node, &config.Config{}, time.Now())).To(BeNil())
+	})
+
+	It("should re-evaluate nodes when they are paused or resumed", func() {
+		resumed := node.DeepCopy()
+		node.Annotations = map[string]string{PausedAnnotation: "true"}
+		Expect(nodeGateChanged(node, resumed, &config.Config{})).To(BeTrue())
+		Expect(nodeGateChanged(node, node.DeepCopy(), &config.Config{})).To(BeFalse())
+	})
+
+	It("should not force remove taints past their maxWait while paused", func() {
+		rule := config.Rule{
+			TargetTaint:  "example.com/not-ready",
+			OwnedByNames: []string{"agent"},
+			MaxWait:      &metav1.Duration{Duration: time.Hour},
+			OnMaxWait:    config.MaxWaitActionForceRemove,
+		}
+		cfg := &config.Config{Paused: true, Rules: []config.Rule{rule}}
+		r := &NodeReconciler{}
+		now := time.Now()
+		eval, err := r.evaluateRules(context.Background(), node, nil, cfg.Rules, untaintPaused(node, cfg, now), cfg, now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(eval.edits).To(BeEmpty())
+		Expect(eval.blocked.reason).To(Equal(untaintv1alpha1.ReasonUntaintPaused))
+	})
+})
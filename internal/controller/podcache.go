@@ -0,0 +1,44 @@
+package controller
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+// TransformPod is a cache transform keeping only the parts of a pod the
+// operator reads: its metadata less annotations and managed fields, the node
+// it runs on, the names, ports and restart policies of its containers, and its
+// status. On big clusters the dropped container specs, environments and
+// volumes make up most of the memory the pod cache takes, so pods read from
+// the cache must never be written back.
+func TransformPod(obj interface{}) (interface{}, error) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		// Tombstones of deleted pods are passed as they are
+		return obj, nil
+	}
+	pod.ManagedFields = nil
+	pod.Annotations = nil
+	pod.Spec = corev1.PodSpec{
+		NodeName:       pod.Spec.NodeName,
+		InitContainers: strippedContainers(pod.Spec.InitContainers),
+		Containers:     strippedContainers(pod.Spec.Containers),
+	}
+	return pod, nil
+}
+
+// strippedContainers returns containers with only their name, ports and
+// restart policy, which tell sidecars apart and resolve named probe ports
+func strippedContainers(containers []corev1.Container) []corev1.Container {
+	if containers == nil {
+		return nil
+	}
+	stripped := make([]corev1.Container, len(containers))
+	for i, container := range containers {
+		stripped[i] = corev1.Container{
+			Name:          container.Name,
+			Ports:         container.Ports,
+			RestartPolicy: container.RestartPolicy,
+		}
+	}
+	return stripped
+}
@@ -0,0 +1,65 @@
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/utils/ptr"
+)
+
+var _ = Describe("TransformPod", func() {
+	It("should keep only what the operator reads", func() {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            "agent",
+				Labels:          map[string]string{"app": "agent"},
+				Annotations:     map[string]string{"kubectl.kubernetes.io/last-applied-configuration": "{}"},
+				ManagedFields:   []metav1.ManagedFieldsEntry{{Manager: "kubelet"}},
+				OwnerReferences: []metav1.OwnerReference{{Kind: "DaemonSet", Name: "agent"}},
+			},
+			Spec: corev1.PodSpec{
+				NodeName: "node",
+				InitContainers: []corev1.Container{{
+					Name:          "proxy",
+					Image:         "proxy:1",
+					RestartPolicy: ptr.To(corev1.ContainerRestartPolicyAlways),
+				}},
+				Containers: []corev1.Container{{
+					Name:  "agent",
+					Image: "agent:1",
+					Env:   []corev1.EnvVar{{Name: "TOKEN", Value: "secret"}},
+					Ports: []corev1.ContainerPort{{Name: "health", ContainerPort: 8080}},
+				}},
+				Volumes: []corev1.Volume{{Name: "data"}},
+			},
+			Status: corev1.PodStatus{Phase: corev1.PodRunning, PodIP: "10.0.0.1"},
+		}
+
+		obj, err := TransformPod(pod)
+		Expect(err).NotTo(HaveOccurred())
+		stripped := obj.(*corev1.Pod)
+		Expect(stripped.Labels).To(HaveKey("app"))
+		Expect(stripped.OwnerReferences).To(HaveLen(1))
+		Expect(stripped.Annotations).To(BeNil())
+		Expect(stripped.ManagedFields).To(BeNil())
+		Expect(stripped.Spec).To(Equal(corev1.PodSpec{
+			NodeName: "node",
+			InitContainers: []corev1.Container{{
+				Name:          "proxy",
+				RestartPolicy: ptr.To(corev1.ContainerRestartPolicyAlways),
+			}},
+			Containers: []corev1.Container{{
+				Name:  "agent",
+				Ports: []corev1.ContainerPort{{Name: "health", ContainerPort: 8080}},
+			}},
+		}))
+		Expect(stripped.Status.PodIP).To(Equal("10.0.0.1"))
+	})
+
+	It("should pass other objects through", func() {
+		tombstone := cache.DeletedFinalStateUnknown{Key: "default/agent"}
+		Expect(TransformPod(tombstone)).To(Equal(tombstone))
+	})
+})
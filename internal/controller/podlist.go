@@ -0,0 +1,75 @@
+package controller
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// podNodeNameField indexes cached pods by the node they run on, and selects
+// them by it when listed from the API server. The cache keys the index by
+// namespace and node name, so a list in a namespace only looks at the pods of
+// the node in that namespace.
+const podNodeNameField = "spec.nodeName"
+
+// podListPageSize is how many pods are listed from the API server at a time
+// when pods aren't cached
+const podListPageSize = 500
+
+// listNodePods returns the pods running on node in each of PodNamespaces, or
+// in every namespace when unset, from the cache or, when pods aren't cached,
+// page by page from the API server
+func (r *NodeReconciler) listNodePods(ctx context.Context, node string) ([]corev1.Pod, error) {
+	namespaces := r.PodNamespaces
+	if len(namespaces) == 0 {
+		namespaces = []string{corev1.NamespaceAll}
+	}
+	if !r.UncachedPods {
+		return r.listCachedNodePods(ctx, node, namespaces)
+	}
+
+	var items []corev1.Pod
+	for _, namespace := range namespaces {
+		opts := []client.ListOption{
+			client.InNamespace(namespace),
+			client.MatchingFieldsSelector{Selector: fields.OneTermEqualSelector(podNodeNameField, node)},
+			client.Limit(podListPageSize),
+		}
+		if r.PodSelector != nil {
+			opts = append(opts, client.MatchingLabelsSelector{Selector: r.PodSelector})
+		}
+		for continueToken := ""; ; {
+			pods := &corev1.PodList{}
+			if err := r.apiReader().List(ctx, pods, append(opts, client.Continue(continueToken))...); err != nil {
+				return nil, err
+			}
+			items = append(items, pods.Items...)
+			if continueToken = pods.Continue; continueToken == "" {
+				break
+			}
+		}
+	}
+	return items, nil
+}
+
+// listCachedNodePods returns the cached pods running on node in namespaces,
+// looking each namespace up in the pod index rather than filtering the pods of
+// the node in every namespace
+func (r *NodeReconciler) listCachedNodePods(
+	ctx context.Context,
+	node string,
+	namespaces []string,
+) ([]corev1.Pod, error) {
+	var items []corev1.Pod
+	for _, namespace := range namespaces {
+		pods := &corev1.PodList{}
+		if err := r.List(ctx, pods, client.InNamespace(namespace),
+			client.MatchingFields{podNodeNameField: node}); err != nil {
+			return nil, err
+		}
+		items = append(items, pods.Items...)
+	}
+	return items, nil
+}
@@ -0,0 +1,111 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+var _ = Describe("listNodePods", func() {
+	pod := func(namespace, name, node string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, Labels: map[string]string{"app": name}},
+			Spec:       corev1.PodSpec{NodeName: node},
+		}
+	}
+	names := func(pods []corev1.Pod) []string {
+		var names []string
+		for _, pod := range pods {
+			names = append(names, pod.Namespace+"/"+pod.Name)
+		}
+		return names
+	}
+	byNodeName := func(obj client.Object) []string {
+		return []string{obj.(*corev1.Pod).Spec.NodeName}
+	}
+
+	It("should list the pods of the node from the API server", func() {
+		reader := fake.NewClientBuilder().
+			WithIndex(&corev1.Pod{}, podNodeNameField, byNodeName).
+			WithObjects(
+				pod("kube-system", "agent", "node"),
+				pod("kube-system", "proxy", "node"),
+				pod("kube-system", "other", "other-node"),
+				pod("default", "app", "node"),
+			).Build()
+		r := &NodeReconciler{APIReader: reader, UncachedPods: true}
+		pods, err := r.listNodePods(context.Background(), "node")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(names(pods)).To(ConsistOf("kube-system/agent", "kube-system/proxy", "default/app"))
+
+		r.PodNamespaces = []string{"kube-system"}
+		r.PodSelector = labels.SelectorFromSet(labels.Set{"app": "agent"})
+		pods, err = r.listNodePods(context.Background(), "node")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(names(pods)).To(ConsistOf("kube-system/agent"))
+	})
+
+	It("should look the pods of the node up in each watched namespace of the cache", func() {
+		var namespaces []string
+		c := fake.NewClientBuilder().
+			WithIndex(&corev1.Pod{}, podNodeNameField, byNodeName).
+			WithObjects(
+				pod("kube-system", "agent", "node"),
+				pod("monitoring", "exporter", "node"),
+				pod("monitoring", "other", "other-node"),
+				pod("default", "app", "node"),
+			).
+			WithInterceptorFuncs(interceptor.Funcs{
+				List: func(ctx context.Context, c client.WithWatch, list client.ObjectList, opts ...client.ListOption) error {
+					listOpts := &client.ListOptions{}
+					listOpts.ApplyOptions(opts)
+					namespaces = append(namespaces, listOpts.Namespace)
+					return c.List(ctx, list, opts...)
+				},
+			}).Build()
+		r := &NodeReconciler{Client: c}
+		pods, err := r.listNodePods(context.Background(), "node")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(names(pods)).To(ConsistOf("kube-system/agent", "monitoring/exporter", "default/app"))
+		Expect(namespaces).To(Equal([]string{""}))
+
+		namespaces = nil
+		r.PodNamespaces = []string{"kube-system", "monitoring"}
+		pods, err = r.listNodePods(context.Background(), "node")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(names(pods)).To(ConsistOf("kube-system/agent", "monitoring/exporter"))
+		Expect(namespaces).To(Equal([]string{"kube-system", "monitoring"}))
+	})
+
+	It("should follow the pages of the list", func() {
+		var tokens []string
+		reader := fake.NewClientBuilder().WithInterceptorFuncs(interceptor.Funcs{
+			List: func(_ context.Context, _ client.WithWatch, list client.ObjectList, opts ...client.ListOption) error {
+				listOpts := &client.ListOptions{}
+				listOpts.ApplyOptions(opts)
+				Expect(listOpts.Limit).To(Equal(int64(podListPageSize)))
+				tokens = append(tokens, listOpts.Continue)
+				pods := list.(*corev1.PodList)
+				if listOpts.Continue == "" {
+					pods.Items = []corev1.Pod{*pod("kube-system", "agent", "node")}
+					pods.Continue = "page-2"
+					return nil
+				}
+				pods.Items = []corev1.Pod{*pod("kube-system", "proxy", "node")}
+				return nil
+			},
+		}).Build()
+		r := &NodeReconciler{APIReader: reader, UncachedPods: true}
+		pods, err := r.listNodePods(context.Background(), "node")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(names(pods)).To(Equal([]string{"kube-system/agent", "kube-system/proxy"}))
+		Expect(tokens).To(Equal([]string{"", "page-2"}))
+	})
+})
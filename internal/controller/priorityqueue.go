@@ -0,0 +1,113 @@
+package controller
+
+import (
+	"container/heap"
+	"context"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// newestFirst is a workqueue.Queue handing out the most recently created
+// nodes first, since fresh capacity waiting on its taints is usually more
+// pressing than nodes that have been blocked for a while. Nodes created at the
+// same time, or unknown ones, are handed out in the order they were added.
+type newestFirst struct {
+	// created returns when the named node was created, or the zero time when
+	// it isn't known
+	created func(name string) time.Time
+
+	items []queuedNode
+	seq   uint64
+}
+
+// queuedNode is a request waiting in a newestFirst queue
+type queuedNode struct {
+	request reconcile.Request
+	created time.Time
+	seq     uint64
+}
+
+// Touch implements workqueue.Queue. A node's creation time never changes, so
+// neither does its place in the queue.
+func (q *newestFirst) Touch(reconcile.Request) {}
+
+// Push implements workqueue.Queue
+func (q *newestFirst) Push(request reconcile.Request) {
+	q.seq++
+	heap.Push((*nodeHeap)(q), queuedNode{request: request, created: q.created(request.Name), seq: q.seq})
+}
+
+// Len implements workqueue.Queue
+func (q *newestFirst) Len() int {
+	return len(q.items)
+}
+
+// Pop implements workqueue.Queue
+func (q *newestFirst) Pop() reconcile.Request {
+	return heap.Pop((*nodeHeap)(q)).(queuedNode).request
+}
+
+// nodeHeap implements heap.Interface over the items of a newestFirst queue
+type nodeHeap newestFirst
+
+func (h *nodeHeap) Len() int {
+	return len(h.items)
+}
+
+func (h *nodeHeap) Less(i, j int) bool {
+	a, b := h.items[i], h.items[j]
+	if !a.created.Equal(b.created) {
+		return a.created.After(b.created)
+	}
+	return a.seq < b.seq
+}
+
+func (h *nodeHeap) Swap(i, j int) {
+	h.items[i], h.items[j] = h.items[j], h.items[i]
+}
+
+func (h *nodeHeap) Push(x any) {
+	h.items = append(h.items, x.(queuedNode))
+}
+
+func (h *nodeHeap) Pop() any {
+	last := len(h.items) - 1
+	item := h.items[last]
+	h.items[last] = queuedNode{}
+	h.items = h.items[:last]
+	return item
+}
+
+// newNewestFirstQueue returns a constructor for the controller's rate-limited
+// work queue that hands out the most recently created nodes in c first
+func newNewestFirstQueue(
+	c client.Reader,
+) func(string, workqueue.TypedRateLimiter[reconcile.Request]) workqueue.TypedRateLimitingInterface[reconcile.Request] {
+	created := func(name string) time.Time {
+		node := &corev1.Node{}
+		if err := c.Get(context.Background(), types.NamespacedName{Name: name}, node); err != nil {
+			return time.Time{}
+		}
+		return node.CreationTimestamp.Time
+	}
+	return func(
+		name string,
+		rateLimiter workqueue.TypedRateLimiter[reconcile.Request],
+	) workqueue.TypedRateLimitingInterface[reconcile.Request] {
+		queue := workqueue.NewTypedWithConfig(workqueue.TypedQueueConfig[reconcile.Request]{
+			Name:  name,
+			Queue: &newestFirst{created: created},
+		})
+		return workqueue.NewTypedRateLimitingQueueWithConfig(rateLimiter,
+			workqueue.TypedRateLimitingQueueConfig[reconcile.Request]{
+				Name: name,
+				DelayingQueue: workqueue.NewTypedDelayingQueueWithConfig(
+					workqueue.TypedDelayingQueueConfig[reconcile.Request]{Name: name, Queue: queue}),
+			})
+	}
+}
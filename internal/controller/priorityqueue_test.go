@@ -0,0 +1,62 @@
+package controller
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("newestFirst", func() {
+	request := func(name string) reconcile.Request {
+		return reconcile.Request{NamespacedName: types.NamespacedName{Name: name}}
+	}
+
+	It("should hand out the newest nodes first", func() {
+		now := time.Now()
+		created := map[string]time.Time{
+			"old":    now.Add(-time.Hour),
+			"new":    now,
+			"newer":  now.Add(time.Minute),
+			"twin-a": now.Add(-time.Minute),
+			"twin-b": now.Add(-time.Minute),
+		}
+		queue := &newestFirst{created: func(name string) time.Time { return created[name] }}
+		for _, name := range []string{"old", "unknown", "twin-a", "new", "twin-b", "newer"} {
+			queue.Push(request(name))
+		}
+		Expect(queue.Len()).To(Equal(6))
+
+		var names []string
+		for queue.Len() > 0 {
+			names = append(names, queue.Pop().Name)
+		}
+		Expect(names).To(Equal([]string{"newer", "new", "twin-a", "twin-b", "old", "unknown"}))
+	})
+
+	It("should back the controller's work queue", func() {
+		node := func(name string, created time.Time) *corev1.Node {
+			return &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, CreationTimestamp: metav1.NewTime(created)}}
+		}
+		now := time.Now().Truncate(time.Second)
+		c := fake.NewClientBuilder().WithObjects(node("old", now.Add(-time.Hour)), node("new", now)).Build()
+		queue := newNewestFirstQueue(c)("newest-first-test",
+			workqueue.DefaultTypedControllerRateLimiter[reconcile.Request]())
+		DeferCleanup(queue.ShutDown)
+
+		queue.Add(request("old"))
+		queue.Add(request("new"))
+		item, _ := queue.Get()
+		Expect(item.Name).To(Equal("new"))
+		queue.Done(item)
+		item, _ = queue.Get()
+		Expect(item.Name).To(Equal("old"))
+		queue.Done(item)
+	})
+})
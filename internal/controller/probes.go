@@ -0,0 +1,146 @@
+package controller
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	untaintv1alpha1 "github.com/jslay88/generic-untaint-operator/api/v1alpha1"
+	"github.com/jslay88/generic-untaint-operator/internal/config"
+)
+
+// probeHTTPClient sends the HTTP probes. Like kubelet, it doesn't verify the
+// certificates of HTTPS probes.
+var probeHTTPClient = &http.Client{
+	Transport: &http.Transport{
+		TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+		DisableKeepAlives: true,
+	},
+}
+
+// probesFailed sends probe to every running pod of the workloads, and returns
+// why the first one failing it blocks the taint, or nil when all pass. The
+// pods are expected to have passed the readiness checks already.
+func probesFailed(ctx context.Context, pods []corev1.Pod, ownedByNames []string, probe *config.PodProbe) *blockReason {
+	if probe == nil {
+		return nil
+	}
+	workloads := newWorkloadSet(ownedByNames)
+	for _, pod := range pods {
+		if !isOwnedBy(&pod, workloads) || pod.DeletionTimestamp != nil {
+			continue
+		}
+		if err := probePod(ctx, &pod, probe); err != nil {
+			log.FromContext(ctx).Info("Pod failed its probe, requeueing", "pod", pod.Name, "error", err.Error())
+			return &blockReason{
+				reason:    untaintv1alpha1.ReasonProbeFailed,
+				message:   fmt.Sprintf("pod %s/%s failed its probe: %v", pod.Namespace, pod.Name, err),
+				workloads: owners(&pod, workloads),
+			}
+		}
+	}
+	return nil
+}
+
+// probePod sends probe to pod, returning why it failed
+func probePod(ctx context.Context, pod *corev1.Pod, probe *config.PodProbe) error {
+	if pod.Status.PodIP == "" {
+		return fmt.Errorf("pod has no IP")
+	}
+	ctx, cancel := context.WithTimeout(ctx, probe.Timeout.Duration)
+	defer cancel()
+	if probe.GRPC != nil {
+		return probeGRPC(ctx, pod, probe.GRPC)
+	}
+	return probeHTTP(ctx, pod, probe.HTTPGet)
+}
+
+// probeHTTP sends an HTTP GET to pod, passing on a status from 200 to 399
+func probeHTTP(ctx context.Context, pod *corev1.Pod, action *corev1.HTTPGetAction) error {
+	port, err := resolvePort(pod, action.Port)
+	if err != nil {
+		return err
+	}
+	host := pod.Status.PodIP
+	if action.Host != "" {
+		host = action.Host
+	}
+	target := url.URL{
+		Scheme: string(action.Scheme),
+		Host:   net.JoinHostPort(host, strconv.Itoa(port)),
+		Path:   action.Path,
+	}
+	if target.Scheme == "" {
+		target.Scheme = "http"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		return err
+	}
+	for _, header := range action.HTTPHeaders {
+		if header.Name == "Host" {
+			req.Host = header.Value
+			continue
+		}
+		req.Header.Add(header.Name, header.Value)
+	}
+	req.Header.Set("User-Agent", FieldManager)
+	resp, err := probeHTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("HTTP probe returned %s", resp.Status)
+	}
+	return nil
+}
+
+// probeGRPC calls the gRPC health service of pod, passing when it is SERVING
+func probeGRPC(ctx context.Context, pod *corev1.Pod, action *corev1.GRPCAction) error {
+	conn, err := grpc.NewClient(net.JoinHostPort(pod.Status.PodIP, strconv.Itoa(int(action.Port))),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = conn.Close() }()
+	request := &healthpb.HealthCheckRequest{}
+	if action.Service != nil {
+		request.Service = *action.Service
+	}
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, request)
+	if err != nil {
+		return err
+	}
+	if resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		return fmt.Errorf("gRPC health service reports %s", resp.GetStatus())
+	}
+	return nil
+}
+
+// resolvePort returns the number of port, looking named ports up among the
+// ports of the pod's containers
+func resolvePort(pod *corev1.Pod, port intstr.IntOrString) (int, error) {
+	if port.Type == intstr.Int {
+		return port.IntValue(), nil
+	}
+	for _, container := range pod.Spec.Containers {
+		for _, containerPort := range container.Ports {
+			if containerPort.Name == port.StrVal {
+				return int(containerPort.ContainerPort), nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("pod has no port named %s", port.StrVal)
+}
@@ -0,0 +1,100 @@
+package controller
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	untaintv1alpha1 "github.com/jslay88/generic-untaint-operator/api/v1alpha1"
+	"github.com/jslay88/generic-untaint-operator/internal/config"
+)
+
+var _ = Describe("Probes", func() {
+	var (
+		ctx context.Context
+		pod corev1.Pod
+	)
+
+	listenerPort := func(addr net.Addr) int32 {
+		_, port, err := net.SplitHostPort(addr.String())
+		Expect(err).NotTo(HaveOccurred())
+		number, err := strconv.Atoi(port)
+		Expect(err).NotTo(HaveOccurred())
+		return int32(number)
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		pod = corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:       "kube-system",
+				Name:            "agent-abc",
+				OwnerReferences: []metav1.OwnerReference{{Name: "agent"}},
+			},
+			Status: corev1.PodStatus{PodIP: "127.0.0.1"},
+		}
+	})
+
+	It("should pass pods answering the HTTP probe", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if req.URL.Path != "/healthz" {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+		}))
+		DeferCleanup(server.Close)
+		pod.Spec.Containers = []corev1.Container{{Ports: []corev1.ContainerPort{
+			{Name: "health", ContainerPort: listenerPort(server.Listener.Addr())},
+		}}}
+		probe := &config.PodProbe{
+			HTTPGet: &corev1.HTTPGetAction{Path: "/healthz", Port: intstr.FromString("health")},
+			Timeout: metav1.Duration{Duration: time.Second},
+		}
+		Expect(probesFailed(ctx, []corev1.Pod{pod}, []string{"agent"}, probe)).To(BeNil())
+
+		probe.HTTPGet.Path = "/ready"
+		reason := probesFailed(ctx, []corev1.Pod{pod}, []string{"agent"}, probe)
+		Expect(reason).NotTo(BeNil())
+		Expect(reason.reason).To(Equal(untaintv1alpha1.ReasonProbeFailed))
+		Expect(reason.message).To(ContainSubstring("503"))
+
+		// Pods of other workloads are not probed
+		Expect(probesFailed(ctx, []corev1.Pod{pod}, []string{"other"}, probe)).To(BeNil())
+	})
+
+	It("should require the gRPC health service to be serving", func() {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		healthServer := health.NewServer()
+		server := grpc.NewServer()
+		healthpb.RegisterHealthServer(server, healthServer)
+		go func() { _ = server.Serve(listener) }()
+		DeferCleanup(server.Stop)
+
+		probe := &config.PodProbe{
+			GRPC:    &corev1.GRPCAction{Port: listenerPort(listener.Addr())},
+			Timeout: metav1.Duration{Duration: time.Second},
+		}
+		Expect(probePod(ctx, &pod, probe)).To(Succeed())
+
+		healthServer.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+		Expect(probePod(ctx, &pod, probe)).To(MatchError(ContainSubstring("NOT_SERVING")))
+	})
+
+	It("should fail pods without an IP", func() {
+		pod.Status.PodIP = ""
+		probe := &config.PodProbe{GRPC: &corev1.GRPCAction{Port: 8080}, Timeout: metav1.Duration{Duration: time.Second}}
+		Expect(probePod(ctx, &pod, probe)).To(MatchError("pod has no IP"))
+	})
+})
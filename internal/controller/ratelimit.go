@@ -0,0 +1,231 @@
+package controller
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	untaintv1alpha1 "github.com/jslay88/generic-untaint-operator/api/v1alpha1"
+	"github.com/jslay88/generic-untaint-operator/internal/config"
+)
+
+// nodeUntaintWindow is the period over which the untaint attempts of a node
+// are counted against MaxNodeUntaintsPerMinute
+const nodeUntaintWindow = time.Minute
+
+// zoneYieldRetry is how soon a node that yielded its untaint to a zone with
+// fewer recent untaints is retried
+const zoneYieldRetry = 5 * time.Second
+
+// untaintLimiter tracks the recent untaints across all nodes, so the
+// configured rate limit holds however many workers reconcile at once. The
+// zero value is ready to use.
+type untaintLimiter struct {
+	mu sync.Mutex
+	// untaints are the recent untaints, oldest first
+	untaints []reservedUntaint
+	// waiting is when a node of each zone was last held back by the limit
+	waiting map[string]time.Time
+	// served is when a node of each zone was last untainted
+	served map[string]time.Time
+}
+
+// reservedUntaint is an untaint reserved at a time for a node in a zone
+type reservedUntaint struct {
+	at   time.Time
+	zone string
+}
+
+// reserve claims one of limit's untaints for a node in zone at now and
+// returns 0, or returns how long until one frees up when all of them were
+// used within the interval. With SpreadZones, it also reports whether the
+// node must yield its untaint to a waiting node of a zone untainted less
+// recently, which hands the untaints out round-robin across zones.
+func (l *untaintLimiter) reserve(limit config.UntaintRateLimit, zone string, now time.Time) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	interval := limit.Interval.Duration
+	expired := 0
+	for expired < len(l.untaints) && now.Sub(l.untaints[expired].at) >= interval {
+		expired++
+	}
+	l.untaints = l.untaints[expired:]
+	for waitingZone, since := range l.waiting {
+		if now.Sub(since) >= interval {
+			delete(l.waiting, waitingZone)
+		}
+	}
+
+	if len(l.untaints) >= int(limit.MaxUntaints) {
+		l.wait(zone, now)
+		// Wait for the untaint that frees up the next slot
+		return l.untaints[len(l.untaints)-int(limit.MaxUntaints)].at.Add(interval).Sub(now), false
+	}
+	if limit.SpreadZones && l.behind(zone) {
+		l.wait(zone, now)
+		return zoneYieldRetry, true
+	}
+	delete(l.waiting, zone)
+	if l.served == nil {
+		l.served = make(map[string]time.Time)
+	}
+	l.served[zone] = now
+	l.untaints = append(l.untaints, reservedUntaint{at: now, zone: zone})
+	return 0, false
+}
+
+// behind reports whether a node of a zone untainted less recently than zone
+// is waiting for an untaint
+func (l *untaintLimiter) behind(zone string) bool {
+	for waitingZone := range l.waiting {
+		if waitingZone != zone && l.served[waitingZone].Before(l.served[zone]) {
+			return true
+		}
+	}
+	return false
+}
+
+// wait records that a node of zone was held back at now
+func (l *untaintLimiter) wait(zone string, now time.Time) {
+	if l.waiting == nil {
+		l.waiting = make(map[string]time.Time)
+	}
+	l.waiting[zone] = now
+}
+
+// release gives back an untaint reserved at the given time that didn't happen
+func (l *untaintLimiter) release(at time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for i := len(l.untaints) - 1; i >= 0; i-- {
+		if l.untaints[i].at.Equal(at) {
+			l.untaints = append(l.untaints[:i], l.untaints[i+1:]...)
+			return
+		}
+	}
+}
+
+// reserveUntaint claims an untaint for the taints of request under the
+// configured rate limit. It returns why they must stay for now, or nil when
+// they may go, nothing is removed or no limit is configured.
+func (r *NodeReconciler) reserveUntaint(
+	node *corev1.Node,
+	request HookRequest,
+	cfg *config.Config,
+	now time.Time,
+) *blockReason {
+	limit := cfg.UntaintRateLimit
+	if limit == nil || len(request.Taints) == 0 {
+		return nil
+	}
+	zone := node.Labels[corev1.LabelTopologyZone]
+	wait, yielded := r.untaints.reserve(*limit, zone, now)
+	if wait <= 0 {
+		return nil
+	}
+	message := fmt.Sprintf("untaint rate limit of %d nodes per %s reached", limit.MaxUntaints,
+		limit.Interval.Duration)
+	if yielded {
+		message = fmt.Sprintf("zone %q yields to zones with fewer recent untaints", zone)
+	}
+	return &blockReason{
+		reason:     untaintv1alpha1.ReasonRateLimited,
+		message:    message,
+		retryAfter: wait,
+	}
+}
+
+// releaseUntaint gives back the untaint reserveUntaint claimed for request at
+// now, when the taints were not removed after all
+func (r *NodeReconciler) releaseUntaint(request HookRequest, cfg *config.Config, now time.Time) {
+	if cfg.UntaintRateLimit != nil && len(request.Taints) > 0 {
+		r.untaints.release(now)
+	}
+}
+
+// nodeUntaintAttempts tracks the recent untaint attempts of each node. The
+// zero value is ready to use.
+type nodeUntaintAttempts struct {
+	mu sync.Mutex
+	// attempts are the attempts within the window of each node, oldest first
+	attempts map[string][]time.Time
+}
+
+// attempt records an untaint attempt on node at now and returns 0, or returns
+// how long until the oldest attempt leaves the window when limit of them were
+// made within it
+func (a *nodeUntaintAttempts) attempt(node string, limit int32, now time.Time) time.Duration {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	attempts := a.attempts[node]
+	expired := 0
+	for expired < len(attempts) && now.Sub(attempts[expired]) >= nodeUntaintWindow {
+		expired++
+	}
+	attempts = attempts[expired:]
+	if len(attempts) >= int(limit) {
+		a.attempts[node] = attempts
+		return attempts[len(attempts)-int(limit)].Add(nodeUntaintWindow).Sub(now)
+	}
+	if a.attempts == nil {
+		a.attempts = make(map[string][]time.Time)
+	}
+	a.attempts[node] = append(attempts, now)
+	return 0
+}
+
+// release gives back an attempt on node recorded at the given time whose
+// taints were not removed after all
+func (a *nodeUntaintAttempts) release(node string, at time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	attempts := a.attempts[node]
+	for i := len(attempts) - 1; i >= 0; i-- {
+		if attempts[i].Equal(at) {
+			a.attempts[node] = append(attempts[:i], attempts[i+1:]...)
+			return
+		}
+	}
+}
+
+// forget drops the attempts recorded for node
+func (a *nodeUntaintAttempts) forget(node string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.attempts, node)
+}
+
+// throttleNode records an attempt to remove the taints of request from node
+// under the configured per-node cap. It returns why they must stay for now,
+// or nil when they may go, nothing is removed or no cap is configured.
+func (r *NodeReconciler) throttleNode(node *corev1.Node, request HookRequest, cfg *config.Config,
+	now time.Time) *blockReason {
+	if cfg.MaxNodeUntaintsPerMinute <= 0 || len(request.Taints) == 0 {
+		return nil
+	}
+	wait := r.nodeAttempts.attempt(node.Name, cfg.MaxNodeUntaintsPerMinute, now)
+	if wait <= 0 {
+		return nil
+	}
+	nodeUntaintThrottledTotal.Inc()
+	return &blockReason{
+		reason: untaintv1alpha1.ReasonRateLimited,
+		message: fmt.Sprintf("taints of the node were removed %d times within %s", cfg.MaxNodeUntaintsPerMinute,
+			nodeUntaintWindow),
+		retryAfter: wait,
+	}
+}
+
+// releaseNodeAttempt gives back the attempt throttleNode recorded for request
+// at now, when the taints were not removed after all
+func (r *NodeReconciler) releaseNodeAttempt(request HookRequest, cfg *config.Config, now time.Time) {
+	if cfg.MaxNodeUntaintsPerMinute > 0 && len(request.Taints) > 0 {
+		r.nodeAttempts.release(request.Node, now)
+	}
+}
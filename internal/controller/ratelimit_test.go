@@ -0,0 +1,157 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	untaintv1alpha1 "github.com/jslay88/generic-untaint-operator/api/v1alpha1"
+	"github.com/jslay88/generic-untaint-operator/internal/config"
+)
+
+var _ = Describe("untaintLimiter", func() {
+	limit := config.UntaintRateLimit{MaxUntaints: 2, Interval: metav1.Duration{Duration: 5 * time.Minute}}
+	now := time.Now()
+
+	It("should allow at most the limit per interval", func() {
+		limiter := &untaintLimiter{}
+		Expect(limiter.reserve(limit, "a", now)).To(BeZero())
+		Expect(limiter.reserve(limit, "a", now.Add(time.Minute))).To(BeZero())
+		Expect(limiter.reserve(limit, "a", now.Add(2*time.Minute))).To(Equal(3 * time.Minute))
+
+		// The first untaint leaves the window after five minutes
+		Expect(limiter.reserve(limit, "a", now.Add(5*time.Minute))).To(BeZero())
+		Expect(limiter.reserve(limit, "a", now.Add(5*time.Minute))).To(Equal(time.Minute))
+	})
+
+	It("should give back untaints that didn't happen", func() {
+		limiter := &untaintLimiter{}
+		Expect(limiter.reserve(limit, "a", now)).To(BeZero())
+		Expect(limiter.reserve(limit, "a", now.Add(time.Minute))).To(BeZero())
+		limiter.release(now.Add(time.Minute))
+		Expect(limiter.reserve(limit, "a", now.Add(2*time.Minute))).To(BeZero())
+	})
+
+	It("should spread untaints across the zones of waiting nodes", func() {
+		spread := limit
+		spread.SpreadZones = true
+		limiter := &untaintLimiter{}
+		Expect(limiter.reserve(spread, "a", now)).To(BeZero())
+		Expect(limiter.reserve(spread, "a", now)).To(BeZero())
+		// Zones a and b both wait for the next slot
+		Expect(limiter.reserve(spread, "a", now.Add(time.Minute))).To(Equal(4 * time.Minute))
+		Expect(limiter.reserve(spread, "b", now.Add(time.Minute))).To(Equal(4 * time.Minute))
+
+		// Zone a, untainted more recently, yields the next slot to zone b
+		wait, yielded := limiter.reserve(spread, "a", now.Add(5*time.Minute))
+		Expect(wait).To(Equal(zoneYieldRetry))
+		Expect(yielded).To(BeTrue())
+		Expect(limiter.reserve(spread, "b", now.Add(5*time.Minute))).To(BeZero())
+		Expect(limiter.reserve(spread, "a", now.Add(5*time.Minute))).To(BeZero())
+		// And the next slot goes to zone b again
+		Expect(limiter.reserve(spread, "b", now.Add(10*time.Minute))).To(BeZero())
+		Expect(limiter.reserve(spread, "a", now.Add(10*time.Minute+time.Second))).To(BeZero())
+		Expect(limiter.reserve(spread, "b", now.Add(11*time.Minute))).To(Equal(4 * time.Minute))
+		Expect(limiter.reserve(spread, "a", now.Add(11*time.Minute))).To(Equal(4 * time.Minute))
+		_, yielded = limiter.reserve(spread, "a", now.Add(15*time.Minute+time.Second))
+		Expect(yielded).To(BeTrue())
+	})
+
+	It("should only limit requests removing taints", func() {
+		r := &NodeReconciler{}
+		cfg := &config.Config{UntaintRateLimit: &config.UntaintRateLimit{
+			MaxUntaints: 1,
+			Interval:    metav1.Duration{Duration: time.Minute},
+		}}
+		request := HookRequest{Node: "node", Taints: []string{"example.com/not-ready"}}
+		node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node"}}
+		Expect(r.reserveUntaint(node, HookRequest{Node: "node"}, cfg, now)).To(BeNil())
+		Expect(r.reserveUntaint(node, request, &config.Config{}, now)).To(BeNil())
+		Expect(r.reserveUntaint(node, request, cfg, now)).To(BeNil())
+
+		reason := r.reserveUntaint(node, request, cfg, now.Add(time.Second))
+		Expect(reason).NotTo(BeNil())
+		Expect(reason.reason).To(Equal(untaintv1alpha1.ReasonRateLimited))
+		Expect(reason.retryAfter).To(Equal(59 * time.Second))
+	})
+})
+
+var _ = Describe("nodeUntaintAttempts", func() {
+	now := time.Now()
+
+	It("should allow at most the limit per node and minute", func() {
+		attempts := &nodeUntaintAttempts{}
+		Expect(attempts.attempt("node", 2, now)).To(BeZero())
+		Expect(attempts.attempt("node", 2, now.Add(10*time.Second))).To(BeZero())
+		Expect(attempts.attempt("node", 2, now.Add(20*time.Second))).To(Equal(40 * time.Second))
+		Expect(attempts.attempt("other", 2, now.Add(20*time.Second))).To(BeZero())
+
+		// The first attempt leaves the window after a minute
+		Expect(attempts.attempt("node", 2, now.Add(time.Minute))).To(BeZero())
+		Expect(attempts.attempt("node", 2, now.Add(time.Minute))).To(Equal(10 * time.Second))
+
+		attempts.forget("node")
+		Expect(attempts.attempt("node", 2, now.Add(time.Minute))).To(BeZero())
+	})
+
+	It("should give back attempts that didn't untaint the node", func() {
+		attempts := &nodeUntaintAttempts{}
+		Expect(attempts.attempt("node", 1, now)).To(BeZero())
+		attempts.release("node", now)
+		Expect(attempts.attempt("node", 1, now.Add(time.Second))).To(BeZero())
+	})
+
+	It("should not use up the cap of a node held back by the untaint rate limit", func() {
+		taint := "example.com/not-ready"
+		node := &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "node"},
+			Spec:       corev1.NodeSpec{Taints: []corev1.Taint{{Key: taint, Effect: corev1.TaintEffectNoSchedule}}},
+		}
+		c := fake.NewClientBuilder().WithObjects(node).Build()
+		Expect(c.Get(context.Background(), client.ObjectKeyFromObject(node), node)).To(Succeed())
+		r := &NodeReconciler{Client: c}
+		cfg := &config.Config{
+			MaxNodeUntaintsPerMinute: 1,
+			UntaintRateLimit:         &config.UntaintRateLimit{MaxUntaints: 1, Interval: metav1.Duration{Duration: time.Minute}},
+		}
+		rules := []config.Rule{{TargetTaint: taint}}
+		// Another node took the only untaint of the minute
+		Expect(r.untaints.reserve(*cfg.UntaintRateLimit, "", now)).To(BeZero())
+
+		eval := &ruleEvaluation{edits: taintEdits{taint: {}}}
+		Expect(r.removeTaints(context.Background(), node, nil, rules, eval, cfg, now.Add(time.Second))).To(Succeed())
+		Expect(eval.blocked).NotTo(BeNil())
+		Expect(eval.blocked.message).To(ContainSubstring("untaint rate limit"))
+
+		// Once the rate limit frees up, the node's own cap still allows it
+		eval = &ruleEvaluation{edits: taintEdits{taint: {}}}
+		Expect(r.removeTaints(context.Background(), node, nil, rules, eval, cfg, now.Add(time.Minute))).To(Succeed())
+		Expect(eval.blocked).To(BeNil())
+		Expect(c.Get(context.Background(), client.ObjectKeyFromObject(node), node)).To(Succeed())
+		Expect(node.Spec.Taints).To(BeEmpty())
+	})
+
+	It("should throttle nodes untainted too often and count them", func() {
+		r := &NodeReconciler{}
+		cfg := &config.Config{MaxNodeUntaintsPerMinute: 1}
+		request := HookRequest{Node: "node", Taints: []string{"example.com/not-ready"}}
+		node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node"}}
+		before := testutil.ToFloat64(nodeUntaintThrottledTotal)
+		Expect(r.throttleNode(node, HookRequest{Node: "node"}, cfg, now)).To(BeNil())
+		Expect(r.throttleNode(node, request, &config.Config{}, now)).To(BeNil())
+		Expect(r.throttleNode(node, request, cfg, now)).To(BeNil())
+
+		reason := r.throttleNode(node, request, cfg, now.Add(15*time.Second))
+		Expect(reason).NotTo(BeNil())
+		Expect(reason.reason).To(Equal(untaintv1alpha1.ReasonRateLimited))
+		Expect(reason.retryAfter).To(Equal(45 * time.Second))
+		Expect(testutil.ToFloat64(nodeUntaintThrottledTotal)).To(Equal(before + 1))
+	})
+})
@@ -0,0 +1,50 @@
+package controller
+
+import "sync"
+
+// removedTaints remembers which target taints the operator removed from each
+// node, so a taint put back afterwards by kubelet or another controller can be
+// told apart from one that was never removed. The zero value is ready to use.
+type removedTaints struct {
+	mu sync.Mutex
+	// removed holds the resourceVersion of the node each taint was removed from
+	removed map[nodeTaintKey]string
+}
+
+// record notes that taint was removed from node as of resourceVersion
+func (t *removedTaints) record(node, taint, resourceVersion string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.removed == nil {
+		t.removed = make(map[nodeTaintKey]string)
+	}
+	t.removed[nodeTaintKey{node: node, taint: taint}] = resourceVersion
+}
+
+// readded reports whether taint, present on node as of resourceVersion, was
+// removed from it before. A cache still serving the node the taint was removed
+// from is not a re-addition. Each removal is reported at most once.
+func (t *removedTaints) readded(node, taint, resourceVersion string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := nodeTaintKey{node: node, taint: taint}
+	removedFrom, ok := t.removed[key]
+	if !ok || removedFrom == resourceVersion {
+		return false
+	}
+	delete(t.removed, key)
+	return true
+}
+
+// forget drops everything recorded for node
+func (t *removedTaints) forget(node string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for key := range t.removed {
+		if key.node == node {
+			delete(t.removed, key)
+		}
+	}
+}
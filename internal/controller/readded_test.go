@@ -0,0 +1,114 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	untaintv1alpha1 "github.com/jslay88/generic-untaint-operator/api/v1alpha1"
+	"github.com/jslay88/generic-untaint-operator/internal/config"
+)
+
+var _ = Describe("removedTaints", func() {
+	It("should report each removed taint once", func() {
+		removed := &removedTaints{}
+		Expect(removed.readded("node", "taint", "2")).To(BeFalse())
+
+		removed.record("node", "taint", "1")
+		Expect(removed.readded("other", "taint", "2")).To(BeFalse())
+		// The cache still serves the node the taint was removed from
+		Expect(removed.readded("node", "taint", "1")).To(BeFalse())
+		Expect(removed.readded("node", "taint", "2")).To(BeTrue())
+		Expect(removed.readded("node", "taint", "3")).To(BeFalse())
+	})
+
+	It("should forget a node", func() {
+		removed := &removedTaints{}
+		removed.record("node", "taint", "1")
+		removed.record("other", "taint", "1")
+		removed.forget("node")
+		Expect(removed.readded("node", "taint", "2")).To(BeFalse())
+		Expect(removed.readded("other", "taint", "2")).To(BeTrue())
+	})
+})
+
+var _ = Describe("reportReadded", func() {
+	const taint = "readded.test/not-ready"
+
+	It("should count and report a taint re-added after it was removed", func() {
+		recorder := record.NewFakeRecorder(10)
+		r := &NodeReconciler{TargetTaint: taint, OwnedByNames: []string{"workload"}, Recorder: recorder}
+		cfg := r.currentConfig()
+		node := &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "readded-node", ResourceVersion: "1"},
+			Spec:       corev1.NodeSpec{Taints: []corev1.Taint{{Key: taint, Effect: corev1.TaintEffectNoSchedule}}},
+		}
+		before := testutil.ToFloat64(taintReaddedTotal.WithLabelValues(taint))
+
+		// A taint the operator never removed is simply reconciled
+		r.reportReadded(context.Background(), node, cfg)
+		Expect(recorder.Events).To(BeEmpty())
+
+		r.removed.record(node.Name, taint, "1")
+		r.reportReadded(context.Background(), node, cfg)
+		Expect(recorder.Events).To(BeEmpty())
+
+		node.ResourceVersion = "3"
+		r.reportReadded(context.Background(), node, cfg)
+		Expect(recorder.Events).To(Receive(ContainSubstring(untaintv1alpha1.ReasonReverted)))
+		Expect(testutil.ToFloat64(taintReaddedTotal.WithLabelValues(taint))).To(Equal(before + 1))
+
+		r.reportReadded(context.Background(), node, cfg)
+		Expect(testutil.ToFloat64(taintReaddedTotal.WithLabelValues(taint))).To(Equal(before + 1))
+	})
+})
+
+var _ = Describe("nodePredicate", func() {
+	const taint = "readded.test/not-ready"
+
+	untainted := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "readded-node"}}
+	tainted := untainted.DeepCopy()
+	tainted.Spec.Taints = []corev1.Taint{{Key: taint, Effect: corev1.TaintEffectNoSchedule}}
+
+	It("should pass updates adding a target taint without reporting them", func() {
+		recorder := record.NewFakeRecorder(10)
+		r := &NodeReconciler{TargetTaint: taint, OwnedByNames: []string{"workload"}, Recorder: recorder}
+		r.removed.record(untainted.Name, taint, "1")
+
+		Expect(r.nodePredicate().Update(event.UpdateEvent{ObjectOld: untainted, ObjectNew: tainted})).To(BeTrue())
+		Expect(recorder.Events).To(BeEmpty())
+		// Updates that leave the taint in place are not re-additions
+		Expect(r.nodePredicate().Update(event.UpdateEvent{ObjectOld: tainted, ObjectNew: tainted})).To(BeFalse())
+	})
+
+	It("should pass deleted nodes so they are forgotten", func() {
+		r := &NodeReconciler{TargetTaint: taint, OwnedByNames: []string{"workload"}}
+		Expect(r.nodePredicate().Delete(event.DeleteEvent{Object: untainted})).To(BeTrue())
+	})
+
+	It("should only pass created nodes carrying a target taint", func() {
+		r := &NodeReconciler{TargetTaint: taint, OwnedByNames: []string{"workload"}}
+		Expect(r.nodePredicate().Create(event.CreateEvent{Object: tainted})).To(BeTrue())
+		Expect(r.nodePredicate().Create(event.CreateEvent{Object: untainted})).To(BeFalse())
+	})
+
+	It("should pass created nodes whose node condition is left to clear", func() {
+		r := &NodeReconciler{Config: config.NewStore(&config.Config{
+			Rules:             []config.Rule{{TargetTaint: taint, OwnedByNames: []string{"workload"}}},
+			NodeConditionType: "example.com/Untainted",
+		})}
+		reported := untainted.DeepCopy()
+		reported.Status.Conditions = []corev1.NodeCondition{{
+			Type:   "example.com/Untainted",
+			Status: corev1.ConditionFalse,
+		}}
+		Expect(r.nodePredicate().Create(event.CreateEvent{Object: reported})).To(BeTrue())
+		Expect(r.nodePredicate().Create(event.CreateEvent{Object: untainted})).To(BeFalse())
+	})
+})
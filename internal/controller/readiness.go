@@ -0,0 +1,128 @@
+package controller
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/jslay88/generic-untaint-operator/internal/config"
+)
+
+// podNotReady reports whether pod is not running and ready under the
+// configured readiness mode, along with a detail explaining why when the
+// Ready condition alone doesn't explain it
+func podNotReady(pod *corev1.Pod, cfg *config.Config) (bool, string) {
+	if !hasPodCondition(pod, corev1.PodReady) {
+		return true, ""
+	}
+	// A pod moving to Failed or Unknown can keep a stale Ready condition
+	if pod.Status.Phase != corev1.PodRunning {
+		return true, fmt.Sprintf("phase is %s", pod.Status.Phase)
+	}
+	if cfg.RequireInitContainers {
+		if detail := initContainersNotCompleted(pod); detail != "" {
+			return true, detail
+		}
+	}
+	if cfg.ReadinessMode != config.ReadinessModeContainers {
+		return false, ""
+	}
+
+	// Readiness gates and lagging kubelet updates can leave the pod Ready while
+	// one of its containers is not, so check each container status as well
+	if detail := containersNotReady(pod.Spec.Containers, pod.Status.ContainerStatuses); detail != "" {
+		return true, detail
+	}
+	var sidecars []corev1.Container
+	for _, container := range pod.Spec.InitContainers {
+		if isSidecar(container) {
+			sidecars = append(sidecars, container)
+		}
+	}
+	if detail := containersNotReady(sidecars, pod.Status.InitContainerStatuses); detail != "" {
+		return true, detail
+	}
+	return false, ""
+}
+
+// isSidecar reports whether an init container keeps running alongside the
+// main containers rather than running to completion
+func isSidecar(container corev1.Container) bool {
+	return container.RestartPolicy != nil && *container.RestartPolicy == corev1.ContainerRestartPolicyAlways
+}
+
+// initContainersNotCompleted returns why one of the init containers of pod,
+// other than sidecars, has not terminated successfully, or an empty string
+// when all of them have
+func initContainersNotCompleted(pod *corev1.Pod) string {
+	for _, container := range pod.Spec.InitContainers {
+		if isSidecar(container) {
+			continue
+		}
+		status := findContainerStatus(pod.Status.InitContainerStatuses, container.Name)
+		switch {
+		case status == nil:
+			return fmt.Sprintf("init container %s has no status", container.Name)
+		case status.State.Terminated == nil:
+			return fmt.Sprintf("init container %s has not completed", container.Name)
+		case status.State.Terminated.ExitCode != 0:
+			return fmt.Sprintf("init container %s exited with code %d", container.Name,
+				status.State.Terminated.ExitCode)
+		}
+	}
+	return ""
+}
+
+// findContainerStatus returns the status of the named container, or nil
+func findContainerStatus(statuses []corev1.ContainerStatus, name string) *corev1.ContainerStatus {
+	for i := range statuses {
+		if statuses[i].Name == name {
+			return &statuses[i]
+		}
+	}
+	return nil
+}
+
+// readyFor returns how long pod has been ready without interruption. A Ready
+// condition without a transition time counts as ready for ever.
+func readyFor(pod *corev1.Pod, now time.Time) time.Duration {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type != corev1.PodReady || condition.Status != corev1.ConditionTrue {
+			continue
+		}
+		if condition.LastTransitionTime.IsZero() {
+			return time.Duration(math.MaxInt64)
+		}
+		return now.Sub(condition.LastTransitionTime.Time)
+	}
+	return 0
+}
+
+// hasPodCondition reports whether the condition of the given type is true
+func hasPodCondition(pod *corev1.Pod, conditionType corev1.PodConditionType) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == conditionType && condition.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
+
+// containersNotReady returns why one of containers is not started and ready
+// according to statuses, or an empty string when all of them are
+func containersNotReady(containers []corev1.Container, statuses []corev1.ContainerStatus) string {
+	for _, container := range containers {
+		status := findContainerStatus(statuses, container.Name)
+		switch {
+		case status == nil:
+			return fmt.Sprintf("container %s has no status", container.Name)
+		case status.Started == nil || !*status.Started:
+			return fmt.Sprintf("container %s has not started", container.Name)
+		case !status.Ready:
+			return fmt.Sprintf("container %s is not ready", container.Name)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,106 @@
+package controller
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	"github.com/jslay88/generic-untaint-operator/internal/config"
+)
+
+var _ = Describe("podNotReady", func() {
+	var pod *corev1.Pod
+
+	BeforeEach(func() {
+		pod = &corev1.Pod{
+			Spec: corev1.PodSpec{
+				InitContainers: []corev1.Container{
+					{Name: "setup"},
+					{Name: "proxy", RestartPolicy: ptr.To(corev1.ContainerRestartPolicyAlways)},
+				},
+				Containers: []corev1.Container{{Name: "agent"}},
+			},
+			Status: corev1.PodStatus{
+				Phase:      corev1.PodRunning,
+				Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+				InitContainerStatuses: []corev1.ContainerStatus{
+					{Name: "setup", State: corev1.ContainerState{
+						Terminated: &corev1.ContainerStateTerminated{ExitCode: 0},
+					}},
+					{Name: "proxy", Started: ptr.To(true), Ready: true},
+				},
+				ContainerStatuses: []corev1.ContainerStatus{{Name: "agent", Started: ptr.To(true), Ready: true}},
+			},
+		}
+	})
+
+	It("should only look at the Ready condition by default", func() {
+		cfg := &config.Config{ReadinessMode: config.ReadinessModePodReady}
+		pod.Status.ContainerStatuses[0].Ready = false
+		Expect(podNotReady(pod, cfg)).To(BeFalse())
+
+		pod.Status.Conditions[0].Status = corev1.ConditionFalse
+		Expect(podNotReady(pod, cfg)).To(BeTrue())
+	})
+
+	It("should not count a pod that is no longer running despite a stale Ready condition", func() {
+		cfg := &config.Config{ReadinessMode: config.ReadinessModePodReady}
+		pod.Status.Phase = corev1.PodFailed
+		notReady, detail := podNotReady(pod, cfg)
+		Expect(notReady).To(BeTrue())
+		Expect(detail).To(Equal("phase is Failed"))
+	})
+
+	It("should require every container and sidecar to be started and ready in Containers mode", func() {
+		cfg := &config.Config{ReadinessMode: config.ReadinessModeContainers}
+		notReady, _ := podNotReady(pod, cfg)
+		Expect(notReady).To(BeFalse())
+
+		pod.Status.ContainerStatuses[0].Ready = false
+		notReady, detail := podNotReady(pod, cfg)
+		Expect(notReady).To(BeTrue())
+		Expect(detail).To(Equal("container agent is not ready"))
+
+		pod.Status.ContainerStatuses[0].Ready = true
+		pod.Status.InitContainerStatuses[1].Started = ptr.To(false)
+		notReady, detail = podNotReady(pod, cfg)
+		Expect(notReady).To(BeTrue())
+		Expect(detail).To(Equal("container proxy has not started"))
+
+		pod.Status.InitContainerStatuses = nil
+		_, detail = podNotReady(pod, cfg)
+		Expect(detail).To(Equal("container proxy has no status"))
+	})
+
+	It("should require init containers to have completed when configured", func() {
+		cfg := &config.Config{ReadinessMode: config.ReadinessModePodReady, RequireInitContainers: true}
+		notReady, _ := podNotReady(pod, cfg)
+		Expect(notReady).To(BeFalse())
+
+		pod.Status.InitContainerStatuses[0].State = corev1.ContainerState{
+			Terminated: &corev1.ContainerStateTerminated{ExitCode: 1},
+		}
+		notReady, detail := podNotReady(pod, cfg)
+		Expect(notReady).To(BeTrue())
+		Expect(detail).To(Equal("init container setup exited with code 1"))
+
+		pod.Status.InitContainerStatuses[0].State = corev1.ContainerState{
+			Running: &corev1.ContainerStateRunning{},
+		}
+		_, detail = podNotReady(pod, cfg)
+		Expect(detail).To(Equal("init container setup has not completed"))
+	})
+
+	It("should measure how long the pod has been ready", func() {
+		now := time.Now()
+		pod.Status.Conditions[0].LastTransitionTime = metav1.NewTime(now.Add(-time.Minute))
+		Expect(readyFor(pod, now)).To(Equal(time.Minute))
+
+		pod.Status.Conditions[0].Status = corev1.ConditionFalse
+		Expect(readyFor(pod, now)).To(BeZero())
+	})
+})
@@ -0,0 +1,28 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// When the API server restarts, every informer relists its objects. Objects
+// that didn't change come back as updates the node and pod predicates drop,
+// since nothing they compare differs, and the work queue holds each node once
+// however many of its pods changed meanwhile. What is left can still be a
+// reconcile of every node at once, which ReconcileLimiter spreads out.
+
+// waitReconcile blocks until ReconcileLimiter allows another reconcile, so a
+// burst of queued nodes reaches the API server at the configured rate. It
+// returns an error when ctx ends first.
+func (r *NodeReconciler) waitReconcile(ctx context.Context) error {
+	if r.ReconcileLimiter == nil {
+		return nil
+	}
+	start := time.Now()
+	if err := r.ReconcileLimiter.Wait(ctx); err != nil {
+		return fmt.Errorf("failed to wait for the reconcile rate limit: %w", err)
+	}
+	reconcileWaitSeconds.Add(time.Since(start).Seconds())
+	return nil
+}
@@ -0,0 +1,50 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/flowcontrol"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+var _ = Describe("relists", func() {
+	It("should not queue nodes for relisted objects that didn't change", func() {
+		const taint = "relist.test/not-ready"
+		node := &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "node", ResourceVersion: "1"},
+			Spec:       corev1.NodeSpec{Taints: []corev1.Taint{{Key: taint, Effect: corev1.TaintEffectNoSchedule}}},
+		}
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Name: "agent", ResourceVersion: "1"},
+			Spec:       corev1.PodSpec{NodeName: node.Name},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+		}
+		r := &NodeReconciler{TargetTaint: taint, OwnedByNames: []string{"agent"}}
+		Expect(r.nodePredicate().Update(event.UpdateEvent{ObjectOld: node, ObjectNew: node.DeepCopy()})).To(BeFalse())
+		Expect(podChangedPredicate().Update(event.UpdateEvent{ObjectOld: pod, ObjectNew: pod.DeepCopy()})).To(BeFalse())
+	})
+})
+
+var _ = Describe("waitReconcile", func() {
+	It("should not wait without a limiter", func() {
+		r := &NodeReconciler{}
+		Expect(r.waitReconcile(context.Background())).To(Succeed())
+	})
+
+	It("should hold reconciles to the configured rate", func() {
+		r := &NodeReconciler{ReconcileLimiter: flowcontrol.NewTokenBucketRateLimiter(20, 1)}
+		before := testutil.ToFloat64(reconcileWaitSeconds)
+		start := time.Now()
+		for range 3 {
+			Expect(r.waitReconcile(context.Background())).To(Succeed())
+		}
+		Expect(time.Since(start)).To(BeNumerically(">=", 90*time.Millisecond))
+		Expect(testutil.ToFloat64(reconcileWaitSeconds)).To(BeNumerically(">", before))
+	})
+})
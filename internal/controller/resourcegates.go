@@ -0,0 +1,98 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+
+	untaintv1alpha1 "github.com/jslay88/generic-untaint-operator/api/v1alpha1"
+	"github.com/jslay88/generic-untaint-operator/internal/config"
+)
+
+// resourceGatesBlocked returns why the first of gates doesn't report the
+// node ready, or nil when all of them do. The resources are read from the API
+// server, since their kinds are only known from the config and the operator
+// can't keep a cache of every kind.
+func (r *NodeReconciler) resourceGatesBlocked(
+	ctx context.Context,
+	node *corev1.Node,
+	gates []config.ResourceGate,
+) (*blockReason, error) {
+	for _, gate := range gates {
+		namespace, name, err := gate.RenderName(node)
+		if err != nil {
+			return &blockReason{
+				reason:  untaintv1alpha1.ReasonResourceNotReady,
+				message: fmt.Sprintf("failed to render the %s to check: %v", gate.Kind, err),
+			}, nil
+		}
+		key := types.NamespacedName{Namespace: namespace, Name: name}
+		if namespace != "" {
+			name = key.String()
+		}
+		obj := newUnstructured(gate.GroupVersionKind())
+		if err := r.apiReader().Get(ctx, key, obj); err != nil {
+			if apierrors.IsNotFound(err) {
+				return &blockReason{
+					reason:  untaintv1alpha1.ReasonResourceNotReady,
+					message: fmt.Sprintf("%s %s does not exist", gate.Kind, name),
+				}, nil
+			}
+			return nil, fmt.Errorf("failed to get %s %s: %w", gate.Kind, name, err)
+		}
+		if reason := resourceGateNotReady(gate, obj); reason != nil {
+			reason.message = fmt.Sprintf("%s %s %s", gate.Kind, name, reason.message)
+			return reason, nil
+		}
+	}
+	return nil, nil
+}
+
+// resourceGateNotReady returns why obj doesn't pass the condition and
+// JSONPath checks of gate, or nil when it does
+func resourceGateNotReady(gate config.ResourceGate, obj *unstructured.Unstructured) *blockReason {
+	if gate.Condition != "" {
+		status := resourceConditionStatus(obj, gate.Condition)
+		if status != metav1.ConditionTrue {
+			return &blockReason{
+				reason:  untaintv1alpha1.ReasonResourceNotReady,
+				message: fmt.Sprintf("condition %s is %s", gate.Condition, status),
+			}
+		}
+	}
+	if gate.JSONPath != "" {
+		ok, result, err := gate.Evaluate(obj.Object)
+		if err != nil {
+			return &blockReason{
+				reason:  untaintv1alpha1.ReasonResourceNotReady,
+				message: fmt.Sprintf("failed to evaluate %s: %v", gate.JSONPath, err),
+			}
+		}
+		if !ok {
+			return &blockReason{
+				reason:  untaintv1alpha1.ReasonResourceNotReady,
+				message: fmt.Sprintf("%s is %q", gate.JSONPath, result),
+			}
+		}
+	}
+	return nil
+}
+
+// resourceConditionStatus returns the status of the condition of obj, or
+// Unknown when obj doesn't report it
+func resourceConditionStatus(obj *unstructured.Unstructured, conditionType string) metav1.ConditionStatus {
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	for _, value := range conditions {
+		condition, ok := value.(map[string]interface{})
+		if ok && condition["type"] == conditionType {
+			status, _ := condition["status"].(string)
+			return metav1.ConditionStatus(status)
+		}
+	}
+	return metav1.ConditionUnknown
+}
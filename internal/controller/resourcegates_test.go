@@ -0,0 +1,78 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	untaintv1alpha1 "github.com/jslay88/generic-untaint-operator/api/v1alpha1"
+	"github.com/jslay88/generic-untaint-operator/internal/config"
+)
+
+var _ = Describe("Resource gates", func() {
+	var (
+		ctx       context.Context
+		r         *NodeReconciler
+		node      *corev1.Node
+		workspace *unstructured.Unstructured
+	)
+
+	gate := config.ResourceGate{
+		APIVersion: "example.com/v1",
+		Kind:       "Workspace",
+		Namespace:  "platform",
+		Name:       "{{ .Name }}",
+		Condition:  "Ready",
+		JSONPath:   "{.status.phase}",
+		Value:      "Provisioned",
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		node = &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node"}}
+		workspace = newUnstructured(schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Workspace"})
+		workspace.SetNamespace("platform")
+		workspace.SetName("node")
+		Expect(unstructured.SetNestedField(workspace.Object, "Provisioned", "status", "phase")).To(Succeed())
+		Expect(unstructured.SetNestedSlice(workspace.Object, []interface{}{
+			map[string]interface{}{"type": "Ready", "status": "True"},
+		}, "status", "conditions")).To(Succeed())
+		r = &NodeReconciler{Client: fake.NewClientBuilder().WithObjects(workspace).Build()}
+	})
+
+	It("should pass once the resource reports the node ready", func() {
+		Expect(r.resourceGatesBlocked(ctx, node, []config.ResourceGate{gate})).To(BeNil())
+	})
+
+	It("should block while the resource doesn't exist", func() {
+		node.Name = "other"
+		reason, err := r.resourceGatesBlocked(ctx, node, []config.ResourceGate{gate})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reason).NotTo(BeNil())
+		Expect(reason.reason).To(Equal(untaintv1alpha1.ReasonResourceNotReady))
+		Expect(reason.message).To(Equal("Workspace platform/other does not exist"))
+	})
+
+	It("should block on the condition and the JSONPath", func() {
+		Expect(unstructured.SetNestedSlice(workspace.Object, []interface{}{
+			map[string]interface{}{"type": "Ready", "status": "False"},
+		}, "status", "conditions")).To(Succeed())
+		reason := resourceGateNotReady(gate, workspace)
+		Expect(reason).NotTo(BeNil())
+		Expect(reason.message).To(Equal("condition Ready is False"))
+
+		Expect(unstructured.SetNestedSlice(workspace.Object, nil, "status", "conditions")).To(Succeed())
+		Expect(resourceGateNotReady(gate, workspace).message).To(Equal("condition Ready is Unknown"))
+
+		gate := gate
+		gate.Condition = ""
+		Expect(unstructured.SetNestedField(workspace.Object, "Pending", "status", "phase")).To(Succeed())
+		Expect(resourceGateNotReady(gate, workspace).message).To(Equal(`{.status.phase} is "Pending"`))
+	})
+})
@@ -0,0 +1,54 @@
+package controller
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	untaintv1alpha1 "github.com/jslay88/generic-untaint-operator/api/v1alpha1"
+)
+
+// missingResources returns why node doesn't advertise the allocatable
+// resources required, or nil when it advertises at least the required
+// quantity of each. Device plugins advertise their resources some time after
+// their pod turns ready.
+func missingResources(node *corev1.Node, required corev1.ResourceList) *blockReason {
+	for _, name := range sortedResourceNames(required) {
+		minimum := required[name]
+		allocatable, ok := node.Status.Allocatable[name]
+		if !ok || allocatable.Cmp(minimum) < 0 {
+			return &blockReason{
+				reason:  untaintv1alpha1.ReasonResourceNotAdvertised,
+				message: fmt.Sprintf("node has %s allocatable %s, less than %s", allocatable.String(), name, minimum.String()),
+			}
+		}
+	}
+	return nil
+}
+
+// allocatableChanged reports whether an update to a node changed its
+// allocatable quantity of one of the required resources
+func allocatableChanged(oldNode, newNode *corev1.Node, required corev1.ResourceList) bool {
+	for name := range required {
+		oldQuantity := oldNode.Status.Allocatable[name]
+		if oldQuantity.Cmp(newNode.Status.Allocatable[name]) != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedResourceNames returns the names in resources, sorted so the first
+// missing one is reported consistently
+func sortedResourceNames(resources corev1.ResourceList) []corev1.ResourceName {
+	names := make([]corev1.ResourceName, 0, len(resources))
+	for name := range resources {
+		names = append(names, name)
+	}
+	slices.SortFunc(names, func(a, b corev1.ResourceName) int {
+		return strings.Compare(string(a), string(b))
+	})
+	return names
+}
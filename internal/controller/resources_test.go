@@ -0,0 +1,41 @@
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	untaintv1alpha1 "github.com/jslay88/generic-untaint-operator/api/v1alpha1"
+)
+
+var _ = Describe("missingResources", func() {
+	withAllocatable := func(allocatable corev1.ResourceList) *corev1.Node {
+		return &corev1.Node{Status: corev1.NodeStatus{Allocatable: allocatable}}
+	}
+	gpus := corev1.ResourceList{"nvidia.com/gpu": resource.MustParse("1")}
+
+	It("should pass once the node advertises the required resources", func() {
+		node := withAllocatable(corev1.ResourceList{"nvidia.com/gpu": resource.MustParse("8")})
+		Expect(missingResources(node, gpus)).To(BeNil())
+		Expect(missingResources(withAllocatable(nil), nil)).To(BeNil())
+	})
+
+	It("should block until the device plugin advertises its resources", func() {
+		reason := missingResources(withAllocatable(nil), gpus)
+		Expect(reason).NotTo(BeNil())
+		Expect(reason.reason).To(Equal(untaintv1alpha1.ReasonResourceNotAdvertised))
+		Expect(reason.message).To(ContainSubstring("nvidia.com/gpu"))
+
+		node := withAllocatable(corev1.ResourceList{"nvidia.com/gpu": resource.MustParse("0")})
+		Expect(missingResources(node, gpus)).NotTo(BeNil())
+	})
+
+	It("should notice when a required resource changes", func() {
+		before := withAllocatable(nil)
+		after := withAllocatable(corev1.ResourceList{"nvidia.com/gpu": resource.MustParse("8")})
+		Expect(allocatableChanged(before, after, gpus)).To(BeTrue())
+		Expect(allocatableChanged(after, after, gpus)).To(BeFalse())
+		Expect(allocatableChanged(before, after, nil)).To(BeFalse())
+	})
+})
@@ -0,0 +1,74 @@
+package controller
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// restartSample is the restart count of a container as first observed at a time
+type restartSample struct {
+	at    time.Time
+	count int32
+}
+
+// podRestarts is the restart history of the containers of a pod
+type podRestarts struct {
+	lastSeen   time.Time
+	containers map[string][]restartSample
+}
+
+// restartTracker keeps the restart history of target pods, since a pod only
+// reports the total number of restarts of each container. History starts when
+// the operator first sees a pod. The zero value is ready to use.
+type restartTracker struct {
+	mu   sync.Mutex
+	pods map[types.UID]*podRestarts
+}
+
+// observe records the current restart counts of pod and returns the container
+// that restarted the most within window before now, with its restart count
+func (t *restartTracker) observe(pod *corev1.Pod, window time.Duration, now time.Time) (string, int32) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.pods == nil {
+		t.pods = make(map[types.UID]*podRestarts)
+	}
+	// Drop the history of pods that haven't been seen for a whole window
+	for uid, history := range t.pods {
+		if now.Sub(history.lastSeen) > window {
+			delete(t.pods, uid)
+		}
+	}
+	history, ok := t.pods[pod.UID]
+	if !ok {
+		history = &podRestarts{containers: make(map[string][]restartSample)}
+		t.pods[pod.UID] = history
+	}
+	history.lastSeen = now
+
+	windowStart := now.Add(-window)
+	var worst string
+	var worstRestarts int32
+	for _, status := range append(slices.Clone(pod.Status.InitContainerStatuses), pod.Status.ContainerStatuses...) {
+		samples := history.containers[status.Name]
+		if len(samples) == 0 || samples[len(samples)-1].count != status.RestartCount {
+			samples = append(samples, restartSample{at: now, count: status.RestartCount})
+		}
+		// Keep the newest sample from before the window, which holds the count
+		// at the start of the window
+		for len(samples) > 1 && !samples[1].at.After(windowStart) {
+			samples = samples[1:]
+		}
+		history.containers[status.Name] = samples
+
+		if restarts := status.RestartCount - samples[0].count; restarts > worstRestarts {
+			worst, worstRestarts = status.Name, restarts
+		}
+	}
+	return worst, worstRestarts
+}
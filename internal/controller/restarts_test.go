@@ -0,0 +1,43 @@
+package controller
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("restartTracker", func() {
+	It("should count the restarts within the window", func() {
+		tracker := &restartTracker{}
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{UID: "pod-uid"},
+			Status: corev1.PodStatus{
+				ContainerStatuses: []corev1.ContainerStatus{{Name: "agent", RestartCount: 4}},
+			},
+		}
+		start := time.Now()
+
+		// Restarts from before the pod was first seen are not counted
+		_, restarts := tracker.observe(pod, 10*time.Minute, start)
+		Expect(restarts).To(BeZero())
+
+		pod.Status.ContainerStatuses[0].RestartCount = 6
+		container, restarts := tracker.observe(pod, 10*time.Minute, start.Add(time.Minute))
+		Expect(container).To(Equal("agent"))
+		Expect(restarts).To(Equal(int32(2)))
+
+		pod.Status.ContainerStatuses[0].RestartCount = 7
+		_, restarts = tracker.observe(pod, 10*time.Minute, start.Add(5*time.Minute))
+		Expect(restarts).To(Equal(int32(3)))
+
+		// The first two restarts leave the window
+		_, restarts = tracker.observe(pod, 10*time.Minute, start.Add(12*time.Minute))
+		Expect(restarts).To(Equal(int32(1)))
+
+		_, restarts = tracker.observe(pod, 10*time.Minute, start.Add(16*time.Minute))
+		Expect(restarts).To(BeZero())
+	})
+})
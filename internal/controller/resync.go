@@ -0,0 +1,66 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// listTaintedNodes returns the names of the nodes of this replica's shard
+// that carry a target taint
+func (r *NodeReconciler) listTaintedNodes(ctx context.Context) ([]string, error) {
+	nodes := &corev1.NodeList{}
+	if err := r.List(ctx, nodes); err != nil {
+		return nil, err
+	}
+	cfg := r.currentConfig()
+	var tainted []string
+	for _, node := range nodes.Items {
+		if !r.ownsNode(node.Name) {
+			continue
+		}
+		for _, rule := range cfg.Rules {
+			if hasRuleTaint(&node, rule) {
+				tainted = append(tainted, node.Name)
+				break
+			}
+		}
+	}
+	return tainted, nil
+}
+
+// resyncTaintedNodes queues every node carrying a target taint each period,
+// so a watch event the informers missed doesn't leave a node tainted until
+// something else about it changes
+func (r *NodeReconciler) resyncTaintedNodes(period time.Duration) source.Func {
+	return func(ctx context.Context, queue workqueue.TypedRateLimitingInterface[reconcile.Request]) error {
+		go func() {
+			log := log.FromContext(ctx)
+			ticker := time.NewTicker(period)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					tainted, err := r.listTaintedNodes(ctx)
+					if err != nil {
+						log.Error(err, "Failed to list nodes for the periodic resync")
+						continue
+					}
+					for _, name := range tainted {
+						queue.Add(reconcile.Request{NamespacedName: types.NamespacedName{Name: name}})
+					}
+					log.V(1).Info("Resynced tainted nodes", "nodes", len(tainted))
+				}
+			}
+		}()
+		return nil
+	}
+}
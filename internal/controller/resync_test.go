@@ -0,0 +1,40 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("resyncTaintedNodes", func() {
+	const taint = "resync.test/not-ready"
+
+	It("should periodically queue the nodes carrying a target taint", func() {
+		tainted := &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "tainted"},
+			Spec:       corev1.NodeSpec{Taints: []corev1.Taint{{Key: taint, Effect: corev1.TaintEffectNoSchedule}}},
+		}
+		untainted := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "untainted"}}
+		r := &NodeReconciler{
+			Client:       fake.NewClientBuilder().WithObjects(tainted, untainted).Build(),
+			TargetTaint:  taint,
+			OwnedByNames: []string{"agent"},
+		}
+		queue := workqueue.NewTypedRateLimitingQueue(workqueue.DefaultTypedControllerRateLimiter[reconcile.Request]())
+		DeferCleanup(queue.ShutDown)
+		ctx, cancel := context.WithCancel(context.Background())
+		DeferCleanup(cancel)
+
+		Expect(r.resyncTaintedNodes(10*time.Millisecond)(ctx, queue)).To(Succeed())
+		Eventually(queue.Len).Should(Equal(1))
+		item, _ := queue.Get()
+		Expect(item.Name).To(Equal("tainted"))
+	})
+})
@@ -0,0 +1,171 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	toolscache "k8s.io/client-go/tools/cache"
+	"k8s.io/utils/ptr"
+
+	untaintv1alpha1 "github.com/jslay88/generic-untaint-operator/api/v1alpha1"
+	"github.com/jslay88/generic-untaint-operator/internal/config"
+)
+
+// The benchmarks below size the operator for a fleet of scaleNodes nodes
+// running scalePodsPerNode pods each, 300k pods in total. Run them with
+// make bench.
+const (
+	scaleNodes       = 10000
+	scalePodsPerNode = 30
+	// scaleWorkloads are the required workloads, the rest of the pods on a
+	// node belonging to unrelated ones
+	scaleWorkloads = 5
+)
+
+// scalePod returns a realistically sized pod of workload on node
+func scalePod(node string, i int, workload string) *corev1.Pod {
+	ready := metav1.NewTime(time.Now().Add(-time.Hour))
+	env := make([]corev1.EnvVar, 20)
+	for j := range env {
+		env[j] = corev1.EnvVar{Name: fmt.Sprintf("SETTING_%d", j), Value: "some-configuration-value"}
+	}
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:       "kube-system",
+			Name:            fmt.Sprintf("%s-%s-%d", workload, node, i),
+			UID:             types.UID(fmt.Sprintf("%s-%d", node, i)),
+			ResourceVersion: "1",
+			Labels:          map[string]string{"app": workload, "controller-revision-hash": "abcdef"},
+			Annotations: map[string]string{
+				"kubectl.kubernetes.io/last-applied-configuration": string(make([]byte, 2048)),
+			},
+			OwnerReferences: []metav1.OwnerReference{{Kind: "DaemonSet", Name: workload}},
+			ManagedFields: []metav1.ManagedFieldsEntry{
+				{Manager: "kube-controller-manager", FieldsV1: &metav1.FieldsV1{Raw: make([]byte, 1024)}},
+				{Manager: "kubelet", FieldsV1: &metav1.FieldsV1{Raw: make([]byte, 1024)}},
+			},
+		},
+		Spec: corev1.PodSpec{
+			NodeName: node,
+			Containers: []corev1.Container{{
+				Name:    "main",
+				Image:   "registry.example.com/" + workload + ":v1.2.3",
+				Command: []string{"/bin/" + workload, "--config=/etc/" + workload + "/config.yaml"},
+				Env:     env,
+				Ports:   []corev1.ContainerPort{{Name: "health", ContainerPort: 8080}},
+				VolumeMounts: []corev1.VolumeMount{
+					{Name: "config", MountPath: "/etc/" + workload},
+					{Name: "token", MountPath: "/var/run/secrets/kubernetes.io/serviceaccount"},
+				},
+			}},
+			Volumes: []corev1.Volume{{Name: "config"}, {Name: "token"}},
+		},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodRunning,
+			Conditions: []corev1.PodCondition{
+				{Type: corev1.PodReady, Status: corev1.ConditionTrue, LastTransitionTime: ready},
+			},
+			ContainerStatuses: []corev1.ContainerStatus{{Name: "main", Ready: true, Started: ptr.To(true)}},
+		},
+	}
+}
+
+// scaleNodePods returns the pods of a single node, scaleWorkloads of them
+// required, and the names of the required workloads
+func scaleNodePods(node string) ([]corev1.Pod, []string) {
+	workloads := make([]string, scaleWorkloads)
+	pods := make([]corev1.Pod, scalePodsPerNode)
+	for i := range pods {
+		workload := fmt.Sprintf("app-%d", i)
+		if i < scaleWorkloads {
+			workload = fmt.Sprintf("agent-%d", i)
+			workloads[i] = workload
+		}
+		pods[i] = *scalePod(node, i, workload)
+	}
+	return pods, workloads
+}
+
+// BenchmarkWorkloadsBlocked evaluates the required workloads of one node
+func BenchmarkWorkloadsBlocked(b *testing.B) {
+	r := &NodeReconciler{}
+	cfg := &config.Config{ReadinessMode: config.ReadinessModeContainers}
+	pods, workloads := scaleNodePods("node")
+	ctx := context.Background()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := r.workloadsBlocked(ctx, pods, workloads, cfg); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkBuildPolicyStatus summarizes a fleet with a tenth of its nodes
+// blocked into the policy status, as large-cluster mode caps it
+func BenchmarkBuildPolicyStatus(b *testing.B) {
+	nodes := make(map[string]nodeState, scaleNodes)
+	for i := 0; i < scaleNodes; i++ {
+		state := nodeState{untainted: true}
+		if i%10 == 0 {
+			state = nodeState{
+				reason:  untaintv1alpha1.ReasonWorkloadUnready,
+				message: "pod kube-system/agent is not ready",
+				since:   metav1.NewTime(time.Now().Add(-time.Duration(i) * time.Second)),
+			}
+		}
+		nodes[fmt.Sprintf("node-%d", i)] = state
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		buildPolicyStatus(nodes, nil, 100)
+	}
+}
+
+// BenchmarkPodCache fills an indexed pod cache, as the manager keeps it, with
+// the pods of the whole fleet and reports the heap it takes
+func BenchmarkPodCache(b *testing.B) {
+	for _, transform := range []bool{false, true} {
+		b.Run(fmt.Sprintf("transform=%t", transform), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				runtime.GC()
+				var before runtime.MemStats
+				runtime.ReadMemStats(&before)
+
+				indexer := toolscache.NewIndexer(toolscache.MetaNamespaceKeyFunc, toolscache.Indexers{
+					podNodeNameField: func(obj interface{}) ([]string, error) {
+						return []string{obj.(*corev1.Pod).Spec.NodeName}, nil
+					},
+				})
+				for n := 0; n < scaleNodes; n++ {
+					node := fmt.Sprintf("node-%d", n)
+					pods, _ := scaleNodePods(node)
+					for p := range pods {
+						var obj interface{} = &pods[p]
+						if transform {
+							obj, _ = TransformPod(obj)
+						}
+						if err := indexer.Add(obj); err != nil {
+							b.Fatal(err)
+						}
+					}
+				}
+
+				runtime.GC()
+				var after runtime.MemStats
+				runtime.ReadMemStats(&after)
+				b.ReportMetric(float64(int64(after.HeapAlloc)-int64(before.HeapAlloc))/(1<<20), "MiB")
+				if pods, err := indexer.ByIndex(podNodeNameField, "node-0"); err != nil || len(pods) != scalePodsPerNode {
+					b.Fatalf("node-0 has %d pods: %v", len(pods), err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package controller
+
+import (
+	"hash/fnv"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+// ownsNode reports whether the named node belongs to the shard of this
+// replica. Nodes are spread across the shards by a hash of their name, so
+// every replica agrees on the owner of a node without talking to the others.
+func (r *NodeReconciler) ownsNode(name string) bool {
+	if r.ShardCount <= 1 {
+		return true
+	}
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(name))
+	return int(hash.Sum32()%uint32(r.ShardCount)) == r.ShardIndex
+}
+
+// shardPredicate passes the events of the nodes this replica owns
+func (r *NodeReconciler) shardPredicate() predicate.Predicate {
+	return predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		return r.ownsNode(obj.GetName())
+	})
+}
@@ -0,0 +1,61 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("ownsNode", func() {
+	It("should own every node without sharding", func() {
+		r := &NodeReconciler{}
+		Expect(r.ownsNode("node")).To(BeTrue())
+	})
+
+	It("should give every node to exactly one shard", func() {
+		shards := make([]*NodeReconciler, 3)
+		for i := range shards {
+			shards[i] = &NodeReconciler{ShardCount: len(shards), ShardIndex: i}
+		}
+		counts := make([]int, len(shards))
+		for n := 0; n < 300; n++ {
+			name := fmt.Sprintf("node-%d", n)
+			owners := 0
+			for i, shard := range shards {
+				if shard.ownsNode(name) {
+					owners++
+					counts[i]++
+				}
+			}
+			Expect(owners).To(Equal(1), name)
+		}
+		for _, count := range counts {
+			Expect(count).To(BeNumerically(">", 50))
+		}
+	})
+
+	It("should leave the nodes of other shards alone", func() {
+		mine := &NodeReconciler{ShardCount: 2, ShardIndex: 0}
+		theirs := &NodeReconciler{ShardCount: 2, ShardIndex: 1}
+		name := "node-0"
+		if !mine.ownsNode(name) {
+			mine, theirs = theirs, mine
+		}
+		node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		Expect(mine.shardPredicate().Create(event.CreateEvent{Object: node})).To(BeTrue())
+		Expect(theirs.shardPredicate().Create(event.CreateEvent{Object: node})).To(BeFalse())
+
+		// The node isn't even read by the replica of the other shard
+		result, err := theirs.Reconcile(context.Background(),
+			reconcile.Request{NamespacedName: types.NamespacedName{Name: name}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(reconcile.Result{}))
+	})
+})
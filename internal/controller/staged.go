@@ -0,0 +1,98 @@
+package controller
+
+import (
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/jslay88/generic-untaint-operator/internal/config"
+)
+
+// effectStrength orders taint effects from the weakest to the strongest
+var effectStrength = map[corev1.TaintEffect]int{
+	corev1.TaintEffectPreferNoSchedule: 1,
+	corev1.TaintEffectNoSchedule:       2,
+	corev1.TaintEffectNoExecute:        3,
+}
+
+// stageTimes remembers when each taint entered its current removal stage. The
+// zero value is ready to use.
+type stageTimes struct {
+	mu      sync.Mutex
+	entered map[nodeTaintKey]time.Time
+}
+
+// enter records that taint entered a new stage on node at the given time
+func (s *stageTimes) enter(node, taint string, at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.entered == nil {
+		s.entered = make(map[nodeTaintKey]time.Time)
+	}
+	s.entered[nodeTaintKey{node: node, taint: taint}] = at
+}
+
+// since returns when taint entered its current stage on node. A taint first
+// seen at a stage, e.g. because the operator restarted, starts dwelling now.
+func (s *stageTimes) since(node, taint string, now time.Time) time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.entered == nil {
+		s.entered = make(map[nodeTaintKey]time.Time)
+	}
+	key := nodeTaintKey{node: node, taint: taint}
+	if at, ok := s.entered[key]; ok {
+		return at
+	}
+	s.entered[key] = now
+	return now
+}
+
+// forget drops the stage of taint on node, or of every taint of node when taint is empty
+func (s *stageTimes) forget(node, taint string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key := range s.entered {
+		if key.node == node && (taint == "" || key.taint == taint) {
+			delete(s.entered, key)
+		}
+	}
+}
+
+// stageEdit returns the edit that moves the taint of a ready rule towards
+// removal, or nil while it must keep dwelling at its current stage, together
+// with how long until the next step. Without staged removal the taint is
+// removed right away.
+func (r *NodeReconciler) stageEdit(node *corev1.Node, rule config.Rule, now time.Time) (*taintEdit, time.Duration) {
+	current := corev1.TaintEffect("")
+	for _, taint := range node.Spec.Taints {
+		if rule.Manages(taint) && effectStrength[taint.Effect] > effectStrength[current] {
+			current = taint.Effect
+		}
+	}
+	effects := rule.ManagedEffects()
+
+	// Stages are validated to weaken the taint one after the other
+	stages := rule.StagedRemoval
+	for i, stage := range stages {
+		if effectStrength[stage.Effect] > effectStrength[current] {
+			// The taint is already weaker than this stage
+			continue
+		}
+		if stage.Effect != current {
+			r.stages.enter(node.Name, rule.TargetTaint, now)
+			return &taintEdit{downgradeTo: stage.Effect, effects: effects}, stage.Dwell.Duration
+		}
+		if left := stage.Dwell.Duration - now.Sub(r.stages.since(node.Name, rule.TargetTaint, now)); left > 0 {
+			return nil, left
+		}
+		if i+1 < len(stages) {
+			r.stages.enter(node.Name, rule.TargetTaint, now)
+			return &taintEdit{downgradeTo: stages[i+1].Effect, effects: effects}, stages[i+1].Dwell.Duration
+		}
+		break
+	}
+	r.stages.forget(node.Name, rule.TargetTaint)
+	return &taintEdit{effects: effects}, 0
+}
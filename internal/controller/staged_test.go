@@ -0,0 +1,113 @@
+package controller
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/jslay88/generic-untaint-operator/internal/config"
+)
+
+var _ = Describe("stageEdit", func() {
+	var (
+		reconciler *NodeReconciler
+		node       *corev1.Node
+		rule       config.Rule
+	)
+
+	BeforeEach(func() {
+		reconciler = &NodeReconciler{}
+		node = &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "node"},
+			Spec: corev1.NodeSpec{Taints: []corev1.Taint{
+				{Key: "test-taint", Effect: corev1.TaintEffectNoExecute},
+			}},
+		}
+		rule = config.Rule{
+			TargetTaint: "test-taint",
+			StagedRemoval: []config.RemovalStage{
+				{Effect: corev1.TaintEffectNoSchedule, Dwell: metav1.Duration{Duration: time.Minute}},
+				{Effect: corev1.TaintEffectPreferNoSchedule, Dwell: metav1.Duration{Duration: 2 * time.Minute}},
+			},
+		}
+	})
+
+	// step applies the edit returned by stageEdit to the node
+	step := func(now time.Time) (*taintEdit, time.Duration) {
+		edit, dwell := reconciler.stageEdit(node, rule, now)
+		if edit != nil {
+			node.Spec.Taints = taintEdits{rule.TargetTaint: *edit}.apply(node.Spec.Taints)
+		}
+		return edit, dwell
+	}
+
+	It("should step the taint down through every stage before removing it", func() {
+		now := time.Now()
+		edit, dwell := step(now)
+		Expect(edit).To(Equal(&taintEdit{downgradeTo: corev1.TaintEffectNoSchedule}))
+		Expect(dwell).To(Equal(time.Minute))
+
+		edit, dwell = step(now.Add(30 * time.Second))
+		Expect(edit).To(BeNil())
+		Expect(dwell).To(Equal(30 * time.Second))
+
+		edit, dwell = step(now.Add(time.Minute))
+		Expect(edit).To(Equal(&taintEdit{downgradeTo: corev1.TaintEffectPreferNoSchedule}))
+		Expect(dwell).To(Equal(2 * time.Minute))
+
+		edit, dwell = step(now.Add(3 * time.Minute))
+		Expect(edit).To(Equal(&taintEdit{}))
+		Expect(dwell).To(BeZero())
+		Expect(node.Spec.Taints).To(BeEmpty())
+	})
+
+	It("should skip stages stronger than the taint", func() {
+		node.Spec.Taints[0].Effect = corev1.TaintEffectPreferNoSchedule
+		now := time.Now()
+		edit, dwell := step(now)
+		Expect(edit).To(BeNil())
+		Expect(dwell).To(Equal(2 * time.Minute))
+	})
+
+	It("should remove the taint right away without stages", func() {
+		rule.StagedRemoval = nil
+		edit, dwell := step(time.Now())
+		Expect(edit).To(Equal(&taintEdit{}))
+		Expect(dwell).To(BeZero())
+	})
+})
+
+var _ = Describe("taintEdits", func() {
+	It("should drop an entry a downgrade made identical to another one", func() {
+		taints := []corev1.Taint{
+			{Key: "a", Effect: corev1.TaintEffectNoExecute, TimeAdded: &metav1.Time{Time: time.Now()}},
+			{Key: "a", Effect: corev1.TaintEffectNoSchedule},
+			{Key: "b", Effect: corev1.TaintEffectNoExecute},
+		}
+		edits := taintEdits{"a": {downgradeTo: corev1.TaintEffectNoSchedule}}
+		Expect(edits.apply(taints)).To(Equal([]corev1.Taint{
+			{Key: "a", Effect: corev1.TaintEffectNoSchedule},
+			{Key: "b", Effect: corev1.TaintEffectNoExecute},
+		}))
+
+		downgraded := corev1.Taint{Key: "a", Effect: corev1.TaintEffectNoSchedule}
+		Expect(taintPatchOps(taints, edits)).To(Equal([]jsonPatchOp{
+			{Op: "test", Path: "/spec/taints/1", Value: &taints[1]},
+			{Op: "remove", Path: "/spec/taints/1"},
+			{Op: "test", Path: "/spec/taints/0", Value: &taints[0]},
+			{Op: "replace", Path: "/spec/taints/0", Value: &downgraded},
+		}))
+	})
+
+	It("should only edit the entries with the selected effects", func() {
+		taints := []corev1.Taint{
+			{Key: "a", Effect: corev1.TaintEffectNoSchedule},
+			{Key: "a", Effect: corev1.TaintEffectNoExecute},
+		}
+		edits := taintEdits{"a": {effects: []corev1.TaintEffect{corev1.TaintEffectNoSchedule}}}
+		Expect(edits.apply(taints)).To(Equal([]corev1.Taint{{Key: "a", Effect: corev1.TaintEffectNoExecute}}))
+	})
+})
@@ -0,0 +1,233 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// DefaultStatsDInterval is how often metrics are sent when StatsDEmitter.Interval is unset
+const DefaultStatsDInterval = 10 * time.Second
+
+// statsDMetricPrefix selects the metrics of the operator itself among the
+// gathered ones
+const statsDMetricPrefix = "untaint_operator_"
+
+// statsDMaxPacket keeps every packet within the payload of a single Ethernet
+// frame, so none is fragmented
+const statsDMaxPacket = 1432
+
+// StatsDFormat selects how labels are sent to the StatsD server
+type StatsDFormat string
+
+const (
+	// StatsDFormatDogStatsD sends labels as DogStatsD tags
+	StatsDFormatDogStatsD StatsDFormat = "dogstatsd"
+	// StatsDFormatStatsD appends label values to the metric names, since plain
+	// StatsD has no tags
+	StatsDFormatStatsD StatsDFormat = "statsd"
+)
+
+// StatsDEmitter periodically sends the operator's own metrics to a StatsD or
+// DogStatsD server over UDP, for pipelines that ingest StatsD rather than
+// scraping Prometheus. Gauges are sent as gauges, counters as the increase
+// since the previous send, histograms and summaries as the increase of their
+// count and sum, and summary quantiles as gauges. It runs on every replica,
+// like the metrics endpoint.
+type StatsDEmitter struct {
+	// Address is the host:port of the StatsD server
+	Address string
+	// Format selects how labels are sent. Defaults to StatsDFormatDogStatsD
+	// when unset.
+	Format StatsDFormat
+	// Interval is how often metrics are sent. Defaults to
+	// DefaultStatsDInterval when unset.
+	Interval time.Duration
+	// Gatherer provides the metrics. Defaults to the controller-runtime
+	// registry when unset.
+	Gatherer prometheus.Gatherer
+
+	// counters are the last values sent of each counter series, to send their
+	// increase
+	counters map[string]float64
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable
+func (e *StatsDEmitter) NeedLeaderElection() bool {
+	return false
+}
+
+// Start implements manager.Runnable and sends metrics until ctx is cancelled,
+// sending them a last time on the way out
+func (e *StatsDEmitter) Start(ctx context.Context) error {
+	log := log.FromContext(ctx).WithName("statsd").WithValues("address", e.Address)
+
+	conn, err := net.Dial("udp", e.Address)
+	if err != nil {
+		return fmt.Errorf("failed to connect to StatsD server: %w", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	interval := e.Interval
+	if interval <= 0 {
+		interval = DefaultStatsDInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if err := e.send(conn); err != nil {
+				log.Error(err, "Failed to send the last metrics to the StatsD server")
+			}
+			return nil
+		case <-ticker.C:
+			if err := e.send(conn); err != nil {
+				log.Error(err, "Failed to send metrics to the StatsD server")
+			}
+		}
+	}
+}
+
+// send gathers the metrics and writes them to conn, packing as many lines
+// into each packet as fit
+func (e *StatsDEmitter) send(conn net.Conn) error {
+	lines, err := e.lines()
+	if err != nil {
+		return err
+	}
+	var packet []byte
+	for _, line := range lines {
+		if len(packet) > 0 && len(packet)+1+len(line) > statsDMaxPacket {
+			if _, err := conn.Write(packet); err != nil {
+				return err
+			}
+			packet = packet[:0]
+		}
+		if len(packet) > 0 {
+			packet = append(packet, '\n')
+		}
+		packet = append(packet, line...)
+	}
+	if len(packet) > 0 {
+		if _, err := conn.Write(packet); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lines gathers the metrics of the operator as StatsD lines
+func (e *StatsDEmitter) lines() ([]string, error) {
+	gatherer := e.Gatherer
+	if gatherer == nil {
+		gatherer = metrics.Registry
+	}
+	families, err := gatherer.Gather()
+	if err != nil {
+		return nil, fmt.Errorf("failed to gather metrics: %w", err)
+	}
+	if e.counters == nil {
+		e.counters = make(map[string]float64)
+	}
+
+	var lines []string
+	for _, family := range families {
+		name := family.GetName()
+		if !strings.HasPrefix(name, statsDMetricPrefix) {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			labels := metric.GetLabel()
+			switch family.GetType() {
+			case dto.MetricType_GAUGE:
+				lines = append(lines, e.line(name, labels, metric.GetGauge().GetValue(), "g"))
+			case dto.MetricType_COUNTER:
+				lines = append(lines, e.counter(name, labels, metric.GetCounter().GetValue())...)
+			case dto.MetricType_HISTOGRAM:
+				histogram := metric.GetHistogram()
+				lines = append(lines, e.counter(name+"_count", labels, float64(histogram.GetSampleCount()))...)
+				lines = append(lines, e.counter(name+"_sum", labels, histogram.GetSampleSum())...)
+			case dto.MetricType_SUMMARY:
+				summary := metric.GetSummary()
+				lines = append(lines, e.counter(name+"_count", labels, float64(summary.GetSampleCount()))...)
+				lines = append(lines, e.counter(name+"_sum", labels, summary.GetSampleSum())...)
+				for _, quantile := range summary.GetQuantile() {
+					quantileLabels := append(labels[:len(labels):len(labels)], &dto.LabelPair{
+						Name:  ptr.To("quantile"),
+						Value: ptr.To(strconv.FormatFloat(quantile.GetQuantile(), 'f', -1, 64)),
+					})
+					lines = append(lines, e.line(name, quantileLabels, quantile.GetValue(), "g"))
+				}
+			}
+		}
+	}
+	return lines, nil
+}
+
+// counter returns the line sending the increase of a counter series since
+// it was last sent, if any. A counter that went down was reset and is sent
+// whole.
+func (e *StatsDEmitter) counter(name string, labels []*dto.LabelPair, value float64) []string {
+	key := e.line(name, labels, 0, "c")
+	previous, seen := e.counters[key]
+	e.counters[key] = value
+	delta := value - previous
+	if !seen || delta < 0 {
+		delta = value
+	}
+	if delta == 0 {
+		return nil
+	}
+	return []string{e.line(name, labels, delta, "c")}
+}
+
+// line formats a single StatsD line of the given type
+func (e *StatsDEmitter) line(name string, labels []*dto.LabelPair, value float64, kind string) string {
+	pairs := make([]*dto.LabelPair, len(labels))
+	copy(pairs, labels)
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].GetName() < pairs[j].GetName() })
+
+	formatted := strconv.FormatFloat(value, 'f', -1, 64)
+	if e.Format == StatsDFormatStatsD {
+		var b strings.Builder
+		b.WriteString(name)
+		for _, pair := range pairs {
+			// Dots would nest the value in the metric hierarchy
+			b.WriteByte('.')
+			b.WriteString(strings.ReplaceAll(statsDSanitize(pair.GetValue()), ".", "_"))
+		}
+		return fmt.Sprintf("%s:%s|%s", b.String(), formatted, kind)
+	}
+	tags := make([]string, len(pairs))
+	for i, pair := range pairs {
+		tags[i] = pair.GetName() + ":" + statsDSanitize(pair.GetValue())
+	}
+	line := fmt.Sprintf("%s:%s|%s", name, formatted, kind)
+	if len(tags) > 0 {
+		line += "|#" + strings.Join(tags, ",")
+	}
+	return line
+}
+
+// statsDSanitize replaces the characters StatsD uses as separators in a label value
+func statsDSanitize(value string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ':', '|', '@', ',', '#', '\n', ' ':
+			return '_'
+		}
+		return r
+	}, value)
+}
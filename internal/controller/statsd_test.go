@@ -0,0 +1,82 @@
+package controller
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var _ = Describe("StatsDEmitter", func() {
+	var (
+		registry *prometheus.Registry
+		counter  *prometheus.CounterVec
+		gauge    prometheus.Gauge
+	)
+
+	BeforeEach(func() {
+		registry = prometheus.NewRegistry()
+		counter = prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "untaint_operator_statsd_test_total",
+			Help: "Test counter",
+		}, []string{"taint"})
+		gauge = prometheus.NewGauge(prometheus.GaugeOpts{Name: "untaint_operator_statsd_test", Help: "Test gauge"})
+		other := prometheus.NewGauge(prometheus.GaugeOpts{Name: "go_statsd_test", Help: "Unrelated gauge"})
+		registry.MustRegister(counter, gauge, other)
+	})
+
+	It("should send gauges and counter increases as DogStatsD tags", func() {
+		emitter := &StatsDEmitter{Gatherer: registry}
+		counter.WithLabelValues("example.com/not-ready").Add(3)
+		gauge.Set(2)
+
+		lines, err := emitter.lines()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lines).To(ConsistOf(
+			"untaint_operator_statsd_test_total:3|c|#taint:example.com/not-ready",
+			"untaint_operator_statsd_test:2|g",
+		))
+
+		counter.WithLabelValues("example.com/not-ready").Add(2)
+		lines, err = emitter.lines()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lines).To(ContainElement("untaint_operator_statsd_test_total:2|c|#taint:example.com/not-ready"))
+
+		lines, err = emitter.lines()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lines).To(ConsistOf("untaint_operator_statsd_test:2|g"))
+	})
+
+	It("should append label values to the names in plain StatsD", func() {
+		emitter := &StatsDEmitter{Gatherer: registry, Format: StatsDFormatStatsD}
+		counter.WithLabelValues("example.com/not-ready").Inc()
+
+		lines, err := emitter.lines()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lines).To(ContainElement("untaint_operator_statsd_test_total.example_com/not-ready:1|c"))
+	})
+
+	It("should send the metrics to the server", func() {
+		server, err := net.ListenPacket("udp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(server.Close)
+		gauge.Set(5)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error)
+		emitter := &StatsDEmitter{Address: server.LocalAddr().String(), Gatherer: registry}
+		Expect(emitter.NeedLeaderElection()).To(BeFalse())
+		go func() { done <- emitter.Start(ctx) }()
+
+		// Stopping sends the metrics a last time
+		cancel()
+		Eventually(done).Should(Receive(BeNil()))
+		buf := make([]byte, statsDMaxPacket)
+		n, _, err := server.ReadFrom(buf)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(strings.Split(string(buf[:n]), "\n")).To(ContainElement("untaint_operator_statsd_test:5|g"))
+	})
+})
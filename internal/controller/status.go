@@ -0,0 +1,278 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	untaintv1alpha1 "github.com/jslay88/generic-untaint-operator/api/v1alpha1"
+)
+
+// DefaultStatusInterval is how often the policy status is written when it changed
+const DefaultStatusInterval = 10 * time.Second
+
+// nodeState is the last known outcome of reconciling a node
+type nodeState struct {
+	untainted bool
+	reason    string
+	message   string
+	since     metav1.Time
+}
+
+// PolicyStatusReporter records the outcome of node reconciles and periodically
+// publishes it to the status of a singleton, cluster-scoped UntaintPolicy. All
+// methods are safe to call on a nil reporter, which records nothing.
+type PolicyStatusReporter struct {
+	client.Client
+	// PolicyName is the name of the UntaintPolicy receiving the status. It is
+	// created if it doesn't exist.
+	PolicyName string
+	// Interval is how often the status is written when it changed
+	Interval time.Duration
+	// MaxNodes, when set, caps how many blocked nodes the status lists, keeping
+	// those blocked longest, so the object stays small on large clusters. The
+	// node counts and conditions still cover every node.
+	MaxNodes int
+
+	mu    sync.Mutex
+	nodes map[string]nodeState
+	dirty bool
+}
+
+// +kubebuilder:rbac:groups=untaint.jslay88.github.io,resources=untaintpolicies,verbs=get;list;watch;create
+// +kubebuilder:rbac:groups=untaint.jslay88.github.io,resources=untaintpolicies/status,verbs=get;update;patch
+
+// SetBlocked records that node still carries a target taint for reason
+func (r *PolicyStatusReporter) SetBlocked(node, reason, message string) {
+	r.set(node, nodeState{reason: reason, message: message})
+}
+
+// SetUntainted records that the target taints were removed from node
+func (r *PolicyStatusReporter) SetUntainted(node string) {
+	r.set(node, nodeState{untainted: true, reason: untaintv1alpha1.ReasonTaintRemoved})
+}
+
+// ClearBlocked records a previously blocked node as untainted once it no
+// longer carries any target taint, whoever removed it
+func (r *PolicyStatusReporter) ClearBlocked(node string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	state, ok := r.nodes[node]
+	r.mu.Unlock()
+	if ok && !state.untainted {
+		r.SetUntainted(node)
+	}
+}
+
+func (r *PolicyStatusReporter) set(node string, state nodeState) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.nodes == nil {
+		r.nodes = make(map[string]nodeState)
+	}
+	previous, ok := r.nodes[node]
+	if ok && previous.untainted == state.untainted && previous.reason == state.reason &&
+		previous.message == state.message {
+		return
+	}
+	// Keep the transition time while the node stays in the same state
+	if ok && previous.untainted == state.untainted && previous.reason == state.reason {
+		state.since = previous.since
+	} else {
+		state.since = metav1.Now()
+	}
+	r.nodes[node] = state
+	r.dirty = true
+}
+
+// Start implements manager.Runnable and writes the status until ctx is cancelled
+func (r *PolicyStatusReporter) Start(ctx context.Context) error {
+	log := log.FromContext(ctx).WithName("policy-status").WithValues("policy", r.PolicyName)
+
+	interval := r.Interval
+	if interval <= 0 {
+		interval = DefaultStatusInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := r.flush(ctx); err != nil {
+				log.Error(err, "Failed to update policy status")
+			}
+		}
+	}
+}
+
+// flush writes the recorded node states to the policy status if they changed
+func (r *PolicyStatusReporter) flush(ctx context.Context) error {
+	r.mu.Lock()
+	if !r.dirty {
+		r.mu.Unlock()
+		return nil
+	}
+	r.dirty = false
+	nodes := make(map[string]nodeState, len(r.nodes))
+	for name, state := range r.nodes {
+		nodes[name] = state
+	}
+	r.mu.Unlock()
+
+	// Nodes are only tracked while they exist
+	for name := range nodes {
+		err := r.Get(ctx, types.NamespacedName{Name: name}, &corev1.Node{})
+		if apierrors.IsNotFound(err) {
+			delete(nodes, name)
+			r.forget(name)
+		}
+	}
+
+	policy := &untaintv1alpha1.UntaintPolicy{}
+	err := r.Get(ctx, types.NamespacedName{Name: r.PolicyName}, policy)
+	if apierrors.IsNotFound(err) {
+		policy.Name = r.PolicyName
+		err = r.Create(ctx, policy)
+	}
+	if err != nil {
+		r.markDirty()
+		return err
+	}
+
+	policy.Status = buildPolicyStatus(nodes, policy.Status.Conditions, r.MaxNodes)
+	if err := r.Status().Update(ctx, policy); err != nil {
+		r.markDirty()
+		return err
+	}
+	return nil
+}
+
+func (r *PolicyStatusReporter) forget(node string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.nodes, node)
+}
+
+func (r *PolicyStatusReporter) markDirty() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.dirty = true
+}
+
+// buildPolicyStatus summarizes the node states into an UntaintPolicy status,
+// updating the previous conditions of the policy and listing up to maxNodes
+// blocked nodes, when set
+func buildPolicyStatus(
+	nodes map[string]nodeState,
+	conditions []metav1.Condition,
+	maxNodes int,
+) untaintv1alpha1.UntaintPolicyStatus {
+	status := untaintv1alpha1.UntaintPolicyStatus{
+		MatchingNodes: int32(len(nodes)),
+		Conditions:    conditions,
+	}
+	meta.SetStatusCondition(&status.Conditions, blockedNodesCondition(nodes))
+	for name, state := range nodes {
+		if state.untainted {
+			status.UntaintedNodes++
+			continue
+		}
+		status.BlockedNodes++
+		status.Nodes = append(status.Nodes, untaintv1alpha1.NodeStatus{
+			Name: name,
+			Conditions: []metav1.Condition{{
+				Type:               untaintv1alpha1.ConditionUntainted,
+				Status:             metav1.ConditionFalse,
+				Reason:             state.reason,
+				Message:            state.message,
+				LastTransitionTime: state.since,
+			}},
+		})
+	}
+	if maxNodes > 0 && len(status.Nodes) > maxNodes {
+		slices.SortFunc(status.Nodes, func(a, b untaintv1alpha1.NodeStatus) int {
+			if c := a.Conditions[0].LastTransitionTime.Compare(b.Conditions[0].LastTransitionTime.Time); c != 0 {
+				return c
+			}
+			return strings.Compare(a.Name, b.Name)
+		})
+		status.Nodes = status.Nodes[:maxNodes]
+	}
+	slices.SortFunc(status.Nodes, func(a, b untaintv1alpha1.NodeStatus) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return status
+}
+
+// blockedNodesCondition rolls the blocking reasons of nodes up into the
+// BlockedNodes condition: its reason is the most common blocking reason, and
+// its message counts the nodes by reason and names the node blocked longest
+func blockedNodesCondition(nodes map[string]nodeState) metav1.Condition {
+	reasons := make(map[string]int)
+	var worst string
+	for name, state := range nodes {
+		if state.untainted {
+			continue
+		}
+		reasons[state.reason]++
+		longest := nodes[worst].since
+		if worst == "" || state.since.Before(&longest) || (state.since.Equal(&longest) && name < worst) {
+			worst = name
+		}
+	}
+	if worst == "" {
+		return metav1.Condition{
+			Type:    untaintv1alpha1.ConditionBlockedNodes,
+			Status:  metav1.ConditionFalse,
+			Reason:  untaintv1alpha1.ReasonNoNodesBlocked,
+			Message: fmt.Sprintf("none of the %d matching nodes is blocked", len(nodes)),
+		}
+	}
+
+	names := make([]string, 0, len(reasons))
+	blocked := 0
+	for reason, count := range reasons {
+		names = append(names, reason)
+		blocked += count
+	}
+	// Most common first, ties in name order
+	slices.SortFunc(names, func(a, b string) int {
+		if reasons[a] != reasons[b] {
+			return reasons[b] - reasons[a]
+		}
+		return strings.Compare(a, b)
+	})
+	counts := make([]string, len(names))
+	for i, reason := range names {
+		counts[i] = fmt.Sprintf("%d %s", reasons[reason], reason)
+	}
+	state := nodes[worst]
+	return metav1.Condition{
+		Type:   untaintv1alpha1.ConditionBlockedNodes,
+		Status: metav1.ConditionTrue,
+		Reason: names[0],
+		Message: fmt.Sprintf("%d of %d matching nodes are blocked (%s); node %s has been blocked longest, "+
+			"since %s: %s", blocked, len(nodes), strings.Join(counts, ", "), worst,
+			state.since.UTC().Format(time.RFC3339), state.message),
+	}
+}
@@ -0,0 +1,130 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	untaintv1alpha1 "github.com/jslay88/generic-untaint-operator/api/v1alpha1"
+)
+
+var _ = Describe("PolicyStatusReporter", func() {
+	var (
+		ctx      context.Context
+		reporter *PolicyStatusReporter
+		nodes    []*corev1.Node
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		reporter = &PolicyStatusReporter{Client: k8sClient, PolicyName: "test-policy"}
+
+		nodes = nil
+		for _, name := range []string{"status-node-a", "status-node-b"} {
+			node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}}
+			Expect(k8sClient.Create(ctx, node)).To(Succeed())
+			nodes = append(nodes, node)
+		}
+	})
+
+	AfterEach(func() {
+		for _, node := range nodes {
+			Expect(k8sClient.Delete(ctx, node)).To(Succeed())
+		}
+		policy := &untaintv1alpha1.UntaintPolicy{ObjectMeta: metav1.ObjectMeta{Name: "test-policy"}}
+		Expect(k8sClient.Delete(ctx, policy)).To(Succeed())
+	})
+
+	It("should publish node counts and blocking reasons", func() {
+		reporter.SetBlocked("status-node-a", untaintv1alpha1.ReasonWorkloadUnready, "pod default/agent is not ready")
+		reporter.SetUntainted("status-node-b")
+		// Nodes that no longer exist are dropped from the status
+		reporter.SetBlocked("status-node-gone", untaintv1alpha1.ReasonWaitingForWorkload, "no pods")
+		Expect(reporter.flush(ctx)).To(Succeed())
+
+		policy := &untaintv1alpha1.UntaintPolicy{}
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: "test-policy"}, policy)).To(Succeed())
+		Expect(policy.Status.MatchingNodes).To(BeEquivalentTo(2))
+		Expect(policy.Status.UntaintedNodes).To(BeEquivalentTo(1))
+		Expect(policy.Status.BlockedNodes).To(BeEquivalentTo(1))
+		Expect(policy.Status.Nodes).To(HaveLen(1))
+		Expect(policy.Status.Nodes[0].Name).To(Equal("status-node-a"))
+		Expect(policy.Status.Nodes[0].Conditions).To(ConsistOf(HaveField("Reason", untaintv1alpha1.ReasonWorkloadUnready)))
+		Expect(policy.Status.Conditions).To(ConsistOf(And(
+			HaveField("Type", untaintv1alpha1.ConditionBlockedNodes),
+			HaveField("Reason", untaintv1alpha1.ReasonWorkloadUnready),
+		)))
+	})
+
+	It("should move blocked nodes to untainted once their taint is gone", func() {
+		reporter.SetBlocked("status-node-a", untaintv1alpha1.ReasonWorkloadUnready, "pod default/agent is not ready")
+		reporter.ClearBlocked("status-node-a")
+		// Nodes that were never blocked are not tracked
+		reporter.ClearBlocked("status-node-b")
+		Expect(reporter.flush(ctx)).To(Succeed())
+
+		policy := &untaintv1alpha1.UntaintPolicy{}
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: "test-policy"}, policy)).To(Succeed())
+		Expect(policy.Status.MatchingNodes).To(BeEquivalentTo(1))
+		Expect(policy.Status.UntaintedNodes).To(BeEquivalentTo(1))
+		Expect(policy.Status.Nodes).To(BeEmpty())
+	})
+})
+
+var _ = Describe("blockedNodesCondition", func() {
+	It("should report no blocked nodes", func() {
+		condition := blockedNodesCondition(map[string]nodeState{"a": {untainted: true}})
+		Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+		Expect(condition.Reason).To(Equal(untaintv1alpha1.ReasonNoNodesBlocked))
+	})
+
+	It("should roll the blocking reasons up and name the node blocked longest", func() {
+		now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+		condition := blockedNodesCondition(map[string]nodeState{
+			"a": {untainted: true},
+			"b": {reason: untaintv1alpha1.ReasonWorkloadUnready, message: "pod kube-system/agent-b is not ready",
+				since: metav1.NewTime(now)},
+			"c": {reason: untaintv1alpha1.ReasonWorkloadUnready, message: "pod kube-system/agent-c is not ready",
+				since: metav1.NewTime(now.Add(time.Minute))},
+			"d": {reason: untaintv1alpha1.ReasonNodeNotReady, message: "node is not ready",
+				since: metav1.NewTime(now.Add(-time.Minute))},
+		})
+		Expect(condition.Type).To(Equal(untaintv1alpha1.ConditionBlockedNodes))
+		Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+		Expect(condition.Reason).To(Equal(untaintv1alpha1.ReasonWorkloadUnready))
+		Expect(condition.Message).To(Equal("3 of 4 matching nodes are blocked (2 WorkloadUnready, 1 NodeNotReady); " +
+			"node d has been blocked longest, since 2025-01-01T11:59:00Z: node is not ready"))
+	})
+
+	It("should keep the transition time while the condition holds", func() {
+		blocked := map[string]nodeState{"a": {reason: untaintv1alpha1.ReasonWorkloadUnready, since: metav1.Now()}}
+		status := buildPolicyStatus(blocked, nil, 0)
+		transition := status.Conditions[0].LastTransitionTime
+
+		blocked["b"] = nodeState{reason: untaintv1alpha1.ReasonNodeNotReady, since: metav1.Now()}
+		status = buildPolicyStatus(blocked, status.Conditions, 0)
+		Expect(status.Conditions).To(HaveLen(1))
+		Expect(status.Conditions[0].LastTransitionTime).To(Equal(transition))
+		Expect(status.Conditions[0].Message).To(HavePrefix("2 of 2 matching nodes are blocked"))
+	})
+
+	It("should only list the nodes blocked longest beyond the cap", func() {
+		now := time.Now()
+		status := buildPolicyStatus(map[string]nodeState{
+			"a": {reason: untaintv1alpha1.ReasonWorkloadUnready, since: metav1.NewTime(now)},
+			"b": {reason: untaintv1alpha1.ReasonWorkloadUnready, since: metav1.NewTime(now.Add(-time.Hour))},
+			"c": {reason: untaintv1alpha1.ReasonWorkloadUnready, since: metav1.NewTime(now.Add(-time.Minute))},
+			"d": {untainted: true},
+		}, nil, 2)
+		Expect(status.MatchingNodes).To(Equal(int32(4)))
+		Expect(status.BlockedNodes).To(Equal(int32(3)))
+		Expect(status.Nodes).To(HaveLen(2))
+		Expect(status.Nodes[0].Name).To(Equal("b"))
+		Expect(status.Nodes[1].Name).To(Equal("c"))
+	})
+})
@@ -0,0 +1,409 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	untaintv1alpha1 "github.com/jslay88/generic-untaint-operator/api/v1alpha1"
+	"github.com/jslay88/generic-untaint-operator/internal/config"
+)
+
+// FieldManager is the field manager used for server-side apply
+const FieldManager = "generic-untaint-operator"
+
+// taintEdit is the change made to the taints with a given key
+type taintEdit struct {
+	// downgradeTo, when set, replaces the effect of the taints instead of
+	// removing them
+	downgradeTo corev1.TaintEffect
+	// effects, when set, limits the edit to the entries with these effects
+	effects []corev1.TaintEffect
+}
+
+// edits reports whether the edit applies to an entry with the given effect
+func (e taintEdit) edits(effect corev1.TaintEffect) bool {
+	return e.effects == nil || slices.Contains(e.effects, effect)
+}
+
+// taintEdits maps taint keys to the change made to them
+type taintEdits map[string]taintEdit
+
+// nodeEdit is the change made to a node in a single write
+type nodeEdit struct {
+	taints taintEdits
+	// uncordon clears spec.unschedulable
+	uncordon bool
+	// setLabels are set on the node
+	setLabels map[string]string
+	// removeLabels are removed from the node unless they are also set
+	removeLabels []string
+	// setAnnotations are set on the node
+	setAnnotations map[string]string
+	// record, when set, is turned into untaint annotations before the write
+	record *untaintRecord
+}
+
+// applyTo makes the edit to node
+func (e nodeEdit) applyTo(node *corev1.Node) {
+	node.Spec.Taints = e.taints.apply(node.Spec.Taints)
+	if e.uncordon {
+		node.Spec.Unschedulable = false
+	}
+	for _, key := range e.removeLabels {
+		delete(node.Labels, key)
+	}
+	if len(e.setLabels) > 0 && node.Labels == nil {
+		node.Labels = make(map[string]string, len(e.setLabels))
+	}
+	for key, value := range e.setLabels {
+		node.Labels[key] = value
+	}
+	if len(e.setAnnotations) > 0 && node.Annotations == nil {
+		node.Annotations = make(map[string]string, len(e.setAnnotations))
+	}
+	for key, value := range e.setAnnotations {
+		node.Annotations[key] = value
+	}
+}
+
+// changes reports whether the edit changes anything on node. The taints,
+// labels and annotations of every rule removed in a reconcile go into one
+// edit, so this decides whether the reconcile writes the node at all.
+func (e nodeEdit) changes(node *corev1.Node) bool {
+	for _, action := range e.taints.actions(node.Spec.Taints) {
+		if action.remove || action.replace != nil {
+			return true
+		}
+	}
+	if e.uncordon && node.Spec.Unschedulable {
+		return true
+	}
+	for key, value := range e.setAnnotations {
+		if current, ok := node.Annotations[key]; !ok || current != value {
+			return true
+		}
+	}
+	return e.relabels(node.Labels)
+}
+
+// taintAction is what happens to a single entry of spec.taints
+type taintAction struct {
+	remove  bool
+	replace *corev1.Taint
+}
+
+// actions returns what happens to each entry of taints. A downgrade can make
+// two entries of the same key identical, in which case the later one is removed.
+func (e taintEdits) actions(taints []corev1.Taint) []taintAction {
+	actions := make([]taintAction, len(taints))
+	kept := make(map[string]bool, len(taints))
+	for i, taint := range taints {
+		if edit, ok := e[taint.Key]; ok && edit.edits(taint.Effect) {
+			if edit.downgradeTo == "" {
+				actions[i].remove = true
+				continue
+			}
+			if edit.downgradeTo != taint.Effect {
+				taint.Effect = edit.downgradeTo
+				// timeAdded is only meaningful for NoExecute taints
+				taint.TimeAdded = nil
+				actions[i].replace = &taint
+			}
+		}
+		id := taint.Key + ":" + string(taint.Effect)
+		if kept[id] {
+			actions[i] = taintAction{remove: true}
+			continue
+		}
+		kept[id] = true
+	}
+	return actions
+}
+
+// apply returns taints with the edits made
+func (e taintEdits) apply(taints []corev1.Taint) []corev1.Taint {
+	newTaints := make([]corev1.Taint, 0, len(taints))
+	for i, action := range e.actions(taints) {
+		switch {
+		case action.remove:
+		case action.replace != nil:
+			newTaints = append(newTaints, *action.replace)
+		default:
+			newTaints = append(newTaints, taints[i])
+		}
+	}
+	return newTaints
+}
+
+// updateNode makes edit to node in a single write using the configured
+// removal strategy. node is updated to the server's response. Nothing is
+// written when the edit doesn't change the node.
+func (r *NodeReconciler) updateNode(
+	ctx context.Context,
+	node *corev1.Node,
+	edit nodeEdit,
+	cfg *config.Config,
+) error {
+	if !edit.changes(node) {
+		return nil
+	}
+	var err error
+	switch cfg.RemovalStrategy {
+	case config.RemovalStrategyApply:
+		err = r.applyNode(ctx, node, edit, cfg.ForceApply)
+	case config.RemovalStrategyJSONPatch:
+		err = r.jsonPatchNode(ctx, node, edit)
+	default:
+		err = r.patchNode(ctx, node, edit)
+	}
+	// Conflicts are counted as they happen, since most are retried
+	switch {
+	case err == nil:
+	case apierrors.IsConflict(err):
+		log.FromContext(ctx).Info("Node kept changing while its taints were updated", "node", node.Name,
+			"reason", untaintv1alpha1.ReasonUpdateConflict, "error", err.Error())
+		r.eventf(node, corev1.EventTypeWarning, untaintv1alpha1.ReasonUpdateConflict,
+			"Failed to update the taints of the node: %v", err)
+	case apierrors.IsNotFound(err):
+		countError(errorNodeNotFound)
+	default:
+		countError(errorNodeUpdate)
+	}
+	return err
+}
+
+// patchNode makes the edit with a merge patch of the edited fields, which
+// leaves concurrent changes to the rest of the node by kubelet or cloud
+// controllers untouched. spec.taints is replaced as a whole, so the patch
+// carries the resourceVersion it was computed from; on a conflict the node is
+// read again and the patch recomputed.
+func (r *NodeReconciler) patchNode(ctx context.Context, node *corev1.Node, edit nodeEdit) error {
+	attempt := 0
+	conflict := func(err error) bool { return nodeConflict(config.RemovalStrategyPatch, err) }
+	err := retry.OnError(retry.DefaultRetry, conflict, func() error {
+		if attempt > 0 {
+			countRetry(config.RemovalStrategyPatch)
+			if err := r.apiReader().Get(ctx, client.ObjectKeyFromObject(node), node); err != nil {
+				return err
+			}
+		}
+		attempt++
+		// Someone else may have made the change since
+		if !edit.changes(node) {
+			return nil
+		}
+
+		patch := client.MergeFromWithOptions(node.DeepCopy(), client.MergeFromWithOptimisticLock{})
+		edit.applyTo(node)
+		if err := r.waitNodeWrite(ctx); err != nil {
+			return err
+		}
+		countWrite(config.RemovalStrategyPatch)
+		return r.Patch(ctx, node, patch)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to patch node: %w", err)
+	}
+	return nil
+}
+
+// jsonPatchNode makes the edit with a JSON patch (RFC 6902) that tests each
+// taint entry before removing or replacing it by index. If another actor
+// changed the taints array in the meantime the test fails, nothing is
+// changed, and the patch is recomputed from a fresh read of the node.
+func (r *NodeReconciler) jsonPatchNode(ctx context.Context, node *corev1.Node, edit nodeEdit) error {
+	attempt := 0
+	// The API server rejects a patch whose test operation fails as invalid
+	retriable := func(err error) bool {
+		if apierrors.IsInvalid(err) {
+			countConflict(config.RemovalStrategyJSONPatch)
+			return true
+		}
+		return nodeConflict(config.RemovalStrategyJSONPatch, err)
+	}
+	err := retry.OnError(retry.DefaultRetry, retriable, func() error {
+		if attempt > 0 {
+			countRetry(config.RemovalStrategyJSONPatch)
+			if err := r.apiReader().Get(ctx, client.ObjectKeyFromObject(node), node); err != nil {
+				return err
+			}
+		}
+		attempt++
+
+		ops := taintPatchOps(node.Spec.Taints, edit.taints)
+		ops = append(ops, labelPatchOps(node.Labels, edit)...)
+		ops = append(ops, metadataPatchOps("/metadata/annotations", node.Annotations, edit.setAnnotations, nil)...)
+		if edit.uncordon && node.Spec.Unschedulable {
+			ops = append(ops,
+				jsonPatchOp{Op: "test", Path: "/spec/unschedulable", Value: true},
+				jsonPatchOp{Op: "replace", Path: "/spec/unschedulable", Value: false},
+			)
+		}
+		if len(ops) == 0 {
+			return nil
+		}
+		data, err := json.Marshal(ops)
+		if err != nil {
+			return fmt.Errorf("failed to encode patch: %w", err)
+		}
+		if err := r.waitNodeWrite(ctx); err != nil {
+			return err
+		}
+		countWrite(config.RemovalStrategyJSONPatch)
+		return r.Patch(ctx, node, client.RawPatch(types.JSONPatchType, data))
+	})
+	if err != nil {
+		return fmt.Errorf("failed to patch node: %w", err)
+	}
+	return nil
+}
+
+// jsonPatchOp is a single RFC 6902 operation
+type jsonPatchOp struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// taintPatchOps builds a test operation followed by a remove or replace
+// operation for every edited taint. Entries are handled from the highest index
+// down so removals keep earlier indices valid.
+func taintPatchOps(taints []corev1.Taint, edits taintEdits) []jsonPatchOp {
+	actions := edits.actions(taints)
+	var ops []jsonPatchOp
+	for i := len(taints) - 1; i >= 0; i-- {
+		path := fmt.Sprintf("/spec/taints/%d", i)
+		switch {
+		case actions[i].remove:
+			ops = append(ops,
+				jsonPatchOp{Op: "test", Path: path, Value: &taints[i]},
+				jsonPatchOp{Op: "remove", Path: path},
+			)
+		case actions[i].replace != nil:
+			ops = append(ops,
+				jsonPatchOp{Op: "test", Path: path, Value: &taints[i]},
+				jsonPatchOp{Op: "replace", Path: path, Value: actions[i].replace},
+			)
+		}
+	}
+	return ops
+}
+
+// applyNode sets spec.taints, the labels and annotations to set, and
+// spec.unschedulable when uncordoning, with server-side apply. spec.taints is
+// an atomic list, so the operator's field manager takes ownership of the whole
+// list; if another manager owns it, the apply fails with a conflict naming
+// that manager unless force is set. As the whole list is written, the apply
+// carries the resourceVersion it was computed from; on a conflict the node is
+// read again and the list recomputed.
+func (r *NodeReconciler) applyNode(ctx context.Context, node *corev1.Node, edit nodeEdit, force bool) error {
+	opts := []client.PatchOption{client.FieldOwner(FieldManager)}
+	if force {
+		opts = append(opts, client.ForceOwnership)
+	}
+	attempt := 0
+	// Conflicts with another field manager are not solved by reading again
+	retriable := func(err error) bool {
+		return !fieldManagerConflict(err) && nodeConflict(config.RemovalStrategyApply, err)
+	}
+	err := retry.OnError(retry.DefaultRetry, retriable, func() error {
+		if attempt > 0 {
+			countRetry(config.RemovalStrategyApply)
+			if err := r.apiReader().Get(ctx, client.ObjectKeyFromObject(node), node); err != nil {
+				return err
+			}
+		}
+		attempt++
+		// Someone else may have made the change since
+		if !edit.changes(node) {
+			return nil
+		}
+
+		apply, err := applyConfiguration(node, edit)
+		if err != nil {
+			return err
+		}
+		if err := r.waitNodeWrite(ctx); err != nil {
+			return err
+		}
+		countWrite(config.RemovalStrategyApply)
+		if err := r.Patch(ctx, apply, client.Apply, opts...); err != nil {
+			return err
+		}
+		return runtime.DefaultUnstructuredConverter.FromUnstructured(apply.Object, node)
+	})
+	switch {
+	case err == nil:
+		return nil
+	case fieldManagerConflict(err):
+		countConflict(config.RemovalStrategyApply)
+		return fmt.Errorf("spec.taints is managed by another field manager, "+
+			"set forceApply to take ownership: %w", err)
+	default:
+		return fmt.Errorf("failed to apply node taints: %w", err)
+	}
+}
+
+// applyConfiguration returns the apply configuration making edit to node, as
+// of the resourceVersion of node
+func applyConfiguration(node *corev1.Node, edit nodeEdit) (*unstructured.Unstructured, error) {
+	taints := edit.taints.apply(node.Spec.Taints)
+	unstructuredTaints := make([]interface{}, 0, len(taints))
+	for i := range taints {
+		taint, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&taints[i])
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert taint: %w", err)
+		}
+		unstructuredTaints = append(unstructuredTaints, taint)
+	}
+
+	spec := map[string]interface{}{
+		"taints": unstructuredTaints,
+	}
+	if edit.uncordon {
+		spec["unschedulable"] = false
+	}
+	metadata := map[string]interface{}{
+		"name":            node.Name,
+		"resourceVersion": node.ResourceVersion,
+	}
+	if len(edit.setLabels) > 0 {
+		metadata["labels"] = unstructuredStrings(edit.setLabels)
+	}
+	if len(edit.setAnnotations) > 0 {
+		metadata["annotations"] = unstructuredStrings(edit.setAnnotations)
+	}
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Node",
+		"metadata":   metadata,
+		"spec":       spec,
+	}}, nil
+}
+
+// fieldManagerConflict reports whether err is an apply conflict with the
+// fields of another field manager
+func fieldManagerConflict(err error) bool {
+	return apierrors.IsConflict(err) && apierrors.HasStatusCause(err, metav1.CauseTypeFieldManagerConflict)
+}
+
+// unstructuredStrings converts a string map for use in an unstructured object
+func unstructuredStrings(values map[string]string) map[string]interface{} {
+	converted := make(map[string]interface{}, len(values))
+	for key, value := range values {
+		converted[key] = value
+	}
+	return converted
+}
@@ -0,0 +1,86 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+var _ = Describe("applyNode", func() {
+	const target = "example.com/not-ready"
+
+	It("should apply as of the node's resourceVersion and recompute the taints on a conflict", func() {
+		node := &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "node"},
+			Spec:       corev1.NodeSpec{Taints: []corev1.Taint{{Key: target, Effect: corev1.TaintEffectNoSchedule}}},
+		}
+		var applied []*unstructured.Unstructured
+		c := fake.NewClientBuilder().WithObjects(node).WithInterceptorFuncs(interceptor.Funcs{
+			Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch,
+				opts ...client.PatchOption) error {
+				apply := obj.(*unstructured.Unstructured)
+				applied = append(applied, apply.DeepCopy())
+				if len(applied) > 1 {
+					return nil
+				}
+				// Another actor adds a taint the cached node doesn't have yet
+				current := &corev1.Node{}
+				Expect(c.Get(ctx, client.ObjectKey{Name: "node"}, current)).To(Succeed())
+				current.Spec.Taints = append(current.Spec.Taints,
+					corev1.Taint{Key: "example.com/added", Effect: corev1.TaintEffectNoExecute})
+				Expect(c.Update(ctx, current)).To(Succeed())
+				return apierrors.NewConflict(schema.GroupResource{Resource: "nodes"}, "node",
+					apierrors.NewBadRequest("the object has been modified"))
+			},
+		}).Build()
+		Expect(c.Get(context.Background(), client.ObjectKeyFromObject(node), node)).To(Succeed())
+		staleVersion := node.ResourceVersion
+		r := &NodeReconciler{Client: c}
+
+		edit := nodeEdit{taints: taintEdits{target: {}}}
+		Expect(r.applyNode(context.Background(), node, edit, true)).To(Succeed())
+		Expect(applied).To(HaveLen(2))
+
+		version, _, _ := unstructured.NestedString(applied[0].Object, "metadata", "resourceVersion")
+		Expect(version).To(Equal(staleVersion))
+		taints, _, _ := unstructured.NestedSlice(applied[0].Object, "spec", "taints")
+		Expect(taints).To(BeEmpty())
+
+		version, _, _ = unstructured.NestedString(applied[1].Object, "metadata", "resourceVersion")
+		Expect(version).NotTo(Equal(staleVersion))
+		taints, _, _ = unstructured.NestedSlice(applied[1].Object, "spec", "taints")
+		Expect(taints).To(HaveLen(1))
+		Expect(taints[0]).To(HaveKeyWithValue("key", "example.com/added"))
+	})
+
+	It("should not retry a conflict with another field manager", func() {
+		node := &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "node", ResourceVersion: "1"},
+			Spec:       corev1.NodeSpec{Taints: []corev1.Taint{{Key: target, Effect: corev1.TaintEffectNoSchedule}}},
+		}
+		patches := 0
+		c := fake.NewClientBuilder().WithInterceptorFuncs(interceptor.Funcs{
+			Patch: func(context.Context, client.WithWatch, client.Object, client.Patch, ...client.PatchOption) error {
+				patches++
+				err := apierrors.NewConflict(schema.GroupResource{Resource: "nodes"}, "node",
+					apierrors.NewBadRequest("conflict with \"kubectl\""))
+				err.ErrStatus.Details.Causes = []metav1.StatusCause{{Type: metav1.CauseTypeFieldManagerConflict}}
+				return err
+			},
+		}).Build()
+		r := &NodeReconciler{Client: c}
+
+		err := r.applyNode(context.Background(), node, nodeEdit{taints: taintEdits{target: {}}}, false)
+		Expect(err).To(MatchError(ContainSubstring("set forceApply to take ownership")))
+		Expect(patches).To(Equal(1))
+	})
+})
@@ -0,0 +1,31 @@
+package controller
+
+import (
+	"fmt"
+	"time"
+
+	untaintv1alpha1 "github.com/jslay88/generic-untaint-operator/api/v1alpha1"
+	"github.com/jslay88/generic-untaint-operator/internal/config"
+)
+
+// untaintWindowClosed returns why the untaint windows rule out removing taints
+// at now, retrying once they may allow it again, or nil when they don't
+func untaintWindowClosed(windows *config.UntaintWindows, now time.Time) *blockReason {
+	blocked, after := windows.Blocked(now)
+	if !blocked {
+		return nil
+	}
+	message := "an untaint window pauses untainting"
+	if windows.Mode == config.WindowModeAllow {
+		message = "untainting is only allowed during an untaint window"
+	}
+	if after > 0 {
+		message = fmt.Sprintf("%s for another %s", message, after.Round(time.Second))
+	}
+	return &blockReason{
+		reason:     untaintv1alpha1.ReasonUntaintPaused,
+		message:    message,
+		retryAfter: after,
+		paused:     true,
+	}
+}
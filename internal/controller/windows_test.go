@@ -0,0 +1,44 @@
+package controller
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	untaintv1alpha1 "github.com/jslay88/generic-untaint-operator/api/v1alpha1"
+	"github.com/jslay88/generic-untaint-operator/internal/config"
+)
+
+var _ = Describe("untaintWindowClosed", func() {
+	windows := &config.UntaintWindows{
+		Mode:     config.WindowModePause,
+		TimeZone: "UTC",
+		Windows:  []config.Window{{Schedule: "0 22 * * *", Duration: metav1.Duration{Duration: 2 * time.Hour}}},
+	}
+	during := time.Date(2025, 6, 5, 23, 0, 0, 0, time.UTC)
+	outside := time.Date(2025, 6, 5, 12, 0, 0, 0, time.UTC)
+
+	It("should not pause untainting without windows", func() {
+		Expect(untaintWindowClosed(nil, during)).To(BeNil())
+	})
+
+	It("should pause untainting until the window closes", func() {
+		reason := untaintWindowClosed(windows, during)
+		Expect(reason).NotTo(BeNil())
+		Expect(reason.reason).To(Equal(untaintv1alpha1.ReasonUntaintPaused))
+		Expect(reason.retryAfter).To(Equal(time.Hour))
+		Expect(untaintWindowClosed(windows, outside)).To(BeNil())
+	})
+
+	It("should only allow untainting during windows in Allow mode", func() {
+		allow := *windows
+		allow.Mode = config.WindowModeAllow
+		Expect(untaintWindowClosed(&allow, during)).To(BeNil())
+		reason := untaintWindowClosed(&allow, outside)
+		Expect(reason).NotTo(BeNil())
+		Expect(reason.message).To(ContainSubstring("only allowed"))
+		Expect(reason.retryAfter).To(Equal(10 * time.Hour))
+	})
+})
@@ -0,0 +1,22 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// waitNodeWrite blocks until NodeWriteLimiter allows another write to a node,
+// so however many workers untaint at once, the writes reach the API server at
+// the configured rate. It returns an error when ctx ends first.
+func (r *NodeReconciler) waitNodeWrite(ctx context.Context) error {
+	if r.NodeWriteLimiter == nil {
+		return nil
+	}
+	start := time.Now()
+	if err := r.NodeWriteLimiter.Wait(ctx); err != nil {
+		return fmt.Errorf("failed to wait for the node write rate limit: %w", err)
+	}
+	nodeWriteWaitSeconds.Add(time.Since(start).Seconds())
+	return nil
+}
@@ -0,0 +1,37 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"k8s.io/client-go/util/flowcontrol"
+)
+
+var _ = Describe("waitNodeWrite", func() {
+	It("should not wait without a limiter", func() {
+		r := &NodeReconciler{}
+		Expect(r.waitNodeWrite(context.Background())).To(Succeed())
+	})
+
+	It("should hold writes to the configured rate", func() {
+		r := &NodeReconciler{NodeWriteLimiter: flowcontrol.NewTokenBucketRateLimiter(20, 1)}
+		before := testutil.ToFloat64(nodeWriteWaitSeconds)
+		start := time.Now()
+		for range 3 {
+			Expect(r.waitNodeWrite(context.Background())).To(Succeed())
+		}
+		Expect(time.Since(start)).To(BeNumerically(">=", 90*time.Millisecond))
+		Expect(testutil.ToFloat64(nodeWriteWaitSeconds)).To(BeNumerically(">", before))
+	})
+
+	It("should give up when the context ends first", func() {
+		r := &NodeReconciler{NodeWriteLimiter: flowcontrol.NewTokenBucketRateLimiter(0.01, 1)}
+		Expect(r.waitNodeWrite(context.Background())).To(Succeed())
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		Expect(r.waitNodeWrite(ctx)).NotTo(Succeed())
+	})
+})
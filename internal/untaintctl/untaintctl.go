@@ -0,0 +1,211 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package untaintctl implements the commands of the kubectl-untaint plugin
+package untaintctl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	"github.com/jslay88/generic-untaint-operator/internal/controller"
+)
+
+// StatusClient reads the operator's status API
+type StatusClient struct {
+	// URL is the base URL of the API, e.g. https://localhost:8444
+	URL string
+	// Token, when set, is sent as a bearer token
+	Token string
+	// HTTP sends the requests, http.DefaultClient when unset
+	HTTP *http.Client
+}
+
+// BlockedNodes returns the nodes keeping their target taints, longest blocked
+// first
+func (s *StatusClient) BlockedNodes(ctx context.Context) ([]controller.BlockedNode, error) {
+	var nodes []controller.BlockedNode
+	if err := s.get(ctx, "nodes", &nodes); err != nil {
+		return nil, err
+	}
+	return nodes, nil
+}
+
+// BlockedNode returns why node keeps its target taints
+func (s *StatusClient) BlockedNode(ctx context.Context, node string) (*controller.BlockedNode, error) {
+	blocked := &controller.BlockedNode{}
+	if err := s.get(ctx, "nodes/"+url.PathEscape(node), blocked); err != nil {
+		return nil, err
+	}
+	return blocked, nil
+}
+
+// get decodes the JSON served at path of the API into out
+func (s *StatusClient) get(ctx context.Context, path string, out interface{}) error {
+	endpoint := strings.TrimSuffix(s.URL, "/") + controller.StatusAPIPrefix + path
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	if s.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.Token)
+	}
+	httpClient := s.HTTP
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach status API: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&apiErr) == nil && apiErr.Error != "" {
+			return fmt.Errorf("status API answered %s: %s", resp.Status, apiErr.Error)
+		}
+		return fmt.Errorf("status API answered %s", resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode status API answer: %w", err)
+	}
+	return nil
+}
+
+// PrintBlocked writes nodes to w as a table
+func PrintBlocked(w io.Writer, nodes []controller.BlockedNode) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "NODE\tTAINTS\tREASON\tBLOCKED FOR\tMESSAGE")
+	for _, node := range nodes {
+		message := node.Message
+		if len(node.Workloads) > 0 {
+			message = "waiting for " + strings.Join(node.Workloads, ", ")
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n",
+			node.Node, strings.Join(node.Taints, ","), node.Reason, node.BlockedFor, message)
+	}
+	return tw.Flush()
+}
+
+// ForceUntaint removes the taints with the given keys from node, whatever
+// the operator's rules say, and returns the keys it removed
+func ForceUntaint(ctx context.Context, c client.Client, name string, keys []string) ([]string, error) {
+	var removed []string
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		removed = nil
+		node := &corev1.Node{}
+		if err := c.Get(ctx, types.NamespacedName{Name: name}, node); err != nil {
+			return err
+		}
+		patch := client.MergeFromWithOptions(node.DeepCopy(), client.MergeFromWithOptimisticLock{})
+		taints := make([]corev1.Taint, 0, len(node.Spec.Taints))
+		for _, taint := range node.Spec.Taints {
+			if slices.Contains(keys, taint.Key) {
+				removed = append(removed, taint.Key)
+				continue
+			}
+			taints = append(taints, taint)
+		}
+		if len(removed) == 0 {
+			return nil
+		}
+		node.Spec.Taints = taints
+		return c.Patch(ctx, node, patch)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to untaint node %s: %w", name, err)
+	}
+	return removed, nil
+}
+
+// SetNodePaused sets or removes the annotation pausing untainting of node
+func SetNodePaused(ctx context.Context, c client.Client, name string, paused bool) error {
+	var value interface{}
+	if paused {
+		value = "true"
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{controller.PausedAnnotation: value},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	node := &corev1.Node{}
+	node.Name = name
+	if err := c.Patch(ctx, node, client.RawPatch(types.MergePatchType, patch)); err != nil {
+		return fmt.Errorf("failed to annotate node %s: %w", name, err)
+	}
+	return nil
+}
+
+// SetPaused sets or clears paused in the configuration held by key of the
+// operator's ConfigMap, which the operator reloads. The YAML is rewritten, so
+// comments in it are lost.
+func SetPaused(ctx context.Context, c client.Client, name types.NamespacedName, key string, paused bool) error {
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cm := &corev1.ConfigMap{}
+		if err := c.Get(ctx, name, cm); err != nil {
+			return err
+		}
+		cfg := map[string]interface{}{}
+		if err := yaml.Unmarshal([]byte(cm.Data[key]), &cfg); err != nil {
+			return fmt.Errorf("failed to parse key %s: %w", key, err)
+		}
+		if cfg == nil {
+			cfg = map[string]interface{}{}
+		}
+		current, _ := cfg["paused"].(bool)
+		if current == paused {
+			return nil
+		}
+		if paused {
+			cfg["paused"] = true
+		} else {
+			delete(cfg, "paused")
+		}
+		data, err := yaml.Marshal(cfg)
+		if err != nil {
+			return err
+		}
+		if cm.Data == nil {
+			cm.Data = map[string]string{}
+		}
+		cm.Data[key] = string(data)
+		return c.Update(ctx, cm)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update ConfigMap %s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package untaintctl
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/jslay88/generic-untaint-operator/internal/controller"
+)
+
+var _ = Describe("StatusClient", func() {
+	var server *httptest.Server
+	var auth string
+
+	BeforeEach(func() {
+		mux := http.NewServeMux()
+		mux.HandleFunc("GET /untaint/v1/nodes", func(w http.ResponseWriter, req *http.Request) {
+			auth = req.Header.Get("Authorization")
+			_, _ = w.Write([]byte(`[{"node":"node-a","taints":["example.com/not-ready"],"reason":"WorkloadUnready",` +
+				`"workloads":["kube-system/agent"],"blockedFor":"2m0s"}]`))
+		})
+		mux.HandleFunc("GET /untaint/v1/nodes/{name}", func(w http.ResponseWriter, req *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":"node ` + req.PathValue("name") + ` keeps no target taints"}`))
+		})
+		server = httptest.NewTLSServer(mux)
+		DeferCleanup(server.Close)
+	})
+
+	It("should list the blocked nodes with the bearer token", func() {
+		s := &StatusClient{URL: server.URL + "/", Token: "secret", HTTP: server.Client()}
+		nodes, err := s.BlockedNodes(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(auth).To(Equal("Bearer secret"))
+		Expect(nodes).To(HaveLen(1))
+		Expect(nodes[0].Node).To(Equal("node-a"))
+
+		var out bytes.Buffer
+		Expect(PrintBlocked(&out, nodes)).To(Succeed())
+		Expect(out.String()).To(ContainSubstring("NODE"))
+		Expect(out.String()).To(ContainSubstring("waiting for kube-system/agent"))
+	})
+
+	It("should return the error of the API", func() {
+		s := &StatusClient{URL: server.URL, HTTP: server.Client()}
+		_, err := s.BlockedNode(context.Background(), "node-b")
+		Expect(err).To(MatchError(ContainSubstring("node node-b keeps no target taints")))
+	})
+})
+
+var _ = Describe("ForceUntaint", func() {
+	It("should remove only the given taints", func() {
+		node := &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "node"},
+			Spec: corev1.NodeSpec{Taints: []corev1.Taint{
+				{Key: "example.com/not-ready", Effect: corev1.TaintEffectNoSchedule},
+				{Key: "dedicated", Value: "gpu", Effect: corev1.TaintEffectNoSchedule},
+			}},
+		}
+		c := fake.NewClientBuilder().WithObjects(node).Build()
+		removed, err := ForceUntaint(context.Background(), c, "node", []string{"example.com/not-ready", "absent"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(removed).To(Equal([]string{"example.com/not-ready"}))
+
+		Expect(c.Get(context.Background(), types.NamespacedName{Name: "node"}, node)).To(Succeed())
+		Expect(node.Spec.Taints).To(HaveLen(1))
+		Expect(node.Spec.Taints[0].Key).To(Equal("dedicated"))
+
+		removed, err = ForceUntaint(context.Background(), c, "node", []string{"example.com/not-ready"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(removed).To(BeEmpty())
+	})
+})
+
+var _ = Describe("pausing", func() {
+	It("should annotate and unannotate a node", func() {
+		c := fake.NewClientBuilder().WithObjects(&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node"}}).Build()
+		node := &corev1.Node{}
+
+		Expect(SetNodePaused(context.Background(), c, "node", true)).To(Succeed())
+		Expect(c.Get(context.Background(), types.NamespacedName{Name: "node"}, node)).To(Succeed())
+		Expect(node.Annotations).To(HaveKeyWithValue(controller.PausedAnnotation, "true"))
+
+		Expect(SetNodePaused(context.Background(), c, "node", false)).To(Succeed())
+		node = &corev1.Node{}
+		Expect(c.Get(context.Background(), types.NamespacedName{Name: "node"}, node)).To(Succeed())
+		Expect(node.Annotations).NotTo(HaveKey(controller.PausedAnnotation))
+	})
+
+	It("should set and clear paused in the ConfigMap", func() {
+		name := types.NamespacedName{Namespace: "system", Name: "untaint-config"}
+		c := fake.NewClientBuilder().WithObjects(&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: name.Namespace, Name: name.Name},
+			Data:       map[string]string{"config.yaml": "readinessMode: PodReady\n"},
+		}).Build()
+		cm := &corev1.ConfigMap{}
+
+		Expect(SetPaused(context.Background(), c, name, "config.yaml", true)).To(Succeed())
+		Expect(c.Get(context.Background(), name, cm)).To(Succeed())
+		Expect(cm.Data["config.yaml"]).To(Equal("paused: true\nreadinessMode: PodReady\n"))
+
+		Expect(SetPaused(context.Background(), c, name, "config.yaml", false)).To(Succeed())
+		Expect(c.Get(context.Background(), name, cm)).To(Succeed())
+		Expect(cm.Data["config.yaml"]).To(Equal("readinessMode: PodReady\n"))
+	})
+})
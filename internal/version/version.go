@@ -0,0 +1,83 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package version describes the build of the operator. Version, Commit and
+// Date are set at build time with
+//
+//	-ldflags "-X github.com/jslay88/generic-untaint-operator/internal/version.Version=v1.2.3 ..."
+//
+// and otherwise fall back to what the Go toolchain recorded in the binary.
+package version
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	// Version is the released version of the operator
+	Version = "dev"
+	// Commit is the git commit the operator was built from
+	Commit = ""
+	// Date is when the operator was built, in RFC 3339 form
+	Date = ""
+)
+
+// buildInfoGauge is always 1, labelled with the build of the operator, to
+// track which versions run across a fleet
+var buildInfoGauge = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "untaint_operator_build_info",
+		Help: "Always 1, labelled with the version, commit, build date and Go version of the operator",
+	},
+	[]string{"version", "commit", "date", "goversion"},
+)
+
+func init() {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && Commit == "":
+				Commit = setting.Value
+			case setting.Key == "vcs.time" && Date == "":
+				Date = setting.Value
+			}
+		}
+	}
+	if Commit == "" {
+		Commit = "unknown"
+	}
+	if Date == "" {
+		Date = "unknown"
+	}
+
+	metrics.Registry.MustRegister(buildInfoGauge)
+	buildInfoGauge.WithLabelValues(Version, Commit, Date, runtime.Version()).Set(1)
+}
+
+// String describes the build on one line
+func String() string {
+	return fmt.Sprintf("generic-untaint-operator %s (commit %s, built %s, %s)", Version, Commit, Date, runtime.Version())
+}
+
+// KeysAndValues describes the build as logging key/value pairs
+func KeysAndValues() []interface{} {
+	return []interface{}{"version", Version, "commit", Commit, "date", Date, "goVersion", runtime.Version()}
+}